@@ -42,6 +42,8 @@ type dependencies struct {
 	Collector       *collector.Collector
 	ShutdownHandler *shutdown.ShutdownHandler
 	Echo            *echo.Echo
+	// ConfigFilePath is the path of the config file given on the command line
+	ConfigFilePath *string `name:"appConfigFilePath"`
 }
 
 var (
@@ -92,7 +94,9 @@ func run() error {
 	if err := CoreComponent.Daemon().BackgroundWorker("Collector", func(ctx context.Context) {
 		CoreComponent.LogInfo("Starting Collector ...")
 
+		collectorDone := make(chan struct{})
 		go func() {
+			defer close(collectorDone)
 			err := deps.Collector.Run(ctx)
 			if err != nil {
 				deps.ShutdownHandler.SelfShutdown(fmt.Sprintf("Collector shut down, error: %s", err), false)
@@ -100,6 +104,12 @@ func run() error {
 		}()
 		close(collectorInitWait)
 
+		// keep the worker alive until the collector finished storing the in-flight blocks
+		<-ctx.Done()
+		CoreComponent.LogInfo("Stopping Collector ...")
+		<-collectorDone
+		CoreComponent.LogInfo("Stopping Collector ... done")
+
 	}, daemon.PriorityStopCollector); err != nil {
 		CoreComponent.LogPanicf("failed to start worker: %s", err)
 	}
@@ -118,7 +128,9 @@ func run() error {
 		CoreComponent.LogInfo("Starting API ... done")
 		CoreComponent.LogInfo("Starting API server ...")
 
-		_ = api.NewServer(deps.Collector, deps.Echo, deps.Collector.WrappedLogger, ctx)
+		if _, err := api.NewServer(deps.Collector, deps.Echo, *ParamsRestAPI, loadParameters, deps.Collector.WrappedLogger, ctx); err != nil {
+			CoreComponent.LogErrorfAndExit("Starting API server ... failed, error: %s", err)
+		}
 
 		go func() {
 			if err := deps.Echo.Start(ParamsRestAPI.BindAddress); err != nil && !errors.Is(err, http.ErrServerClosed) {
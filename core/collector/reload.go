@@ -0,0 +1,48 @@
+package collector
+
+import (
+	"collector/pkg/api"
+	"collector/pkg/collector"
+	"collector/pkg/listener"
+	"collector/pkg/poi"
+	"collector/pkg/storage"
+	"fmt"
+	"os"
+
+	"github.com/iotaledger/hive.go/core/configuration"
+	flag "github.com/spf13/pflag"
+)
+
+// loadParameters reads the parameters of the component again as on startup: the defaults, overridden by the config
+// file, the command line flags and the environment variables. The configuration of the app is left as is.
+func loadParameters() (collector.Parameters, api.Parameters, error) {
+	listenerParams := &listener.Parameters{}
+	storageParams := &storage.Parameters{}
+	restAPIParams := &api.Parameters{}
+	poiParams := &poi.Parameters{}
+
+	flagSet := configuration.NewUnsortedFlagSet("reload", flag.ContinueOnError)
+	config := configuration.New()
+	config.BindParameters(flagSet, "listener", listenerParams)
+	config.BindParameters(flagSet, "POI", poiParams)
+	config.BindParameters(flagSet, "restAPI", restAPIParams)
+	config.BindParameters(flagSet, "storage", storageParams)
+
+	// the command line also holds the flags of the other components
+	flagSet.ParseErrorsWhitelist.UnknownFlags = true
+	if err := flagSet.Parse(os.Args[1:]); err != nil {
+		return collector.Parameters{}, api.Parameters{}, err
+	}
+	if err := config.LoadFile(*deps.ConfigFilePath); err != nil && !os.IsNotExist(err) {
+		return collector.Parameters{}, api.Parameters{}, fmt.Errorf("loading config file '%s' failed: %w", *deps.ConfigFilePath, err)
+	}
+	if err := config.LoadFlagSet(flagSet); err != nil {
+		return collector.Parameters{}, api.Parameters{}, err
+	}
+	if err := config.LoadEnvironmentVars(""); err != nil {
+		return collector.Parameters{}, api.Parameters{}, err
+	}
+	config.UpdateBoundParameters()
+
+	return collector.Parameters{Storage: *storageParams, Listener: *listenerParams, POI: *poiParams}, *restAPIParams, nil
+}
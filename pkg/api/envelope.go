@@ -0,0 +1,108 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// MIMEEnvelope is accepted by the clients asking for their JSON responses wrapped in an envelope.
+const MIMEEnvelope = "application/vnd.collector.envelope+json"
+
+// Envelope wraps a JSON response with the metadata of its request.
+type Envelope struct {
+	Data json.RawMessage `json:"data"`
+	Meta EnvelopeMeta    `json:"meta"`
+}
+
+// EnvelopeMeta describes the request an enveloped response answers.
+type EnvelopeMeta struct {
+	RequestId   string `json:"requestId"`
+	TookMs      int64  `json:"tookMs"`
+	CollectorId string `json:"collectorId,omitempty"`
+}
+
+// collectorId returns the id reported in the envelopes, the host name unless configured.
+func collectorId(params Parameters) string {
+	if params.CollectorId != "" {
+		return params.CollectorId
+	}
+	hostname, _ := os.Hostname()
+	return hostname
+}
+
+// envelopeWriter holds back the JSON responses, to be wrapped once complete. Other responses, e.g. streamed
+// downloads, are written through.
+type envelopeWriter struct {
+	http.ResponseWriter
+	buffering bool
+	status    int
+	body      bytes.Buffer
+}
+
+func (w *envelopeWriter) WriteHeader(status int) {
+	if strings.HasPrefix(w.Header().Get(echo.HeaderContentType), echo.MIMEApplicationJSON) {
+		w.buffering, w.status = true, status
+		return
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *envelopeWriter) Write(b []byte) (int, error) {
+	if w.buffering {
+		return w.body.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *envelopeWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok && !w.buffering {
+		flusher.Flush()
+	}
+}
+
+// envelope wraps the JSON responses in an Envelope when enabled by the configuration or asked for by the Accept
+// header of the request. Enveloped responses are not compressed.
+func (s *Server) envelope(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !s.envelopeResponses && !strings.Contains(c.Request().Header.Get(echo.HeaderAccept), MIMEEnvelope) {
+			return next(c)
+		}
+		start := time.Now()
+		// the body must reach the envelope uncompressed
+		c.Request().Header.Del(echo.HeaderAcceptEncoding)
+		writer := &envelopeWriter{ResponseWriter: c.Response().Writer}
+		c.Response().Writer = writer
+		defer func() { c.Response().Writer = writer.ResponseWriter }()
+
+		err := next(c)
+		if err != nil {
+			// the error response is enveloped too
+			c.Error(err)
+		}
+		if !writer.buffering {
+			return nil
+		}
+
+		data := writer.body.Bytes()
+		if !json.Valid(data) {
+			data = []byte("null")
+		}
+		enveloped, err := json.Marshal(Envelope{
+			Data: data,
+			Meta: EnvelopeMeta{RequestId: requestId(c), TookMs: time.Since(start).Milliseconds(), CollectorId: s.collectorId},
+		})
+		if err != nil {
+			return err
+		}
+		writer.Header().Del(echo.HeaderContentLength)
+		writer.ResponseWriter.WriteHeader(writer.status)
+		_, err = writer.ResponseWriter.Write(enveloped)
+		return err
+	}
+}
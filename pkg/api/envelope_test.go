@@ -0,0 +1,71 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/iotaledger/hive.go/core/logger"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestEnvelope(t *testing.T) {
+	s, e := newTestServer(t, "")
+	s.collectorId = "collector-1"
+	core, logs := observer.New(zapcore.InfoLevel)
+	s.WrappedLogger = logger.NewWrappedLogger(zap.New(core).Sugar())
+	get := func(accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, RouteFilters, nil)
+		if accept != "" {
+			req.Header.Set(echo.HeaderAccept, accept)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// responses are not enveloped by default
+	plain := get("")
+	if plain.Code != http.StatusOK || strings.Contains(plain.Body.String(), `"meta"`) {
+		t.Errorf("got status %d, body %s, expected the plain response", plain.Code, plain.Body)
+	}
+	plainId := plain.Header().Get(echo.HeaderXRequestID)
+	if plainId == "" {
+		t.Error("got no request id, expected one on every response")
+	}
+
+	for _, tc := range []struct {
+		accept      string
+		configured  bool
+		description string
+	}{
+		{MIMEEnvelope, false, "asked for"},
+		{"", true, "configured"},
+	} {
+		s.envelopeResponses = tc.configured
+		rec := get(tc.accept)
+		var envelope Envelope
+		if err := json.Unmarshal(rec.Body.Bytes(), &envelope); err != nil {
+			t.Fatalf("%s: can't decode the envelope %s: %v", tc.description, rec.Body, err)
+		}
+		if rec.Code != http.StatusOK || !json.Valid(envelope.Data) || string(envelope.Data) == "null" {
+			t.Errorf("%s: got status %d, data %s, expected the response in the envelope", tc.description, rec.Code, envelope.Data)
+		}
+		if id := rec.Header().Get(echo.HeaderXRequestID); id == "" || envelope.Meta.RequestId != id || id == plainId {
+			t.Errorf("%s: got request id '%s' in the envelope and '%s' in the header, expected a new id in both", tc.description, envelope.Meta.RequestId, id)
+		}
+		if envelope.Meta.CollectorId != "collector-1" {
+			t.Errorf("%s: got collector id '%s', expected 'collector-1'", tc.description, envelope.Meta.CollectorId)
+		}
+	}
+
+	// the request id is logged
+	if n := logs.FilterMessageSnippet("request '" + plainId + "'").Len(); n != 2 {
+		t.Errorf("got %d log lines with request id '%s', expected 2", n, plainId)
+	}
+}
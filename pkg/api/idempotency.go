@@ -0,0 +1,164 @@
+package api
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/iotaledger/inx-app/httpserver"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// HeaderIdempotencyKey lets a client retry a mutating request without repeating its effects.
+	HeaderIdempotencyKey = "Idempotency-Key"
+	// HeaderIdempotentReplayed is set on the responses replayed for an idempotency key.
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+)
+
+// idempotentResponse is the response recorded for an idempotency key, pending while the first request is served.
+type idempotentResponse struct {
+	key         string
+	fingerprint [sha256.Size]byte
+	pending     bool
+	status      int
+	contentType string
+	body        []byte
+	expiresAt   time.Time
+}
+
+// idempotencyCache keeps the responses of the latest requests sent with an idempotency key, in memory,
+// evicting the oldest ones beyond size.
+type idempotencyCache struct {
+	sync.Mutex
+	ttl     time.Duration
+	size    int
+	entries map[string]*list.Element
+	// order holds the entries from the oldest to the newest, they all live for the same ttl
+	order *list.List
+}
+
+func newIdempotencyCache(params Parameters) *idempotencyCache {
+	if params.IdempotencyCacheSize < 1 || params.IdempotencyKeyTTL <= 0 {
+		return nil
+	}
+	return &idempotencyCache{
+		ttl:     params.IdempotencyKeyTTL,
+		size:    params.IdempotencyCacheSize,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// reserve records a pending entry for key and returns true, or returns the entry already recorded and false.
+func (c *idempotencyCache) reserve(key string, fingerprint [sha256.Size]byte) (idempotentResponse, bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	now := time.Now()
+	for front := c.order.Front(); front != nil && front.Value.(*idempotentResponse).expiresAt.Before(now); front = c.order.Front() {
+		c.remove(front)
+	}
+	if element, ok := c.entries[key]; ok {
+		return *element.Value.(*idempotentResponse), false
+	}
+
+	c.entries[key] = c.order.PushBack(&idempotentResponse{key: key, fingerprint: fingerprint, pending: true, expiresAt: now.Add(c.ttl)})
+	if c.order.Len() > c.size {
+		c.remove(c.order.Front())
+	}
+	return idempotentResponse{}, true
+}
+
+// complete records the response of a pending entry, unless it has been evicted meanwhile.
+func (c *idempotencyCache) complete(key string, status int, contentType string, body []byte) {
+	c.Lock()
+	defer c.Unlock()
+	if element, ok := c.entries[key]; ok {
+		entry := element.Value.(*idempotentResponse)
+		entry.pending, entry.status, entry.contentType, entry.body = false, status, contentType, body
+	}
+}
+
+// release forgets a pending entry, so that the request can be sent again with the same key.
+func (c *idempotencyCache) release(key string) {
+	c.Lock()
+	defer c.Unlock()
+	if element, ok := c.entries[key]; ok {
+		c.remove(element)
+	}
+}
+
+func (c *idempotencyCache) remove(element *list.Element) {
+	delete(c.entries, element.Value.(*idempotentResponse).key)
+	c.order.Remove(element)
+}
+
+// responseRecorder copies the body of a response while it is written.
+type responseRecorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+// idempotent serves the requests carrying an Idempotency-Key header once: a request sent again with the same key,
+// method and path gets the recorded response without being served again. Only successful responses are recorded,
+// a failed request can be retried with its key.
+func (s *Server) idempotent(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		idempotencyKey := c.Request().Header.Get(HeaderIdempotencyKey)
+		if idempotencyKey == "" || s.idempotency == nil {
+			return next(c)
+		}
+		if len(idempotencyKey) > maxIdempotencyKeyLength {
+			return httpserver.JSONResponse(c, http.StatusBadRequest, fmt.Sprintf("idempotency key longer than %d characters", maxIdempotencyKeyLength))
+		}
+
+		body, err := io.ReadAll(c.Request().Body)
+		if err != nil {
+			return httpserver.JSONResponse(c, http.StatusBadRequest, fmt.Sprintf("%v", err))
+		}
+		c.Request().Body = io.NopCloser(bytes.NewReader(body))
+		// a key must not be reused for a request with other parameters
+		fingerprint := sha256.Sum256(append([]byte(c.Request().URL.RawQuery+"\n"), body...))
+		key := c.Request().Method + " " + c.Request().URL.Path + " " + idempotencyKey
+		// the keys of a tenant never replay the responses of another
+		if tenant := tenantOf(c); tenant != nil {
+			key = tenant.Name + " " + key
+		}
+
+		entry, reserved := s.idempotency.reserve(key, fingerprint)
+		if !reserved {
+			switch {
+			case entry.fingerprint != fingerprint:
+				return httpserver.JSONResponse(c, http.StatusUnprocessableEntity, fmt.Sprintf("Idempotency key '%s' was already used with other parameters", idempotencyKey))
+			case entry.pending:
+				return httpserver.JSONResponse(c, http.StatusConflict, fmt.Sprintf("A request with idempotency key '%s' is still being served", idempotencyKey))
+			}
+			s.WrappedLogger.LogDebugf("Replaying the response of idempotency key '%s'", idempotencyKey)
+			c.Response().Header().Set(HeaderIdempotentReplayed, "true")
+			return c.Blob(entry.status, entry.contentType, entry.body)
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Response().Writer}
+		c.Response().Writer = recorder
+		err = next(c)
+		if status := c.Response().Status; err == nil && status >= 200 && status < 300 {
+			s.idempotency.complete(key, status, c.Response().Header().Get(echo.HeaderContentType), recorder.body.Bytes())
+		} else {
+			s.idempotency.release(key)
+		}
+		return err
+	}
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"crypto/sha256"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestIdempotencyKey(t *testing.T) {
+	s, _ := newTestServer(t, "")
+	e := echo.New()
+	served := 0
+	failures := 1
+	e.POST("/job", s.idempotent(func(c echo.Context) error {
+		if failures > 0 {
+			failures--
+			return c.String(http.StatusInternalServerError, "failed")
+		}
+		served++
+		return c.JSON(http.StatusAccepted, map[string]int{"job": served})
+	}))
+	send := func(key string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/job", strings.NewReader(body))
+		req.Header.Set(HeaderIdempotencyKey, key)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// a failed response is not recorded, the request can be retried with its key
+	if rec := send("first", "{}"); rec.Code != http.StatusInternalServerError {
+		t.Fatalf("got status %d, expected %d", rec.Code, http.StatusInternalServerError)
+	}
+	first := send("first", "{}")
+	if first.Code != http.StatusAccepted || first.Header().Get(HeaderIdempotentReplayed) != "" {
+		t.Fatalf("got status %d, replayed '%s', expected %d served", first.Code, first.Header().Get(HeaderIdempotentReplayed), http.StatusAccepted)
+	}
+	replayed := send("first", "{}")
+	if replayed.Code != http.StatusAccepted || replayed.Header().Get(HeaderIdempotentReplayed) != "true" || replayed.Body.String() != first.Body.String() {
+		t.Errorf("got status %d, body %s, expected the response %s replayed", replayed.Code, replayed.Body, first.Body)
+	}
+	if served != 1 {
+		t.Errorf("got %d requests served, expected 1", served)
+	}
+
+	if rec := send("first", `{"other":true}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("got status %d reusing a key with another body, expected %d", rec.Code, http.StatusUnprocessableEntity)
+	}
+	if rec := send("second", "{}"); rec.Code != http.StatusAccepted || served != 2 {
+		t.Errorf("got status %d and %d requests served with another key, expected %d and 2", rec.Code, served, http.StatusAccepted)
+	}
+	if rec := send(strings.Repeat("k", maxIdempotencyKeyLength+1), "{}"); rec.Code != http.StatusBadRequest {
+		t.Errorf("got status %d for a too long key, expected %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestIdempotencyCache(t *testing.T) {
+	if cache := newIdempotencyCache(Parameters{IdempotencyCacheSize: 0, IdempotencyKeyTTL: time.Minute}); cache != nil {
+		t.Errorf("got a cache of size 0, expected none")
+	}
+	cache := newIdempotencyCache(Parameters{IdempotencyCacheSize: 2, IdempotencyKeyTTL: 50 * time.Millisecond})
+	fingerprint := sha256.Sum256(nil)
+	for _, key := range []string{"a", "b", "c"} {
+		if _, reserved := cache.reserve(key, fingerprint); !reserved {
+			t.Fatalf("got key '%s' already reserved, expected a new one", key)
+		}
+	}
+	// the oldest key is evicted beyond the size
+	if _, reserved := cache.reserve("a", fingerprint); !reserved {
+		t.Errorf("got key 'a' still recorded, expected it evicted")
+	}
+	if entry, reserved := cache.reserve("c", fingerprint); reserved || !entry.pending {
+		t.Errorf("got key 'c' reserved: %t, pending: %t, expected it pending", reserved, entry.pending)
+	}
+
+	// the keys expire after the ttl
+	time.Sleep(60 * time.Millisecond)
+	if _, reserved := cache.reserve("c", fingerprint); !reserved {
+		t.Errorf("got key 'c' still recorded after the ttl, expected it expired")
+	}
+}
@@ -1,13 +1,20 @@
 package api
 
-func (s *Server) apiLogStart(route string) {
-	s.WrappedLogger.LogInfof("Serving RestAPI '%s' request ... ", route)
+import "github.com/labstack/echo/v4"
+
+// requestId returns the id of a request, set on its response by the request id middleware.
+func requestId(c echo.Context) string {
+	return c.Response().Header().Get(echo.HeaderXRequestID)
+}
+
+func (s *Server) apiLogStart(c echo.Context, route string) {
+	s.WrappedLogger.LogInfof("Serving RestAPI '%s' request '%s' ... ", route, requestId(c))
 }
 
-func (s *Server) apiLogEnd(route string, err error) {
+func (s *Server) apiLogEnd(c echo.Context, route string, err error) {
 	if err != nil {
-		s.WrappedLogger.LogErrorf("Serving RestAPI '%s' request ... failed, error: %w", route, err)
+		s.WrappedLogger.LogErrorf("Serving RestAPI '%s' request '%s' ... failed, error: %w", route, requestId(c), err)
 	} else {
-		s.WrappedLogger.LogInfof("Serving RestAPI '%s' request ... done", route)
+		s.WrappedLogger.LogInfof("Serving RestAPI '%s' request '%s' ... done", route, requestId(c))
 	}
 }
@@ -1,5 +1,7 @@
 package api
 
+import "time"
+
 // ParametersRestAPI contains the definition of the parameters used by the Collector HTTP server.
 type Parameters struct {
 	// BindAddress defines the bind address on which the Collector HTTP server listens.
@@ -10,4 +12,31 @@ type Parameters struct {
 
 	// DebugRequestLoggerEnabled defines whether the debug logging for requests should be enabled
 	DebugRequestLoggerEnabled bool `default:"false" usage:"whether the debug logging for requests should be enabled"`
+
+	// JobHistorySize defines how many finished background jobs are kept for their status to be queried
+	JobHistorySize int `default:"100" usage:"how many finished background jobs are kept for their status to be queried"`
+
+	// DefaultWithPOI defines whether the requests omitting withPOI store and retrieve blocks with their Proof of Inclusion
+	DefaultWithPOI bool `default:"false" usage:"whether the requests omitting withPOI store and retrieve blocks with their Proof of Inclusion"`
+
+	// Tenants is a json string mapping API keys to the buckets they may use, empty to serve every request
+	Tenants string `default:"" usage:"the tenants of the API as a json string, mapping API keys to the buckets they may use, empty to serve every request"`
+
+	// AllowEmptyInfrastructureBuckets defines whether the POI and events buckets can be emptied through the API
+	AllowEmptyInfrastructureBuckets bool `default:"false" usage:"whether the POI and events buckets can be emptied through the API"`
+
+	// EnvelopeResponses defines whether every JSON response is wrapped in an envelope with the request metadata
+	EnvelopeResponses bool `default:"false" usage:"whether every JSON response is wrapped in an envelope with the request metadata, clients can also ask for it with their Accept header"`
+
+	// CollectorId defines the id of the collector reported in the response envelopes
+	CollectorId string `default:"" usage:"the id of the collector reported in the response envelopes, the host name if empty"`
+
+	// IdempotencyKeyTTL defines how long the response of a request sent with an Idempotency-Key header is replayed
+	IdempotencyKeyTTL time.Duration `default:"1h" usage:"how long the response of a request sent with an Idempotency-Key header is replayed"`
+
+	// IdempotencyCacheSize defines how many idempotency keys are remembered at most, 0 disables them
+	IdempotencyCacheSize int `default:"10000" usage:"how many idempotency keys are remembered at most, 0 disables them"`
+
+	// ExpvarEnabled defines whether the counters of the collector are served with the runtime statistics on /debug/vars
+	ExpvarEnabled bool `default:"false" usage:"whether the counters of the collector are served to admins on /debug/vars, along with the memory statistics and command line of the process"`
 }
@@ -1,42 +1,160 @@
 package api
 
 import (
+	"collector/pkg/storage"
 	"encoding/json"
+	"fmt"
 	"io"
 	"strconv"
 	"strings"
+	"time"
 
-	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
 )
 
 type RequestConstraint interface {
-	RequestSubscribeBody | RequestStoreBody | RequestCreateBucket
+	RequestSubscribeBody | RequestStoreBody | RequestCreateBucket | RequestBackfill | RequestBucketLifecycle | RequestMigrate | RequestSelfCheck | RequestPinBlocks
 }
 
 type RequestSubscribeBody struct {
-	Tag        string `json:"tag" validate:"required"`
-	PublicKey  string `json:"publicKey"`
-	Duration   string `json:"duration"`
-	BucketName string `json:"bucketName"`
-	WithPOI    bool   `json:"withPOI"`
+	Tag          string          `json:"tag" validate:"required_without=MatchAll,excluded_with=MatchAll"`
+	MatchAll     bool            `json:"matchAll"`
+	PublicKey    string          `json:"publicKey"`
+	Duration     string          `json:"duration"`
+	BucketName   string          `json:"bucketName" validate:"omitempty,bucketname"`
+	WithPOI      *bool           `json:"withPOI"`
+	StoreFormat  string          `json:"storeFormat"`
+	Transform    string          `json:"transform" validate:"omitempty,oneof=none json-minify field-redact"`
+	RedactFields []string        `json:"redactFields"`
+	Schema       json.RawMessage `json:"schema"`
+	AliasField   string          `json:"aliasField"`
+	KeyField     string          `json:"keyField"`
+	IndexFields  []string        `json:"indexFields"`
 }
 
 type RequestStoreBody struct {
-	BlockId    string `json:"blockId" validate:"required"`
-	BucketName string `json:"bucketName"`
-	WithPOI    bool   `json:"withPOI"`
+	BlockId       string   `json:"blockId" validate:"required,blockid"`
+	BucketName    string   `json:"bucketName" validate:"omitempty,bucketname"`
+	WithPOI       *bool    `json:"withPOI"`
+	RetentionDays int      `json:"retentionDays" validate:"gte=0"`
+	LegalHold     bool     `json:"legalHold"`
+	Alias         string   `json:"alias" validate:"omitempty,max=256"`
+	IndexTags     []string `json:"indexTags" validate:"max=8,dive,min=1,max=64"`
+	ContentType   string   `json:"contentType" validate:"omitempty,max=255"`
+}
+
+// ResponseHealth tells whether the collector is ready to serve every request.
+type ResponseHealth struct {
+	Ready bool `json:"ready"`
 }
 
 type RequestCreateBucket struct {
-	BucketName    string `json:"bucketName" validate:"required"`
-	LifecycleDays int    `json:"days"`
+	BucketName    string `json:"bucketName" validate:"required,bucketname"`
+	LifecycleDays int    `json:"days" validate:"gte=0"`
+}
+
+type ResponseCreateBucket struct {
+	BucketName    string `json:"bucketName"`
+	AlreadyExists bool   `json:"alreadyExists"`
+	Days          int    `json:"days"`
+}
+
+type RequestBucketLifecycle struct {
+	Days *int `json:"days" validate:"required,gte=0"`
+}
+
+type ResponseBucketLifecycle struct {
+	BucketName string `json:"bucketName"`
+	Days       int    `json:"days"`
+}
+
+type RequestBackfill struct {
+	From uint32 `json:"from" validate:"required"`
+	To   uint32 `json:"to" validate:"required,gtefield=From"`
+	Tag  string `json:"tag"`
+}
+
+// RequestMigrate selects the objects to rewrite with the configured extension and encoding.
+type RequestMigrate struct {
+	BucketName    string `json:"bucketName" validate:"required,bucketname"`
+	FromExtension string `json:"fromExtension"`
+}
+
+// RequestSelfCheck selects the bucket to check.
+type RequestSelfCheck struct {
+	BucketName string `json:"bucketName" validate:"required,bucketname"`
+}
+
+// RequestPinBlocks selects the objects to pin or unpin, by block id or by prefix of their block id.
+type RequestPinBlocks struct {
+	BlockIds []string `json:"blockIds" validate:"required_without=Prefix,excluded_with=Prefix,dive,blockid"`
+	Prefix   string   `json:"prefix"`
+	Unpin    bool     `json:"unpin"`
+}
+
+const (
+	defaultEventsLimit = 100
+	maxEventsLimit     = 1000
+
+	defaultTaggedLimit = 100
+	maxTaggedLimit     = 1000
+)
+
+// ResponseEvents is a page of the event log, Next is the offset to read the following page from.
+type ResponseEvents struct {
+	Events []storage.Event `json:"events"`
+	Next   uint64          `json:"next"`
+}
+
+// ResponseTaggedBlocks is a page of the blocks indexed under a tag, Next is the name to list the following page after,
+// empty on the last page.
+type ResponseTaggedBlocks struct {
+	Tag      string   `json:"tag"`
+	BlockIds []string `json:"blockIds"`
+	Next     string   `json:"next,omitempty"`
+}
+
+// ResponseBlockMetadata describes a stored block without its content.
+type ResponseBlockMetadata struct {
+	BlockId      string            `json:"blockId"`
+	BucketName   string            `json:"bucketName"`
+	Size         int64             `json:"size"`
+	ETag         string            `json:"etag"`
+	LastModified time.Time         `json:"lastModified"`
+	ContentType  string            `json:"contentType"`
+	VersionId    string            `json:"versionId,omitempty"`
+	UserMetadata map[string]string `json:"userMetadata,omitempty"`
+	WithPOI      bool              `json:"withPOI"`
+	// PayloadContentType is the content type of the payload of the block, if recorded
+	PayloadContentType string `json:"payloadContentType,omitempty"`
+}
+
+const (
+	// VerifyResultMatch means the stored block is the one known by the node.
+	VerifyResultMatch = "match"
+	// VerifyResultMismatch means the stored block differs from the one known by the node.
+	VerifyResultMismatch = "mismatch"
+	// VerifyResultNodePruned means the node no longer knows the block, so the stored copy can't be compared.
+	VerifyResultNodePruned = "node-pruned"
+)
+
+// ResponseVerifyBlock is the result of comparing a stored block with the one known by the node.
+type ResponseVerifyBlock struct {
+	BlockId        string `json:"blockId"`
+	BucketName     string `json:"bucketName"`
+	Result         string `json:"result"`
+	NodeKnowsBlock bool   `json:"nodeKnowsBlock"`
+	// StoredIdMatches tells whether the ID derived from the stored block is the requested one, unset without a block.
+	StoredIdMatches *bool  `json:"storedIdMatches,omitempty"`
+	Reason          string `json:"reason,omitempty"`
 }
 
 type ObjectParams struct {
 	BlockId    string
 	BucketName string
 	WithPOI    bool
+	VersionId  string
+	Raw        bool
 }
 
 func extractRequestBody[Request RequestConstraint](request *Request, c echo.Context) error {
@@ -49,24 +167,75 @@ func extractRequestBody[Request RequestConstraint](request *Request, c echo.Cont
 	if err != nil {
 		return err
 	}
-	err = validator.New().Struct(request)
+	err = validate.Struct(request)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+func extractBlockIdList(c echo.Context) ([]string, error) {
+	var blockIds []string
+	err := json.NewDecoder(c.Request().Body).Decode(&blockIds)
+	if err != nil {
+		return nil, err
+	}
+	if len(blockIds) == 0 {
+		return nil, fmt.Errorf("no block ids provided")
+	}
+	for i, blockId := range blockIds {
+		blockIds[i], err = normalizeBlockId(blockId)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return blockIds, nil
+}
+
+// withPOI returns whether a request body asks for the Proof of Inclusion, the server default if it doesn't say.
+func (s *Server) withPOI(requested *bool) bool {
+	if requested == nil {
+		return s.defaultWithPOI
+	}
+	return *requested
+}
+
 func (s Server) parseObjectInput(c echo.Context) (ObjectParams, error) {
 	var params ObjectParams
-	params.BlockId = strings.ToLower(c.Param(ParameterBlockID))
-	params.BucketName = s.Collector.Storage.DefaultBucketName
+	var err error
+	// routes acting on several blocks have no block id in their path
+	if c.Param(ParameterBlockID) != "" {
+		params.BlockId, err = normalizeObjectName(c.Param(ParameterBlockID), s.Collector.Listener.UsesKeyField())
+		if err != nil {
+			return params, err
+		}
+	}
+	params.BucketName = s.defaultBucket(c)
+	params.WithPOI = s.defaultWithPOI
 
-	err := c.Request().ParseForm()
+	err = c.Request().ParseForm()
 	if err != nil {
 		return params, err
 	}
 	if c.Request().Form.Has(ParameterBucketName) {
 		params.BucketName = c.QueryParam(ParameterBucketName)
+		err = validateBucketName(params.BucketName)
+		if err != nil {
+			return params, err
+		}
+	}
+	err = authorizeBucket(c, params.BucketName)
+	if err != nil {
+		return params, err
+	}
+	if c.Request().Form.Has(ParameterVersionId) {
+		params.VersionId = c.QueryParam(ParameterVersionId)
+	}
+	if c.Request().Form.Has(ParameterRaw) {
+		params.Raw, err = strconv.ParseBool(c.QueryParam(ParameterRaw))
+		if err != nil {
+			return params, err
+		}
 	}
 	if c.Request().Form.Has(ParameterWithPOI) {
 		params.WithPOI, err = strconv.ParseBool(c.QueryParam(ParameterWithPOI))
@@ -77,3 +246,53 @@ func (s Server) parseObjectInput(c echo.Context) (ObjectParams, error) {
 
 	return params, nil
 }
+
+// parseByteRange reads the single byte range of a Range header for an object of the given size, returning
+// false when the whole object is to be served: without a header, or with one this server ignores, e.g. with
+// several ranges. A range starting after the object fails.
+func parseByteRange(rangeHeader string, size int64) (int64, int64, bool, error) {
+	if !strings.HasPrefix(rangeHeader, "bytes=") || strings.Contains(rangeHeader, ",") {
+		return 0, 0, false, nil
+	}
+	first, last, ok := strings.Cut(strings.TrimSpace(strings.TrimPrefix(rangeHeader, "bytes=")), "-")
+	if !ok {
+		return 0, 0, false, nil
+	}
+
+	var start, end int64
+	var err error
+	if first == "" {
+		// a suffix range, the last bytes
+		var length int64
+		length, err = strconv.ParseInt(last, 10, 64)
+		if err != nil || length < 0 {
+			return 0, 0, false, nil
+		}
+		if length == 0 || size == 0 {
+			return 0, 0, false, fmt.Errorf("range '%s' is not satisfiable, the object has %d bytes", rangeHeader, size)
+		}
+		if length > size {
+			length = size
+		}
+		return size - length, size - 1, true, nil
+	}
+
+	start, err = strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 {
+		return 0, 0, false, nil
+	}
+	end = size - 1
+	if last != "" {
+		end, err = strconv.ParseInt(last, 10, 64)
+		if err != nil || end < start {
+			return 0, 0, false, nil
+		}
+		if end >= size {
+			end = size - 1
+		}
+	}
+	if start >= size {
+		return 0, 0, false, fmt.Errorf("range '%s' is not satisfiable, the object has %d bytes", rangeHeader, size)
+	}
+	return start, end, true, nil
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+)
+
+// TestObjectInputDefaults resolves the bucket and withPOI of a request: request parameter > tenant default > server default.
+func TestObjectInputDefaults(t *testing.T) {
+	s, e := newTestServer(t, "")
+	s.defaultWithPOI = true
+	alice := &Tenant{Name: "alice", Buckets: []string{"alice", "shared"}, DefaultBucket: "alice"}
+	serverBucket := s.Collector.Storage.DefaultBucketName
+
+	for _, tc := range []struct {
+		query   string
+		tenant  *Tenant
+		bucket  string
+		withPOI bool
+	}{
+		{"", nil, serverBucket, true},
+		{"?withPOI=false", nil, serverBucket, false},
+		{"?bucketName=other", nil, "other", true},
+		{"", alice, "alice", true},
+		{"?bucketName=shared&withPOI=false", alice, "shared", false},
+	} {
+		c := requestContext(e, http.MethodGet, "/blocks"+tc.query, "")
+		if tc.tenant != nil {
+			c.Set(contextKeyTenant, tc.tenant)
+		}
+		params, err := s.parseObjectInput(c)
+		if err != nil {
+			t.Errorf("query '%s': can't parse the request: %v", tc.query, err)
+			continue
+		}
+		if params.BucketName != tc.bucket || params.WithPOI != tc.withPOI {
+			t.Errorf("query '%s': got bucket '%s' and withPOI %t, expected '%s' and %t", tc.query, params.BucketName, params.WithPOI, tc.bucket, tc.withPOI)
+		}
+	}
+
+	// the body of a request omitting withPOI gets the server default
+	no := false
+	if !s.withPOI(nil) || s.withPOI(&no) {
+		t.Errorf("got withPOI %t omitted and %t for false, expected true and false", s.withPOI(nil), s.withPOI(&no))
+	}
+}
+
+func TestParseByteRange(t *testing.T) {
+	for _, tc := range []struct {
+		header        string
+		start, end    int64
+		partial       bool
+		unsatisfiable bool
+	}{
+		{"", 0, 0, false, false},
+		{"bytes=0-9", 0, 9, true, false},
+		{"bytes=90-", 90, 99, true, false},
+		{"bytes=90-200", 90, 99, true, false},
+		{"bytes=-10", 90, 99, true, false},
+		{"bytes=-200", 0, 99, true, false},
+		{"bytes=100-", 0, 0, false, true},
+		{"bytes=-0", 0, 0, false, true},
+		{"bytes=0-1,5-6", 0, 0, false, false},
+		{"bytes=9-1", 0, 0, false, false},
+		{"items=0-9", 0, 0, false, false},
+	} {
+		start, end, partial, err := parseByteRange(tc.header, 100)
+		if (err != nil) != tc.unsatisfiable || partial != tc.partial || start != tc.start || end != tc.end {
+			t.Errorf("range '%s': got %d-%d, partial %t, error %v, expected %d-%d, partial %t, unsatisfiable %t", tc.header, start, end, partial, err, tc.start, tc.end, tc.partial, tc.unsatisfiable)
+		}
+	}
+}
@@ -1,17 +1,32 @@
 package api
 
 import (
+	"archive/tar"
+	"bytes"
+	"collector/pkg/collector"
+	"collector/pkg/jobs"
 	"collector/pkg/listener"
 	"collector/pkg/storage"
+	"context"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"expvar"
 	"fmt"
+	"io"
 	"net/http"
+	"strconv"
 
 	"strings"
+	"time"
 
+	"github.com/iotaledger/datapayloads.go"
+	"github.com/iotaledger/hive.go/serializer/v2"
 	"github.com/iotaledger/inx-app/httpserver"
 	iotago "github.com/iotaledger/iota.go/v3"
 	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 const (
@@ -29,133 +44,1126 @@ const (
 	ParameterFilterId = "filterId"
 	// ParameterLifecycleDays is used to express the number of days before data expiration in the bucket.
 	ParameterLifecycleDays = "days"
+	// ParameterVersionId is used to identify a specific version of an object in a versioned bucket.
+	ParameterVersionId = "versionId"
+	// ParameterRaw is used to identify wether a get request should return the object as stored, streamed.
+	ParameterRaw = "raw"
+	// ParameterWithExpiration is used to identify wether a bucket listing should include the expiration days.
+	ParameterWithExpiration = "withExpiration"
+	// ParameterSince is used to restrict an export to the objects modified after a RFC3339 time,
+	// or to read the events following an offset of the event log.
+	ParameterSince = "since"
+	// ParameterLimit is used to bound the number of returned items.
+	ParameterLimit = "limit"
+	// ParameterConfirm is used to confirm a destructive request.
+	ParameterConfirm = "confirm"
+	// ParameterPermanent is used to identify wether a delete request should remove every version of an object.
+	ParameterPermanent = "permanent"
 
-	RouteGetBlock     = "/block/:" + ParameterBlockID
-	RouteDeleteBlock  = "/block/:" + ParameterBlockID
-	RouteStore        = "/block"
-	RouteSubscribe    = "/filter"
-	RouteUnsubscribe  = "/filter/:" + ParameterFilterId
-	RouteCreateBucket = "/bucket"
+	// ParameterJobId is used to identify a background job.
+	ParameterJobId = "jobId"
+
+	// ParameterAlias is used to identify a block by the external key it was stored with.
+	ParameterAlias = "alias"
+	// ParameterTag is used to list the blocks indexed under a tag.
+	ParameterTag = "tag"
+	// ParameterStartAfter is used to list the items following a name.
+	ParameterStartAfter = "startAfter"
+
+	// HeaderBlockId carries the id of the block an alias resolved to.
+	HeaderBlockId = "X-Block-Id"
+	// HeaderRange asks for a byte range of a raw block, answered with HeaderContentRange.
+	HeaderRange        = "Range"
+	HeaderContentRange = "Content-Range"
+	HeaderAcceptRanges = "Accept-Ranges"
+	// HeaderObjectVersionId carries the version of the returned object when versioning is enabled.
+	HeaderObjectVersionId = "X-Object-Version-Id"
+	// HeaderPayloadContentType carries the content type of the payload of a raw block, if recorded.
+	HeaderPayloadContentType = "X-Payload-Content-Type"
+
+	RouteGetBlock        = "/block/:" + ParameterBlockID
+	RouteBlockByAlias    = "/block/by-alias/:" + ParameterAlias
+	RouteDeleteBlock     = "/block/:" + ParameterBlockID
+	RouteStore           = "/block"
+	RouteSubscribe       = "/filter"
+	RouteUnsubscribe     = "/filter/:" + ParameterFilterId
+	RouteFilters         = "/filters"
+	RouteListenerStatus  = "/listener/status"
+	RouteCreateBucket    = "/bucket"
+	RouteListBuckets     = "/buckets"
+	RouteDownloadBlocks  = "/blocks/download"
+	RouteBlockVersions   = "/block/:" + ParameterBlockID + "/versions"
+	RoutePinBlock        = "/block/:" + ParameterBlockID + "/pin"
+	RoutePinBlocks       = "/blocks/pin"
+	RouteBlocksByTag     = "/blocks/by-tag/:" + ParameterTag
+	RouteBlockMetadata   = "/block/:" + ParameterBlockID + "/metadata"
+	RouteVerifyBlock     = "/block/:" + ParameterBlockID + "/verify"
+	RouteAttachments     = "/block/:" + ParameterBlockID + "/attachments"
+	RouteMetrics         = "/metrics"
+	RouteDebugVars       = "/debug/vars"
+	RouteHealth          = "/health"
+	RouteBackfill        = "/backfill"
+	RouteBucket          = "/bucket/:" + ParameterBucketName
+	RouteBucketLifecycle = "/bucket/:" + ParameterBucketName + "/lifecycle"
+	RouteBucketPolicy    = "/bucket/:" + ParameterBucketName + "/policy"
+	RouteEmptyBucket     = "/bucket/:" + ParameterBucketName + "/empty"
+	RouteBackfillStatus  = "/backfill/:" + ParameterJobId
+	RouteJobs            = "/jobs"
+	RouteExport          = "/export"
+	RouteMigrate         = "/migrate"
+	RouteSelfCheck       = "/selfcheck"
+	RouteEvents          = "/events"
+	RouteReloadStorage   = "/admin/reload-storage"
+	RouteReload          = "/admin/reload"
+	RouteJob             = "/jobs/:" + ParameterJobId
 )
 
+// blockGzip compresses the blocks, except the byte ranges, which refer to the uncompressed bytes.
+var blockGzip = middleware.GzipWithConfig(middleware.GzipConfig{
+	Skipper: func(c echo.Context) bool {
+		return c.Request().Header.Get(HeaderRange) != ""
+	},
+})
+
 func (s *Server) setupRoutes(e *echo.Echo) {
+	e.Use(middleware.RequestID(), s.envelope, s.authenticate, s.readiness)
+	e.GET(RouteMetrics, echo.WrapHandler(promhttp.HandlerFor(s.Collector.Registry, promhttp.HandlerOpts{})))
+	if s.expvarEnabled {
+		s.Collector.PublishCounters()
+		e.GET(RouteDebugVars, echo.WrapHandler(expvar.Handler()), s.adminOnly)
+	}
+	e.GET(RouteHealth, func(c echo.Context) error {
+		if !s.Collector.Ready() {
+			return httpserver.JSONResponse(c, http.StatusServiceUnavailable, ResponseHealth{Ready: false})
+		}
+		return httpserver.JSONResponse(c, http.StatusOK, ResponseHealth{Ready: true})
+	})
 	e.GET(RouteGetBlock, func(c echo.Context) error {
 		var err error
-		s.apiLogStart(RouteGetBlock)
-		defer s.apiLogEnd(RouteGetBlock, err)
+		s.apiLogStart(c, RouteGetBlock)
+		defer s.apiLogEnd(c, RouteGetBlock, err)
+
+		params, err := s.parseObjectInput(c)
+		if err != nil {
+			return httpserver.JSONResponse(c, requestErrorStatus(err), fmt.Sprintf("%v", err))
+		}
+		return s.serveBlock(params, c)
+	}, blockGzip) // compressed only for clients sending Accept-Encoding: gzip
+	e.GET(RouteBlockByAlias, func(c echo.Context) error {
+		var err error
+		s.apiLogStart(c, RouteBlockByAlias)
+		defer s.apiLogEnd(c, RouteBlockByAlias, err)
+
+		params, err := s.parseObjectInput(c)
+		if err != nil {
+			return httpserver.JSONResponse(c, requestErrorStatus(err), fmt.Sprintf("%v", err))
+		}
+		params.BlockId, err = s.Collector.Storage.ResolveAlias(params.BucketName, c.Param(ParameterAlias), s.Context)
+		if err != nil {
+			return httpserver.JSONResponse(c, storageErrorStatus(err), fmt.Sprintf("%v", err))
+		}
+		c.Response().Header().Set(HeaderBlockId, params.BlockId)
+		return s.serveBlock(params, c)
+	}, blockGzip)
+	e.GET(RouteBlocksByTag, func(c echo.Context) error {
+		var err error
+		s.apiLogStart(c, RouteBlocksByTag)
+		defer s.apiLogEnd(c, RouteBlocksByTag, err)
 
 		params, err := s.parseObjectInput(c)
+		if err != nil {
+			return httpserver.JSONResponse(c, requestErrorStatus(err), fmt.Sprintf("%v", err))
+		}
+		tag := c.Param(ParameterTag)
+		err = storage.ValidateIndexTag(tag)
 		if err != nil {
 			return httpserver.JSONResponse(c, http.StatusBadRequest, fmt.Sprintf("%v", err))
 		}
-		if params.WithPOI {
-			resp, err := s.getBlockWithPOI(params.BlockId, params.BucketName, c)
-			if err != nil {
-				return httpserver.JSONResponse(c, http.StatusBadRequest, fmt.Sprintf("%v", err))
+		limit := defaultTaggedLimit
+		if c.QueryParam(ParameterLimit) != "" {
+			limit, err = strconv.Atoi(c.QueryParam(ParameterLimit))
+			if err != nil || limit < 1 || limit > maxTaggedLimit {
+				return httpserver.JSONResponse(c, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxTaggedLimit))
 			}
-			return httpserver.JSONResponse(c, http.StatusOK, &resp)
 		}
 
-		resp, err := s.getBlock(params.BlockId, params.BucketName, c)
+		blockIds, err := s.Collector.Storage.ListTagged(params.BucketName, tag, c.QueryParam(ParameterStartAfter), limit, s.Context)
 		if err != nil {
-			return httpserver.JSONResponse(c, http.StatusBadRequest, fmt.Sprintf("%v", err))
+			return httpserver.JSONResponse(c, storageErrorStatus(err), fmt.Sprintf("%v", err))
 		}
-		return httpserver.JSONResponse(c, http.StatusOK, &resp)
+		resp := ResponseTaggedBlocks{Tag: tag, BlockIds: blockIds}
+		if len(blockIds) == limit {
+			resp.Next = blockIds[len(blockIds)-1]
+		}
+		return httpserver.JSONResponse(c, http.StatusOK, resp)
 	})
 	e.POST(RouteStore, func(c echo.Context) error {
 		var err error
-		s.apiLogStart(RouteStore)
-		defer s.apiLogEnd(RouteStore, err)
+		s.apiLogStart(c, RouteStore)
+		defer s.apiLogEnd(c, RouteStore, err)
 
 		blockId, bucketName, err := s.storeBlockFromTangle(c)
 		if err != nil {
-			return httpserver.JSONResponse(c, http.StatusBadRequest, fmt.Sprintf("%v", err))
+			return httpserver.JSONResponse(c, requestErrorStatus(err), fmt.Sprintf("%v", err))
 		}
 		return httpserver.JSONResponse(c, http.StatusOK, fmt.Sprintf("Block '%s' uploaded to bucket '%s'", blockId, bucketName))
-	})
+	}, s.idempotent)
 	e.POST(RouteSubscribe, func(c echo.Context) error {
 		var err error
-		s.apiLogStart(RouteSubscribe)
-		defer s.apiLogEnd(RouteSubscribe, err)
+		s.apiLogStart(c, RouteSubscribe)
+		defer s.apiLogEnd(c, RouteSubscribe, err)
 
 		filterId, tag, err := s.subscribeToTag(c)
 		if err != nil {
-			return httpserver.JSONResponse(c, http.StatusBadRequest, fmt.Sprintf("%v", err))
+			return httpserver.JSONResponse(c, requestErrorStatus(err), fmt.Sprintf("%v", err))
 		}
 		return httpserver.JSONResponse(c, http.StatusOK, fmt.Sprintf("Subscription to '%s' started, id is: '%s'", tag, filterId))
-	})
+	}, s.idempotent)
 	e.POST(RouteCreateBucket, func(c echo.Context) error {
 		var err error
-		s.apiLogStart(RouteCreateBucket)
-		defer s.apiLogEnd(RouteCreateBucket, err)
+		s.apiLogStart(c, RouteCreateBucket)
+		defer s.apiLogEnd(c, RouteCreateBucket, err)
+
+		resp, err := s.createBucketFromRequest(c)
+		if err != nil {
+			return httpserver.JSONResponse(c, requestErrorStatus(err), fmt.Sprintf("could not create bucket, error: %v", err))
+		}
+		if resp.AlreadyExists {
+			return httpserver.JSONResponse(c, http.StatusOK, resp)
+		}
+		return httpserver.JSONResponse(c, http.StatusCreated, resp)
+	}, s.idempotent)
+	e.GET(RouteListBuckets, func(c echo.Context) error {
+		var err error
+		s.apiLogStart(c, RouteListBuckets)
+		defer s.apiLogEnd(c, RouteListBuckets, err)
+
+		withExpiration := false
+		if c.QueryParam(ParameterWithExpiration) != "" {
+			withExpiration, err = strconv.ParseBool(c.QueryParam(ParameterWithExpiration))
+			if err != nil {
+				return httpserver.JSONResponse(c, http.StatusBadRequest, fmt.Sprintf("%v", err))
+			}
+		}
+
+		buckets, err := s.Collector.Storage.ListBuckets(withExpiration, s.Context)
+		if err != nil {
+			return httpserver.JSONResponse(c, http.StatusServiceUnavailable, fmt.Sprintf("%v", err))
+		}
+		if tenant := tenantOf(c); tenant != nil {
+			allowed := make([]storage.BucketInfo, 0, len(buckets))
+			for _, bucket := range buckets {
+				if tenant.allows(bucket.Name) {
+					allowed = append(allowed, bucket)
+				}
+			}
+			buckets = allowed
+		}
+		return httpserver.JSONResponse(c, http.StatusOK, buckets)
+	})
+	e.POST(RouteDownloadBlocks, func(c echo.Context) error {
+		var err error
+		s.apiLogStart(c, RouteDownloadBlocks)
+		defer s.apiLogEnd(c, RouteDownloadBlocks, err)
 
-		bucketName, err := s.createBucketFromRequest(c)
+		params, err := s.parseObjectInput(c)
+		if err != nil {
+			return httpserver.JSONResponse(c, requestErrorStatus(err), fmt.Sprintf("%v", err))
+		}
+		blockIds, err := extractBlockIdList(c)
 		if err != nil {
-			return httpserver.JSONResponse(c, http.StatusBadRequest, fmt.Sprintf("could not create bucket, error: %v", err))
+			return httpserver.JSONResponse(c, http.StatusBadRequest, fmt.Sprintf("%v", err))
 		}
-		return httpserver.JSONResponse(c, http.StatusOK, fmt.Sprintf("Bucket '%s' created", bucketName))
+
+		err = s.downloadBlocksArchive(blockIds, params.BucketName, c)
+		return err
 	})
-	e.DELETE(RouteDeleteBlock, func(c echo.Context) error {
+	e.GET(RouteExport, func(c echo.Context) error {
 		var err error
-		s.apiLogStart(RouteDeleteBlock)
-		defer s.apiLogEnd(RouteDeleteBlock, err)
+		s.apiLogStart(c, RouteExport)
+		defer s.apiLogEnd(c, RouteExport, err)
 
 		params, err := s.parseObjectInput(c)
+		if err != nil {
+			return httpserver.JSONResponse(c, requestErrorStatus(err), fmt.Sprintf("%v", err))
+		}
+		var since time.Time
+		if c.QueryParam(ParameterSince) != "" {
+			since, err = time.Parse(time.RFC3339, c.QueryParam(ParameterSince))
+			if err != nil {
+				return httpserver.JSONResponse(c, http.StatusBadRequest, fmt.Sprintf("%v", err))
+			}
+		}
+
+		err = s.exportBucket(params.BucketName, since, c)
+		return err
+	})
+	e.GET(RouteEvents, func(c echo.Context) error {
+		var err error
+		s.apiLogStart(c, RouteEvents)
+		defer s.apiLogEnd(c, RouteEvents, err)
+
+		var offset uint64
+		if c.QueryParam(ParameterSince) != "" {
+			offset, err = strconv.ParseUint(c.QueryParam(ParameterSince), 10, 64)
+			if err != nil {
+				return httpserver.JSONResponse(c, http.StatusBadRequest, fmt.Sprintf("invalid offset, error: %v", err))
+			}
+		}
+		limit := defaultEventsLimit
+		if c.QueryParam(ParameterLimit) != "" {
+			limit, err = strconv.Atoi(c.QueryParam(ParameterLimit))
+			if err != nil || limit < 1 || limit > maxEventsLimit {
+				return httpserver.JSONResponse(c, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxEventsLimit))
+			}
+		}
+
+		events, err := s.Collector.Storage.ReadEvents(offset, limit, s.Context)
+		if err != nil {
+			return httpserver.JSONResponse(c, storageErrorStatus(err), fmt.Sprintf("%v", err))
+		}
+		resp := ResponseEvents{Events: events, Next: offset}
+		if len(events) > 0 {
+			resp.Next = events[len(events)-1].Offset
+		}
+		return httpserver.JSONResponse(c, http.StatusOK, resp)
+	}, s.adminOnly)
+	e.POST(RouteBucketLifecycle, func(c echo.Context) error {
+		var err error
+		s.apiLogStart(c, RouteBucketLifecycle)
+		defer s.apiLogEnd(c, RouteBucketLifecycle, err)
+
+		resp, err := s.setBucketLifecycleFromRequest(c)
+		if err != nil {
+			return httpserver.JSONResponse(c, storageErrorStatus(err), fmt.Sprintf("could not set bucket lifecycle, error: %v", err))
+		}
+		return httpserver.JSONResponse(c, http.StatusOK, resp)
+	})
+	e.POST(RouteEmptyBucket, func(c echo.Context) error {
+		var err error
+		s.apiLogStart(c, RouteEmptyBucket)
+		defer s.apiLogEnd(c, RouteEmptyBucket, err)
+
+		bucketName := c.Param(ParameterBucketName)
+		err = validateBucketName(bucketName)
+		if err != nil {
+			return httpserver.JSONResponse(c, http.StatusBadRequest, fmt.Sprintf("%v", err))
+		}
+		err = authorizeBucket(c, bucketName)
+		if err != nil {
+			return httpserver.JSONResponse(c, http.StatusForbidden, fmt.Sprintf("%v", err))
+		}
+		confirm, _ := strconv.ParseBool(c.QueryParam(ParameterConfirm))
+		if !confirm {
+			return httpserver.JSONResponse(c, http.StatusBadRequest, fmt.Sprintf("Emptying bucket '%s' removes all its objects, it must be confirmed with %s=true", bucketName, ParameterConfirm))
+		}
+		if s.isInfrastructureBucket(bucketName) && !s.allowEmptyInfrastructure {
+			return httpserver.JSONResponse(c, http.StatusForbidden, fmt.Sprintf("Bucket '%s' is managed by the collector, emptying it is not allowed", bucketName))
+		}
+
+		exists, err := s.Collector.Storage.BucketExists(bucketName, s.Context)
+		if err != nil {
+			return httpserver.JSONResponse(c, http.StatusBadRequest, fmt.Sprintf("%v", err))
+		}
+		if !exists {
+			return httpserver.JSONResponse(c, http.StatusNotFound, fmt.Sprintf("Bucket '%s' not found", bucketName))
+		}
+		jobId := s.Jobs.Start("empty-bucket", tenantName(c), func(ctx context.Context, report func(progress any)) error {
+			return s.Collector.Storage.EmptyBucket(bucketName, ctx, report)
+		}, s.Context)
+		return httpserver.JSONResponse(c, http.StatusAccepted, fmt.Sprintf("Emptying of bucket '%s' started, id is: '%s'", bucketName, jobId))
+	}, s.idempotent)
+	e.GET(RouteBucket, func(c echo.Context) error {
+		var err error
+		s.apiLogStart(c, RouteBucket)
+		defer s.apiLogEnd(c, RouteBucket, err)
+
+		bucketName := c.Param(ParameterBucketName)
+		err = validateBucketName(bucketName)
 		if err != nil {
 			return httpserver.JSONResponse(c, http.StatusBadRequest, fmt.Sprintf("%v", err))
 		}
+		err = authorizeBucket(c, bucketName)
+		if err != nil {
+			return httpserver.JSONResponse(c, http.StatusForbidden, fmt.Sprintf("%v", err))
+		}
+		usage, err := s.Collector.Storage.GetBucketUsage(bucketName, s.Context)
+		if err != nil {
+			return httpserver.JSONResponse(c, storageErrorStatus(err), fmt.Sprintf("%v", err))
+		}
+		return httpserver.JSONResponse(c, http.StatusOK, usage)
+	})
+	e.GET(RouteBucketPolicy, func(c echo.Context) error {
+		var err error
+		s.apiLogStart(c, RouteBucketPolicy)
+		defer s.apiLogEnd(c, RouteBucketPolicy, err)
 
-		err = s.Collector.Storage.DeleteObject(params.BucketName, params.BlockId, s.Context)
+		bucketName := c.Param(ParameterBucketName)
+		err = validateBucketName(bucketName)
 		if err != nil {
 			return httpserver.JSONResponse(c, http.StatusBadRequest, fmt.Sprintf("%v", err))
 		}
+		err = authorizeBucket(c, bucketName)
+		if err != nil {
+			return httpserver.JSONResponse(c, http.StatusForbidden, fmt.Sprintf("%v", err))
+		}
+		policy, err := s.Collector.Storage.GetBucketPolicy(bucketName, s.Context)
+		if err != nil {
+			return httpserver.JSONResponse(c, storageErrorStatus(err), fmt.Sprintf("%v", err))
+		}
+		if policy == "" {
+			return httpserver.JSONResponse(c, http.StatusNotFound, fmt.Sprintf("Bucket '%s' has no policy", bucketName))
+		}
+		return c.JSONBlob(http.StatusOK, []byte(policy))
+	})
+	e.PUT(RouteBucketPolicy, func(c echo.Context) error {
+		var err error
+		s.apiLogStart(c, RouteBucketPolicy)
+		defer s.apiLogEnd(c, RouteBucketPolicy, err)
 
+		bucketName := c.Param(ParameterBucketName)
+		err = validateBucketName(bucketName)
+		if err != nil {
+			return httpserver.JSONResponse(c, http.StatusBadRequest, fmt.Sprintf("%v", err))
+		}
+		err = authorizeBucket(c, bucketName)
+		if err != nil {
+			return httpserver.JSONResponse(c, http.StatusForbidden, fmt.Sprintf("%v", err))
+		}
+		policy, err := io.ReadAll(c.Request().Body)
+		if err != nil {
+			return httpserver.JSONResponse(c, http.StatusBadRequest, fmt.Sprintf("%v", err))
+		}
+		err = s.Collector.Storage.SetBucketPolicy(bucketName, string(policy), s.Context)
+		if err != nil {
+			return httpserver.JSONResponse(c, storageErrorStatus(err), fmt.Sprintf("could not set bucket policy, error: %v", err))
+		}
+		return httpserver.JSONResponse(c, http.StatusOK, fmt.Sprintf("Policy of bucket '%s' set", bucketName))
+	})
+	e.DELETE(RouteDeleteBlock, func(c echo.Context) error {
+		var err error
+		s.apiLogStart(c, RouteDeleteBlock)
+		defer s.apiLogEnd(c, RouteDeleteBlock, err)
+
+		params, err := s.parseObjectInput(c)
+		if err != nil {
+			return httpserver.JSONResponse(c, requestErrorStatus(err), fmt.Sprintf("%v", err))
+		}
+
+		permanent := false
+		if c.QueryParam(ParameterPermanent) != "" {
+			permanent, err = strconv.ParseBool(c.QueryParam(ParameterPermanent))
+			if err != nil {
+				return httpserver.JSONResponse(c, http.StatusBadRequest, fmt.Sprintf("%v", err))
+			}
+		}
+
+		if permanent {
+			err = s.Collector.Storage.PermanentlyDeleteObject(params.BucketName, params.BlockId, s.Context)
+			if err != nil {
+				return httpserver.JSONResponse(c, storageErrorStatus(err), fmt.Sprintf("%v", err))
+			}
+			return httpserver.JSONResponse(c, http.StatusOK, fmt.Sprintf("Every version of object '%s' removed from bucket '%s'", params.BlockId, params.BucketName))
+		}
+
+		err = s.Collector.Storage.DeleteObject(params.BucketName, params.BlockId, params.VersionId, s.Context)
+		if err != nil {
+			return httpserver.JSONResponse(c, storageErrorStatus(err), fmt.Sprintf("%v", err))
+		}
+
+		if params.VersionId != "" && s.Collector.Storage.VersioningEnabled {
+			return httpserver.JSONResponse(c, http.StatusOK, fmt.Sprintf("Version '%s' of object '%s' removed from bucket '%s'", params.VersionId, params.BlockId, params.BucketName))
+		}
 		return httpserver.JSONResponse(c, http.StatusOK, fmt.Sprintf("Object '%s' removed from bucket '%s'", params.BlockId, params.BucketName))
 	})
+	e.GET(RouteBlockMetadata, func(c echo.Context) error {
+		var err error
+		s.apiLogStart(c, RouteBlockMetadata)
+		defer s.apiLogEnd(c, RouteBlockMetadata, err)
+
+		params, err := s.parseObjectInput(c)
+		if err != nil {
+			return httpserver.JSONResponse(c, requestErrorStatus(err), fmt.Sprintf("%v", err))
+		}
+
+		info, err := s.Collector.Storage.GetObjectInfo(params.BucketName, params.BlockId, params.VersionId, s.Context)
+		if err != nil {
+			return httpserver.JSONResponse(c, storageErrorStatus(err), fmt.Sprintf("%v", err))
+		}
+		return httpserver.JSONResponse(c, http.StatusOK, ResponseBlockMetadata{
+			BlockId:            params.BlockId,
+			BucketName:         params.BucketName,
+			Size:               info.Size,
+			ETag:               info.ETag,
+			LastModified:       info.LastModified,
+			ContentType:        info.ContentType,
+			VersionId:          info.VersionID,
+			UserMetadata:       info.UserMetadata,
+			WithPOI:            info.UserMetadata[storage.MetadataProofOfInclusion] == "true",
+			PayloadContentType: info.UserMetadata[storage.MetadataPayloadContentType],
+		})
+	})
+	e.POST(RouteVerifyBlock, func(c echo.Context) error {
+		var err error
+		s.apiLogStart(c, RouteVerifyBlock)
+		defer s.apiLogEnd(c, RouteVerifyBlock, err)
+
+		params, err := s.parseObjectInput(c)
+		if err != nil {
+			return httpserver.JSONResponse(c, requestErrorStatus(err), fmt.Sprintf("%v", err))
+		}
+
+		object, err := s.getObjectFromStorage(params.BlockId, params.BucketName, params.VersionId, c)
+		if err != nil {
+			return httpserver.JSONResponse(c, storageErrorStatus(err), fmt.Sprintf("%v", err))
+		}
+
+		resp, err := s.verifyBlock(params, object)
+		if err != nil {
+			return httpserver.JSONResponse(c, http.StatusInternalServerError, fmt.Sprintf("%v", err))
+		}
+		return httpserver.JSONResponse(c, http.StatusOK, resp)
+	})
+	e.GET(RouteAttachments, func(c echo.Context) error {
+		var err error
+		s.apiLogStart(c, RouteAttachments)
+		defer s.apiLogEnd(c, RouteAttachments, err)
+
+		params, err := s.parseObjectInput(c)
+		if err != nil {
+			return httpserver.JSONResponse(c, requestErrorStatus(err), fmt.Sprintf("%v", err))
+		}
+
+		object, err := s.getObjectFromStorage(params.BlockId, params.BucketName, params.VersionId, c)
+		if err != nil {
+			return httpserver.JSONResponse(c, storageErrorStatus(err), fmt.Sprintf("%v", err))
+		}
+
+		attachments, err := s.getAttachments(params.BucketName, object)
+		if err != nil {
+			return httpserver.JSONResponse(c, storageErrorStatus(err), fmt.Sprintf("%v", err))
+		}
+		return httpserver.JSONResponse(c, http.StatusOK, attachments)
+	})
+	e.PUT(RoutePinBlock, func(c echo.Context) error {
+		var err error
+		s.apiLogStart(c, RoutePinBlock)
+		defer s.apiLogEnd(c, RoutePinBlock, err)
+
+		params, err := s.parseObjectInput(c)
+		if err != nil {
+			return httpserver.JSONResponse(c, requestErrorStatus(err), fmt.Sprintf("%v", err))
+		}
+
+		err = s.Collector.Storage.PinObject(params.BucketName, params.BlockId, s.Context)
+		if err != nil {
+			return httpserver.JSONResponse(c, storageErrorStatus(err), fmt.Sprintf("%v", err))
+		}
+		return httpserver.JSONResponse(c, http.StatusOK, fmt.Sprintf("Object '%s' of bucket '%s' pinned", params.BlockId, params.BucketName))
+	})
+	e.DELETE(RoutePinBlock, func(c echo.Context) error {
+		var err error
+		s.apiLogStart(c, RoutePinBlock)
+		defer s.apiLogEnd(c, RoutePinBlock, err)
+
+		params, err := s.parseObjectInput(c)
+		if err != nil {
+			return httpserver.JSONResponse(c, requestErrorStatus(err), fmt.Sprintf("%v", err))
+		}
+
+		err = s.Collector.Storage.UnpinObject(params.BucketName, params.BlockId, s.Context)
+		if err != nil {
+			return httpserver.JSONResponse(c, storageErrorStatus(err), fmt.Sprintf("%v", err))
+		}
+		return httpserver.JSONResponse(c, http.StatusOK, fmt.Sprintf("Object '%s' of bucket '%s' unpinned", params.BlockId, params.BucketName))
+	})
+	e.POST(RoutePinBlocks, func(c echo.Context) error {
+		var err error
+		s.apiLogStart(c, RoutePinBlocks)
+		defer s.apiLogEnd(c, RoutePinBlocks, err)
+
+		params, err := s.parseObjectInput(c)
+		if err != nil {
+			return httpserver.JSONResponse(c, requestErrorStatus(err), fmt.Sprintf("%v", err))
+		}
+		var request RequestPinBlocks
+		err = extractRequestBody(&request, c)
+		if err != nil {
+			return httpserver.JSONResponse(c, http.StatusBadRequest, fmt.Sprintf("%v", err))
+		}
+		objectNames := make([]string, 0, len(request.BlockIds))
+		for _, blockId := range request.BlockIds {
+			objectName, _ := normalizeBlockId(blockId)
+			objectNames = append(objectNames, objectName)
+		}
+		prefix := strings.TrimPrefix(strings.ToLower(request.Prefix), "0x")
+
+		jobId := s.Jobs.Start("pin", tenantName(c), func(ctx context.Context, report func(progress any)) error {
+			return s.Collector.Storage.PinObjects(params.BucketName, prefix, objectNames, request.Unpin, ctx, report)
+		}, s.Context)
+		action := "Pinning"
+		if request.Unpin {
+			action = "Unpinning"
+		}
+		return httpserver.JSONResponse(c, http.StatusAccepted, fmt.Sprintf("%s objects of bucket '%s' started, id is: '%s'", action, params.BucketName, jobId))
+	}, s.idempotent)
+	e.GET(RouteBlockVersions, func(c echo.Context) error {
+		var err error
+		s.apiLogStart(c, RouteBlockVersions)
+		defer s.apiLogEnd(c, RouteBlockVersions, err)
+
+		params, err := s.parseObjectInput(c)
+		if err != nil {
+			return httpserver.JSONResponse(c, requestErrorStatus(err), fmt.Sprintf("%v", err))
+		}
+
+		versions, err := s.Collector.Storage.ListObjectVersions(params.BucketName, params.BlockId, s.Context)
+		if err != nil {
+			return httpserver.JSONResponse(c, storageErrorStatus(err), fmt.Sprintf("%v", err))
+		}
+		return httpserver.JSONResponse(c, http.StatusOK, versions)
+	})
+	e.POST(RouteBackfill, func(c echo.Context) error {
+		var err error
+		s.apiLogStart(c, RouteBackfill)
+		defer s.apiLogEnd(c, RouteBackfill, err)
+
+		var request RequestBackfill
+		err = extractRequestBody(&request, c)
+		if err != nil {
+			return httpserver.JSONResponse(c, http.StatusBadRequest, fmt.Sprintf("%v", err))
+		}
+
+		err = listener.CheckBackfillRange(request.From, request.To)
+		if err != nil {
+			return httpserver.JSONResponse(c, http.StatusBadRequest, fmt.Sprintf("%v", err))
+		}
+		jobId := s.Jobs.Start("backfill", tenantName(c), func(ctx context.Context, report func(progress any)) error {
+			return s.Collector.Listener.Backfill(request.From, request.To, request.Tag, s.Collector.Client(), ctx, report)
+		}, s.Context)
+		return httpserver.JSONResponse(c, http.StatusAccepted, fmt.Sprintf("Backfill of milestones %d to %d started, id is: '%s'", request.From, request.To, jobId))
+	}, s.adminOnly, s.idempotent)
+	e.POST(RouteMigrate, func(c echo.Context) error {
+		var err error
+		s.apiLogStart(c, RouteMigrate)
+		defer s.apiLogEnd(c, RouteMigrate, err)
+
+		var request RequestMigrate
+		err = extractRequestBody(&request, c)
+		if err != nil {
+			return httpserver.JSONResponse(c, http.StatusBadRequest, fmt.Sprintf("%v", err))
+		}
+		err = authorizeBucket(c, request.BucketName)
+		if err != nil {
+			return httpserver.JSONResponse(c, http.StatusForbidden, fmt.Sprintf("%v", err))
+		}
+
+		exists, err := s.Collector.Storage.BucketExists(request.BucketName, s.Context)
+		if err != nil {
+			return httpserver.JSONResponse(c, http.StatusBadRequest, fmt.Sprintf("%v", err))
+		}
+		if !exists {
+			return httpserver.JSONResponse(c, http.StatusNotFound, fmt.Sprintf("Bucket '%s' not found", request.BucketName))
+		}
+		jobId := s.Jobs.Start("migration", tenantName(c), func(ctx context.Context, report func(progress any)) error {
+			return s.Collector.Storage.Migrate(request.BucketName, request.FromExtension, ctx, report)
+		}, s.Context)
+		return httpserver.JSONResponse(c, http.StatusAccepted, fmt.Sprintf("Migration of bucket '%s' started, id is: '%s'", request.BucketName, jobId))
+	}, s.idempotent)
+	e.POST(RouteSelfCheck, func(c echo.Context) error {
+		var err error
+		s.apiLogStart(c, RouteSelfCheck)
+		defer s.apiLogEnd(c, RouteSelfCheck, err)
+
+		var request RequestSelfCheck
+		err = extractRequestBody(&request, c)
+		if err != nil {
+			return httpserver.JSONResponse(c, http.StatusBadRequest, fmt.Sprintf("%v", err))
+		}
+		err = authorizeBucket(c, request.BucketName)
+		if err != nil {
+			return httpserver.JSONResponse(c, http.StatusForbidden, fmt.Sprintf("%v", err))
+		}
+
+		exists, err := s.Collector.Storage.BucketExists(request.BucketName, s.Context)
+		if err != nil {
+			return httpserver.JSONResponse(c, http.StatusBadRequest, fmt.Sprintf("%v", err))
+		}
+		if !exists {
+			return httpserver.JSONResponse(c, http.StatusNotFound, fmt.Sprintf("Bucket '%s' not found", request.BucketName))
+		}
+		jobId := s.Jobs.Start("selfcheck", tenantName(c), func(ctx context.Context, report func(progress any)) error {
+			return s.Collector.Storage.Check(request.BucketName, ctx, report)
+		}, s.Context)
+		return httpserver.JSONResponse(c, http.StatusAccepted, fmt.Sprintf("Check of bucket '%s' started, id is: '%s'", request.BucketName, jobId))
+	}, s.idempotent)
+	e.POST(RouteReloadStorage, func(c echo.Context) error {
+		var err error
+		s.apiLogStart(c, RouteReloadStorage)
+		defer s.apiLogEnd(c, RouteReloadStorage, err)
+
+		err = s.Collector.Storage.ReloadClient(s.Context)
+		if err != nil {
+			return httpserver.JSONResponse(c, http.StatusServiceUnavailable, fmt.Sprintf("could not reload the storage client, error: %v", err))
+		}
+		return httpserver.JSONResponse(c, http.StatusOK, "Storage client reloaded")
+	}, s.adminOnly)
+	e.POST(RouteReload, func(c echo.Context) error {
+		var err error
+		s.apiLogStart(c, RouteReload)
+		defer s.apiLogEnd(c, RouteReload, err)
+
+		collectorParams, apiParams, err := s.loadParameters()
+		if err != nil {
+			return httpserver.JSONResponse(c, http.StatusInternalServerError, fmt.Sprintf("could not read the configuration, error: %v", err))
+		}
+		result := s.Collector.Reload(collectorParams)
+		// the API is set up once, its parameters only change on restart
+		result.RequireRestart = append(result.RequireRestart, collector.ChangedParameters("restAPI", s.params, apiParams)...)
+		return httpserver.JSONResponse(c, http.StatusOK, result)
+	}, s.adminOnly)
+	e.GET(RouteBackfillStatus, func(c echo.Context) error {
+		var err error
+		s.apiLogStart(c, RouteBackfillStatus)
+		defer s.apiLogEnd(c, RouteBackfillStatus, err)
+
+		jobId := strings.ToLower(c.Param(ParameterJobId))
+		job, ok := s.Jobs.Get(jobId)
+		if !ok || job.Kind != "backfill" {
+			return httpserver.JSONResponse(c, http.StatusNotFound, fmt.Sprintf("Backfill '%s' not found", jobId))
+		}
+		return httpserver.JSONResponse(c, http.StatusOK, job)
+	}, s.adminOnly)
+	e.GET(RouteJobs, func(c echo.Context) error {
+		var err error
+		s.apiLogStart(c, RouteJobs)
+		defer s.apiLogEnd(c, RouteJobs, err)
+
+		return httpserver.JSONResponse(c, http.StatusOK, s.Jobs.List())
+	}, s.adminOnly)
+	e.GET(RouteJob, func(c echo.Context) error {
+		var err error
+		s.apiLogStart(c, RouteJob)
+		defer s.apiLogEnd(c, RouteJob, err)
+
+		jobId := strings.ToLower(c.Param(ParameterJobId))
+		job, ok := s.Jobs.Get(jobId)
+		// the jobs of the other tenants are not revealed
+		if !ok || !jobVisible(c, job) {
+			return httpserver.JSONResponse(c, http.StatusNotFound, fmt.Sprintf("Job '%s' not found", jobId))
+		}
+		return httpserver.JSONResponse(c, http.StatusOK, job)
+	})
+	e.DELETE(RouteJob, func(c echo.Context) error {
+		var err error
+		s.apiLogStart(c, RouteJob)
+		defer s.apiLogEnd(c, RouteJob, err)
+
+		jobId := strings.ToLower(c.Param(ParameterJobId))
+		err = s.Jobs.Cancel(jobId)
+		if errors.Is(err, jobs.ErrNotFound) {
+			return httpserver.JSONResponse(c, http.StatusNotFound, fmt.Sprintf("Job '%s' not found", jobId))
+		}
+		if err != nil {
+			return httpserver.JSONResponse(c, http.StatusConflict, fmt.Sprintf("%v", err))
+		}
+		return httpserver.JSONResponse(c, http.StatusAccepted, fmt.Sprintf("Job '%s' is being cancelled", jobId))
+	}, s.adminOnly)
+	e.GET(RouteFilters, func(c echo.Context) error {
+		var err error
+		s.apiLogStart(c, RouteFilters)
+		defer s.apiLogEnd(c, RouteFilters, err)
+
+		filters := s.Collector.Listener.ListFilters()
+		if tenant := tenantOf(c); tenant != nil {
+			allowed := make([]listener.FilterStatus, 0, len(filters))
+			for _, filter := range filters {
+				if tenant.allows(filter.BucketName) {
+					allowed = append(allowed, filter)
+				}
+			}
+			filters = allowed
+		}
+		return httpserver.JSONResponse(c, http.StatusOK, filters)
+	})
+	e.GET(RouteListenerStatus, func(c echo.Context) error {
+		var err error
+		s.apiLogStart(c, RouteListenerStatus)
+		defer s.apiLogEnd(c, RouteListenerStatus, err)
+
+		return httpserver.JSONResponse(c, http.StatusOK, s.Collector.Listener.Status())
+	}, s.adminOnly)
 	e.DELETE(RouteUnsubscribe, func(c echo.Context) error {
 		var err error
-		s.apiLogStart(RouteUnsubscribe)
-		defer s.apiLogEnd(RouteUnsubscribe, err)
+		s.apiLogStart(c, RouteUnsubscribe)
+		defer s.apiLogEnd(c, RouteUnsubscribe, err)
 
 		filterId := strings.ToLower(c.Param(ParameterFilterId))
+		if filter, ok := s.Collector.Listener.GetFilter(filterId); ok {
+			err = authorizeBucket(c, filter.BucketName)
+			if err != nil {
+				return httpserver.JSONResponse(c, http.StatusForbidden, fmt.Sprintf("%v", err))
+			}
+		}
 		s.Collector.Listener.RemoveFilter(filterId)
 
 		return httpserver.JSONResponse(c, http.StatusOK, fmt.Sprintf("Subscription with id '%s' has stopped", filterId))
 	})
 }
 
-func (s *Server) getBlock(blockId string, bucketName string, c echo.Context) (*iotago.Block, error) {
-	object, err := s.getObjectFromStorage(blockId, bucketName)
+// isInfrastructureBucket tells whether a bucket holds the collector's own data rather than blocks.
+func (s *Server) isInfrastructureBucket(bucketName string) bool {
+	return bucketName != "" && (bucketName == s.Collector.Storage.POIBucketName || bucketName == s.Collector.Storage.EventsBucketName)
+}
+
+// storageErrorStatus returns the HTTP status for a storage error, distinguishing missing objects.
+func storageErrorStatus(err error) int {
+	if errors.Is(err, storage.ErrNotFound) {
+		return http.StatusNotFound
+	}
+	return requestErrorStatus(err)
+}
+
+func (s *Server) getBlock(blockId string, bucketName string, versionId string, c echo.Context) (any, error) {
+	object, err := s.getObjectFromStorage(blockId, bucketName, versionId, c)
 	if err != nil {
 		return nil, err
 	}
+	// objects stored with a payload only format carry no block, they are returned as stored
+	if object.Block == nil {
+		return &object, nil
+	}
 	return object.Block, nil
 }
 
-func (s *Server) getBlockWithPOI(blockId string, bucketName string, c echo.Context) (storage.Object, error) {
-	object, err := s.getObjectFromStorage(blockId, bucketName)
+func (s *Server) getBlockWithPOI(blockId string, bucketName string, versionId string, c echo.Context) (storage.Object, error) {
+	object, err := s.getObjectFromStorage(blockId, bucketName, versionId, c)
 	if err != nil {
 		return storage.Object{}, err
 	}
 
+	// the proof may be kept apart from the block
+	if object.Proof == nil && s.Collector.Storage.POIBucketName != "" {
+		poi, err := s.Collector.Storage.GetPOI(blockId, s.Context)
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return storage.Object{}, err
+		}
+		object.Milestone, object.Proof = poi.Milestone, poi.Proof
+	}
+	if object.Proof == nil && object.Block != nil {
+		poi, err := s.proofOf(object.Block, bucketName)
+		if err != nil {
+			return storage.Object{}, err
+		}
+		object.Milestone, object.Proof = poi.Milestone, poi.Proof
+	}
+
 	if object.Milestone == nil || object.Proof == nil {
 		return storage.Object{}, fmt.Errorf("error: malformed or missing proof of inclusion")
 	}
 	return storage.Object{Milestone: object.Milestone, Block: object.Block, Proof: object.Proof}, nil
 }
 
-func (s *Server) getObjectFromStorage(blockId string, bucketName string) (storage.Object, error) {
+// proofOf returns the Proof of Inclusion of a block stored without it, with only Milestone and Proof set. The proof
+// is asked to the POI plugin the first time, then kept by block id for the next requests.
+func (s *Server) proofOf(block *iotago.Block, bucketName string) (storage.Object, error) {
+	id, err := block.ID()
+	if err != nil {
+		return storage.Object{}, err
+	}
+	blockId := hex.EncodeToString(id[:])
+
+	poi, err := s.Collector.Storage.GetProof(bucketName, blockId, s.Context)
+	if !errors.Is(err, storage.ErrNotFound) {
+		return poi, err
+	}
+
+	poi, err = listener.GetObjectFromTanglePOI(blockId, s.Collector.POIHandler)
+	if err != nil {
+		return storage.Object{}, err
+	}
+	err = s.Collector.Storage.StoreProof(bucketName, blockId, poi, s.Context)
+	if err != nil {
+		// the proof is still served, it will be asked again next time
+		s.WrappedLogger.LogWarnf("Can't keep the Proof of Inclusion of block '%s', error: %w", blockId, err)
+	}
+	return storage.Object{Milestone: poi.Milestone, Proof: poi.Proof}, nil
+}
+
+func (s *Server) getObjectFromStorage(blockId string, bucketName string, versionId string, c echo.Context) (storage.Object, error) {
 	var object storage.Object
-	resp, err := s.Collector.Storage.GetObject(bucketName, blockId, s.Context)
+	resp, err := s.Collector.Storage.GetObject(bucketName, blockId, versionId, s.Context)
 	if err != nil {
 		return object, err
 	}
+	defer resp.Close()
+
+	if s.Collector.Storage.VersioningEnabled {
+		info, err := resp.Stat()
+		if err != nil {
+			return object, err
+		}
+		c.Response().Header().Set(HeaderObjectVersionId, info.VersionID)
+	}
+
+	return resp.Decode()
+}
 
-	err = json.NewDecoder(resp).Decode(&object)
+// getAttachments returns the manifest of the blocks carrying the same tagged data as a stored object.
+func (s *Server) getAttachments(bucketName string, object storage.Object) (storage.Attachments, error) {
+	var taggedData iotago.TaggedData
+	var err error
+	switch {
+	case object.TaggedData != nil:
+		taggedData = *object.TaggedData
+	case object.Block != nil:
+		taggedData, err = listener.GetTaggedDataFromBlock(object.Block, s.Context)
+		if err != nil {
+			return storage.Attachments{}, err
+		}
+	}
+
+	key, ok, err := listener.AttachmentKey(taggedData, s.Context)
 	if err != nil {
-		return object, err
+		return storage.Attachments{}, err
+	}
+	if !ok {
+		return storage.Attachments{}, fmt.Errorf("the stored object carries no tagged data")
+	}
+	return s.Collector.Storage.GetAttachments(bucketName, key, s.Context)
+}
+
+// verifyBlock compares a stored object with the block known by the node: stored blocks byte for byte,
+// payload-only objects against the payload of the node's block.
+func (s *Server) verifyBlock(params ObjectParams, object storage.Object) (ResponseVerifyBlock, error) {
+	resp := ResponseVerifyBlock{BlockId: params.BlockId, BucketName: params.BucketName}
+	if object.Block != nil {
+		storedId, err := object.Block.ID()
+		idMatches := err == nil && hex.EncodeToString(storedId[:]) == params.BlockId
+		resp.StoredIdMatches = &idMatches
+	}
+
+	block, data, err := listener.GetBlockFromTangle(params.BlockId, s.Collector.Client(), s.Context)
+	if errors.Is(err, listener.ErrBlockNotFound) {
+		resp.Result = VerifyResultNodePruned
+		return resp, nil
+	}
+	if err != nil {
+		return resp, fmt.Errorf("can't read block '%s' from the node, error: %w", params.BlockId, err)
+	}
+	resp.NodeKnowsBlock = true
+
+	resp.Reason = storedObjectDifference(object, block, data, s.Context)
+	if resp.Reason != "" {
+		resp.Result = VerifyResultMismatch
+	} else {
+		resp.Result = VerifyResultMatch
+	}
+	return resp, nil
+}
+
+// storedObjectDifference describes how a stored object differs from the node's block and its serialized bytes,
+// an empty string if it doesn't. Transformed payloads always differ.
+func storedObjectDifference(object storage.Object, block *iotago.Block, data []byte, ctx context.Context) string {
+	if object.Block != nil {
+		stored, err := object.Block.Serialize(serializer.DeSeriModeNoValidation, nil)
+		if err != nil {
+			return fmt.Sprintf("the stored block can't be serialized, error: %v", err)
+		}
+		if !bytes.Equal(stored, data) {
+			return "the stored block differs from the node's one"
+		}
+		return ""
+	}
+
+	taggedData, err := listener.GetTaggedDataFromBlock(block, ctx)
+	if err != nil {
+		return fmt.Sprintf("the node's block payload can't be read, error: %v", err)
+	}
+	switch {
+	case object.TaggedData != nil:
+		if !bytes.Equal(object.TaggedData.Tag, taggedData.Tag) || !bytes.Equal(object.TaggedData.Data, taggedData.Data) {
+			return "the stored tagged data differs from the node's block payload"
+		}
+	case object.Data != nil:
+		signedPayload, err := datapayloads.NewSignedDataContainerFromBytes(taggedData.Data)
+		if err != nil || !bytes.Equal(object.Data, signedPayload.Data) {
+			return "the stored signed data differs from the node's block payload"
+		}
+	default:
+		return "the stored object holds neither a block nor a payload"
+	}
+	return ""
+}
+
+// streamObjectFromStorage writes the stored object to the response as is, without holding it in memory.
+func (s *Server) streamObjectFromStorage(blockId string, bucketName string, versionId string, c echo.Context) error {
+	info, err := s.Collector.Storage.GetObjectInfo(bucketName, blockId, versionId, s.Context)
+	if err != nil {
+		return err
+	}
+
+	header := c.Response().Header()
+	header.Set(HeaderAcceptRanges, "bytes")
+	if payloadContentType := info.UserMetadata[storage.MetadataPayloadContentType]; payloadContentType != "" {
+		header.Set(HeaderPayloadContentType, payloadContentType)
+	}
+	if s.Collector.Storage.VersioningEnabled {
+		header.Set(HeaderObjectVersionId, info.VersionID)
+		// stream the version we just described, even if a newer one is uploaded meanwhile
+		versionId = info.VersionID
+	}
+	start, end, partial, err := parseByteRange(c.Request().Header.Get(HeaderRange), info.Size)
+	if err != nil {
+		header.Set(HeaderContentRange, fmt.Sprintf("bytes */%d", info.Size))
+		return httpserver.JSONResponse(c, http.StatusRequestedRangeNotSatisfiable, fmt.Sprintf("%v", err))
+	}
+	if partial {
+		reader, err := s.Collector.Storage.GetObjectRange(bucketName, blockId, versionId, start, end, s.Context)
+		if err != nil {
+			return err
+		}
+		defer reader.Close()
+
+		header.Set(echo.HeaderContentType, info.ContentType)
+		header.Set(echo.HeaderContentLength, strconv.FormatInt(end-start+1, 10))
+		header.Set(HeaderContentRange, fmt.Sprintf("bytes %d-%d/%d", start, end, info.Size))
+		c.Response().WriteHeader(http.StatusPartialContent)
+		_, err = io.Copy(c.Response(), reader)
+		if err != nil {
+			s.WrappedLogger.LogWarnf("Streaming block '%s' from bucket '%s' ... failed, error: %w", blockId, bucketName, err)
+		}
+		return nil
+	}
+
+	header.Set(echo.HeaderContentType, info.ContentType)
+	header.Set(echo.HeaderContentLength, strconv.FormatInt(info.Size, 10))
+	c.Response().WriteHeader(http.StatusOK)
+
+	_, err = s.Collector.Storage.StreamObject(bucketName, blockId, versionId, c.Response(), s.Context)
+	if err != nil {
+		// the status is already sent, the client sees a truncated body
+		s.WrappedLogger.LogWarnf("Streaming block '%s' from bucket '%s' ... failed, error: %w", blockId, bucketName, err)
+	}
+	return nil
+}
+
+// ExportedBlock is a line of a bucket export.
+type ExportedBlock struct {
+	BlockId      string         `json:"blockId"`
+	LastModified time.Time      `json:"lastModified"`
+	Object       storage.Object `json:"object"`
+}
+
+// exportBucket streams the objects of a bucket as newline delimited JSON, one object at a time.
+// It stops as soon as the client goes away.
+func (s *Server) exportBucket(bucketName string, since time.Time, c echo.Context) error {
+	ctx := c.Request().Context()
+	c.Response().Header().Set(echo.HeaderContentType, "application/x-ndjson")
+	c.Response().WriteHeader(http.StatusOK)
+
+	encoder := json.NewEncoder(c.Response())
+	for entry := range s.Collector.Storage.ListObjects(bucketName, since, ctx) {
+		if entry.Err != nil {
+			// the status is already sent, the client sees a truncated export
+			s.WrappedLogger.LogWarnf("Exporting bucket '%s' ... failed, error: %w", bucketName, entry.Err)
+			return nil
+		}
+
+		object, err := s.getObjectForExport(bucketName, entry.Name, ctx)
+		if err != nil {
+			s.WrappedLogger.LogWarnf("Skipping block '%s' from export, error: %w", entry.Name, err)
+			continue
+		}
+		err = encoder.Encode(ExportedBlock{BlockId: entry.Name, LastModified: entry.LastModified, Object: object})
+		if err != nil {
+			// the client went away
+			return nil
+		}
+		c.Response().Flush()
+	}
+	return nil
+}
+
+func (s *Server) getObjectForExport(bucketName string, blockId string, ctx context.Context) (storage.Object, error) {
+	object, err := s.Collector.Storage.GetObject(bucketName, blockId, "", ctx)
+	if err != nil {
+		return storage.Object{}, err
+	}
+	defer object.Close()
+	return object.Decode()
+}
+
+// downloadBlocksArchive streams the requested blocks as a tar archive, one entry per block named by its ID.
+// Blocks that can't be retrieved are skipped with a warning.
+func (s *Server) downloadBlocksArchive(blockIds []string, bucketName string, c echo.Context) error {
+	c.Response().Header().Set(echo.HeaderContentType, "application/x-tar")
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", bucketName+".tar"))
+	c.Response().WriteHeader(http.StatusOK)
+
+	archive := tar.NewWriter(c.Response())
+	for _, blockId := range blockIds {
+		blockId = strings.ToLower(blockId)
+		err := s.writeBlockToArchive(archive, blockId, bucketName)
+		if err != nil {
+			s.WrappedLogger.LogWarnf("Skipping block '%s' from archive, error: %w", blockId, err)
+			continue
+		}
+		c.Response().Flush()
+	}
+
+	return archive.Close()
+}
+
+func (s *Server) writeBlockToArchive(archive *tar.Writer, blockId string, bucketName string) error {
+	object, err := s.Collector.Storage.GetObject(bucketName, blockId, "", s.Context)
+	if err != nil {
+		return err
+	}
+	defer object.Close()
+
+	// the tar header needs the size up front, stat also tells us if the object is missing
+	info, err := object.Stat()
+	if err != nil {
+		return err
+	}
+
+	err = archive.WriteHeader(&tar.Header{
+		Name:    blockId,
+		Mode:    0644,
+		Size:    info.Size,
+		ModTime: info.LastModified,
+	})
+	if err != nil {
+		return err
 	}
-	return object, nil
+
+	_, err = io.Copy(archive, object)
+	return err
+}
+
+// serveBlock responds with a stored block, raw or decoded, with its Proof of Inclusion if requested.
+func (s *Server) serveBlock(params ObjectParams, c echo.Context) error {
+	if params.Raw {
+		err := s.streamObjectFromStorage(params.BlockId, params.BucketName, params.VersionId, c)
+		if err != nil {
+			return httpserver.JSONResponse(c, storageErrorStatus(err), fmt.Sprintf("%v", err))
+		}
+		return nil
+	}
+	if params.WithPOI {
+		resp, err := s.getBlockWithPOI(params.BlockId, params.BucketName, params.VersionId, c)
+		if err != nil {
+			return httpserver.JSONResponse(c, storageErrorStatus(err), fmt.Sprintf("%v", err))
+		}
+		return httpserver.JSONResponse(c, http.StatusOK, &resp)
+	}
+
+	resp, err := s.getBlock(params.BlockId, params.BucketName, params.VersionId, c)
+	if err != nil {
+		return httpserver.JSONResponse(c, storageErrorStatus(err), fmt.Sprintf("%v", err))
+	}
+	return httpserver.JSONResponse(c, http.StatusOK, resp)
 }
 
 func (s *Server) storeBlockFromTangle(c echo.Context) (string, string, error) {
@@ -164,23 +1172,48 @@ func (s *Server) storeBlockFromTangle(c echo.Context) (string, string, error) {
 	if err != nil {
 		return "", "", err
 	}
+	// already validated, only strips the prefix and lowercases
+	request.BlockId, _ = normalizeBlockId(request.BlockId)
 
-	bucketName := s.Collector.Storage.DefaultBucketName
+	bucketName := s.defaultBucket(c)
 	if request.BucketName != "" {
 		bucketName = request.BucketName
 	}
+	err = authorizeBucket(c, bucketName)
+	if err != nil {
+		return "", "", err
+	}
+	var payloadContentType string
+	if request.ContentType != "" {
+		payloadContentType, err = storage.NormalizeContentType(request.ContentType)
+		if err != nil {
+			return "", "", err
+		}
+	}
 
 	var object storage.Object
-	if request.WithPOI {
+	if s.withPOI(request.WithPOI) {
 		object, err = listener.GetObjectFromTanglePOI(request.BlockId, s.Collector.POIHandler)
 	} else {
-		object, err = listener.GetObjectFromTangleBlock(request.BlockId, s.Collector.NodeBridge.Client(), s.Context)
+		object, err = listener.GetObjectFromTangleBlock(request.BlockId, s.Collector.Client(), s.Context)
 	}
 	if err != nil {
 		return "", "", err
 	}
 
-	err = s.Collector.Storage.UploadObject(request.BlockId, bucketName, object, s.Context)
+	object.Alias = request.Alias
+	object.IndexTags = request.IndexTags
+	object.PayloadContentType = payloadContentType
+
+	retention := s.Collector.Storage.DefaultRetention()
+	if request.RetentionDays != 0 {
+		retention.Days = request.RetentionDays
+	}
+	if request.LegalHold {
+		retention.LegalHold = true
+	}
+
+	err = s.Collector.Storage.UploadObjectWithRetention(request.BlockId, bucketName, object, retention, s.Context)
 	if err != nil {
 		return "", "", err
 	}
@@ -195,42 +1228,116 @@ func (s *Server) subscribeToTag(c echo.Context) (string, string, error) {
 		return "", "", err
 	}
 
-	bucketName := s.Collector.Storage.DefaultBucketName
+	err = s.Collector.Listener.CheckSubscribable(request.Tag, request.MatchAll)
+	if err != nil {
+		return "", "", err
+	}
+
+	bucketName := s.defaultBucket(c)
 	if request.BucketName != "" {
 		bucketName = request.BucketName
 	}
+	err = authorizeBucket(c, bucketName)
+	if err != nil {
+		return "", "", err
+	}
+	if request.BucketName != "" {
+		err = s.Collector.Listener.PrepareFilterBucket(bucketName, s.Context)
+		if err != nil {
+			return "", "", err
+		}
+	}
 
-	filter, err := listener.NewFilter(request.Tag, request.PublicKey, bucketName, request.Duration, request.WithPOI)
+	filter, err := listener.NewFilter(request.Tag, request.MatchAll, request.PublicKey, bucketName, request.Duration, s.withPOI(request.WithPOI), request.StoreFormat)
 	if err != nil {
 		return "", "", err
 	}
+	filter.Transform = request.Transform
+	filter.RedactFields = request.RedactFields
+	filter.Schema = request.Schema
+	filter.AliasField = request.AliasField
+	filter.KeyField = request.KeyField
+	filter.IndexFields = request.IndexFields
 
 	filterId, err := s.Collector.Listener.AddFilter(filter)
 	if err != nil {
 		return "", "", err
 	}
 
+	if request.MatchAll {
+		return filterId, "every block", nil
+	}
 	return filterId, request.Tag, nil
 }
 
-func (s *Server) createBucketFromRequest(c echo.Context) (string, error) {
+// createBucketFromRequest creates the requested bucket if it doesn't exist yet and reconciles its lifecycle
+// with the requested days in both cases.
+func (s *Server) createBucketFromRequest(c echo.Context) (ResponseCreateBucket, error) {
 	var request RequestCreateBucket
 	err := extractRequestBody(&request, c)
 	if err != nil {
-		return "", err
+		return ResponseCreateBucket{}, err
+	}
+	err = authorizeBucket(c, request.BucketName)
+	if err != nil {
+		return ResponseCreateBucket{}, err
 	}
 
-	err = s.Collector.Storage.CreateBucket(request.BucketName, s.Context)
+	exists, err := s.Collector.Storage.CheckCreateBucket(request.BucketName, s.Context)
 	if err != nil {
-		return "", err
+		return ResponseCreateBucket{}, err
 	}
 
-	if request.LifecycleDays != 0 {
+	days := 0
+	if exists {
+		days, err = s.Collector.Storage.GetBucketExpirationDays(request.BucketName, s.Context)
+		if err != nil {
+			return ResponseCreateBucket{}, err
+		}
+	}
+	if days != request.LifecycleDays {
 		err = s.Collector.Storage.SetBucketExpirationDays(request.BucketName, request.LifecycleDays, s.Context)
 		if err != nil {
-			return "", err
+			return ResponseCreateBucket{}, err
 		}
 	}
 
-	return request.BucketName, nil
+	return ResponseCreateBucket{BucketName: request.BucketName, AlreadyExists: exists, Days: request.LifecycleDays}, nil
+}
+
+func (s *Server) setBucketLifecycleFromRequest(c echo.Context) (ResponseBucketLifecycle, error) {
+	var request RequestBucketLifecycle
+	bucketName := c.Param(ParameterBucketName)
+	err := validateBucketName(bucketName)
+	if err != nil {
+		return ResponseBucketLifecycle{}, err
+	}
+	err = authorizeBucket(c, bucketName)
+	if err != nil {
+		return ResponseBucketLifecycle{}, err
+	}
+
+	err = extractRequestBody(&request, c)
+	if err != nil {
+		return ResponseBucketLifecycle{}, err
+	}
+
+	exists, err := s.Collector.Storage.BucketExists(bucketName, s.Context)
+	if err != nil {
+		return ResponseBucketLifecycle{}, err
+	}
+	if !exists {
+		return ResponseBucketLifecycle{}, fmt.Errorf("bucket '%s' %w", bucketName, storage.ErrNotFound)
+	}
+
+	err = s.Collector.Storage.SetBucketExpirationDays(bucketName, *request.Days, s.Context)
+	if err != nil {
+		return ResponseBucketLifecycle{}, err
+	}
+
+	days, err := s.Collector.Storage.GetBucketExpirationDays(bucketName, s.Context)
+	if err != nil {
+		return ResponseBucketLifecycle{}, err
+	}
+	return ResponseBucketLifecycle{BucketName: bucketName, Days: days}, nil
 }
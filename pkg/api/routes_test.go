@@ -0,0 +1,388 @@
+package api
+
+import (
+	"collector/pkg/listener"
+	"collector/pkg/storage"
+	"compress/gzip"
+	"context"
+	"crypto"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	iotago "github.com/iotaledger/iota.go/v3"
+	"github.com/iotaledger/iota.go/v3/merklehasher"
+	"github.com/labstack/echo/v4"
+)
+
+// TestBlockFlow subscribes to a tag, stores a block as the listener does and serves it.
+func TestBlockFlow(t *testing.T) {
+	s, e := newTestServer(t, "")
+	ctx := context.Background()
+	bucketName := s.Collector.Storage.DefaultBucketName
+	if _, err := s.Collector.Storage.CheckCreateBucket(bucketName, ctx); err != nil {
+		t.Fatalf("can't create the default bucket: %v", err)
+	}
+
+	// the state can't be changed until the collector is ready
+	body := `{"tag": "flow", "storeFormat": "tagged-data"}`
+	if rec := requestWithBody(e, http.MethodPost, RouteSubscribe, body); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("POST /filter before ready: got status %d, expected %d", rec.Code, http.StatusServiceUnavailable)
+	}
+	filter, err := listener.NewFilter("flow", false, "", bucketName, "", false, listener.StoreFormatTaggedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Collector.Listener.AddFilter(filter); err != nil {
+		t.Fatal(err)
+	}
+	rec := request(e, http.MethodGet, RouteFilters, "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"tag":"flow"`) {
+		t.Errorf("GET /filters: got status %d, body: %s", rec.Code, rec.Body)
+	}
+
+	blockId := strings.Repeat("ab", iotago.BlockIDLength)
+	object := storage.Object{TaggedData: &iotago.TaggedData{Tag: []byte("flow"), Data: []byte("stored")}}
+	if err := s.Collector.Storage.UploadObject(blockId, bucketName, object, ctx); err != nil {
+		t.Fatalf("can't upload the block: %v", err)
+	}
+	rec = request(e, http.MethodGet, "/block/"+blockId, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /block/:blockId: got status %d, body: %s", rec.Code, rec.Body)
+	}
+	var served storage.Object
+	if err := json.Unmarshal(rec.Body.Bytes(), &served); err != nil {
+		t.Fatalf("can't decode the block: %v", err)
+	}
+	if served.TaggedData == nil || string(served.TaggedData.Data) != "stored" {
+		t.Errorf("got block %s, expected the stored tagged data", rec.Body)
+	}
+
+	if rec := request(e, http.MethodGet, "/block/"+strings.Repeat("cd", iotago.BlockIDLength), ""); rec.Code != http.StatusNotFound {
+		t.Errorf("GET /block/:blockId of a missing block: got status %d, expected %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func requestWithBody(e *echo.Echo, method string, target string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+// TestBlockGzip serves a block over HTTP, compressed for a client accepting gzip and plain for one that doesn't.
+func TestBlockGzip(t *testing.T) {
+	s, e := newTestServer(t, "")
+	ctx := context.Background()
+	bucketName := s.Collector.Storage.DefaultBucketName
+	if _, err := s.Collector.Storage.CheckCreateBucket(bucketName, ctx); err != nil {
+		t.Fatalf("can't create the default bucket: %v", err)
+	}
+	blockId := strings.Repeat("ab", iotago.BlockIDLength)
+	data := strings.Repeat("compressible ", 100)
+	object := storage.Object{TaggedData: &iotago.TaggedData{Tag: []byte("gzip"), Data: []byte(data)}}
+	if err := s.Collector.Storage.UploadObject(blockId, bucketName, object, ctx); err != nil {
+		t.Fatalf("can't upload the block: %v", err)
+	}
+	server := httptest.NewServer(e)
+	defer server.Close()
+	// the transport would otherwise ask for gzip and decompress on its own
+	client := &http.Client{Transport: &http.Transport{DisableCompression: true}}
+
+	for _, tc := range []struct {
+		acceptEncoding  string
+		contentEncoding string
+	}{
+		{"gzip", "gzip"},
+		{"identity", ""},
+		{"", ""},
+	} {
+		req, err := http.NewRequest(http.MethodGet, server.URL+"/block/"+blockId, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tc.acceptEncoding != "" {
+			req.Header.Set(echo.HeaderAcceptEncoding, tc.acceptEncoding)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("GET /block/:blockId with Accept-Encoding '%s' failed: %v", tc.acceptEncoding, err)
+		}
+		var body io.Reader = resp.Body
+		if encoding := resp.Header.Get(echo.HeaderContentEncoding); encoding != tc.contentEncoding {
+			t.Errorf("GET /block/:blockId with Accept-Encoding '%s': got Content-Encoding '%s', expected '%s'", tc.acceptEncoding, encoding, tc.contentEncoding)
+		} else if encoding == "gzip" {
+			if body, err = gzip.NewReader(resp.Body); err != nil {
+				t.Fatalf("can't decompress the block: %v", err)
+			}
+		}
+		var served storage.Object
+		err = json.NewDecoder(body).Decode(&served)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("can't decode the block served with Accept-Encoding '%s': %v", tc.acceptEncoding, err)
+		}
+		if resp.StatusCode != http.StatusOK || served.TaggedData == nil || string(served.TaggedData.Data) != data {
+			t.Errorf("GET /block/:blockId with Accept-Encoding '%s': got status %d, expected the stored tagged data", tc.acceptEncoding, resp.StatusCode)
+		}
+	}
+}
+
+// requestContext returns the context of a request carrying a JSON body, for the handlers of the routes refused until
+// the collector is ready.
+func requestContext(e *echo.Echo, method string, target string, body string) echo.Context {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	return e.NewContext(req, httptest.NewRecorder())
+}
+
+func TestCreateBucketIdempotent(t *testing.T) {
+	s, e := newTestServer(t, "")
+	create := func(days int) ResponseCreateBucket {
+		t.Helper()
+		resp, err := s.createBucketFromRequest(requestContext(e, http.MethodPost, RouteCreateBucket, fmt.Sprintf(`{"bucketName": "idempotent", "days": %d}`, days)))
+		if err != nil {
+			t.Fatalf("can't create the bucket: %v", err)
+		}
+		return resp
+	}
+
+	if resp := create(7); resp.AlreadyExists || resp.Days != 7 {
+		t.Errorf("got %+v creating the bucket, expected it created with 7 days", resp)
+	}
+	// creating it again reconciles its lifecycle
+	if resp := create(3); !resp.AlreadyExists || resp.Days != 3 {
+		t.Errorf("got %+v creating the bucket again, expected it existing with 3 days", resp)
+	}
+	if days, err := s.Collector.Storage.GetBucketExpirationDays("idempotent", context.Background()); err != nil || days != 3 {
+		t.Errorf("got %d expiration days, error %v, expected 3", days, err)
+	}
+}
+
+func TestRawBlock(t *testing.T) {
+	s, e := newTestServer(t, "")
+	ctx := context.Background()
+	bucketName := s.Collector.Storage.DefaultBucketName
+	if _, err := s.Collector.Storage.CheckCreateBucket(bucketName, ctx); err != nil {
+		t.Fatalf("can't create the default bucket: %v", err)
+	}
+	blockId := strings.Repeat("ab", iotago.BlockIDLength)
+	object := storage.Object{TaggedData: &iotago.TaggedData{Tag: []byte("raw"), Data: []byte("stored as is")}}
+	if err := s.Collector.Storage.UploadObject(blockId, bucketName, object, ctx); err != nil {
+		t.Fatalf("can't upload the block: %v", err)
+	}
+	expected, err := object.GetByteReader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedBytes, _ := io.ReadAll(expected)
+
+	rec := request(e, http.MethodGet, "/block/"+blockId+"?raw=true", "")
+	if rec.Code != http.StatusOK || rec.Body.String() != string(expectedBytes) {
+		t.Fatalf("GET /block/:blockId?raw=true: got status %d, body %s, expected the stored %s", rec.Code, rec.Body, expectedBytes)
+	}
+	if contentType := rec.Header().Get(echo.HeaderContentType); contentType != storage.ContentTypeJSON {
+		t.Errorf("got Content-Type '%s', expected '%s'", contentType, storage.ContentTypeJSON)
+	}
+	if length := rec.Header().Get(echo.HeaderContentLength); length != fmt.Sprint(len(expectedBytes)) {
+		t.Errorf("got Content-Length '%s', expected %d", length, len(expectedBytes))
+	}
+}
+
+func TestAttachmentsRoute(t *testing.T) {
+	s, e := newTestServer(t, "")
+	ctx := context.Background()
+	bucketName := s.Collector.Storage.DefaultBucketName
+	if _, err := s.Collector.Storage.CheckCreateBucket(bucketName, ctx); err != nil {
+		t.Fatalf("can't create the default bucket: %v", err)
+	}
+	taggedData := iotago.TaggedData{Tag: []byte("attached"), Data: []byte("same data")}
+	key, _, err := listener.AttachmentKey(taggedData, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blockIds := []string{strings.Repeat("ab", iotago.BlockIDLength), strings.Repeat("cd", iotago.BlockIDLength)}
+	for _, blockId := range blockIds {
+		if err := s.Collector.Storage.UploadObject(blockId, bucketName, storage.Object{TaggedData: &taggedData}, ctx); err != nil {
+			t.Fatalf("can't upload the block: %v", err)
+		}
+		if err := s.Collector.Storage.AddAttachment(bucketName, key, blockId, ctx); err != nil {
+			t.Fatalf("can't record the attachment: %v", err)
+		}
+	}
+
+	rec := request(e, http.MethodGet, "/block/"+blockIds[1]+"/attachments", "")
+	var attachments storage.Attachments
+	if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &attachments) != nil {
+		t.Fatalf("GET /block/:blockId/attachments: got status %d, body: %s", rec.Code, rec.Body)
+	}
+	if attachments.PayloadHash != key || len(attachments.BlockIds) != 2 || attachments.BlockIds[0] != blockIds[0] {
+		t.Errorf("got attachments %+v, expected both blocks", attachments)
+	}
+	if rec := request(e, http.MethodGet, "/block/"+strings.Repeat("ef", iotago.BlockIDLength)+"/attachments", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("GET /block/:blockId/attachments of a missing block: got status %d, expected %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestSubscribeCreatesBucket(t *testing.T) {
+	s, e := newTestServer(t, "")
+	ctx := context.Background()
+	if _, err := s.Collector.Storage.CheckCreateBucket(s.Collector.Storage.DefaultBucketName, ctx); err != nil {
+		t.Fatalf("can't create the default bucket: %v", err)
+	}
+
+	_, tag, err := s.subscribeToTag(requestContext(e, http.MethodPost, RouteSubscribe, `{"tag": "new", "bucketName": "new-bucket"}`))
+	if err != nil || tag != "new" {
+		t.Fatalf("got tag '%s', error %v subscribing, expected 'new'", tag, err)
+	}
+	if exists, err := s.Collector.Storage.BucketExists("new-bucket", ctx); err != nil || !exists {
+		t.Fatalf("got bucket existing: %t, error %v, expected it created", exists, err)
+	}
+	expected := s.Collector.Storage.DefaultBucketExpirationDays
+	if days, err := s.Collector.Storage.GetBucketExpirationDays("new-bucket", ctx); err != nil || days != expected {
+		t.Errorf("got %d expiration days, error %v, expected the default %d", days, err, expected)
+	}
+}
+
+func TestSubscribeDeniedTag(t *testing.T) {
+	s, e := newTestServer(t, "")
+	s.Collector.Listener.SetTagLists("", "system*")
+	_, _, err := s.subscribeToTag(requestContext(e, http.MethodPost, RouteSubscribe, `{"tag": "system-heartbeat"}`))
+	if status := requestErrorStatus(err); err == nil || status != http.StatusForbidden {
+		t.Errorf("got error %v, status %d subscribing to a denied tag, expected %d", err, status, http.StatusForbidden)
+	}
+	if filters := s.Collector.Listener.ListFilters(); len(filters) != 0 {
+		t.Errorf("got filters %+v, expected none", filters)
+	}
+}
+
+func TestRawBlockRange(t *testing.T) {
+	s, e := newTestServer(t, "")
+	ctx := context.Background()
+	bucketName := s.Collector.Storage.DefaultBucketName
+	if _, err := s.Collector.Storage.CheckCreateBucket(bucketName, ctx); err != nil {
+		t.Fatalf("can't create the default bucket: %v", err)
+	}
+	blockId := strings.Repeat("ab", iotago.BlockIDLength)
+	object := storage.Object{TaggedData: &iotago.TaggedData{Tag: []byte("range"), Data: []byte("served by ranges")}}
+	if err := s.Collector.Storage.UploadObject(blockId, bucketName, object, ctx); err != nil {
+		t.Fatalf("can't upload the block: %v", err)
+	}
+	reader, err := object.GetByteReader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, _ := io.ReadAll(reader)
+	size := len(stored)
+	get := func(rangeHeader string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/block/"+blockId+"?raw=true", nil)
+		req.Header.Set(HeaderRange, rangeHeader)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("bytes=2-6")
+	if rec.Code != http.StatusPartialContent || rec.Body.String() != string(stored[2:7]) {
+		t.Errorf("got status %d, body '%s', expected %d and '%s'", rec.Code, rec.Body, http.StatusPartialContent, stored[2:7])
+	}
+	if contentRange := rec.Header().Get(HeaderContentRange); contentRange != fmt.Sprintf("bytes 2-6/%d", size) {
+		t.Errorf("got Content-Range '%s', expected 'bytes 2-6/%d'", contentRange, size)
+	}
+	if length := rec.Header().Get(echo.HeaderContentLength); length != "5" {
+		t.Errorf("got Content-Length '%s', expected 5", length)
+	}
+
+	rec = get(fmt.Sprintf("bytes=%d-", size))
+	if rec.Code != http.StatusRequestedRangeNotSatisfiable {
+		t.Errorf("got status %d for a range after the block, expected %d", rec.Code, http.StatusRequestedRangeNotSatisfiable)
+	}
+	if contentRange := rec.Header().Get(HeaderContentRange); contentRange != fmt.Sprintf("bytes */%d", size) {
+		t.Errorf("got Content-Range '%s', expected 'bytes */%d'", contentRange, size)
+	}
+
+	// a range this server ignores gets the whole block
+	rec = get("bytes=0-1,4-5")
+	if rec.Code != http.StatusOK || rec.Body.String() != string(stored) || rec.Header().Get(HeaderAcceptRanges) != "bytes" {
+		t.Errorf("got status %d, body '%s', expected the whole block", rec.Code, rec.Body)
+	}
+}
+
+func TestPayloadContentType(t *testing.T) {
+	s, e := newTestServer(t, "")
+	ctx := context.Background()
+	bucketName := s.Collector.Storage.DefaultBucketName
+	if _, err := s.Collector.Storage.CheckCreateBucket(bucketName, ctx); err != nil {
+		t.Fatalf("can't create the default bucket: %v", err)
+	}
+	described := strings.Repeat("cd", iotago.BlockIDLength)
+	object := storage.Object{TaggedData: &iotago.TaggedData{Tag: []byte("csv"), Data: []byte("a,b\n1,2\n")}, PayloadContentType: "text/csv"}
+	if err := s.Collector.Storage.UploadObject(described, bucketName, object, ctx); err != nil {
+		t.Fatalf("can't upload the block: %v", err)
+	}
+	undescribed := strings.Repeat("ef", iotago.BlockIDLength)
+	if err := s.Collector.Storage.UploadObject(undescribed, bucketName, storage.Object{TaggedData: &iotago.TaggedData{Tag: []byte("csv")}}, ctx); err != nil {
+		t.Fatalf("can't upload the block: %v", err)
+	}
+
+	for blockId, expected := range map[string]string{described: "text/csv", undescribed: ""} {
+		rec := request(e, http.MethodGet, "/block/"+blockId+"?raw=true", "")
+		if rec.Code != http.StatusOK || rec.Header().Get(HeaderPayloadContentType) != expected {
+			t.Errorf("got status %d and payload content type '%s', expected %d and '%s'", rec.Code, rec.Header().Get(HeaderPayloadContentType), http.StatusOK, expected)
+		}
+		// the raw block keeps the content type it is encoded with
+		if contentType := rec.Header().Get(echo.HeaderContentType); contentType != storage.ContentTypeJSON {
+			t.Errorf("got content type '%s', expected '%s'", contentType, storage.ContentTypeJSON)
+		}
+
+		rec = request(e, http.MethodGet, "/block/"+blockId+"/metadata", "")
+		var metadata ResponseBlockMetadata
+		if err := json.Unmarshal(rec.Body.Bytes(), &metadata); err != nil || rec.Code != http.StatusOK {
+			t.Fatalf("got status %d and body '%s' for the metadata", rec.Code, rec.Body)
+		}
+		if metadata.PayloadContentType != expected {
+			t.Errorf("got payload content type '%s' in the metadata, expected '%s'", metadata.PayloadContentType, expected)
+		}
+	}
+}
+
+// TestBlockWithKeptProof joins a block stored without its proof with the proof kept for it, without asking the POI plugin.
+func TestBlockWithKeptProof(t *testing.T) {
+	s, e := newTestServer(t, "")
+	ctx := context.Background()
+	bucketName := s.Collector.Storage.DefaultBucketName
+	if _, err := s.Collector.Storage.CheckCreateBucket(bucketName, ctx); err != nil {
+		t.Fatalf("can't create the default bucket: %v", err)
+	}
+	block := &iotago.Block{ProtocolVersion: 2, Parents: iotago.BlockIDs{{}}, Payload: &iotago.TaggedData{Tag: []byte("poi"), Data: []byte("proven")}}
+	id, err := block.ID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	blockId := hex.EncodeToString(id[:])
+	if err := s.Collector.Storage.UploadObject(blockId, bucketName, storage.Object{Block: block}, ctx); err != nil {
+		t.Fatalf("can't upload the block: %v", err)
+	}
+	blockIds := iotago.BlockIDs{id, {1}}
+	proof, err := merklehasher.NewHasher(crypto.BLAKE2b_256).ComputeProof(blockIds, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Collector.Storage.StoreProof(bucketName, blockId, storage.Object{Milestone: &iotago.Milestone{Index: 3}, Proof: proof}, ctx); err != nil {
+		t.Fatalf("can't keep the proof: %v", err)
+	}
+
+	object, err := s.getBlockWithPOI(blockId, bucketName, "", requestContext(e, http.MethodGet, "/block/"+blockId+"?withPOI=true", ""))
+	if err != nil {
+		t.Fatalf("can't get the block with its proof: %v", err)
+	}
+	if object.Block == nil || object.Proof == nil || object.Milestone == nil || object.Milestone.Index != 3 {
+		t.Errorf("got object %+v, expected the block joined with the kept proof", object)
+	}
+}
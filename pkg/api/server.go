@@ -2,9 +2,12 @@ package api
 
 import (
 	"collector/pkg/collector"
+	"collector/pkg/jobs"
 	"context"
+	"net/http"
 
 	"github.com/iotaledger/hive.go/core/logger"
+	"github.com/iotaledger/inx-app/httpserver"
 	"github.com/labstack/echo/v4"
 )
 
@@ -12,14 +15,60 @@ type Server struct {
 	*logger.WrappedLogger
 	Collector *collector.Collector
 	Context   context.Context
+	Jobs      *jobs.Manager
+
+	idempotency    *idempotencyCache
+	defaultWithPOI bool
+	tenants        []*Tenant
+	// allowEmptyInfrastructure lets the POI and events buckets be emptied
+	allowEmptyInfrastructure bool
+	envelopeResponses        bool
+	collectorId              string
+	expvarEnabled            bool
+	// params are the parameters the API runs with, loadParameters reads them again from the configuration
+	params         Parameters
+	loadParameters ParametersLoader
 }
 
-func NewServer(collector *collector.Collector, echo *echo.Echo, log *logger.WrappedLogger, ctx context.Context) *Server {
+// ParametersLoader reads the parameters of the collector and of the API from the configuration.
+type ParametersLoader func() (collector.Parameters, Parameters, error)
+
+func NewServer(collector *collector.Collector, echo *echo.Echo, params Parameters, loadParameters ParametersLoader, log *logger.WrappedLogger, ctx context.Context) (*Server, error) {
+	tenants, err := parseTenants(params.Tenants)
+	if err != nil {
+		return nil, err
+	}
 	s := &Server{
-		WrappedLogger: logger.NewWrappedLogger(log.LoggerNamed("ServerRestAPI")),
-		Collector:     collector,
-		Context:       ctx,
+		WrappedLogger:            logger.NewWrappedLogger(log.LoggerNamed("ServerRestAPI")),
+		Collector:                collector,
+		Context:                  ctx,
+		Jobs:                     jobs.NewManager(params.JobHistorySize, log),
+		idempotency:              newIdempotencyCache(params),
+		defaultWithPOI:           params.DefaultWithPOI,
+		tenants:                  tenants,
+		allowEmptyInfrastructure: params.AllowEmptyInfrastructureBuckets,
+		envelopeResponses:        params.EnvelopeResponses,
+		collectorId:              collectorId(params),
+		expvarEnabled:            params.ExpvarEnabled,
+		params:                   params,
+		loadParameters:           loadParameters,
 	}
 	s.setupRoutes(echo)
-	return s
+	return s, nil
+}
+
+// retryAfterNotReady is the delay, in seconds, suggested to the requests refused during the startup.
+const retryAfterNotReady = "5"
+
+// readiness refuses the requests changing the state of the collector with 503 until it is ready, i.e. until the
+// buckets are managed and the startup filters loaded. Reads are served, they fail on their own if need be.
+func (s *Server) readiness(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		method := c.Request().Method
+		if s.Collector.Ready() || method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions {
+			return next(c)
+		}
+		c.Response().Header().Set(echo.HeaderRetryAfter, retryAfterNotReady)
+		return httpserver.JSONResponse(c, http.StatusServiceUnavailable, "The collector is starting, buckets and startup filters are not ready yet")
+	}
 }
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"collector/pkg/collector"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestReadiness(t *testing.T) {
+	s, e := newTestServer(t, "")
+	const body = `{"bucketName": "ready", "days": 1}`
+
+	// while initializing, reads are served and changes refused
+	if rec := request(e, http.MethodGet, RouteHealth, ""); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("GET /health while initializing: got status %d, expected %d", rec.Code, http.StatusServiceUnavailable)
+	}
+	if rec := request(e, http.MethodGet, RouteFilters, ""); rec.Code != http.StatusOK {
+		t.Errorf("GET /filters while initializing: got status %d, expected %d", rec.Code, http.StatusOK)
+	}
+	rec := requestWithBody(e, http.MethodPost, RouteCreateBucket, body)
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get(echo.HeaderRetryAfter) != retryAfterNotReady {
+		t.Errorf("POST /bucket while initializing: got status %d, Retry-After '%s', expected %d and '%s'", rec.Code, rec.Header().Get(echo.HeaderRetryAfter), http.StatusServiceUnavailable, retryAfterNotReady)
+	}
+
+	if err := s.Collector.Prepare(context.Background()); err != nil {
+		t.Fatalf("can't prepare the collector: %v", err)
+	}
+	if rec := request(e, http.MethodGet, RouteHealth, ""); rec.Code != http.StatusOK {
+		t.Errorf("GET /health once ready: got status %d, expected %d", rec.Code, http.StatusOK)
+	}
+	if rec := requestWithBody(e, http.MethodPost, RouteCreateBucket, body); rec.Code != http.StatusCreated {
+		t.Errorf("POST /bucket once ready: got status %d, expected %d", rec.Code, http.StatusCreated)
+	}
+}
+
+func TestDebugVars(t *testing.T) {
+	s, e := newTestServer(t, testTenants)
+	if rec := request(e, http.MethodGet, RouteDebugVars, "key-ops"); rec.Code != http.StatusNotFound {
+		t.Errorf("got status %d with expvar disabled, expected %d", rec.Code, http.StatusNotFound)
+	}
+
+	s.expvarEnabled = true
+	e = echo.New()
+	s.setupRoutes(e)
+	for _, tc := range []struct {
+		apiKey   string
+		expected int
+	}{
+		{"", http.StatusUnauthorized},
+		{"key-alice", http.StatusForbidden},
+		{"key-ops", http.StatusOK},
+	} {
+		rec := request(e, http.MethodGet, RouteDebugVars, tc.apiKey)
+		if rec.Code != tc.expected {
+			t.Errorf("got status %d with key '%s', expected %d", rec.Code, tc.apiKey, tc.expected)
+			continue
+		}
+		if rec.Code != http.StatusOK {
+			continue
+		}
+		var vars struct {
+			Collector *collector.Counters `json:"collector"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &vars); err != nil || vars.Collector == nil {
+			t.Fatalf("got vars '%s' (error %v), expected the collector counters", rec.Body, err)
+		}
+		if *vars.Collector != s.Collector.Counters() {
+			t.Errorf("got counters %+v, expected %+v", *vars.Collector, s.Collector.Counters())
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package api
+
+import (
+	"collector/pkg/jobs"
+	"collector/pkg/listener"
+	"collector/pkg/storage"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/iotaledger/inx-app/httpserver"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// HeaderAPIKey carries the API key identifying the tenant of a request.
+	HeaderAPIKey = "X-API-Key"
+
+	// contextKeyTenant holds the tenant of the request in the echo context.
+	contextKeyTenant = "tenant"
+)
+
+// ErrForbidden is returned when a tenant references a bucket it is not allowed to use.
+var ErrForbidden = errors.New("forbidden")
+
+// Tenant is a client of the API, identified by its API key and restricted to its buckets unless it is an admin.
+// A bucket ending with '*' allows every bucket starting with the preceding prefix, e.g. its time partitions.
+type Tenant struct {
+	Name          string   `json:"name"`
+	APIKey        string   `json:"apiKey"`
+	Buckets       []string `json:"buckets"`
+	DefaultBucket string   `json:"defaultBucket"`
+	Admin         bool     `json:"admin"`
+}
+
+// Tenants is the format of the tenants parameter.
+type Tenants struct {
+	Tenants []Tenant `json:"tenants"`
+}
+
+// parseTenants reads the tenants parameter, an empty parameter disables the API keys.
+func parseTenants(tenantsString string) ([]*Tenant, error) {
+	if tenantsString == "" {
+		return nil, nil
+	}
+	var config Tenants
+	err := json.Unmarshal([]byte(tenantsString), &config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenants, error: %w", err)
+	}
+
+	names := make(map[string]struct{})
+	keys := make(map[string]struct{})
+	tenants := make([]*Tenant, 0, len(config.Tenants))
+	for i := range config.Tenants {
+		tenant := config.Tenants[i]
+		if tenant.Name == "" || tenant.APIKey == "" {
+			return nil, fmt.Errorf("every tenant needs a name and an api key")
+		}
+		if _, ok := names[tenant.Name]; ok {
+			return nil, fmt.Errorf("tenant '%s' is defined twice", tenant.Name)
+		}
+		if _, ok := keys[tenant.APIKey]; ok {
+			return nil, fmt.Errorf("tenant '%s' reuses the api key of another tenant", tenant.Name)
+		}
+		names[tenant.Name], keys[tenant.APIKey] = struct{}{}, struct{}{}
+
+		if !tenant.Admin {
+			if len(tenant.Buckets) == 0 {
+				return nil, fmt.Errorf("tenant '%s' has no buckets", tenant.Name)
+			}
+			if tenant.DefaultBucket == "" {
+				tenant.DefaultBucket = tenant.Buckets[0]
+			}
+			if !tenant.allows(tenant.DefaultBucket) || strings.HasSuffix(tenant.DefaultBucket, "*") {
+				return nil, fmt.Errorf("the default bucket of tenant '%s' is not one of its buckets", tenant.Name)
+			}
+		}
+		tenants = append(tenants, &tenant)
+	}
+	return tenants, nil
+}
+
+// allows tells whether the tenant may reference a bucket.
+func (t *Tenant) allows(bucketName string) bool {
+	if t.Admin {
+		return true
+	}
+	for _, allowed := range t.Buckets {
+		if strings.HasSuffix(allowed, "*") && strings.HasPrefix(bucketName, strings.TrimSuffix(allowed, "*")) {
+			return true
+		}
+		if allowed == bucketName {
+			return true
+		}
+	}
+	return false
+}
+
+// authenticate resolves the tenant of a request from its API key, refusing requests without a known key.
+// The metrics and the health are served without a key.
+func (s *Server) authenticate(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if s.tenants == nil || c.Path() == RouteMetrics || c.Path() == RouteHealth {
+			return next(c)
+		}
+		key := []byte(c.Request().Header.Get(HeaderAPIKey))
+		for _, tenant := range s.tenants {
+			// every key is compared, in constant time, not to leak how much of a key matches
+			if subtle.ConstantTimeCompare(key, []byte(tenant.APIKey)) == 1 {
+				c.Set(contextKeyTenant, tenant)
+				return next(c)
+			}
+		}
+		return httpserver.JSONResponse(c, http.StatusUnauthorized, "missing or unknown API key")
+	}
+}
+
+// adminOnly refuses the requests of tenants that are not admins, for the routes acting across buckets. Without
+// tenants nobody is an admin, the routes are refused to every request.
+func (s *Server) adminOnly(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if tenant := tenantOf(c); tenant == nil || !tenant.Admin {
+			return httpserver.JSONResponse(c, http.StatusForbidden, fmt.Sprintf("Route '%s' is reserved to admins, they are defined by the tenants", c.Path()))
+		}
+		return next(c)
+	}
+}
+
+// tenantOf returns the tenant of a request, nil if the API keys are disabled.
+func tenantOf(c echo.Context) *Tenant {
+	tenant, _ := c.Get(contextKeyTenant).(*Tenant)
+	return tenant
+}
+
+// tenantName returns the name of the tenant of a request, empty if the API keys are disabled.
+func tenantName(c echo.Context) string {
+	if tenant := tenantOf(c); tenant != nil {
+		return tenant.Name
+	}
+	return ""
+}
+
+// jobVisible tells whether the tenant of a request may follow a job: admins follow every job, the other tenants the
+// ones they started.
+func jobVisible(c echo.Context, job jobs.Job) bool {
+	tenant := tenantOf(c)
+	return tenant == nil || tenant.Admin || job.Tenant == tenant.Name
+}
+
+// defaultBucket returns the bucket used by the requests omitting it: the tenant's, or the storage default.
+func (s *Server) defaultBucket(c echo.Context) string {
+	if tenant := tenantOf(c); tenant != nil && tenant.DefaultBucket != "" {
+		return tenant.DefaultBucket
+	}
+	return s.Collector.Storage.DefaultBucketName
+}
+
+// authorizeBucket fails with ErrForbidden if the tenant of the request may not reference a bucket.
+func authorizeBucket(c echo.Context, bucketName string) error {
+	if tenant := tenantOf(c); tenant != nil && !tenant.allows(bucketName) {
+		return fmt.Errorf("%w: tenant '%s' can't access bucket '%s'", ErrForbidden, tenant.Name, bucketName)
+	}
+	return nil
+}
+
+// requestErrorStatus returns the HTTP status for an invalid request, distinguishing the forbidden buckets and tags.
+func requestErrorStatus(err error) int {
+	if errors.Is(err, ErrForbidden) || errors.Is(err, listener.ErrTagNotAllowed) {
+		return http.StatusForbidden
+	}
+	if errors.Is(err, storage.ErrQuotaExceeded) {
+		return http.StatusInsufficientStorage
+	}
+	return http.StatusBadRequest
+}
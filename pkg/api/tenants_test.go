@@ -0,0 +1,131 @@
+package api
+
+import (
+	"collector/pkg/collector"
+	"collector/pkg/listener"
+	"collector/pkg/poi"
+	"collector/pkg/storage"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/iotaledger/hive.go/core/configuration"
+	"github.com/iotaledger/hive.go/core/logger"
+	"github.com/labstack/echo/v4"
+	flag "github.com/spf13/pflag"
+)
+
+const testTenants = `{"tenants": [
+	{"name": "alice", "apiKey": "key-alice", "buckets": ["alice"]},
+	{"name": "bob", "apiKey": "key-bob", "buckets": ["bob"]},
+	{"name": "ops", "apiKey": "key-ops", "admin": true}
+]}`
+
+// newTestServer returns a server with the default parameters and the given tenants, over an in-memory storage.
+func newTestServer(t *testing.T, tenants string) (*Server, *echo.Echo) {
+	t.Helper()
+	storageParams := &storage.Parameters{}
+	listenerParams := &listener.Parameters{}
+	poiParams := &poi.Parameters{}
+	restAPIParams := &Parameters{}
+	config := configuration.New()
+	flagSet := configuration.NewUnsortedFlagSet("test", flag.ContinueOnError)
+	config.BindParameters(flagSet, "storage", storageParams)
+	config.BindParameters(flagSet, "listener", listenerParams)
+	config.BindParameters(flagSet, "POI", poiParams)
+	config.BindParameters(flagSet, "restAPI", restAPIParams)
+	storageParams.InMemory = true
+	restAPIParams.Tenants = tenants
+
+	log := logger.NewNopLogger()
+	c, err := collector.NewCollector(log, nil, nil, *storageParams, *listenerParams, *poiParams)
+	if err != nil {
+		t.Fatalf("can't create the collector: %v", err)
+	}
+	e := echo.New()
+	s, err := NewServer(c, e, *restAPIParams, nil, logger.NewWrappedLogger(log), context.Background())
+	if err != nil {
+		t.Fatalf("can't create the server: %v", err)
+	}
+	return s, e
+}
+
+func request(e *echo.Echo, method string, target string, apiKey string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	if apiKey != "" {
+		req.Header.Set(HeaderAPIKey, apiKey)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestJobOfAnotherTenant(t *testing.T) {
+	s, e := newTestServer(t, testTenants)
+	jobId := s.Jobs.Start("selfcheck", "alice", func(ctx context.Context, report func(progress any)) error {
+		return nil
+	}, context.Background())
+
+	for _, tc := range []struct {
+		apiKey string
+		status int
+	}{
+		{"key-alice", http.StatusOK},
+		{"key-ops", http.StatusOK},
+		{"key-bob", http.StatusNotFound},
+		{"", http.StatusUnauthorized},
+	} {
+		rec := request(e, http.MethodGet, "/jobs/"+jobId, tc.apiKey)
+		if rec.Code != tc.status {
+			t.Errorf("GET /jobs/:jobId with key '%s': got status %d, expected %d, body: %s", tc.apiKey, rec.Code, tc.status, rec.Body)
+		}
+	}
+}
+
+func TestJobsWithoutTenants(t *testing.T) {
+	s, e := newTestServer(t, "")
+	jobId := s.Jobs.Start("selfcheck", "", func(ctx context.Context, report func(progress any)) error {
+		return nil
+	}, context.Background())
+
+	if rec := request(e, http.MethodGet, "/jobs/"+jobId, ""); rec.Code != http.StatusOK {
+		t.Errorf("GET /jobs/:jobId: got status %d, expected %d, body: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+}
+
+func TestRequestErrorStatus(t *testing.T) {
+	for _, tc := range []struct {
+		err    error
+		status int
+	}{
+		{fmt.Errorf("%w: tenant 'alice' can't access bucket 'bob'", ErrForbidden), http.StatusForbidden},
+		{fmt.Errorf("%w: bucket 'alice' holds 10 blocks, 100 bytes", storage.ErrQuotaExceeded), http.StatusInsufficientStorage},
+		{fmt.Errorf("%w: tag 'system' is denied", listener.ErrTagNotAllowed), http.StatusForbidden},
+		{errors.New("invalid block id"), http.StatusBadRequest},
+	} {
+		if status := requestErrorStatus(tc.err); status != tc.status {
+			t.Errorf("got status %d for error '%v', expected %d", status, tc.err, tc.status)
+		}
+	}
+}
+
+func TestAdminRoutes(t *testing.T) {
+	for _, tc := range []struct {
+		tenants string
+		apiKey  string
+		status  int
+	}{
+		{testTenants, "key-ops", http.StatusOK},
+		{testTenants, "key-alice", http.StatusForbidden},
+		// without tenants nobody is an admin
+		{"", "", http.StatusForbidden},
+	} {
+		_, e := newTestServer(t, tc.tenants)
+		if rec := request(e, http.MethodGet, "/jobs", tc.apiKey); rec.Code != tc.status {
+			t.Errorf("GET /jobs with key '%s': got status %d, expected %d, body: %s", tc.apiKey, rec.Code, tc.status, rec.Body)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"collector/pkg/storage"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	iotago "github.com/iotaledger/iota.go/v3"
+	"github.com/minio/minio-go/v7/pkg/s3utils"
+)
+
+// validate is shared by all requests, it knows the custom tags of the API.
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+	// the tag is only registered once, at package initialization
+	_ = v.RegisterValidation("bucketname", func(fl validator.FieldLevel) bool {
+		return validateBucketName(fl.Field().String()) == nil
+	})
+	_ = v.RegisterValidation("blockid", func(fl validator.FieldLevel) bool {
+		_, err := normalizeBlockId(fl.Field().String())
+		return err == nil
+	})
+	return v
+}
+
+// validateBucketName enforces the S3 bucket naming rules: 3 to 63 characters, lowercase letters, numbers,
+// dots and hyphens, starting and ending with a letter or number, not formatted as an IP address.
+func validateBucketName(bucketName string) error {
+	err := s3utils.CheckValidBucketNameStrict(bucketName)
+	if err != nil {
+		return fmt.Errorf("invalid bucket name '%s': %w", bucketName, err)
+	}
+	return nil
+}
+
+// normalizeBlockId checks that blockId is a well-formed IOTA block id, with or without its 0x prefix,
+// and returns it in the lowercase unprefixed form used as object name.
+func normalizeBlockId(blockId string) (string, error) {
+	normalized := strings.TrimPrefix(strings.ToLower(blockId), "0x")
+	if len(normalized) != hex.EncodedLen(iotago.BlockIDLength) {
+		return "", fmt.Errorf("invalid block id '%s': expected %d hexadecimal characters", blockId, hex.EncodedLen(iotago.BlockIDLength))
+	}
+	_, err := iotago.BlockIDFromHexString("0x" + normalized)
+	if err != nil {
+		return "", fmt.Errorf("invalid block id '%s': %w", blockId, err)
+	}
+	return normalized, nil
+}
+
+// normalizeObjectName returns the object name of a block id. A name that isn't a block id is taken as is when keys
+// are allowed, i.e. when a filter stores its blocks under a KeyField, unless its 0x prefix tells it is meant as a
+// block id.
+func normalizeObjectName(name string, keysAllowed bool) (string, error) {
+	objectName, err := normalizeBlockId(name)
+	if err == nil {
+		return objectName, nil
+	}
+	if !keysAllowed || strings.HasPrefix(strings.ToLower(name), "0x") || storage.ValidateObjectName(name) != nil {
+		return "", err
+	}
+	return name, nil
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"collector/pkg/listener"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+const testBlockId = "0x" + "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+func TestNormalizeObjectName(t *testing.T) {
+	unprefixed := strings.TrimPrefix(testBlockId, "0x")
+	for _, tc := range []struct {
+		name        string
+		keysAllowed bool
+		expected    string
+		valid       bool
+	}{
+		{testBlockId, false, unprefixed, true},
+		{unprefixed, false, unprefixed, true},
+		{strings.ToUpper(unprefixed), false, unprefixed, true},
+		{"0X" + strings.ToUpper(unprefixed), false, unprefixed, true},
+		{testBlockId[:len(testBlockId)-1], false, "", false},
+		{testBlockId[:len(testBlockId)-1] + "g", false, "", false},
+		{"order-1", false, "", false},
+		{"order-1", true, "order-1", true},
+		{"42", true, "42", true},
+		// a 0x prefix means a block id, even with keys
+		{"0x1234", true, "", false},
+		{testBlockId[:len(testBlockId)-1] + "g", true, "", false},
+		{"with/slash", true, "", false},
+	} {
+		objectName, err := normalizeObjectName(tc.name, tc.keysAllowed)
+		if tc.valid != (err == nil) || objectName != tc.expected {
+			t.Errorf("got object name '%s', error %v for '%s' with keys allowed %t, expected '%s', valid %t", objectName, err, tc.name, tc.keysAllowed, tc.expected, tc.valid)
+		}
+	}
+}
+
+func TestBlockIdStatus(t *testing.T) {
+	s, e := newTestServer(t, "")
+	for _, tc := range []struct {
+		blockId string
+		status  int
+	}{
+		{testBlockId, http.StatusNotFound},
+		{"0xzz", http.StatusBadRequest},
+		{"order-1", http.StatusBadRequest},
+	} {
+		if rec := request(e, http.MethodGet, "/block/"+tc.blockId, ""); rec.Code != tc.status {
+			t.Errorf("GET /block/%s: got status %d, expected %d, body: %s", tc.blockId, rec.Code, tc.status, rec.Body)
+		}
+	}
+
+	filter, err := listener.NewFilter("keyed", false, "", "", "", false, listener.StoreFormatTaggedData)
+	if err != nil {
+		t.Fatalf("can't create the filter: %v", err)
+	}
+	filter.KeyField = "order.id"
+	if _, err := s.Collector.Listener.AddFilter(filter); err != nil {
+		t.Fatalf("can't add the filter: %v", err)
+	}
+	for _, tc := range []struct {
+		blockId string
+		status  int
+	}{
+		{"order-1", http.StatusNotFound},
+		{"0xzz", http.StatusBadRequest},
+	} {
+		if rec := request(e, http.MethodGet, "/block/"+tc.blockId, ""); rec.Code != tc.status {
+			t.Errorf("GET /block/%s with a keyed filter: got status %d, expected %d, body: %s", tc.blockId, rec.Code, tc.status, rec.Body)
+		}
+	}
+}
+
+func TestValidateBucketName(t *testing.T) {
+	for _, tc := range []struct {
+		bucketName string
+		valid      bool
+	}{
+		{"blocks", true},
+		{"blocks-2023.archive", true},
+		{"ab", false},
+		{strings.Repeat("a", 64), false},
+		{"Blocks", false},
+		{"blocks_archive", false},
+		{"-blocks", false},
+		{"blocks..archive", false},
+		{"192.168.1.1", false},
+	} {
+		if err := validateBucketName(tc.bucketName); (err == nil) != tc.valid {
+			t.Errorf("bucket name '%s': got error %v, expected valid %v", tc.bucketName, err, tc.valid)
+		}
+		err := validate.Struct(&RequestCreateBucket{BucketName: tc.bucketName})
+		if (err == nil) != tc.valid {
+			t.Errorf("request creating bucket '%s': got error %v, expected valid %v", tc.bucketName, err, tc.valid)
+		}
+	}
+}
+
+func TestInvalidBucketNameStatus(t *testing.T) {
+	_, e := newTestServer(t, "")
+	if rec := request(e, http.MethodGet, "/block/"+testBlockId+"?bucketName=Invalid_Bucket", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("GET /block/:blockId of an invalid bucket: got status %d, expected %d, body: %s", rec.Code, http.StatusBadRequest, rec.Body)
+	}
+}
@@ -6,10 +6,15 @@ import (
 	"collector/pkg/storage"
 	"context"
 	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/iotaledger/hive.go/core/app/pkg/shutdown"
 	"github.com/iotaledger/hive.go/core/logger"
 	"github.com/iotaledger/inx-app/nodebridge"
+	inx "github.com/iotaledger/inx/go"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 type Collector struct {
@@ -20,17 +25,44 @@ type Collector struct {
 	Listener        listener.Listener
 	Storage         storage.Storage
 	POIHandler      poi.POIHandler
+	Registry        *prometheus.Registry
+
+	objectCountInterval time.Duration
+	shutdownTimeout     time.Duration
+	// ready is set once the buckets are managed and the startup filters loaded
+	ready atomic.Bool
+	// parameters are the running parameters, reloading serializes their reloads
+	parameters Parameters
+	reloading  sync.Mutex
+}
+
+// Ready tells whether the buckets are managed and the startup filters loaded, requests changing the state of the
+// collector fail until then.
+func (c *Collector) Ready() bool {
+	return c.ready.Load()
+}
+
+// Client returns the client of the node the listener listens to, which may be a fallback node.
+func (c *Collector) Client() inx.INXClient {
+	if client := c.Listener.Client(); client != nil {
+		return client
+	}
+	return c.NodeBridge.Client()
 }
 
 func NewCollector(log *logger.Logger, bridge *nodebridge.NodeBridge,
 	shutdownHandler *shutdown.ShutdownHandler, storageParameters storage.Parameters, listenerParameters listener.Parameters, poiParameters poi.Parameters) (*Collector, error) {
 	collector := &Collector{
-		WrappedLogger:   logger.NewWrappedLogger(log),
-		NodeBridge:      bridge,
-		shutdownHandler: shutdownHandler,
+		WrappedLogger:       logger.NewWrappedLogger(log),
+		NodeBridge:          bridge,
+		shutdownHandler:     shutdownHandler,
+		Registry:            prometheus.NewRegistry(),
+		objectCountInterval: storageParameters.ObjectCountInterval,
+		shutdownTimeout:     listenerParameters.ShutdownTimeout,
+		parameters:          Parameters{Storage: storageParameters, Listener: listenerParameters, POI: poiParameters},
 	}
 
-	storage, err := storage.NewStorage(storageParameters, collector.WrappedLogger)
+	storage, err := storage.NewStorage(storageParameters, collector.Registry, collector.WrappedLogger)
 	if err != nil {
 		return collector, err
 	}
@@ -39,7 +71,7 @@ func NewCollector(log *logger.Logger, bridge *nodebridge.NodeBridge,
 	poiHandler := poi.NewPOIHandler(poiParameters)
 	collector.POIHandler = poiHandler
 
-	listener, err := listener.NewListener(listenerParameters, storage, poiHandler, collector.WrappedLogger)
+	listener, err := listener.NewListener(listenerParameters, storage, poiHandler, collector.Registry, collector.WrappedLogger)
 	if err != nil {
 		return collector, err
 	}
@@ -48,27 +80,44 @@ func NewCollector(log *logger.Logger, bridge *nodebridge.NodeBridge,
 	return collector, nil
 }
 
-func (c *Collector) Run(ctx context.Context) error {
+// Prepare manages the buckets and loads the startup filters, after which the collector is ready.
+func (c *Collector) Prepare(ctx context.Context) error {
+
+	// check storage connectivity before touching any bucket
+	err := c.Storage.Ping(ctx)
+	if err != nil {
+		c.WrappedLogger.LogErrorf("Can't reach storage : %w", err)
+		return err
+	}
 
 	// manage default storage
-	exists, err := c.Storage.CheckCreateBucket(c.Storage.DefaultBucketName, ctx)
+	err = c.manageBucket("default", c.Storage.DefaultBucketName, c.Storage.DefaultBucketExpirationDays, ctx)
 	if err != nil {
 		c.WrappedLogger.LogErrorf("Can't istantiate storage : %w", err)
 		return err
 	}
-	if exists {
-		days, err := c.Storage.GetBucketExpirationDays(c.Storage.DefaultBucketName, ctx)
+
+	// manage the storage of the proofs of inclusion, if kept apart
+	if c.Storage.POIBucketName != "" {
+		err = c.manageBucket("POI", c.Storage.POIBucketName, c.Storage.POIBucketExpirationDays, ctx)
 		if err != nil {
 			c.WrappedLogger.LogErrorf("Can't istantiate storage : %w", err)
 			return err
 		}
-		if days != c.Storage.DefaultBucketExpirationDays {
-			err = fmt.Errorf("default bucket already exists, but expiration days are %d instead of the specified %d", days, c.Storage.DefaultBucketExpirationDays)
+	}
+
+	// manage the storage of the event log, if enabled
+	if c.Storage.EventsBucketName != "" {
+		err = c.manageBucket("events", c.Storage.EventsBucketName, c.Storage.EventsBucketExpirationDays, ctx)
+		if err != nil {
 			c.WrappedLogger.LogErrorf("Can't istantiate storage : %w", err)
 			return err
 		}
-	} else {
-		err := c.Storage.SetBucketExpirationDays(c.Storage.DefaultBucketName, c.Storage.DefaultBucketExpirationDays, ctx)
+	}
+
+	// a default retention can only be applied if the default bucket supports object locking
+	if c.Storage.DefaultRetention().Days > 0 || c.Storage.DefaultRetention().LegalHold {
+		err = c.Storage.CheckObjectLock(c.Storage.DefaultBucketName, ctx)
 		if err != nil {
 			c.WrappedLogger.LogErrorf("Can't istantiate storage : %w", err)
 			return err
@@ -82,14 +131,107 @@ func (c *Collector) Run(ctx context.Context) error {
 		return err
 	}
 
+	c.ready.Store(true)
+	c.WrappedLogger.LogInfo("Collector ready")
+	return nil
+}
+
+func (c *Collector) Run(ctx context.Context) error {
+	err := c.Prepare(ctx)
+	if err != nil {
+		return err
+	}
+
+	if c.objectCountInterval > 0 {
+		go c.refreshObjectCounts(ctx)
+	}
+	go c.Listener.RetryUploads(ctx)
+	go c.Listener.RunWebhooks(ctx)
+	go c.Storage.RunClientCheck(ctx)
+	go c.Storage.RunDeletionWatch(ctx)
+	go c.Storage.RunUsageReconciliation(ctx)
+
 	// run listener
 	client := c.NodeBridge.Client()
+	startedAt := time.Now()
+	var unstored int64
+	defer func() {
+		c.logSessionSummary(startedAt, unstored)
+	}()
 	c.WrappedLogger.LogInfo("Running Listener ...")
-	err = c.Listener.Run(client, ctx)
+	// uploads get their own context, so the ones in flight at shutdown can still complete
+	storeCtx, cancelStore := context.WithCancel(context.Background())
+	defer cancelStore()
+	// the events of the in-flight uploads are still written
+	go c.Storage.RunEventLog(storeCtx)
+	go c.Storage.RunBatches(storeCtx)
+	err = c.Listener.Run(client, ctx, storeCtx)
 	if err != nil {
 		c.WrappedLogger.LogErrorf("Running Listener ... exit on error: %w", err)
 		return err
 	}
 
+	c.WrappedLogger.LogInfo("Finishing in-flight uploads ...")
+	unstored = c.Listener.Drain(c.shutdownTimeout)
+	// the batches are written once the blocks in flight are, the later ones are uploaded on their own; an
+	// unreachable storage must not hold the shutdown
+	flushCtx, cancelFlush := context.WithTimeout(storeCtx, c.shutdownTimeout)
+	defer cancelFlush()
+	c.Storage.FlushBatches(flushCtx)
+	// and the events of the stores are written last
+	c.Storage.DrainEventLog(flushCtx)
+	if unstored > 0 {
+		c.WrappedLogger.LogWarnf("Finishing in-flight uploads ... timed out, %d blocks were not stored", unstored)
+		return nil
+	}
+	c.WrappedLogger.LogInfo("Finishing in-flight uploads ... done")
+
+	return nil
+}
+
+// manageBucket creates a bucket the Collector is responsible for with the given expiration days,
+// or checks the expiration days of the existing one.
+func (c *Collector) manageBucket(role string, bucketName string, days int, ctx context.Context) error {
+	exists, err := c.Storage.CheckCreateBucket(bucketName, ctx)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return c.Storage.SetBucketExpirationDays(bucketName, days, ctx)
+	}
+
+	existingDays, err := c.Storage.GetBucketExpirationDays(bucketName, ctx)
+	if err != nil {
+		return err
+	}
+	if existingDays != days {
+		return fmt.Errorf("%s bucket already exists, but expiration days are %d instead of the specified %d", role, existingDays, days)
+	}
 	return nil
 }
+
+// managedBuckets returns the buckets the Collector is responsible for.
+func (c *Collector) managedBuckets() []string {
+	if c.Storage.POIBucketName != "" {
+		return []string{c.Storage.DefaultBucketName, c.Storage.POIBucketName}
+	}
+	return []string{c.Storage.DefaultBucketName}
+}
+
+func (c *Collector) refreshObjectCounts(ctx context.Context) {
+	ticker := time.NewTicker(c.objectCountInterval)
+	defer ticker.Stop()
+
+	for {
+		err := c.Storage.RefreshObjectCounts(c.managedBuckets(), ctx)
+		if err != nil {
+			c.WrappedLogger.LogWarnf("Refreshing object counts ... failed, error: %w", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
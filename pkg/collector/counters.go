@@ -0,0 +1,40 @@
+package collector
+
+import (
+	"collector/pkg/listener"
+	"expvar"
+	"sync"
+)
+
+// countersVar is the name the counters are published under by expvar.
+const countersVar = "collector"
+
+// publishCounters publishes the counters once, the expvar names are global to the process.
+var publishCounters sync.Once
+
+// Counters are the live counters of the collector, read from the atomic counters of its components.
+type Counters struct {
+	Ready bool `json:"ready"`
+	listener.SessionCounters
+	PendingBatchedObjects int `json:"pendingBatchedObjects"`
+	EventQueueDepth       int `json:"eventQueueDepth"`
+}
+
+// Counters returns the counters of the collector since it started, with the depths of its queues.
+func (c *Collector) Counters() Counters {
+	return Counters{
+		Ready:                 c.Ready(),
+		SessionCounters:       c.Listener.SessionCounters(),
+		PendingBatchedObjects: c.Storage.PendingBatchedObjects(),
+		EventQueueDepth:       c.Storage.EventQueueDepth(),
+	}
+}
+
+// PublishCounters publishes the counters of the collector with expvar, read every time the variables are served.
+func (c *Collector) PublishCounters() {
+	publishCounters.Do(func() {
+		expvar.Publish(countersVar, expvar.Func(func() any {
+			return c.Counters()
+		}))
+	})
+}
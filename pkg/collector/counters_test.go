@@ -0,0 +1,123 @@
+package collector
+
+import (
+	"collector/pkg/listener"
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"io"
+	"testing"
+	"time"
+
+	inx "github.com/iotaledger/inx/go"
+	iotago "github.com/iotaledger/iota.go/v3"
+	"google.golang.org/grpc"
+)
+
+// fakeINXClient streams referenced blocks carrying tagged data payloads, cancelling the listening once they are
+// all received.
+type fakeINXClient struct {
+	inx.INXClient
+	blocks     map[string]*iotago.Block
+	referenced []*inx.BlockMetadata
+	cancel     context.CancelFunc
+}
+
+func (c *fakeINXClient) addBlock(id byte, tag string) {
+	blockId := make([]byte, iotago.BlockIDLength)
+	blockId[0] = id
+	c.blocks[string(blockId)] = &iotago.Block{
+		ProtocolVersion: 2,
+		Parents:         iotago.BlockIDs{{}},
+		Payload:         &iotago.TaggedData{Tag: []byte(tag), Data: []byte{id}},
+	}
+	c.referenced = append(c.referenced, &inx.BlockMetadata{BlockId: &inx.BlockId{Id: blockId}})
+}
+
+type fakeReferencedBlocks struct {
+	grpc.ClientStream
+	client *fakeINXClient
+}
+
+func (s *fakeReferencedBlocks) Recv() (*inx.BlockMetadata, error) {
+	if len(s.client.referenced) == 0 {
+		s.client.cancel()
+		return nil, io.EOF
+	}
+	next := s.client.referenced[0]
+	s.client.referenced = s.client.referenced[1:]
+	return next, nil
+}
+
+func (c *fakeINXClient) ListenToReferencedBlocks(ctx context.Context, in *inx.NoParams, opts ...grpc.CallOption) (inx.INX_ListenToReferencedBlocksClient, error) {
+	return &fakeReferencedBlocks{client: c}, nil
+}
+
+func (c *fakeINXClient) ReadBlock(ctx context.Context, in *inx.BlockId, opts ...grpc.CallOption) (*inx.RawBlock, error) {
+	block, ok := c.blocks[string(in.GetId())]
+	if !ok {
+		return nil, errors.New("unknown block")
+	}
+	return inx.WrapBlock(block)
+}
+
+func (c *fakeINXClient) ReadMilestone(ctx context.Context, in *inx.MilestoneRequest, opts ...grpc.CallOption) (*inx.Milestone, error) {
+	return nil, errors.New("milestone not found")
+}
+
+// publishedCounters returns the counters served by expvar.
+func publishedCounters(t *testing.T) Counters {
+	t.Helper()
+	published := expvar.Get(countersVar)
+	if published == nil {
+		t.Fatalf("the counters are not published")
+	}
+	var counters Counters
+	if err := json.Unmarshal([]byte(published.String()), &counters); err != nil {
+		t.Fatalf("can't read the published counters '%s': %v", published, err)
+	}
+	return counters
+}
+
+func TestPublishCounters(t *testing.T) {
+	c := newTestCollector(t, testParameters())
+	c.PublishCounters()
+	// publishing again doesn't register the counters twice, expvar would panic
+	c.PublishCounters()
+	if counters := publishedCounters(t); counters != (Counters{}) {
+		t.Errorf("got counters %+v before any block, expected none", counters)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := c.Prepare(ctx); err != nil {
+		t.Fatalf("can't prepare the collector: %v", err)
+	}
+	filter, err := listener.NewFilter("counted", false, "", c.Storage.DefaultBucketName, "", false, listener.StoreFormatTaggedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Listener.AddFilter(filter); err != nil {
+		t.Fatalf("can't add the filter: %v", err)
+	}
+	client := &fakeINXClient{blocks: make(map[string]*iotago.Block), cancel: cancel}
+	client.addBlock(1, "counted")
+	client.addBlock(2, "ignored")
+	client.addBlock(3, "counted")
+	if err := c.Listener.Run(client, ctx, context.Background()); err != nil {
+		t.Fatalf("listening failed: %v", err)
+	}
+	if pending := c.Listener.Drain(5 * time.Second); pending != 0 {
+		t.Fatalf("%d blocks still in flight", pending)
+	}
+
+	// the published values are read again every time they are served
+	counters := publishedCounters(t)
+	if !counters.Ready || counters.BlocksProcessed != 3 || counters.BlocksStored != 2 || counters.BlocksFailed != 0 {
+		t.Errorf("got counters %+v, expected ready with 3 blocks processed and 2 stored", counters)
+	}
+	if counters != c.Counters() {
+		t.Errorf("got published counters %+v, expected %+v", counters, c.Counters())
+	}
+}
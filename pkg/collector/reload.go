@@ -0,0 +1,100 @@
+package collector
+
+import (
+	"collector/pkg/listener"
+	"collector/pkg/poi"
+	"collector/pkg/storage"
+	"reflect"
+
+	"github.com/iotaledger/hive.go/core/configuration"
+)
+
+// Parameters are the parameters of the components of the collector, as read from the configuration.
+type Parameters struct {
+	Storage  storage.Parameters  `name:"storage"`
+	Listener listener.Parameters `name:"listener"`
+	POI      poi.Parameters      `name:"POI"`
+}
+
+// ReloadResult lists the parameters changed in the configuration since startup, the ones applied to the running
+// collector and the ones only taking effect on restart.
+type ReloadResult struct {
+	Applied        []string `json:"applied"`
+	RequireRestart []string `json:"requireRestart"`
+}
+
+// reloaders apply a parameter to the running collector, returning false if it can't be applied without a restart.
+var reloaders = map[string]func(c *Collector, params Parameters) bool{
+	"listener.backfillConcurrency": func(c *Collector, params Parameters) bool {
+		c.Listener.SetBackfillConcurrency(params.Listener.BackfillConcurrency)
+		return true
+	},
+	"listener.tagAllowlist": reloadTagLists,
+	"listener.tagDenylist":  reloadTagLists,
+	"listener.logSamplingWindow": func(c *Collector, params Parameters) bool {
+		c.Listener.SetLogSamplingWindow(params.Listener.LogSamplingWindow)
+		return true
+	},
+	"listener.nodeCheckInterval": func(c *Collector, params Parameters) bool {
+		c.Listener.SetNodeCheckInterval(params.Listener.NodeCheckInterval)
+		return true
+	},
+	"storage.clientCheckInterval": func(c *Collector, params Parameters) bool {
+		return c.Storage.SetClientCheckInterval(params.Storage.ClientCheckInterval)
+	},
+}
+
+func reloadTagLists(c *Collector, params Parameters) bool {
+	c.Listener.SetTagLists(params.Listener.TagAllowlist, params.Listener.TagDenylist)
+	return true
+}
+
+// Reload applies the parameters changed since startup that can be applied while running. The other ones are reported
+// by every reload, until the collector is restarted.
+func (c *Collector) Reload(params Parameters) ReloadResult {
+	c.reloading.Lock()
+	defer c.reloading.Unlock()
+	c.WrappedLogger.LogInfo("Reloading parameters ...")
+
+	result := ReloadResult{Applied: []string{}, RequireRestart: []string{}}
+	running := reflect.ValueOf(&c.parameters).Elem()
+	reloaded := reflect.ValueOf(params)
+	for i := 0; i < running.NumField(); i++ {
+		namespace := running.Type().Field(i).Tag.Get("name")
+		fields := running.Field(i)
+		for j := 0; j < fields.NumField(); j++ {
+			value := reloaded.Field(i).Field(j)
+			if reflect.DeepEqual(fields.Field(j).Interface(), value.Interface()) {
+				continue
+			}
+			name := namespace + "." + configuration.LowerCamelCase(fields.Type().Field(j).Name)
+			reload, ok := reloaders[name]
+			switch {
+			case !ok:
+				result.RequireRestart = append(result.RequireRestart, name)
+			case !reload(c, params):
+				c.WrappedLogger.LogWarnf("Parameter '%s' can't be changed to this value while running, it requires a restart", name)
+				result.RequireRestart = append(result.RequireRestart, name)
+			default:
+				// the running parameters only take the applied values, the others keep being reported
+				fields.Field(j).Set(value)
+				result.Applied = append(result.Applied, name)
+			}
+		}
+	}
+	c.WrappedLogger.LogInfof("Reloading parameters ... done, %d applied, %d requiring a restart", len(result.Applied), len(result.RequireRestart))
+	return result
+}
+
+// ChangedParameters returns the names of the fields differing between two parameter structs of the same type, as
+// they are named in the configuration under namespace.
+func ChangedParameters(namespace string, running any, reloaded any) []string {
+	runningValue, reloadedValue := reflect.ValueOf(running), reflect.ValueOf(reloaded)
+	changed := []string{}
+	for i := 0; i < runningValue.NumField(); i++ {
+		if !reflect.DeepEqual(runningValue.Field(i).Interface(), reloadedValue.Field(i).Interface()) {
+			changed = append(changed, namespace+"."+configuration.LowerCamelCase(runningValue.Type().Field(i).Name))
+		}
+	}
+	return changed
+}
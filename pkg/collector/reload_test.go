@@ -0,0 +1,65 @@
+package collector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/iotaledger/hive.go/core/configuration"
+	"github.com/iotaledger/hive.go/core/logger"
+	flag "github.com/spf13/pflag"
+)
+
+// testParameters returns the default parameters, over an in-memory storage.
+func testParameters() Parameters {
+	var params Parameters
+	config := configuration.New()
+	flagSet := configuration.NewUnsortedFlagSet("test", flag.ContinueOnError)
+	config.BindParameters(flagSet, "storage", &params.Storage)
+	config.BindParameters(flagSet, "listener", &params.Listener)
+	config.BindParameters(flagSet, "POI", &params.POI)
+	params.Storage.InMemory = true
+	return params
+}
+
+func newTestCollector(t *testing.T, params Parameters) *Collector {
+	t.Helper()
+	c, err := NewCollector(logger.NewNopLogger(), nil, nil, params.Storage, params.Listener, params.POI)
+	if err != nil {
+		t.Fatalf("can't create the collector: %v", err)
+	}
+	return c
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+func TestReloadNodeCheckInterval(t *testing.T) {
+	params := testParameters()
+	c := newTestCollector(t, params)
+
+	reloaded := params
+	reloaded.Listener.NodeCheckInterval = params.Listener.NodeCheckInterval + time.Minute
+	reloaded.Storage.DefaultBucketName = "renamed"
+	result := c.Reload(reloaded)
+	if !contains(result.Applied, "listener.nodeCheckInterval") || len(result.Applied) != 1 {
+		t.Errorf("got applied %v, expected the node check interval only", result.Applied)
+	}
+	if !contains(result.RequireRestart, "storage.defaultBucketName") || len(result.RequireRestart) != 1 {
+		t.Errorf("got %v requiring a restart, expected the default bucket name only", result.RequireRestart)
+	}
+	if c.parameters.Listener.NodeCheckInterval != reloaded.Listener.NodeCheckInterval {
+		t.Errorf("got running node check interval %v, expected %v", c.parameters.Listener.NodeCheckInterval, reloaded.Listener.NodeCheckInterval)
+	}
+
+	// the applied parameters are not reported again, the ones requiring a restart are until then
+	result = c.Reload(reloaded)
+	if len(result.Applied) != 0 || !contains(result.RequireRestart, "storage.defaultBucketName") {
+		t.Errorf("got applied %v and %v requiring a restart reloading again", result.Applied, result.RequireRestart)
+	}
+}
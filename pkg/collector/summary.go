@@ -0,0 +1,37 @@
+package collector
+
+import (
+	"collector/pkg/listener"
+	"encoding/json"
+	"time"
+)
+
+// SessionSummary is logged on shutdown, to tell what the session collected and what was left behind: the blocks
+// still in flight or batched when the collector stopped are lost, the queued retries and notifications too.
+type SessionSummary struct {
+	StartedAt time.Time `json:"startedAt"`
+	Uptime    string    `json:"uptime"`
+	listener.SessionCounters
+	BlocksUnstored        int64 `json:"blocksUnstored"`
+	PendingBatchedObjects int   `json:"pendingBatchedObjects"`
+	EventQueueDepth       int   `json:"eventQueueDepth"`
+}
+
+// logSessionSummary logs the summary of the session. It only reads counters kept in memory, so that it never
+// waits on an unreachable storage.
+func (c *Collector) logSessionSummary(startedAt time.Time, unstored int64) {
+	summary := SessionSummary{
+		StartedAt:             startedAt.UTC(),
+		Uptime:                time.Since(startedAt).Round(time.Second).String(),
+		SessionCounters:       c.Listener.SessionCounters(),
+		BlocksUnstored:        unstored,
+		PendingBatchedObjects: c.Storage.PendingBatchedObjects(),
+		EventQueueDepth:       c.Storage.EventQueueDepth(),
+	}
+	data, err := json.Marshal(summary)
+	if err != nil {
+		c.WrappedLogger.LogWarnf("Can't encode the session summary, error: %w", err)
+		return
+	}
+	c.WrappedLogger.LogInfof("Session summary: %s", data)
+}
@@ -0,0 +1,166 @@
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/iotaledger/hive.go/core/logger"
+)
+
+const (
+	StateRunning   = "running"
+	StateDone      = "done"
+	StateFailed    = "failed"
+	StateCancelled = "cancelled"
+)
+
+var (
+	ErrNotFound   = errors.New("job not found")
+	ErrNotRunning = errors.New("job is not running")
+)
+
+// Job reports the state of a background job, Progress is specific to its kind.
+type Job struct {
+	Id         string     `json:"id"`
+	Kind       string     `json:"kind"`
+	Tenant     string     `json:"tenant,omitempty"`
+	State      string     `json:"state"`
+	Progress   any        `json:"progress,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	StartedAt  time.Time  `json:"startedAt"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+}
+
+// Func runs a job until it is done or ctx is cancelled, publishing its progress with report.
+// The progress values must not be modified once reported.
+type Func func(ctx context.Context, report func(progress any)) error
+
+type job struct {
+	Job
+	cancel context.CancelFunc
+}
+
+// Manager runs long operations in the background and keeps track of them, along with a bounded
+// history of the finished ones.
+type Manager struct {
+	*logger.WrappedLogger
+	sync.RWMutex
+	jobs        map[string]*job
+	finished    []string
+	historySize int
+}
+
+func NewManager(historySize int, log *logger.WrappedLogger) *Manager {
+	return &Manager{
+		WrappedLogger: logger.NewWrappedLogger(log.LoggerNamed("Jobs")),
+		jobs:          make(map[string]*job),
+		historySize:   historySize,
+	}
+}
+
+// newJobId returns a random id, jobs started at the same instant don't share it.
+func newJobId() string {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// Start runs fn in the background for a tenant, empty without API keys, and returns the id of its job. The job is
+// cancelled with ctx.
+func (m *Manager) Start(kind string, tenant string, fn Func, ctx context.Context) string {
+	jobCtx, cancel := context.WithCancel(ctx)
+	j := &job{
+		Job: Job{
+			Id:        newJobId(),
+			Kind:      kind,
+			Tenant:    tenant,
+			State:     StateRunning,
+			StartedAt: time.Now(),
+		},
+		cancel: cancel,
+	}
+	m.Lock()
+	m.jobs[j.Id] = j
+	m.Unlock()
+
+	m.WrappedLogger.LogInfof("Job '%s' (%s) started", j.Id, kind)
+	go func() {
+		defer cancel()
+		err := fn(jobCtx, func(progress any) {
+			m.Lock()
+			defer m.Unlock()
+			j.Progress = progress
+		})
+		m.finish(j, err, jobCtx.Err() != nil)
+	}()
+
+	return j.Id
+}
+
+func (m *Manager) finish(j *job, err error, cancelled bool) {
+	m.Lock()
+	defer m.Unlock()
+
+	finishedAt := time.Now()
+	j.FinishedAt = &finishedAt
+	switch {
+	case cancelled:
+		j.State = StateCancelled
+	case err != nil:
+		j.State = StateFailed
+		j.Error = err.Error()
+	default:
+		j.State = StateDone
+	}
+	m.WrappedLogger.LogInfof("Job '%s' (%s) finished, state: %s", j.Id, j.Kind, j.State)
+
+	// forget the oldest finished jobs beyond the history size
+	m.finished = append(m.finished, j.Id)
+	for len(m.finished) > m.historySize {
+		delete(m.jobs, m.finished[0])
+		m.finished = m.finished[1:]
+	}
+}
+
+// Get returns a copy of a job.
+func (m *Manager) Get(jobId string) (Job, bool) {
+	m.RLock()
+	defer m.RUnlock()
+	j, ok := m.jobs[jobId]
+	if !ok {
+		return Job{}, false
+	}
+	return j.Job, true
+}
+
+// List returns a copy of the running jobs and of the finished ones still in the history, oldest first.
+func (m *Manager) List() []Job {
+	m.RLock()
+	defer m.RUnlock()
+	jobs := make([]Job, 0, len(m.jobs))
+	for _, j := range m.jobs {
+		jobs = append(jobs, j.Job)
+	}
+	sort.Slice(jobs, func(i, k int) bool { return jobs[i].StartedAt.Before(jobs[k].StartedAt) })
+	return jobs
+}
+
+// Cancel stops a running job, it is reported as cancelled once its function returns.
+func (m *Manager) Cancel(jobId string) error {
+	m.RLock()
+	defer m.RUnlock()
+	j, ok := m.jobs[jobId]
+	if !ok {
+		return ErrNotFound
+	}
+	if j.State != StateRunning {
+		return ErrNotRunning
+	}
+	j.cancel()
+	return nil
+}
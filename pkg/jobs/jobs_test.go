@@ -0,0 +1,23 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+
+	"github.com/iotaledger/hive.go/core/logger"
+)
+
+func TestJobIdsUnique(t *testing.T) {
+	m := NewManager(100, logger.NewWrappedLogger(logger.NewNopLogger()))
+	ids := make(map[string]bool)
+	// started in a tight loop, several jobs share their start time
+	for i := 0; i < 100; i++ {
+		id := m.Start("selfcheck", "", func(ctx context.Context, report func(progress any)) error {
+			return nil
+		}, context.Background())
+		if ids[id] {
+			t.Fatalf("job id '%s' was given twice", id)
+		}
+		ids[id] = true
+	}
+}
@@ -0,0 +1,90 @@
+package listener
+
+import (
+	"bytes"
+	"collector/pkg/storage"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+func (f *Filter) validateAlias() error {
+	if f.AliasField == "" {
+		return nil
+	}
+	if f.MatchAll {
+		return fmt.Errorf("a filter matching every block can't have an alias field")
+	}
+	// the alias is read before the transform, a redacted value must not be indexed
+	if f.redacts(f.AliasField) {
+		return fmt.Errorf("alias field '%s' is redacted", f.AliasField)
+	}
+	return nil
+}
+
+// redacts tells whether the transform of the filter removes the value at a dotted path of the payload.
+func (f *Filter) redacts(path string) bool {
+	if f.Transform != TransformFieldRedact {
+		return false
+	}
+	fields := strings.Split(path, ".")
+	for _, field := range f.RedactFields {
+		if field == fields[len(fields)-1] {
+			return true
+		}
+	}
+	return false
+}
+
+// aliasOf reads the alias of a block from the payload field named by the filter's AliasField, a dotted path
+// into a JSON payload. Only string and number values are aliases; a payload without the field has none.
+func (f *Filter) aliasOf(payload []byte) (string, bool) {
+	if f.AliasField == "" {
+		return "", false
+	}
+	alias, ok := payloadField(payload, f.AliasField)
+	if !ok || storage.ValidateAlias(alias) != nil {
+		return "", false
+	}
+	return alias, true
+}
+
+// payloadField reads the string or number at a dotted path of a JSON payload.
+func payloadField(payload []byte, path string) (string, bool) {
+	value, ok := payloadValue(payload, path)
+	if !ok {
+		return "", false
+	}
+	return scalarString(value)
+}
+
+// payloadValue reads the value at a dotted path of a JSON payload, numbers being kept as they are written.
+func payloadValue(payload []byte, path string) (any, bool) {
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+	var value any
+	if decoder.Decode(&value) != nil {
+		return nil, false
+	}
+	for _, field := range strings.Split(path, ".") {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		value, ok = object[field]
+		if !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+func scalarString(value any) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	}
+	return "", false
+}
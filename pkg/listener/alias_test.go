@@ -0,0 +1,51 @@
+package listener
+
+import (
+	"context"
+	"encoding/hex"
+	"testing"
+	"time"
+)
+
+func TestFilterAlias(t *testing.T) {
+	filter := Filter{AliasField: "order.id"}
+	for _, tc := range []struct {
+		payload string
+		alias   string
+	}{
+		{`{"order": {"id": "a-1"}}`, "a-1"},
+		{`{"order": {"id": 12345678901234567890}}`, "12345678901234567890"},
+		{`{"order": {"id": true}}`, ""},
+		{`{"order": {}}`, ""},
+		{`{"order": {"id": ""}}`, ""},
+		{`not json`, ""},
+	} {
+		if alias, _ := filter.aliasOf([]byte(tc.payload)); alias != tc.alias {
+			t.Errorf("payload %s: got alias '%s', expected '%s'", tc.payload, alias, tc.alias)
+		}
+	}
+
+	redacted := Filter{Tag: "alias", AliasField: "order.id", Transform: TransformFieldRedact, RedactFields: []string{"id"}}
+	if err := redacted.validateAlias(); err == nil {
+		t.Error("got a redacted alias field validated, expected an error")
+	}
+}
+
+func TestStoreWithAlias(t *testing.T) {
+	l := newTestListener(t, nil)
+	filter, err := NewFilter("alias", false, "", l.Storage.DefaultBucketName, "", false, StoreFormatTaggedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	filter.AliasField = "order.id"
+	if _, err := l.AddFilter(filter); err != nil {
+		t.Fatalf("can't add the filter: %v", err)
+	}
+
+	block := referenced(1, "alias", `{"order": {"id": 42}}`, time.Now(), l)
+	l.storeBlock(block, context.Background())
+	blockId, err := l.Storage.ResolveAlias(l.Storage.DefaultBucketName, "42", context.Background())
+	if expected := hex.EncodeToString(block.blockId.GetId()); err != nil || blockId != expected {
+		t.Errorf("got block '%s', error %v resolving the alias, expected '%s'", blockId, err, expected)
+	}
+}
@@ -0,0 +1,35 @@
+package listener
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/iotaledger/hive.go/serializer/v2"
+	iotago "github.com/iotaledger/iota.go/v3"
+)
+
+// AttachmentKey returns the key grouping the attachments of a tagged data payload: the hash of its serialization,
+// the same for every block carrying the same tag and data. It returns false for blocks without such a payload.
+func AttachmentKey(taggedData iotago.TaggedData, ctx context.Context) (string, bool, error) {
+	if len(taggedData.Tag) == 0 && len(taggedData.Data) == 0 {
+		return "", false, nil
+	}
+	payloadBytes, err := taggedData.Serialize(serializer.DeSeriModeNoValidation, ctx)
+	if err != nil {
+		return "", false, err
+	}
+	sum := sha256.Sum256(payloadBytes)
+	return hex.EncodeToString(sum[:]), true, nil
+}
+
+// recordAttachment adds a block to the manifest of the blocks carrying its payload, failures are only logged.
+func (l *Listener) recordAttachment(taggedData iotago.TaggedData, bucketName string, blockId string, ctx context.Context) {
+	key, ok, err := AttachmentKey(taggedData, ctx)
+	if err == nil && ok {
+		err = l.Storage.AddAttachment(bucketName, key, blockId, ctx)
+	}
+	if err != nil {
+		l.sampledLog.LogWarnf("Can't record the attachment '%s', error: %w", blockId, err)
+	}
+}
@@ -0,0 +1,52 @@
+package listener
+
+import (
+	"context"
+	"encoding/hex"
+	"testing"
+	"time"
+)
+
+func TestTrackAttachments(t *testing.T) {
+	l := newTestListener(t, func(params *Parameters) {
+		params.TrackAttachments = true
+	})
+	addTaggedDataFilter(t, l, "attached")
+	ctx := context.Background()
+
+	first := referenced(1, "attached", "same data", time.Now(), l)
+	second := referenced(2, "attached", "same data", time.Now(), l)
+	other := referenced(3, "attached", "other data", time.Now(), l)
+	for _, block := range []*referencedBlock{first, second, other, first} {
+		l.storeBlock(block, ctx)
+	}
+
+	key, ok, err := AttachmentKey(first.taggedData, ctx)
+	if err != nil || !ok {
+		t.Fatalf("got no attachment key, error %v", err)
+	}
+	attachments, err := l.Storage.GetAttachments(l.Storage.DefaultBucketName, key, ctx)
+	if err != nil {
+		t.Fatalf("can't get the attachments: %v", err)
+	}
+	// a block stored again is recorded once
+	expected := []string{hex.EncodeToString(first.blockId.GetId()), hex.EncodeToString(second.blockId.GetId())}
+	if attachments.PayloadHash != key || len(attachments.BlockIds) != 2 || attachments.BlockIds[0] != expected[0] || attachments.BlockIds[1] != expected[1] {
+		t.Errorf("got attachments %+v, expected %v", attachments, expected)
+	}
+	if otherKey, _, _ := AttachmentKey(other.taggedData, ctx); otherKey == key {
+		t.Error("different payloads share their attachment key")
+	}
+
+	// the manifests are not listed with the blocks
+	count := 0
+	for entry := range l.Storage.ListObjects(l.Storage.DefaultBucketName, time.Time{}, ctx) {
+		if entry.Err != nil {
+			t.Fatalf("can't list the objects: %v", entry.Err)
+		}
+		count++
+	}
+	if count != 3 {
+		t.Errorf("got %d objects listed, expected the 3 blocks", count)
+	}
+}
@@ -0,0 +1,143 @@
+package listener
+
+import (
+	"collector/pkg/storage"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/iotaledger/hive.go/serializer/v2"
+	inx "github.com/iotaledger/inx/go"
+	iotago "github.com/iotaledger/iota.go/v3"
+)
+
+// BackfillProgress reports the progress of a backfill job.
+type BackfillProgress struct {
+	From                uint32 `json:"from"`
+	To                  uint32 `json:"to"`
+	Tag                 string `json:"tag,omitempty"`
+	ProcessedMilestones uint32 `json:"processedMilestones"`
+	ProcessedBlocks     uint64 `json:"processedBlocks"`
+	Errors              uint64 `json:"errors"`
+}
+
+// CheckBackfillRange validates a milestone range before a backfill is started.
+func CheckBackfillRange(from uint32, to uint32) error {
+	if from == 0 || to < from {
+		return fmt.Errorf("invalid milestone range %d to %d", from, to)
+	}
+	return nil
+}
+
+// SetBackfillConcurrency sets the number of milestones the next backfills process in parallel.
+func (l *Listener) SetBackfillConcurrency(concurrency int) {
+	l.backfillConcurrency.Store(int64(concurrency))
+}
+
+// Backfill runs the blocks referenced by the milestones in [from, to] through the active filters,
+// until done or ctx is cancelled. If tag is not empty only blocks with that tag are considered.
+func (l *Listener) Backfill(from uint32, to uint32, tag string, client inx.INXClient, ctx context.Context, report func(progress any)) error {
+	err := CheckBackfillRange(from, to)
+	if err != nil {
+		return err
+	}
+
+	l.WrappedLogger.LogInfof("Backfill of milestones %d to %d started", from, to)
+	ctx = storage.ContextWithOrigin(ctx, storage.OriginBackfill)
+	concurrency := int(l.backfillConcurrency.Load())
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var mu sync.Mutex
+	progress := BackfillProgress{From: from, To: to, Tag: tag}
+	report(progress)
+
+	milestones := make(chan uint32)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range milestones {
+				blocks, errors := l.backfillMilestone(index, tag, client, ctx)
+				mu.Lock()
+				progress.ProcessedMilestones++
+				progress.ProcessedBlocks += blocks
+				progress.Errors += errors
+				// the reported value is a copy, it is not modified afterwards
+				report(progress)
+				mu.Unlock()
+			}
+		}()
+	}
+
+	// a wider index avoids overflowing when to is the maximum milestone index
+	cancelled := false
+	for index := uint64(from); index <= uint64(to) && !cancelled; index++ {
+		select {
+		case <-ctx.Done():
+			cancelled = true
+		case milestones <- uint32(index):
+		}
+	}
+	close(milestones)
+	wg.Wait()
+
+	if cancelled {
+		l.WrappedLogger.LogInfof("Backfill of milestones %d to %d cancelled", from, to)
+		return ctx.Err()
+	}
+	l.WrappedLogger.LogInfof("Backfill of milestones %d to %d finished", from, to)
+	return nil
+}
+
+// backfillMilestone stores the matching blocks of a milestone cone, returning the processed blocks and the errors.
+func (l *Listener) backfillMilestone(index uint32, tag string, client inx.INXClient, ctx context.Context) (uint64, uint64) {
+	var blocks, errors uint64
+
+	stream, err := client.ReadMilestoneCone(ctx, &inx.MilestoneRequest{MilestoneIndex: index})
+	if err != nil {
+		l.WrappedLogger.LogErrorf("Backfill can't read milestone %d, error: %w", index, err)
+		return blocks, errors + 1
+	}
+
+	referencedAt := l.referencedAt(index, client, ctx)
+	for {
+		blockWithMetadata, err := stream.Recv()
+		if err == io.EOF {
+			return blocks, errors
+		}
+		if err != nil {
+			l.WrappedLogger.LogErrorf("Backfill can't receive block of milestone %d, error: %w", index, err)
+			return blocks, errors + 1
+		}
+
+		block, err := blockWithMetadata.GetBlock().UnwrapBlock(serializer.DeSeriModeNoValidation, &iotago.ProtocolParameters{})
+		if err != nil {
+			l.WrappedLogger.LogErrorf("Backfill could not process block, error: %w", err)
+			errors++
+			continue
+		}
+		taggedData, err := GetTaggedDataFromBlock(block, ctx)
+		if err != nil {
+			l.WrappedLogger.LogErrorf("Backfill could not process block, error: %w", err)
+			errors++
+			continue
+		}
+		blocks++
+		if tag != "" && string(taggedData.Tag) != tag {
+			continue
+		}
+
+		blockId := blockWithMetadata.GetMetadata().GetBlockId()
+		for _, filter := range l.filters.matching(taggedData) {
+			err := l.checkAndStore(taggedData, filter, block, blockId, referencedAt, ctx)
+			if err != nil {
+				l.WrappedLogger.LogErrorf("Tagged data error: %w", err)
+				errors++
+			}
+		}
+	}
+}
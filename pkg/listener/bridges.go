@@ -0,0 +1,210 @@
+package listener
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	inx "github.com/iotaledger/inx/go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+const (
+	// primaryNode names the node of the node bridge in the logs and the status.
+	primaryNode = "primary"
+	// bridgeCheckTimeout bounds a health check of a node.
+	bridgeCheckTimeout = 5 * time.Second
+	// maxReconnectDelay bounds the delay between two rounds of connection attempts while no node is healthy.
+	maxReconnectDelay = time.Minute
+)
+
+// nodeBridge is an INX connection to a node.
+type nodeBridge struct {
+	address string
+	client  inx.INXClient
+	conn    *grpc.ClientConn
+}
+
+// bridges are the node bridge, the primary, followed by the fallback nodes in order of preference.
+type bridges struct {
+	sync.RWMutex
+	nodes []nodeBridge
+	// checkInterval is a time.Duration, it can be changed while a fallback node is listened to
+	checkInterval atomic.Int64
+	// active is the index of the node being listened to
+	active atomic.Int32
+}
+
+// newBridges connects to the fallback nodes, the connections are established lazily so that a node down at startup
+// doesn't prevent it.
+func newBridges(params Parameters) (*bridges, error) {
+	b := &bridges{nodes: []nodeBridge{{address: primaryNode}}}
+	b.checkInterval.Store(int64(params.NodeCheckInterval))
+	for _, address := range strings.Split(params.FallbackINXAddresses, ",") {
+		address = strings.TrimSpace(address)
+		if address == "" {
+			continue
+		}
+		conn, err := grpc.Dial(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			b.close()
+			return nil, fmt.Errorf("invalid fallback INX address '%s', error: %w", address, err)
+		}
+		b.nodes = append(b.nodes, nodeBridge{address: address, client: inx.NewINXClient(conn), conn: conn})
+	}
+	return b, nil
+}
+
+// setPrimary sets the client of the node bridge, known once the plugin is connected to it.
+func (b *bridges) setPrimary(client inx.INXClient) {
+	b.Lock()
+	defer b.Unlock()
+	b.nodes[0].client = client
+}
+
+func (b *bridges) node(index int) nodeBridge {
+	b.RLock()
+	defer b.RUnlock()
+	return b.nodes[index]
+}
+
+// activeNode returns the node being listened to.
+func (b *bridges) activeNode() nodeBridge {
+	return b.node(int(b.active.Load()))
+}
+
+// healthy tells whether a node answers and reports itself healthy.
+func (b *bridges) healthy(index int, ctx context.Context) bool {
+	node := b.node(index)
+	if node.client == nil {
+		return false
+	}
+	checkCtx, cancel := context.WithTimeout(ctx, bridgeCheckTimeout)
+	defer cancel()
+	status, err := node.client.ReadNodeStatus(checkCtx, &inx.NoParams{})
+	return err == nil && status.GetIsHealthy()
+}
+
+// pick returns the first healthy node in order of preference. Without fallbacks the node bridge is always picked,
+// its stream tells whether it is reachable.
+func (b *bridges) pick(ctx context.Context) (int, bool) {
+	if len(b.nodes) == 1 {
+		return 0, true
+	}
+	for index := range b.nodes {
+		if b.healthy(index, ctx) {
+			return index, true
+		}
+	}
+	return 0, false
+}
+
+// watchPrimary checks the primary node every checkInterval while a fallback one is listened to, calling recovered
+// once it is healthy again. The interval is read again after every check.
+func (b *bridges) watchPrimary(ctx context.Context, recovered func()) {
+	for {
+		interval := time.Duration(b.checkInterval.Load())
+		if interval <= 0 {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+		if b.healthy(0, ctx) {
+			recovered()
+			return
+		}
+	}
+}
+
+// close closes the connections to the fallback nodes, the node bridge is closed by its component.
+func (b *bridges) close() {
+	for _, node := range b.nodes[1:] {
+		if node.conn != nil {
+			_ = node.conn.Close()
+		}
+	}
+}
+
+// SetNodeCheckInterval sets how often the node bridge is checked while a fallback node is listened to, from the
+// next check on.
+func (l *Listener) SetNodeCheckInterval(interval time.Duration) {
+	l.bridges.checkInterval.Store(int64(interval))
+}
+
+// Client returns the client of the node being listened to, the node bridge until the listener runs.
+func (l *Listener) Client() inx.INXClient {
+	return l.bridges.activeNode().client
+}
+
+// runWithFailover listens to the first healthy node, in order of preference, and to the next one when its stream
+// drops. While a fallback node is listened to the primary is checked, and listened to again once it recovers. The
+// filters are kept by the listener, so they apply to whichever node is listened to.
+func (l *Listener) runWithFailover(ctx context.Context, storeCtx context.Context, workers *orderedWorkers) error {
+	defer l.bridges.close()
+	delay := time.Second
+	for {
+		index, ok := l.bridges.pick(ctx)
+		if !ok {
+			if ctx.Err() != nil {
+				return nil
+			}
+			l.sampledLog.LogWarnf("No healthy node to listen to, retrying in %s", delay)
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(delay):
+			}
+			delay *= 2
+			if delay > maxReconnectDelay {
+				delay = maxReconnectDelay
+			}
+			continue
+		}
+
+		node := l.bridges.node(index)
+		l.bridges.active.Store(int32(index))
+		l.WrappedLogger.LogInfof("Listening to node '%s' ...", node.address)
+		listenCtx, cancel := context.WithCancel(ctx)
+		var recovered atomic.Bool
+		if index > 0 {
+			go l.bridges.watchPrimary(listenCtx, func() {
+				recovered.Store(true)
+				cancel()
+			})
+		}
+		// listen only returns without error once listenCtx is done
+		received, err := l.listen(node.client, listenCtx, storeCtx, workers)
+		cancel()
+		switch {
+		case ctx.Err() != nil:
+			return nil
+		case recovered.Load():
+			l.WrappedLogger.LogInfof("Listening to node '%s' ... done, the primary node recovered", node.address)
+			delay = time.Second
+			continue
+		}
+		l.WrappedLogger.LogWarnf("Listening to node '%s' ... failed, error: %w", node.address, err)
+		if received {
+			delay = time.Second
+		}
+		// the same node may be picked again right away, a stream failing on connection is then retried at a slower pace
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(delay):
+		}
+		if !received {
+			delay *= 2
+			if delay > maxReconnectDelay {
+				delay = maxReconnectDelay
+			}
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package listener
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	inx "github.com/iotaledger/inx/go"
+	"google.golang.org/grpc"
+)
+
+// fakeNode is a node reporting its health, whose stream of referenced blocks stays open until listening stops. A
+// dropping node reports itself unhealthy and fails its open stream.
+type fakeNode struct {
+	inx.INXClient
+	healthy  atomic.Bool
+	dropping atomic.Bool
+}
+
+func newFakeNode(healthy bool) *fakeNode {
+	node := &fakeNode{}
+	node.healthy.Store(healthy)
+	return node
+}
+
+func (n *fakeNode) ReadNodeStatus(ctx context.Context, in *inx.NoParams, opts ...grpc.CallOption) (*inx.NodeStatus, error) {
+	return &inx.NodeStatus{IsHealthy: n.healthy.Load()}, nil
+}
+
+type fakeNodeBlocks struct {
+	grpc.ClientStream
+	ctx  context.Context
+	node *fakeNode
+}
+
+func (s *fakeNodeBlocks) Recv() (*inx.BlockMetadata, error) {
+	for !s.node.dropping.Swap(false) {
+		select {
+		case <-s.ctx.Done():
+			return nil, s.ctx.Err()
+		case <-time.After(time.Millisecond):
+		}
+	}
+	s.node.healthy.Store(false)
+	return nil, errors.New("stream dropped")
+}
+
+func (n *fakeNode) ListenToReferencedBlocks(ctx context.Context, in *inx.NoParams, opts ...grpc.CallOption) (inx.INX_ListenToReferencedBlocksClient, error) {
+	return &fakeNodeBlocks{ctx: ctx, node: n}, nil
+}
+
+func TestPickNode(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		healthy  []bool
+		expected int
+		ok       bool
+	}{
+		{"healthy primary", []bool{true, true}, 0, true},
+		{"unhealthy primary", []bool{false, true}, 1, true},
+		{"first healthy fallback", []bool{false, false, true, true}, 2, true},
+		{"no healthy node", []bool{false, false}, 0, false},
+		{"without fallbacks", []bool{false}, 0, true},
+	} {
+		b := &bridges{}
+		for _, healthy := range tc.healthy {
+			b.nodes = append(b.nodes, nodeBridge{client: newFakeNode(healthy)})
+		}
+		index, ok := b.pick(context.Background())
+		if index != tc.expected || ok != tc.ok {
+			t.Errorf("%s: got node %d (%t), expected %d (%t)", tc.name, index, ok, tc.expected, tc.ok)
+		}
+	}
+}
+
+func TestWatchPrimary(t *testing.T) {
+	primary := newFakeNode(false)
+	b := &bridges{nodes: []nodeBridge{{address: primaryNode, client: primary}, {address: "fallback", client: newFakeNode(true)}}}
+	b.checkInterval.Store(int64(10 * time.Millisecond))
+
+	var recovered atomic.Int32
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		b.watchPrimary(context.Background(), func() { recovered.Add(1) })
+	}()
+	time.Sleep(50 * time.Millisecond)
+	if recovered.Load() != 0 {
+		t.Fatalf("the unhealthy primary node was reported as recovered")
+	}
+	primary.healthy.Store(true)
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("timed out waiting for the primary node to recover")
+	}
+	if got := recovered.Load(); got != 1 {
+		t.Errorf("got %d recoveries, expected 1", got)
+	}
+
+	// without an interval the primary node isn't watched
+	b.checkInterval.Store(0)
+	b.watchPrimary(context.Background(), func() { recovered.Add(1) })
+	if got := recovered.Load(); got != 1 {
+		t.Errorf("got %d recoveries without an interval, expected 1", got)
+	}
+}
+
+func TestRunWithFailover(t *testing.T) {
+	l := newTestListener(t, func(params *Parameters) {
+		params.NodeCheckInterval = 10 * time.Millisecond
+	})
+	primary, fallback := newFakeNode(true), newFakeNode(true)
+	l.bridges.nodes = []nodeBridge{{address: primaryNode, client: primary}, {address: "fallback", client: fallback}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- l.runWithFailover(ctx, context.Background(), nil)
+	}()
+
+	waitFor(t, "listening to the primary node", func() bool { return l.Client() == primary && l.status.connected.Load() })
+
+	// the stream of the primary node drops, the fallback node is listened to until the primary recovers
+	primary.dropping.Store(true)
+	waitFor(t, "failing over to the fallback node", func() bool { return l.Client() == fallback })
+	if status := l.Status(); status.Node != "fallback" {
+		t.Errorf("got node '%s' in the status, expected 'fallback'", status.Node)
+	}
+
+	primary.healthy.Store(true)
+	waitFor(t, "switching back to the primary node", func() bool { return l.Client() == primary })
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("got error %v once stopped, expected none", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("timed out waiting for the listener to stop")
+	}
+}
@@ -7,33 +7,65 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"os"
 	"time"
 
 	"github.com/go-playground/validator/v10"
+	iotago "github.com/iotaledger/iota.go/v3"
+)
+
+const (
+	// StoreFormatFullBlock stores the whole block, with its POI if requested.
+	StoreFormatFullBlock = "full-block"
+	// StoreFormatTaggedData stores only the tagged data payload of the block.
+	StoreFormatTaggedData = "tagged-data"
+	// StoreFormatSignedDataPlaintext stores only the verified data of a SignedDataContainer payload.
+	StoreFormatSignedDataPlaintext = "signed-data-plaintext"
 )
 
 type Filter struct {
-	Tag              string `json:"tag" validate:"required"`
-	PublicKey        string `json:"publicKey,omitempty"`
-	Id               string `json:"id,omitempty"`
-	BucketName       string `json:"bucketName,omitempty"`
-	WithPOI          bool   `json:"withPOI,omitempty"`
-	Duration         string `json:"duration,omitempty"`
+	Tag              string          `json:"tag" validate:"required_without=MatchAll,excluded_with=MatchAll"`
+	MatchAll         bool            `json:"matchAll,omitempty"`
+	PublicKey        string          `json:"publicKey,omitempty"`
+	Id               string          `json:"id,omitempty"`
+	BucketName       string          `json:"bucketName,omitempty"`
+	WithPOI          bool            `json:"withPOI,omitempty"`
+	Duration         string          `json:"duration,omitempty"`
+	StoreFormat      string          `json:"storeFormat,omitempty" validate:"omitempty,oneof=full-block tagged-data signed-data-plaintext"`
+	Transform        string          `json:"transform,omitempty" validate:"omitempty,oneof=none json-minify field-redact"`
+	RedactFields     []string        `json:"redactFields,omitempty"`
+	Schema           json.RawMessage `json:"schema,omitempty"`
+	AliasField       string          `json:"aliasField,omitempty"`
+	KeyField         string          `json:"keyField,omitempty"`
+	IndexFields      []string        `json:"indexFields,omitempty"`
 	Expiration       time.Time
-	PublicKeyDecoded crypto.PublicKey
+	PublicKeyDecoded crypto.PublicKey `json:"-"`
+	schema           *payloadSchema
 }
 
 type StartupFilters struct {
 	Filters []Filter `json:"filters"`
 }
 
-func NewFilter(tag string, publicKey string, bucketName string, duration string, withPOI bool) (Filter, error) {
+func NewFilter(tag string, matchAll bool, publicKey string, bucketName string, duration string, withPOI bool, storeFormat string) (Filter, error) {
 	filter := Filter{
-		Tag:        tag,
-		PublicKey:  publicKey,
-		BucketName: bucketName,
-		WithPOI:    withPOI,
-		Duration:   duration,
+		Tag:         tag,
+		MatchAll:    matchAll,
+		PublicKey:   publicKey,
+		BucketName:  bucketName,
+		WithPOI:     withPOI,
+		Duration:    duration,
+		StoreFormat: storeFormat,
+	}
+
+	err := filter.validateMatch()
+	if err != nil {
+		return Filter{}, err
+	}
+
+	err = filter.validateStoreFormat()
+	if err != nil {
+		return Filter{}, err
 	}
 
 	if filter.PublicKey != "" {
@@ -46,8 +78,57 @@ func NewFilter(tag string, publicKey string, bucketName string, duration string,
 	return filter, nil
 }
 
+// validateMatch checks that the filter matches either a tag or every block.
+func (f *Filter) validateMatch() error {
+	if f.MatchAll && f.Tag != "" {
+		return fmt.Errorf("a filter can't match every block and a tag at the same time")
+	}
+	if !f.MatchAll && f.Tag == "" {
+		return fmt.Errorf("a filter needs a tag unless it matches every block")
+	}
+	return nil
+}
+
+// matches returns whether a block with the given tagged data is selected by the filter.
+func (f *Filter) matches(taggedData iotago.TaggedData) bool {
+	return f.MatchAll || string(taggedData.Tag) == f.Tag
+}
+
+func (f *Filter) validateStoreFormat() error {
+	switch f.StoreFormat {
+	case "", StoreFormatFullBlock:
+		return nil
+	case StoreFormatTaggedData, StoreFormatSignedDataPlaintext:
+		if f.WithPOI {
+			return fmt.Errorf("store format '%s' can't be used with a Proof of Inclusion", f.StoreFormat)
+		}
+		if f.MatchAll {
+			return fmt.Errorf("store format '%s' can't be used by a filter matching every block", f.StoreFormat)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown store format '%s'", f.StoreFormat)
+	}
+}
+
+// validateSchema compiles the schema the payloads must conform to, if any.
+func (f *Filter) validateSchema() error {
+	if len(f.Schema) == 0 {
+		return nil
+	}
+	if f.MatchAll {
+		return fmt.Errorf("a filter matching every block can't have a schema")
+	}
+	var err error
+	f.schema, err = compileSchema(f.Schema)
+	return err
+}
+
 func (f *Filter) setId() {
-	f.Id = fmt.Sprintf("%x", md5.Sum([]byte(fmt.Sprintf("%v", f))))
+	// the compiled schema is a pointer, the id derives from the schema itself
+	identity := *f
+	identity.schema = nil
+	f.Id = fmt.Sprintf("%x", md5.Sum([]byte(fmt.Sprintf("%v", &identity))))
 }
 
 func (f *Filter) setPublicKeyDecoded() error {
@@ -105,3 +186,81 @@ func UnmarshalStartupFilters(filtersString string) ([]Filter, error) {
 
 	return filters.Filters, nil
 }
+
+// ReadStartupFilters reads the startup filters layered from the filters file and the filters parameter,
+// validating the combined set.
+func ReadStartupFilters(params Parameters) ([]Filter, error) {
+	var sources [][]Filter
+	if params.FiltersFile != "" {
+		content, err := os.ReadFile(params.FiltersFile)
+		if err != nil {
+			return nil, fmt.Errorf("can't read startup filters file '%s', error: %w", params.FiltersFile, err)
+		}
+		filters, err := UnmarshalStartupFilters(string(content))
+		if err != nil {
+			return nil, fmt.Errorf("invalid startup filters file '%s', error: %w", params.FiltersFile, err)
+		}
+		sources = append(sources, filters)
+	}
+	if params.Filters != "" {
+		filters, err := UnmarshalStartupFilters(params.Filters)
+		if err != nil {
+			return nil, fmt.Errorf("invalid startup filters, error: %w", err)
+		}
+		sources = append(sources, filters)
+	}
+
+	filters := MergeStartupFilters(sources...)
+	for _, filter := range filters {
+		err := filter.validateMatch()
+		if err == nil {
+			err = filter.validateStoreFormat()
+		}
+		if err == nil {
+			err = filter.validateTransform()
+		}
+		if err == nil {
+			err = filter.validateSchema()
+		}
+		if err == nil {
+			err = filter.validateAlias()
+		}
+		if err == nil {
+			err = filter.validateKey()
+		}
+		if err == nil {
+			err = filter.validateIndexFields()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid startup filter with tag '%s', error: %w", filter.Tag, err)
+		}
+	}
+	return filters, nil
+}
+
+// MergeStartupFilters layers filter sets, later ones taking precedence: a filter replaces the earlier one with the
+// same tag (or matching every block) and bucket, keeping its position. Duplicates within a set are merged the same way.
+func MergeStartupFilters(sources ...[]Filter) []Filter {
+	var merged []Filter
+	positions := make(map[string]int)
+	for _, filters := range sources {
+		for _, filter := range filters {
+			key := filter.startupKey()
+			if i, ok := positions[key]; ok {
+				merged[i] = filter
+				continue
+			}
+			positions[key] = len(merged)
+			merged = append(merged, filter)
+		}
+	}
+	return merged
+}
+
+// startupKey identifies a startup filter across the sources it can be defined in.
+func (f *Filter) startupKey() string {
+	if f.MatchAll {
+		return "*/" + f.BucketName
+	}
+	return "tag:" + f.Tag + "/" + f.BucketName
+}
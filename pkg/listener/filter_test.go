@@ -0,0 +1,130 @@
+package listener
+
+import (
+	"collector/pkg/storage"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/iotaledger/datapayloads.go"
+	"github.com/iotaledger/hive.go/serializer/v2"
+)
+
+// storedObject returns the object stored for a block in the default bucket.
+func storedObject(l *Listener, block *referencedBlock) (storage.Object, error) {
+	reader, err := l.Storage.GetObject(l.Storage.DefaultBucketName, hex.EncodeToString(block.blockId.GetId()), "", context.Background())
+	if err != nil {
+		return storage.Object{}, err
+	}
+	defer reader.Close()
+	return reader.Decode()
+}
+
+// signedData returns a signed data container holding data, serialized as a tagged data payload carries it.
+func signedData(t *testing.T, data string) []byte {
+	t.Helper()
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	container, err := datapayloads.NewSignedDataContainer(datapayloads.NewInMemorySigner(privateKey), []byte(data))
+	if err != nil {
+		t.Fatalf("can't sign the data: %v", err)
+	}
+	containerBytes, err := container.Serialize(serializer.DeSeriModePerformValidation, nil)
+	if err != nil {
+		t.Fatalf("can't serialize the signed data: %v", err)
+	}
+	return containerBytes
+}
+
+func TestStoreFormat(t *testing.T) {
+	l := newTestListener(t, nil)
+	for _, format := range []string{StoreFormatFullBlock, StoreFormatTaggedData, StoreFormatSignedDataPlaintext} {
+		filter, err := NewFilter(format, false, "", l.Storage.DefaultBucketName, "", false, format)
+		if err != nil {
+			t.Fatalf("can't create the filter: %v", err)
+		}
+		if _, err := l.AddFilter(filter); err != nil {
+			t.Fatalf("can't add the filter: %v", err)
+		}
+	}
+	if _, err := NewFilter("poi", false, "", l.Storage.DefaultBucketName, "", true, StoreFormatTaggedData); err == nil {
+		t.Error("a filter storing the tagged data only was created with a proof of inclusion")
+	}
+
+	fullBlock := referenced(1, StoreFormatFullBlock, "block", time.Now(), l)
+	taggedData := referenced(2, StoreFormatTaggedData, "tagged", time.Now(), l)
+	signed := referenced(3, StoreFormatSignedDataPlaintext, "", time.Now(), l)
+	signed.taggedData.Data = signedData(t, "plaintext")
+	// a payload which is not signed data is discarded
+	unsigned := referenced(4, StoreFormatSignedDataPlaintext, "not signed", time.Now(), l)
+	for _, block := range []*referencedBlock{fullBlock, taggedData, signed, unsigned} {
+		l.storeBlock(block, context.Background())
+	}
+
+	if object, err := storedObject(l, fullBlock); err != nil || object.Block == nil || object.TaggedData != nil {
+		t.Errorf("got object %+v, error %v, expected the full block", object, err)
+	}
+	if object, err := storedObject(l, taggedData); err != nil || object.Block != nil || object.TaggedData == nil || string(object.TaggedData.Data) != "tagged" {
+		t.Errorf("got object %+v, error %v, expected the tagged data only", object, err)
+	}
+	if object, err := storedObject(l, signed); err != nil || object.Block != nil || string(object.Data) != "plaintext" {
+		t.Errorf("got object %+v, error %v, expected the signed plaintext only", object, err)
+	}
+	if _, err := storedObject(l, unsigned); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("got error %v for a payload which is not signed data, expected ErrNotFound", err)
+	}
+}
+
+func TestReadStartupFilters(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "filters.json")
+	content := `{"filters":[
+		{"tag":"a","storeFormat":"full-block"},
+		{"tag":"b"},
+		{"tag":"a","bucketName":"other"},
+		{"tag":"b","storeFormat":"tagged-data"}
+	]}`
+	if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+		t.Fatalf("can't write the filters file: %v", err)
+	}
+	filters, err := ReadStartupFilters(Parameters{
+		FiltersFile: file,
+		Filters:     `{"filters":[{"tag":"c"},{"tag":"a","storeFormat":"signed-data-plaintext"}]}`,
+	})
+	if err != nil {
+		t.Fatalf("can't read the startup filters: %v", err)
+	}
+
+	// the duplicate in the file and the parameter replace the earlier filters, keeping their positions
+	expected := []struct{ tag, bucket, storeFormat string }{
+		{"a", "", StoreFormatSignedDataPlaintext},
+		{"b", "", StoreFormatTaggedData},
+		{"a", "other", ""},
+		{"c", "", ""},
+	}
+	if len(filters) != len(expected) {
+		t.Fatalf("got %d filters, expected %d", len(filters), len(expected))
+	}
+	for i, e := range expected {
+		if f := filters[i]; f.Tag != e.tag || f.BucketName != e.bucket || f.StoreFormat != e.storeFormat {
+			t.Errorf("got filter %d with tag '%s', bucket '%s' and format '%s', expected %+v", i, f.Tag, f.BucketName, f.StoreFormat, e)
+		}
+	}
+
+	// the combined set is validated
+	_, err = ReadStartupFilters(Parameters{FiltersFile: file, Filters: `{"filters":[{"tag":"b","storeFormat":"unknown"}]}`})
+	if err == nil {
+		t.Errorf("got no error for an invalid override, expected one")
+	}
+	_, err = ReadStartupFilters(Parameters{FiltersFile: filepath.Join(t.TempDir(), "missing.json")})
+	if err == nil {
+		t.Errorf("got no error for a missing filters file, expected one")
+	}
+}
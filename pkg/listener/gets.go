@@ -5,13 +5,19 @@ import (
 	"collector/pkg/storage"
 	"context"
 	"encoding/hex"
+	"errors"
 	"fmt"
 
 	"github.com/iotaledger/hive.go/serializer/v2"
 	inx "github.com/iotaledger/inx/go"
 	iotago "github.com/iotaledger/iota.go/v3"
+	"google.golang.org/grpc/codes"
+	grpcstatus "google.golang.org/grpc/status"
 )
 
+// ErrBlockNotFound is returned when the node doesn't know a block, it may have been pruned.
+var ErrBlockNotFound = errors.New("block not found on the node")
+
 func GetTaggedDataFromId(blockId *inx.BlockId, client inx.INXClient, ctx context.Context) (iotago.TaggedData, *iotago.Block, error) {
 	var block *iotago.Block
 	taggedData := iotago.TaggedData{}
@@ -24,19 +30,28 @@ func GetTaggedDataFromId(blockId *inx.BlockId, client inx.INXClient, ctx context
 	if err != nil {
 		return taggedData, block, err
 	}
+
+	taggedData, err = GetTaggedDataFromBlock(block, ctx)
+	return taggedData, block, err
+}
+
+// GetTaggedDataFromBlock returns the tagged data payload of a block, an empty one if the block carries another payload.
+func GetTaggedDataFromBlock(block *iotago.Block, ctx context.Context) (iotago.TaggedData, error) {
+	taggedData := iotago.TaggedData{}
+
 	blockPayload := block.Payload
-	if blockPayload.PayloadType() != iotago.PayloadTaggedData {
-		return taggedData, block, nil
+	if blockPayload == nil || blockPayload.PayloadType() != iotago.PayloadTaggedData {
+		return taggedData, nil
 	}
 
 	payloadBytes, _ := blockPayload.Serialize(serializer.DeSeriModeNoValidation, ctx)
 
-	_, err = taggedData.Deserialize(payloadBytes, serializer.DeSeriModeNoValidation, ctx)
+	_, err := taggedData.Deserialize(payloadBytes, serializer.DeSeriModeNoValidation, ctx)
 	if err != nil {
-		return taggedData, block, err
+		return taggedData, err
 	}
 
-	return taggedData, block, nil
+	return taggedData, nil
 }
 
 func GetObjectFromTanglePOI(blockId string, poiHandler poi.POIHandler) (storage.Object, error) {
@@ -53,25 +68,40 @@ func GetObjectFromTanglePOI(blockId string, poiHandler poi.POIHandler) (storage.
 }
 
 func GetObjectFromTangleBlock(blockId string, client inx.INXClient, ctx context.Context) (storage.Object, error) {
-	var err error
 	var object storage.Object
+
+	block, _, err := GetBlockFromTangle(blockId, client, ctx)
+	if err != nil {
+		return object, err
+	}
+
+	object.Block = block
+
+	return object, nil
+}
+
+// GetBlockFromTangle returns a block along with its serialized bytes as the node knows it,
+// ErrBlockNotFound if the node doesn't know it.
+func GetBlockFromTangle(blockId string, client inx.INXClient, ctx context.Context) (*iotago.Block, []byte, error) {
+	var err error
 	var blockID inx.BlockId
 
 	blockID.Id, err = hex.DecodeString(blockId)
 	if err != nil {
-		return object, err
+		return nil, nil, err
 	}
 
-	block, err := client.ReadBlock(ctx, &blockID)
+	rawBlock, err := client.ReadBlock(ctx, &blockID)
 	if err != nil {
-		return object, err
+		if grpcstatus.Code(err) == codes.NotFound {
+			return nil, nil, ErrBlockNotFound
+		}
+		return nil, nil, err
 	}
-	uBlock, err := block.UnwrapBlock(serializer.DeSeriModeNoValidation, &iotago.ProtocolParameters{})
+	block, err := rawBlock.UnwrapBlock(serializer.DeSeriModeNoValidation, &iotago.ProtocolParameters{})
 	if err != nil {
-		return object, err
+		return nil, nil, err
 	}
 
-	object.Block = uBlock
-
-	return object, nil
+	return block, rawBlock.GetData(), nil
 }
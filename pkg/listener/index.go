@@ -0,0 +1,56 @@
+package listener
+
+import (
+	"collector/pkg/storage"
+	"fmt"
+)
+
+func (f *Filter) validateIndexFields() error {
+	if len(f.IndexFields) == 0 {
+		return nil
+	}
+	if f.MatchAll {
+		return fmt.Errorf("a filter matching every block can't have index fields")
+	}
+	for _, field := range f.IndexFields {
+		if field == "" {
+			return fmt.Errorf("empty index field")
+		}
+		// the tags are read before the transform, a redacted value must not be indexed
+		if f.redacts(field) {
+			return fmt.Errorf("index field '%s' is redacted", field)
+		}
+	}
+	return nil
+}
+
+// indexTagsOf reads the index tags of a block from the payload fields named by the filter's IndexFields, dotted
+// paths into a JSON payload. A field holds a string or a number, or an array of them; the same tag found several
+// times is indexed once, and the tags beyond storage.MaxIndexTags or too long to be indexed are ignored.
+func (f *Filter) indexTagsOf(payload []byte) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	add := func(value any) {
+		tag, ok := scalarString(value)
+		if !ok || seen[tag] || len(tags) == storage.MaxIndexTags || storage.ValidateIndexTag(tag) != nil {
+			return
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+
+	for _, field := range f.IndexFields {
+		value, ok := payloadValue(payload, field)
+		if !ok {
+			continue
+		}
+		if values, isArray := value.([]any); isArray {
+			for _, v := range values {
+				add(v)
+			}
+			continue
+		}
+		add(value)
+	}
+	return tags
+}
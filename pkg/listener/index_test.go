@@ -0,0 +1,60 @@
+package listener
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIndexTagsOf(t *testing.T) {
+	filter := Filter{IndexFields: []string{"color", "labels", "owner.id"}}
+	many := make([]string, 12)
+	for i := range many {
+		many[i] = fmt.Sprintf(`"t%d"`, i)
+	}
+	for _, tc := range []struct {
+		payload string
+		tags    []string
+	}{
+		{`{"color": "red", "labels": ["a", "b"], "owner": {"id": 7}}`, []string{"red", "a", "b", "7"}},
+		// the same tag is indexed once
+		{`{"color": "red", "labels": ["red", "a", "a"]}`, []string{"red", "a"}},
+		{`{"color": true, "labels": [{"nested": 1}, ""], "other": "x"}`, nil},
+		{`{"color": "` + strings.Repeat("x", 65) + `"}`, nil},
+		{`{"labels": [` + strings.Join(many, ",") + `]}`, []string{"t0", "t1", "t2", "t3", "t4", "t5", "t6", "t7"}},
+		{`not json`, nil},
+	} {
+		if tags := filter.indexTagsOf([]byte(tc.payload)); !reflect.DeepEqual(tags, tc.tags) {
+			t.Errorf("payload %s: got tags %v, expected %v", tc.payload, tags, tc.tags)
+		}
+	}
+}
+
+func TestStoreWithIndexTags(t *testing.T) {
+	l := newTestListener(t, nil)
+	filter, err := NewFilter("indexed", false, "", l.Storage.DefaultBucketName, "", false, StoreFormatTaggedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	filter.IndexFields = []string{"labels"}
+	if _, err := l.AddFilter(filter); err != nil {
+		t.Fatalf("can't add the filter: %v", err)
+	}
+
+	first := referenced(1, "indexed", `{"labels": ["shared", "first"]}`, time.Now(), l)
+	second := referenced(2, "indexed", `{"labels": ["shared"]}`, time.Now(), l)
+	l.storeBlock(first, context.Background())
+	l.storeBlock(second, context.Background())
+
+	firstId, secondId := hex.EncodeToString(first.blockId.GetId()), hex.EncodeToString(second.blockId.GetId())
+	for tag, expected := range map[string][]string{"shared": {firstId, secondId}, "first": {firstId}} {
+		names, err := l.Storage.ListTagged(l.Storage.DefaultBucketName, tag, "", 10, context.Background())
+		if err != nil || !reflect.DeepEqual(names, expected) {
+			t.Errorf("got blocks %v, error %v tagged '%s', expected %v", names, err, tag, expected)
+		}
+	}
+}
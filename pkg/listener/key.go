@@ -0,0 +1,39 @@
+package listener
+
+import (
+	"collector/pkg/storage"
+	"fmt"
+)
+
+func (f *Filter) validateKey() error {
+	if f.KeyField == "" {
+		return nil
+	}
+	if f.MatchAll {
+		return fmt.Errorf("a filter matching every block can't have a key field")
+	}
+	// the key is read before the transform, a redacted value must not name the object
+	if f.redacts(f.KeyField) {
+		return fmt.Errorf("key field '%s' is redacted", f.KeyField)
+	}
+	return nil
+}
+
+// objectNameOf derives the name a block is stored under: its block id, or with a KeyField the string or number
+// found at that dotted path of the JSON payload. A payload without a usable key is stored under its block id.
+func (l *Listener) objectNameOf(filter Filter, payload []byte, blockId string) string {
+	if filter.KeyField == "" {
+		return blockId
+	}
+	key, ok := payloadField(payload, filter.KeyField)
+	if !ok {
+		l.sampledLog.LogWarnf("Block '%s' has no key at '%s' for filter '%s', storing it under its block id", blockId, filter.KeyField, filter.Id)
+		return blockId
+	}
+	err := storage.ValidateObjectName(key)
+	if err != nil {
+		l.sampledLog.LogWarnf("Block '%s' can't be stored under its key for filter '%s', storing it under its block id, error: %v", blockId, filter.Id, err)
+		return blockId
+	}
+	return key
+}
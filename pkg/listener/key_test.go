@@ -0,0 +1,59 @@
+package listener
+
+import (
+	"collector/pkg/storage"
+	"context"
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestStoreWithKeyField(t *testing.T) {
+	l := newTestListener(t, nil)
+	filter, err := NewFilter("keyed", false, "", l.Storage.DefaultBucketName, "", false, StoreFormatTaggedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	filter.KeyField = "tx.id"
+	if _, err := l.AddFilter(filter); err != nil {
+		t.Fatalf("can't add the filter: %v", err)
+	}
+	if !l.UsesKeyField() {
+		t.Error("got no filter using a key field, expected one")
+	}
+
+	for _, tc := range []struct {
+		id      byte
+		payload string
+		key     string
+	}{
+		{1, `{"tx": {"id": "tx-1"}}`, "tx-1"},
+		{2, `{"tx": {"id": 42}}`, "42"},
+		// the blocks without a usable key are stored under their block id
+		{3, `{"tx": {}}`, ""},
+		{4, `{"tx": {"id": "a/b"}}`, ""},
+		{5, `not json`, ""},
+	} {
+		block := referenced(tc.id, "keyed", tc.payload, time.Now(), l)
+		l.storeBlock(block, context.Background())
+		blockId := hex.EncodeToString(block.blockId.GetId())
+		name := tc.key
+		if name == "" {
+			name = blockId
+		}
+		if data, err := storedData(l, name); err != nil || data != tc.payload {
+			t.Errorf("payload %s: got data '%s', error %v under '%s', expected the payload", tc.payload, data, err, name)
+		}
+		if name != blockId {
+			if _, err := storedData(l, blockId); !errors.Is(err, storage.ErrNotFound) {
+				t.Errorf("payload %s: got error %v under the block id, expected ErrNotFound", tc.payload, err)
+			}
+		}
+	}
+
+	redacted := Filter{Tag: "keyed", KeyField: "tx.id", Transform: TransformFieldRedact, RedactFields: []string{"id"}}
+	if err := redacted.validateKey(); err == nil {
+		t.Error("got a redacted key field validated, expected an error")
+	}
+}
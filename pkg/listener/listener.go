@@ -6,82 +6,364 @@ import (
 	"context"
 	"crypto"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/iotaledger/datapayloads.go"
 	"github.com/iotaledger/hive.go/core/logger"
 	inx "github.com/iotaledger/inx/go"
 	iotago "github.com/iotaledger/iota.go/v3"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 type Listener struct {
 	*logger.WrappedLogger
-	Filters        map[string]Filter
 	Storage        storage.Storage
 	POIHandler     poi.POIHandler
 	StartupFilters []Filter
+
+	filters                *filterRegistry
+	backfillConcurrency    *atomic.Int64
+	startupConcurrency     int
+	startupFailFast        bool
+	matchAllEnabled        bool
+	tagLists               *atomic.Pointer[tagLists]
+	autoCreateBuckets      bool
+	transformFailurePolicy string
+	orderedWorkers         int
+	trackAttachments       bool
+	inFlight               *inFlight
+	filterStats            *filterStats
+	retries                *retryQueue
+	webhooks               *webhooks
+	status                 *status
+	sampledLog             *sampledLogger
+	metrics                *Metrics
+	lastMilestone          *atomic.Pointer[milestoneTime]
+	bridges                *bridges
+	maxBlockAge            time.Duration
 }
 
-func NewListener(params Parameters, storage storage.Storage, poiHandler poi.POIHandler, log *logger.WrappedLogger) (Listener, error) {
-	var filters []Filter
-	var err error
+// milestoneTime is the timestamp of a milestone, cached since every block of a cone shares it.
+type milestoneTime struct {
+	index uint32
+	at    time.Time
+}
 
-	if params.Filters != "" {
-		filters, err = UnmarshalStartupFilters(params.Filters)
-		if err != nil {
-			return Listener{}, err
+// FilterStatus is a filter along with its counters.
+type FilterStatus struct {
+	Filter
+	Stats FilterStats `json:"stats"`
+}
+
+const (
+	// InFlightPolicyDrop drops the new blocks while the in-flight limit is reached.
+	InFlightPolicyDrop = "drop"
+	// InFlightPolicyBlock stops reading the node stream while the in-flight limit is reached.
+	InFlightPolicyBlock = "block"
+)
+
+// inFlight tracks the blocks the listener is currently storing, bounded by slots if set.
+type inFlight struct {
+	sync.WaitGroup
+	count  atomic.Int64
+	slots  chan struct{}
+	policy string
+}
+
+func newInFlight(params Parameters) (*inFlight, error) {
+	f := &inFlight{policy: params.InFlightPolicy}
+	switch f.policy {
+	case InFlightPolicyDrop, InFlightPolicyBlock:
+	default:
+		return nil, fmt.Errorf("unknown in-flight policy '%s'", f.policy)
+	}
+	if params.MaxInFlightBlocks > 0 {
+		f.slots = make(chan struct{}, params.MaxInFlightBlocks)
+	}
+	return f, nil
+}
+
+// add reserves a slot for a block, returning false if the block must be dropped or ctx is done.
+func (f *inFlight) add(ctx context.Context) bool {
+	if f.slots != nil {
+		if f.policy == InFlightPolicyBlock {
+			select {
+			case f.slots <- struct{}{}:
+			case <-ctx.Done():
+				return false
+			}
+		} else {
+			select {
+			case f.slots <- struct{}{}:
+			default:
+				return false
+			}
 		}
 	}
+	f.count.Add(1)
+	f.Add(1)
+	return true
+}
+
+func (f *inFlight) done() {
+	if f.slots != nil {
+		<-f.slots
+	}
+	f.count.Add(-1)
+	f.Done()
+}
+
+func NewListener(params Parameters, storage storage.Storage, poiHandler poi.POIHandler, registerer prometheus.Registerer, log *logger.WrappedLogger) (Listener, error) {
+	filters, err := ReadStartupFilters(params)
+	if err != nil {
+		return Listener{}, err
+	}
+
+	metrics, err := NewMetrics(registerer)
+	if err != nil {
+		return Listener{}, err
+	}
+
+	retries, err := newRetryQueue(params, metrics)
+	if err != nil {
+		return Listener{}, err
+	}
+
+	blocksInFlight, err := newInFlight(params)
+	if err != nil {
+		return Listener{}, err
+	}
 
+	notifier, err := newWebhooks(params)
+	if err != nil {
+		return Listener{}, err
+	}
+
+	nodes, err := newBridges(params)
+	if err != nil {
+		return Listener{}, err
+	}
+
+	switch params.TransformFailurePolicy {
+	case TransformFailureStoreOriginal, TransformFailureDrop:
+	default:
+		return Listener{}, fmt.Errorf("unknown transform failure policy '%s'", params.TransformFailurePolicy)
+	}
+
+	wrappedLogger := logger.NewWrappedLogger(log.LoggerNamed("Listener"))
 	listener := Listener{
-		WrappedLogger:  logger.NewWrappedLogger(log.LoggerNamed("Listener")),
-		Filters:        make(map[string]Filter),
-		Storage:        storage,
-		POIHandler:     poiHandler,
-		StartupFilters: filters,
+		WrappedLogger:          wrappedLogger,
+		Storage:                storage,
+		POIHandler:             poiHandler,
+		StartupFilters:         filters,
+		filters:                newFilterRegistry(),
+		backfillConcurrency:    &atomic.Int64{},
+		startupConcurrency:     params.StartupFiltersConcurrency,
+		startupFailFast:        params.StartupFiltersFailFast,
+		matchAllEnabled:        params.MatchAllEnabled,
+		tagLists:               &atomic.Pointer[tagLists]{},
+		autoCreateBuckets:      params.AutoCreateBuckets,
+		transformFailurePolicy: params.TransformFailurePolicy,
+		orderedWorkers:         params.OrderedWorkers,
+		trackAttachments:       params.TrackAttachments,
+		inFlight:               blocksInFlight,
+		metrics:                metrics,
+		lastMilestone:          &atomic.Pointer[milestoneTime]{},
+		filterStats:            &filterStats{metrics: metrics},
+		retries:                retries,
+		webhooks:               notifier,
+		status:                 &status{},
+		sampledLog:             newSampledLogger(wrappedLogger, params.LogSamplingWindow),
+		bridges:                nodes,
+		maxBlockAge:            params.MaxBlockAge,
 	}
+	listener.SetBackfillConcurrency(params.BackfillConcurrency)
+	listener.SetTagLists(params.TagAllowlist, params.TagDenylist)
 	return listener, err
 }
 
-func (l *Listener) Run(client inx.INXClient, ctx context.Context) error {
+// Run listens to the referenced blocks until ctx is done, failing over to the fallback nodes when the stream of
+// client, the node bridge, drops. Matching blocks are stored using storeCtx, so that in-flight uploads can outlive
+// the stream and be finished with Drain.
+func (l *Listener) Run(client inx.INXClient, ctx context.Context, storeCtx context.Context) error {
+	l.bridges.setPrimary(client)
+	storeCtx = storage.ContextWithOrigin(storeCtx, storage.OriginListener)
+	defer l.status.connected.Store(false)
+
+	var workers *orderedWorkers
+	if l.orderedWorkers > 0 {
+		workers = l.startOrderedWorkers(l.orderedWorkers, storeCtx)
+		defer workers.stop()
+	}
+	return l.runWithFailover(ctx, storeCtx, workers)
+}
+
+// listen listens to the referenced blocks of a node until ctx is done or its stream drops, telling whether
+// any block was received.
+func (l *Listener) listen(client inx.INXClient, ctx context.Context, storeCtx context.Context, workers *orderedWorkers) (bool, error) {
 	// Listen to all referenced blocks
 	stream, err := client.ListenToReferencedBlocks(ctx, &inx.NoParams{})
 	if err != nil {
-		return err
+		return false, err
 	}
+	l.status.connected.Store(true)
+	defer l.status.connected.Store(false)
 
+	received := false
 	for {
 		newBlock, err := stream.Recv()
 		if err != nil {
-			l.WrappedLogger.LogErrorf("Could not receive block, error: %w", err)
-			continue
+			if ctx.Err() != nil {
+				return received, nil
+			}
+			return received, err
 		}
+		received = true
+		l.status.blockProcessed(hex.EncodeToString(newBlock.GetBlockId().GetId()))
 		// we do something only if we have filters
-		if len(l.Filters) == 0 {
+		if l.filters.len() == 0 {
 			continue
 		}
 		// get tagged data
 		blockId := newBlock.GetBlockId()
 		taggedData, block, err := GetTaggedDataFromId(blockId, client, ctx)
 		if err != nil {
-			l.WrappedLogger.LogErrorf("Could not process block, error: %w", err)
+			l.sampledLog.LogErrorf("Could not process block, error: %w", err)
+			continue
+		}
+		matching := l.filters.matching(taggedData)
+		if len(matching) == 0 {
+			continue
+		}
+		referencedAt := l.referencedAt(newBlock.GetReferencedByMilestoneIndex(), client, ctx)
+		if l.maxBlockAge > 0 && time.Since(referencedAt) > l.maxBlockAge {
+			l.metrics.incBlocksTooOld()
+			l.sampledLog.LogWarnf("Block '%s' is older than the maximum block age, dropping it", hex.EncodeToString(blockId.GetId()))
 			continue
 		}
 		// starts a routine to manage the tagged payload and keeps listening
-		go func(filters map[string]Filter, taggedData iotago.TaggedData, block iotago.Block, blockId inx.BlockId, c context.Context) {
-			for filterId := range filters {
-				err := l.checkAndStore(taggedData, filterId, &block, blockId, ctx)
-				if err != nil {
-					l.WrappedLogger.LogErrorf("Tagged data error: %w", err)
-					continue
-				}
+		if !l.inFlight.add(ctx) {
+			if ctx.Err() != nil {
+				return received, nil
+			}
+			l.metrics.incBlocksDropped()
+			l.sampledLog.LogWarnf("Too many blocks in flight, dropping block '%s'", hex.EncodeToString(blockId.GetId()))
+			continue
+		}
+		b := &referencedBlock{
+			filters:      matching,
+			taggedData:   taggedData,
+			block:        *block,
+			blockId:      blockId,
+			referencedAt: referencedAt,
+		}
+		if workers != nil {
+			// keeps the arrival order within the tag
+			if !workers.dispatch(b, ctx) {
+				l.inFlight.done()
+				return received, nil
 			}
-		}(l.Filters, taggedData, *block, *blockId, ctx)
+			continue
+		}
+		go func(b *referencedBlock, c context.Context) {
+			defer l.inFlight.done()
+			l.storeBlock(b, c)
+		}(b, storeCtx)
+	}
+}
+
+// storeBlock runs a block through the filters, storing it for each one it matches.
+func (l *Listener) storeBlock(b *referencedBlock, ctx context.Context) {
+	for _, filter := range b.filters {
+		err := l.checkAndStore(b.taggedData, filter, &b.block, b.blockId, b.referencedAt, ctx)
+		if err != nil {
+			l.status.failedCount.Add(1)
+			l.WrappedLogger.LogErrorf("Tagged data error: %w", err)
+			continue
+		}
+	}
+}
+
+// referencedAt returns the timestamp of the milestone referencing a block, only needed by partitioned storages and
+// to check the age of the blocks.
+func (l *Listener) referencedAt(milestoneIndex uint32, client inx.INXClient, ctx context.Context) time.Time {
+	if !l.Storage.Partitioned() && l.maxBlockAge <= 0 {
+		return time.Now()
+	}
+	if last := l.lastMilestone.Load(); last != nil && last.index == milestoneIndex {
+		return last.at
+	}
+
+	milestone, err := client.ReadMilestone(ctx, &inx.MilestoneRequest{MilestoneIndex: milestoneIndex})
+	if err != nil {
+		l.sampledLog.LogWarnf("Can't read milestone %d, partitioning by the current time, error: %w", milestoneIndex, err)
+		return time.Now()
+	}
+	at := time.Unix(int64(milestone.GetMilestoneInfo().GetMilestoneTimestamp()), 0)
+	l.lastMilestone.Store(&milestoneTime{index: milestoneIndex, at: at})
+	return at
+}
+
+// Drain waits up to timeout for the in-flight blocks to be stored, returning how many are still pending.
+func (l *Listener) Drain(timeout time.Duration) int64 {
+	done := make(chan struct{})
+	go func() {
+		l.inFlight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return 0
+	case <-time.After(timeout):
+		return l.inFlight.count.Load()
 	}
 }
 
 func (l *Listener) AddFilter(filter Filter) (string, error) {
+	err := filter.validateMatch()
+	if err != nil {
+		return "", err
+	}
+	if filter.MatchAll && !l.matchAllEnabled {
+		return "", fmt.Errorf("filters matching every block are not enabled")
+	}
+
+	err = filter.validateStoreFormat()
+	if err != nil {
+		return "", err
+	}
+
+	err = filter.validateTransform()
+	if err != nil {
+		return "", err
+	}
+
+	err = filter.validateSchema()
+	if err != nil {
+		return "", err
+	}
+
+	err = filter.validateAlias()
+	if err != nil {
+		return "", err
+	}
+
+	err = filter.validateKey()
+	if err != nil {
+		return "", err
+	}
+
+	err = filter.validateIndexFields()
+	if err != nil {
+		return "", err
+	}
+
 	// sets filter expiration
 	if filter.Duration != "" {
 		err := filter.setExpiration()
@@ -99,15 +381,14 @@ func (l *Listener) AddFilter(filter Filter) (string, error) {
 	}
 
 	filter.setId()
-	for _, f := range l.Filters {
-		if f.Id == filter.Id {
-			err := fmt.Errorf("Filter id '%s' already exists", filter.Id)
-			return "", err
-		}
+	err = l.filters.add(filter)
+	if err != nil {
+		return "", err
 	}
 
-	l.Filters[filter.Id] = filter
-	if filter.PublicKeyDecoded == nil {
+	if filter.MatchAll {
+		l.WrappedLogger.LogWarnf("Filter '%s' added, storing every block", filter.Id)
+	} else if filter.PublicKeyDecoded == nil {
 		l.WrappedLogger.LogInfof("Filter '%s' added, listening on tag: '%s'", filter.Id, filter.Tag)
 	} else {
 		l.WrappedLogger.LogInfof("Filter '%s' added, listening on tag: '%s' , for public key '%s'", filter.Id, filter.Tag, filter.PublicKey)
@@ -116,61 +397,164 @@ func (l *Listener) AddFilter(filter Filter) (string, error) {
 }
 
 func (l *Listener) RemoveFilter(filterId string) error {
-	tag := l.Filters[filterId].Tag
-	delete(l.Filters, filterId)
-	l.WrappedLogger.LogInfof("Filter '%s' added, is no longer listening on tag: '%s'", filterId, tag)
+	filter, ok := l.filters.remove(filterId)
+	if !ok {
+		return nil
+	}
+	l.filterStats.remove(filter)
+	l.WrappedLogger.LogInfof("Filter '%s' removed, no longer listening on tag: '%s'", filterId, filter.Tag)
 	return nil
 }
 
+// GetFilter returns an active filter.
+func (l *Listener) GetFilter(filterId string) (Filter, bool) {
+	return l.filters.get(filterId)
+}
+
+// UsesKeyField tells whether an active filter stores its blocks under a key read from their payload, rather than
+// their block id.
+func (l *Listener) UsesKeyField() bool {
+	for _, filter := range l.filters.list() {
+		if filter.KeyField != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// ListFilters returns the active filters along with their counters.
+func (l *Listener) ListFilters() []FilterStatus {
+	registered := l.filters.list()
+	filters := make([]FilterStatus, 0, len(registered))
+	for _, filter := range registered {
+		filters = append(filters, FilterStatus{
+			Filter: filter,
+			Stats:  l.filterStats.get(filter.Id).stats(),
+		})
+	}
+	return filters
+}
+
+// LoadStartupFilters deploys the startup filters, several at a time. A filter that can't be deployed is logged
+// and skipped, unless the listener fails fast: then the remaining filters are not deployed and an error is returned.
 func (l *Listener) LoadStartupFilters(ctx context.Context) error {
-	for _, filter := range l.StartupFilters {
-		// use default bucket if none
-		if filter.BucketName == "" {
-			filter.BucketName = l.Storage.DefaultBucketName
-		} else {
-			// check if provided bucket exists
-			exists, err := l.Storage.BucketExists(filter.BucketName, ctx)
-			if err != nil && exists {
-				l.WrappedLogger.LogErrorf("Can't deploy startup filters : %w", err)
-				return err
-			}
-			if !exists {
-				err = fmt.Errorf("bucket '%s' doesn't exist", filter.BucketName)
-				l.WrappedLogger.LogErrorf("Can't deploy startup filters : %w", err)
-				return err
+	concurrency := l.startupConcurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	loadCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errs := make([]error, len(l.StartupFilters))
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, filter := range l.StartupFilters {
+		select {
+		case slots <- struct{}{}:
+		case <-loadCtx.Done():
+		}
+		if loadCtx.Err() != nil {
+			errs[i] = loadCtx.Err()
+			continue
+		}
+		wg.Add(1)
+		go func(i int, filter Filter) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			errs[i] = l.loadStartupFilter(filter, loadCtx)
+			if errs[i] != nil && l.startupFailFast {
+				cancel()
 			}
+		}(i, filter)
+	}
+	wg.Wait()
+
+	var firstErr error
+	failed := 0
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		failed++
+		if firstErr == nil {
+			firstErr = err
+		}
+		l.WrappedLogger.LogErrorf("Can't deploy startup filter %d with tag '%s' : %w", i, l.StartupFilters[i].Tag, err)
+	}
+	l.WrappedLogger.LogInfof("Startup filters deployed: %d loaded, %d failed", len(errs)-failed, failed)
+	if failed > 0 && l.startupFailFast {
+		return fmt.Errorf("%d of %d startup filters failed, first error: %w", failed, len(errs), firstErr)
+	}
+	return nil
+}
+
+func (l *Listener) loadStartupFilter(filter Filter, ctx context.Context) error {
+	// use default bucket if none
+	if filter.BucketName == "" {
+		filter.BucketName = l.Storage.DefaultBucketName
+	} else {
+		err := l.PrepareFilterBucket(filter.BucketName, ctx)
+		if err != nil {
+			return err
 		}
-		l.AddFilter(filter)
+	}
+	_, err := l.AddFilter(filter)
+	return err
+}
+
+// PrepareFilterBucket makes sure the bucket of a new filter exists, creating it with the default expiration if
+// buckets are created automatically. The partitions of a partitioned storage are created on demand instead.
+func (l *Listener) PrepareFilterBucket(bucketName string, ctx context.Context) error {
+	if l.Storage.Partitioned() {
+		return nil
+	}
+	if !l.autoCreateBuckets {
+		exists, err := l.Storage.BucketExists(bucketName, ctx)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return fmt.Errorf("bucket '%s' doesn't exist", bucketName)
+		}
+		return nil
+	}
+
+	exists, err := l.Storage.CheckCreateBucket(bucketName, ctx)
+	if err != nil {
+		return fmt.Errorf("can't create bucket '%s', error: %w", bucketName, err)
+	}
+	if !exists {
+		return l.Storage.SetBucketExpirationDays(bucketName, l.Storage.DefaultBucketExpirationDays, ctx)
 	}
 	return nil
 }
 
-func (l *Listener) checkFilterExpired(filterId string) bool {
-	filter := l.Filters[filterId]
+func (l *Listener) checkFilterExpired(filter Filter) bool {
 	filterExpired := filter.IsExpired()
 	if filterExpired {
-		l.RemoveFilter(filterId)
+		l.RemoveFilter(filter.Id)
 	}
 	return filterExpired
 }
 
-func (l *Listener) checkAndStore(taggedData iotago.TaggedData, filterId string, block *iotago.Block, blockId inx.BlockId, ctx context.Context) error {
+func (l *Listener) checkAndStore(taggedData iotago.TaggedData, filter Filter, block *iotago.Block, blockId *inx.BlockId, referencedAt time.Time, ctx context.Context) error {
 	var err error
-	filter := l.Filters[filterId]
-	if string(taggedData.Tag) == filter.Tag {
+	if filter.matches(taggedData) {
 		if filter.Duration != "" {
 			// checks if the filter expired, if it is, skips and removes the filter
-			if l.checkFilterExpired(filterId) {
+			if l.checkFilterExpired(filter) {
 				l.WrappedLogger.LogInfof("Filter '%s' expired, with tag: '%s'", filter.Id, filter.Tag)
 				return nil
 			}
 		}
+		l.filterStats.matched(filter)
 
 		// checks if the filter has a specified public key, if it does it verifies the data
+		var signedPayload *datapayloads.SignedDataContainer
 		if filter.PublicKeyDecoded != nil {
 
 			// check if this payload is a signed payload compliant to the filter specification
-			signedPayload, err := getSubscribedSignedPayload(taggedData, filter.PublicKeyDecoded)
+			signedPayload, err = getSubscribedSignedPayload(taggedData, filter.PublicKeyDecoded)
 			if err != nil {
 				l.WrappedLogger.LogInfof("Discarding unsubscribed payload")
 				return nil
@@ -184,21 +568,88 @@ func (l *Listener) checkAndStore(taggedData iotago.TaggedData, filterId string,
 			}
 		}
 
+		// without a public key the container has not been decoded and verified yet
+		if signedPayload == nil && filter.StoreFormat == StoreFormatSignedDataPlaintext {
+			signedPayload, err = datapayloads.NewSignedDataContainerFromBytes(taggedData.Data)
+			if err != nil {
+				l.WrappedLogger.LogInfof("Discarding a payload which is not signed data")
+				return nil
+			}
+			err = signedPayload.VerifySignature()
+			if err != nil {
+				l.WrappedLogger.LogWarnf("Discarding a signed payload with invalid signature")
+				return nil
+			}
+		}
+
 		blockIdStr := hex.EncodeToString(blockId.GetId())
-		var object storage.Object
-		if filter.WithPOI {
-			object, err = GetObjectFromTanglePOI(blockIdStr, l.POIHandler)
+		// the data of a signed container is what the application wrote
+		payload := taggedData.Data
+		if signedPayload != nil {
+			payload = signedPayload.Data
+		}
+		if filter.schema != nil {
+			err = filter.schema.validate(payload)
 			if err != nil {
-				return err
+				l.filterStats.rejected(filter)
+				l.sampledLog.LogWarnf("Discarding block '%s' not conforming to the schema of filter '%s', error: %v", blockIdStr, filter.Id, err)
+				return nil
 			}
-		} else {
-			object.Block = block
 		}
-		err = l.Storage.UploadObject(blockIdStr, filter.BucketName, object, ctx)
+
+		var object storage.Object
+		switch filter.StoreFormat {
+		case StoreFormatTaggedData:
+			object.TaggedData = &taggedData
+		case StoreFormatSignedDataPlaintext:
+			object.Data = signedPayload.Data
+		default:
+			if filter.WithPOI {
+				object, err = GetObjectFromTanglePOI(blockIdStr, l.POIHandler)
+				if err != nil {
+					return err
+				}
+			} else {
+				object.Block = block
+			}
+		}
+		if !l.transformObject(filter, &object, blockIdStr) {
+			return nil
+		}
+		object.Alias, _ = filter.aliasOf(payload)
+		object.IndexTags = filter.indexTagsOf(payload)
+		objectName := l.objectNameOf(filter, payload, blockIdStr)
+
+		var bucketName string
+		bucketName, err = l.Storage.EnsurePartition(filter.BucketName, referencedAt, ctx)
+		if err != nil {
+			return err
+		}
+		if l.trackAttachments {
+			l.recordAttachment(taggedData, bucketName, objectName, ctx)
+		}
+		if !l.Storage.AddToBatch(objectName, bucketName, object, ctx) {
+			err = l.Storage.UploadObject(objectName, bucketName, object, ctx)
+		}
+		if errors.Is(err, storage.ErrQuotaExceeded) {
+			l.sampledLog.LogWarnf("Discarding block '%s', error: %w", blockIdStr, err)
+			return nil
+		}
 		if err != nil {
+			queued, queueErr := l.retries.enqueue(objectName, bucketName, filter.Id, object)
+			if queued {
+				l.WrappedLogger.LogWarnf("Can't upload the block '%s', retrying later, error: %w", blockIdStr, err)
+				return nil
+			}
+			if queueErr != nil {
+				l.WrappedLogger.LogErrorf("Can't queue the block '%s' for retry, error: %w", blockIdStr, queueErr)
+			}
 			err = fmt.Errorf("can't upload the block '%s', error: %w", blockIdStr, err)
 			return err
 		}
+		l.filterStats.stored(filter)
+		l.status.blockStored(blockIdStr)
+		l.notifyStored(objectName, bucketName, string(taggedData.Tag))
 	}
 	return nil
 }
@@ -0,0 +1,385 @@
+package listener
+
+import (
+	"collector/pkg/poi"
+	"collector/pkg/storage"
+	"context"
+	"encoding/hex"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/iotaledger/hive.go/core/configuration"
+	"github.com/iotaledger/hive.go/core/logger"
+	inx "github.com/iotaledger/inx/go"
+	iotago "github.com/iotaledger/iota.go/v3"
+	"github.com/minio/minio-go/v7"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	flag "github.com/spf13/pflag"
+	"google.golang.org/grpc"
+)
+
+// newTestListener returns a listener with the default parameters changed by configure, storing to a memory backend
+// whose default bucket is created.
+func newTestListener(t *testing.T, configure func(params *Parameters)) *Listener {
+	t.Helper()
+	return newTestListenerWithBackend(t, storage.NewMemoryBackend(), configure)
+}
+
+// newTestListenerWithBackend returns a listener like newTestListener, storing to the given backend.
+func newTestListenerWithBackend(t *testing.T, backend storage.Backend, configure func(params *Parameters)) *Listener {
+	t.Helper()
+	storageParams := &storage.Parameters{}
+	configuration.New().BindParameters(configuration.NewUnsortedFlagSet("test", flag.ContinueOnError), "storage", storageParams)
+	s, err := storage.NewStorageWithBackend(*storageParams, backend, prometheus.NewRegistry(), logger.NewWrappedLogger(logger.NewNopLogger()))
+	if err != nil {
+		t.Fatalf("can't create the storage: %v", err)
+	}
+	if _, err := s.CheckCreateBucket(s.DefaultBucketName, context.Background()); err != nil {
+		t.Fatalf("can't create the default bucket: %v", err)
+	}
+	return newTestListenerWithStorage(t, s, configure)
+}
+
+// newTestListenerWithStorage returns a listener with the default parameters changed by configure, storing to s.
+func newTestListenerWithStorage(t *testing.T, s storage.Storage, configure func(params *Parameters)) *Listener {
+	t.Helper()
+	params := &Parameters{}
+	configuration.New().BindParameters(configuration.NewUnsortedFlagSet("test", flag.ContinueOnError), "listener", params)
+	if configure != nil {
+		configure(params)
+	}
+
+	l, err := NewListener(*params, s, poi.POIHandler{}, prometheus.NewRegistry(), logger.NewWrappedLogger(logger.NewNopLogger()))
+	if err != nil {
+		t.Fatalf("can't create the listener: %v", err)
+	}
+	return &l
+}
+
+// addTaggedDataFilter subscribes to a tag, storing its tagged data payloads to the default bucket.
+func addTaggedDataFilter(t *testing.T, l *Listener, tag string) {
+	t.Helper()
+	filter, err := NewFilter(tag, false, "", l.Storage.DefaultBucketName, "", false, StoreFormatTaggedData)
+	if err != nil {
+		t.Fatalf("can't create the filter: %v", err)
+	}
+	if _, err := l.AddFilter(filter); err != nil {
+		t.Fatalf("can't add the filter: %v", err)
+	}
+}
+
+// referenced returns a block carrying a tagged data payload, referenced at the given time.
+func referenced(id byte, tag string, data string, referencedAt time.Time, l *Listener) *referencedBlock {
+	taggedData := iotago.TaggedData{Tag: []byte(tag), Data: []byte(data)}
+	blockId := make([]byte, iotago.BlockIDLength)
+	blockId[0] = id
+	return &referencedBlock{
+		filters:      l.filters.matching(taggedData),
+		taggedData:   taggedData,
+		block:        iotago.Block{Payload: &taggedData},
+		blockId:      &inx.BlockId{Id: blockId},
+		referencedAt: referencedAt,
+	}
+}
+
+// storedData returns the data of the tagged data payload stored under a block id in the default bucket.
+func storedData(l *Listener, blockId string) (string, error) {
+	reader, err := l.Storage.GetObject(l.Storage.DefaultBucketName, blockId, "", context.Background())
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+	object, err := reader.Decode()
+	if err != nil {
+		return "", err
+	}
+	if object.TaggedData == nil {
+		return "", errors.New("no tagged data stored")
+	}
+	return string(object.TaggedData.Data), nil
+}
+
+func TestStoreBlockFlow(t *testing.T) {
+	l := newTestListener(t, nil)
+	addTaggedDataFilter(t, l, "flow")
+
+	matching := referenced(1, "flow", "matching", time.Now(), l)
+	other := referenced(2, "other", "not matching", time.Now(), l)
+	l.storeBlock(matching, context.Background())
+	l.storeBlock(other, context.Background())
+
+	data, err := storedData(l, hex.EncodeToString(matching.blockId.GetId()))
+	if err != nil {
+		t.Fatalf("the matching block was not stored: %v", err)
+	}
+	if data != "matching" {
+		t.Errorf("got data '%s', expected 'matching'", data)
+	}
+	if _, err := storedData(l, hex.EncodeToString(other.blockId.GetId())); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("got error %v for the block of another tag, expected ErrNotFound", err)
+	}
+	if counters := l.SessionCounters(); counters.BlocksStored != 1 || counters.BlocksFailed != 0 {
+		t.Errorf("got %d blocks stored and %d failed, expected 1 and 0", counters.BlocksStored, counters.BlocksFailed)
+	}
+}
+
+// fakeINXClient streams referenced blocks carrying tagged data payloads, cancelling the listening once they are
+// all received. The milestones missing from milestones can't be read.
+type fakeINXClient struct {
+	inx.INXClient
+	blocks     map[string]*iotago.Block
+	referenced []*inx.BlockMetadata
+	milestones map[uint32]time.Time
+	cancel     context.CancelFunc
+}
+
+func (c *fakeINXClient) addBlock(id byte, tag string, milestoneIndex uint32) string {
+	blockId := make([]byte, iotago.BlockIDLength)
+	blockId[0] = id
+	c.blocks[string(blockId)] = &iotago.Block{
+		ProtocolVersion: 2,
+		Parents:         iotago.BlockIDs{{}},
+		Payload:         &iotago.TaggedData{Tag: []byte(tag), Data: []byte{id}},
+	}
+	c.referenced = append(c.referenced, &inx.BlockMetadata{BlockId: &inx.BlockId{Id: blockId}, ReferencedByMilestoneIndex: milestoneIndex})
+	return hex.EncodeToString(blockId)
+}
+
+type fakeReferencedBlocks struct {
+	grpc.ClientStream
+	client *fakeINXClient
+}
+
+func (s *fakeReferencedBlocks) Recv() (*inx.BlockMetadata, error) {
+	if len(s.client.referenced) == 0 {
+		s.client.cancel()
+		return nil, io.EOF
+	}
+	next := s.client.referenced[0]
+	s.client.referenced = s.client.referenced[1:]
+	return next, nil
+}
+
+func (c *fakeINXClient) ListenToReferencedBlocks(ctx context.Context, in *inx.NoParams, opts ...grpc.CallOption) (inx.INX_ListenToReferencedBlocksClient, error) {
+	return &fakeReferencedBlocks{client: c}, nil
+}
+
+func (c *fakeINXClient) ReadBlock(ctx context.Context, in *inx.BlockId, opts ...grpc.CallOption) (*inx.RawBlock, error) {
+	block, ok := c.blocks[string(in.GetId())]
+	if !ok {
+		return nil, errors.New("unknown block")
+	}
+	return inx.WrapBlock(block)
+}
+
+func (c *fakeINXClient) ReadMilestone(ctx context.Context, in *inx.MilestoneRequest, opts ...grpc.CallOption) (*inx.Milestone, error) {
+	at, ok := c.milestones[in.GetMilestoneIndex()]
+	if !ok {
+		return nil, errors.New("milestone not found")
+	}
+	return &inx.Milestone{MilestoneInfo: &inx.MilestoneInfo{MilestoneIndex: in.GetMilestoneIndex(), MilestoneTimestamp: uint32(at.Unix())}}, nil
+}
+
+func TestMaxBlockAge(t *testing.T) {
+	const maxBlockAge = time.Hour
+	l := newTestListener(t, func(params *Parameters) {
+		params.MaxBlockAge = maxBlockAge
+	})
+	addTaggedDataFilter(t, l, "aged")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	now := time.Now()
+	client := &fakeINXClient{
+		blocks: make(map[string]*iotago.Block),
+		milestones: map[uint32]time.Time{
+			1: now.Add(-maxBlockAge + time.Minute),
+			2: now.Add(-maxBlockAge - time.Minute),
+		},
+		cancel: cancel,
+	}
+	recent := client.addBlock(1, "aged", 1)
+	old := client.addBlock(2, "aged", 2)
+	// without its milestone the age of a block is unknown, it is stored
+	unknown := client.addBlock(3, "aged", 3)
+
+	if _, err := l.listen(client, ctx, context.Background(), nil); err != nil {
+		t.Fatalf("listening failed: %v", err)
+	}
+	if pending := l.Drain(5 * time.Second); pending != 0 {
+		t.Fatalf("%d blocks still in flight", pending)
+	}
+
+	for _, blockId := range []string{recent, unknown} {
+		if _, err := storedData(l, blockId); err != nil {
+			t.Errorf("block '%s' was not stored: %v", blockId, err)
+		}
+	}
+	if _, err := storedData(l, old); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("got error %v for the block older than the maximum age, expected ErrNotFound", err)
+	}
+}
+
+func TestInFlightPolicy(t *testing.T) {
+	dropping, err := newInFlight(Parameters{MaxInFlightBlocks: 1, InFlightPolicy: InFlightPolicyDrop})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if !dropping.add(ctx) || dropping.add(ctx) {
+		t.Fatal("expected a single slot")
+	}
+	dropping.done()
+	if !dropping.add(ctx) {
+		t.Error("the slot was not released")
+	}
+
+	blocking, err := newInFlight(Parameters{MaxInFlightBlocks: 1, InFlightPolicy: InFlightPolicyBlock})
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocking.add(ctx)
+	added := make(chan bool)
+	go func() {
+		added <- blocking.add(ctx)
+	}()
+	select {
+	case <-added:
+		t.Fatal("a block was added past the limit")
+	case <-time.After(50 * time.Millisecond):
+	}
+	blocking.done()
+	if !<-added {
+		t.Error("the waiting block was not added once a slot was released")
+	}
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if blocking.add(cancelled) {
+		t.Error("a block was added with a cancelled context")
+	}
+
+	if _, err := newInFlight(Parameters{InFlightPolicy: "unknown"}); err == nil {
+		t.Error("an unknown policy was accepted")
+	}
+}
+
+// blockingPutBackend holds the uploads until release is closed.
+type blockingPutBackend struct {
+	*storage.MemoryBackend
+	release chan struct{}
+}
+
+func (b *blockingPutBackend) PutObject(ctx context.Context, bucketName string, objectName string, reader io.Reader, objectSize int64, opts minio.PutObjectOptions) (minio.UploadInfo, error) {
+	<-b.release
+	return b.MemoryBackend.PutObject(ctx, bucketName, objectName, reader, objectSize, opts)
+}
+
+func TestMaxInFlightBlocks(t *testing.T) {
+	backend := &blockingPutBackend{MemoryBackend: storage.NewMemoryBackend(), release: make(chan struct{})}
+	l := newTestListenerWithBackend(t, backend, func(params *Parameters) {
+		params.MaxInFlightBlocks = 1
+		params.InFlightPolicy = InFlightPolicyDrop
+	})
+	addTaggedDataFilter(t, l, "flight")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client := &fakeINXClient{blocks: make(map[string]*iotago.Block), cancel: cancel}
+	stored := client.addBlock(1, "flight", 1)
+	for id := byte(2); id <= 4; id++ {
+		client.addBlock(id, "flight", 1)
+	}
+	if _, err := l.listen(client, ctx, context.Background(), nil); err != nil {
+		t.Fatalf("listening failed: %v", err)
+	}
+	close(backend.release)
+	if pending := l.Drain(5 * time.Second); pending != 0 {
+		t.Fatalf("%d blocks still in flight", pending)
+	}
+
+	if dropped := testutil.ToFloat64(l.metrics.blocksDropped); dropped != 3 {
+		t.Errorf("got %v blocks dropped, expected 3", dropped)
+	}
+	if _, err := storedData(l, stored); err != nil {
+		t.Errorf("the block in flight was not stored: %v", err)
+	}
+}
+
+func TestPartitionedStore(t *testing.T) {
+	storageParams := &storage.Parameters{}
+	configuration.New().BindParameters(configuration.NewUnsortedFlagSet("test", flag.ContinueOnError), "storage", storageParams)
+	storageParams.PartitionTemplate = "{bucket}-{yyyy}{mm}"
+	s, err := storage.NewStorageWithBackend(*storageParams, storage.NewMemoryBackend(), prometheus.NewRegistry(), logger.NewWrappedLogger(logger.NewNopLogger()))
+	if err != nil {
+		t.Fatalf("can't create the storage: %v", err)
+	}
+	l := newTestListenerWithStorage(t, s, nil)
+	addTaggedDataFilter(t, l, "partition")
+
+	block := referenced(1, "partition", "data", time.Date(2023, time.May, 1, 12, 0, 0, 0, time.UTC), l)
+	l.storeBlock(block, context.Background())
+
+	partition := l.Storage.DefaultBucketName + "-202305"
+	reader, err := l.Storage.GetObject(partition, hex.EncodeToString(block.blockId.GetId()), "", context.Background())
+	if err != nil {
+		t.Fatalf("the block was not stored in partition '%s': %v", partition, err)
+	}
+	reader.Close()
+}
+
+func TestLoadStartupFilters(t *testing.T) {
+	startupFilters := []Filter{
+		{Tag: "valid"},
+		{Tag: "missing-bucket", BucketName: "missing"},
+		{Tag: "invalid-format", StoreFormat: "unknown"},
+		{Tag: "other-valid", StoreFormat: StoreFormatTaggedData},
+	}
+	for _, tc := range []struct {
+		failFast bool
+		err      bool
+	}{
+		{false, false},
+		{true, true},
+	} {
+		l := newTestListener(t, func(params *Parameters) {
+			params.StartupFiltersFailFast = tc.failFast
+			params.StartupFiltersConcurrency = 1
+			params.AutoCreateBuckets = false
+		})
+		l.StartupFilters = startupFilters
+		err := l.LoadStartupFilters(context.Background())
+		if (err != nil) != tc.err {
+			t.Errorf("fail fast %t: got error %v, expected one: %t", tc.failFast, err, tc.err)
+		}
+		if tc.failFast {
+			continue
+		}
+		tags := map[string]bool{}
+		for _, filter := range l.ListFilters() {
+			tags[filter.Tag] = true
+		}
+		if len(tags) != 2 || !tags["valid"] || !tags["other-valid"] {
+			t.Errorf("got filters %v, expected the valid ones only", tags)
+		}
+	}
+}
+
+func TestPrepareFilterBucket(t *testing.T) {
+	ctx := context.Background()
+	for _, autoCreate := range []bool{true, false} {
+		l := newTestListener(t, func(params *Parameters) {
+			params.AutoCreateBuckets = autoCreate
+		})
+		if err := l.PrepareFilterBucket(l.Storage.DefaultBucketName, ctx); err != nil {
+			t.Errorf("auto-create %t: got error %v for an existing bucket, expected none", autoCreate, err)
+		}
+		err := l.PrepareFilterBucket("new-bucket", ctx)
+		exists, _ := l.Storage.BucketExists("new-bucket", ctx)
+		if (err == nil) != autoCreate || exists != autoCreate {
+			t.Errorf("auto-create %t: got error %v, bucket existing: %t", autoCreate, err, exists)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package listener
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/iotaledger/hive.go/core/logger"
+)
+
+// sampledLogger logs the first occurrence of a message, then at most one summary per window with the number
+// of repetitions, so that a persistent failure doesn't flood the logs. Messages are keyed by their format.
+type sampledLogger struct {
+	*logger.WrappedLogger
+	// window is a time.Duration, it can be changed while logging
+	window atomic.Int64
+
+	mu       sync.Mutex
+	messages map[string]*sampledMessage
+}
+
+type sampledMessage struct {
+	loggedAt   time.Time
+	suppressed int
+}
+
+func newSampledLogger(log *logger.WrappedLogger, window time.Duration) *sampledLogger {
+	l := &sampledLogger{
+		WrappedLogger: log,
+		messages:      make(map[string]*sampledMessage),
+	}
+	l.window.Store(int64(window))
+	return l
+}
+
+// SetLogSamplingWindow sets the interval at which repeated errors are logged again, 0 logs every occurrence.
+func (l *Listener) SetLogSamplingWindow(window time.Duration) {
+	l.sampledLog.window.Store(int64(window))
+}
+
+// sample returns whether a message must be logged, and how many times it was suppressed since it last was.
+func (l *sampledLogger) sample(format string) (bool, int) {
+	window := time.Duration(l.window.Load())
+	if window <= 0 {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	message, ok := l.messages[format]
+	if !ok || now.Sub(message.loggedAt) >= window {
+		suppressed := 0
+		if ok {
+			suppressed = message.suppressed
+		}
+		l.messages[format] = &sampledMessage{loggedAt: now}
+		return true, suppressed
+	}
+	message.suppressed++
+	return false, 0
+}
+
+func (l *sampledLogger) LogErrorf(format string, args ...interface{}) {
+	log, suppressed := l.sample(format)
+	if !log {
+		return
+	}
+	if suppressed > 0 {
+		format = fmt.Sprintf("%s (repeated %d times in the last %v)", format, suppressed, time.Duration(l.window.Load()))
+	}
+	l.WrappedLogger.LogErrorf(format, args...)
+}
+
+func (l *sampledLogger) LogWarnf(format string, args ...interface{}) {
+	log, suppressed := l.sample(format)
+	if !log {
+		return
+	}
+	if suppressed > 0 {
+		format = fmt.Sprintf("%s (repeated %d times in the last %v)", format, suppressed, time.Duration(l.window.Load()))
+	}
+	l.WrappedLogger.LogWarnf(format, args...)
+}
@@ -0,0 +1,45 @@
+package listener
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iotaledger/hive.go/core/logger"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestSampledLogger(t *testing.T) {
+	const window = 100 * time.Millisecond
+	core, logs := observer.New(zapcore.WarnLevel)
+	l := newSampledLogger(logger.NewWrappedLogger(zap.New(core).Sugar()), window)
+
+	for i := 0; i < 5; i++ {
+		l.LogErrorf("Can't reach the storage, attempt %d", i)
+	}
+	l.LogWarnf("Another failure")
+	if n := logs.Len(); n != 2 {
+		t.Fatalf("got %d messages logged, expected the first of each", n)
+	}
+
+	time.Sleep(window)
+	l.LogErrorf("Can't reach the storage, attempt %d", 5)
+	entries := logs.FilterMessageSnippet("Can't reach the storage").All()
+	if len(entries) != 2 {
+		t.Fatalf("got %d messages logged once the window passed, expected 2", len(entries))
+	}
+	if expected := "attempt 5 (repeated 4 times in the last 100ms)"; !strings.HasSuffix(entries[1].Message, expected) {
+		t.Errorf("got message '%s', expected it to end with '%s'", entries[1].Message, expected)
+	}
+
+	// without a window every occurrence is logged
+	l.window.Store(0)
+	for i := 0; i < 3; i++ {
+		l.LogWarnf("Another failure")
+	}
+	if n := logs.FilterMessage("Another failure").Len(); n != 4 {
+		t.Errorf("got %d messages logged without a window, expected 4", n)
+	}
+}
@@ -0,0 +1,169 @@
+package listener
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// FilterStats counts the blocks a filter matched and stored.
+type FilterStats struct {
+	BlocksMatched  int64      `json:"blocksMatched"`
+	BlocksStored   int64      `json:"blocksStored"`
+	BlocksRejected int64      `json:"blocksRejected"`
+	LastMatch      *time.Time `json:"lastMatch,omitempty"`
+}
+
+// filterCounters holds the live counters of a filter, updated without locking on the matching path.
+type filterCounters struct {
+	matched   atomic.Int64
+	stored    atomic.Int64
+	rejected  atomic.Int64
+	lastMatch atomic.Int64
+}
+
+func (c *filterCounters) stats() FilterStats {
+	stats := FilterStats{
+		BlocksMatched:  c.matched.Load(),
+		BlocksStored:   c.stored.Load(),
+		BlocksRejected: c.rejected.Load(),
+	}
+	if lastMatch := c.lastMatch.Load(); lastMatch != 0 {
+		t := time.Unix(0, lastMatch)
+		stats.LastMatch = &t
+	}
+	return stats
+}
+
+// Metrics collects the Prometheus metrics of the filters.
+// A nil *Metrics is valid and records nothing.
+type Metrics struct {
+	matched         *prometheus.CounterVec
+	stored          *prometheus.CounterVec
+	rejected        *prometheus.CounterVec
+	lastMatch       *prometheus.GaugeVec
+	retryQueueDepth prometheus.Gauge
+	uploadsDropped  prometheus.Counter
+	blocksDropped   prometheus.Counter
+	blocksTooOld    prometheus.Counter
+}
+
+func NewMetrics(registerer prometheus.Registerer) (*Metrics, error) {
+	m := &Metrics{
+		matched: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "listener_filter_blocks_matched_total",
+			Help: "Number of blocks matching the tag of a filter.",
+		}, []string{"filter", "tag"}),
+		stored: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "listener_filter_blocks_stored_total",
+			Help: "Number of blocks stored by a filter.",
+		}, []string{"filter", "tag"}),
+		rejected: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "listener_filter_blocks_rejected_total",
+			Help: "Number of blocks discarded by a filter because their payload doesn't conform to its schema.",
+		}, []string{"filter", "tag"}),
+		lastMatch: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "listener_filter_last_match_timestamp_seconds",
+			Help: "Unix time of the last block matching the tag of a filter.",
+		}, []string{"filter", "tag"}),
+		retryQueueDepth: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "listener_retry_queue_depth",
+			Help: "Number of failed uploads waiting to be retried.",
+		}),
+		uploadsDropped: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "listener_uploads_dropped_total",
+			Help: "Number of uploads dropped after exhausting their retries.",
+		}),
+		blocksDropped: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "listener_blocks_dropped_total",
+			Help: "Number of blocks dropped because too many were in flight.",
+		}),
+		blocksTooOld: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "listener_blocks_too_old_total",
+			Help: "Number of blocks dropped because their milestone is older than the maximum block age.",
+		}),
+	}
+
+	for _, collector := range []prometheus.Collector{m.matched, m.stored, m.rejected, m.lastMatch, m.retryQueueDepth, m.uploadsDropped, m.blocksDropped, m.blocksTooOld} {
+		if err := registerer.Register(collector); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func (m *Metrics) setRetryQueueDepth(depth int) {
+	if m == nil {
+		return
+	}
+	m.retryQueueDepth.Set(float64(depth))
+}
+
+func (m *Metrics) incUploadsDropped() {
+	if m == nil {
+		return
+	}
+	m.uploadsDropped.Inc()
+}
+
+func (m *Metrics) incBlocksDropped() {
+	if m == nil {
+		return
+	}
+	m.blocksDropped.Inc()
+}
+
+func (m *Metrics) incBlocksTooOld() {
+	if m == nil {
+		return
+	}
+	m.blocksTooOld.Inc()
+}
+
+// filterStats keeps the counters of every active filter.
+type filterStats struct {
+	counters sync.Map
+	metrics  *Metrics
+}
+
+func (s *filterStats) get(filterId string) *filterCounters {
+	counters, _ := s.counters.LoadOrStore(filterId, &filterCounters{})
+	return counters.(*filterCounters)
+}
+
+func (s *filterStats) matched(filter Filter) {
+	now := time.Now()
+	counters := s.get(filter.Id)
+	counters.matched.Add(1)
+	counters.lastMatch.Store(now.UnixNano())
+	if s.metrics != nil {
+		s.metrics.matched.WithLabelValues(filter.Id, filter.Tag).Inc()
+		s.metrics.lastMatch.WithLabelValues(filter.Id, filter.Tag).Set(float64(now.Unix()))
+	}
+}
+
+func (s *filterStats) stored(filter Filter) {
+	s.get(filter.Id).stored.Add(1)
+	if s.metrics != nil {
+		s.metrics.stored.WithLabelValues(filter.Id, filter.Tag).Inc()
+	}
+}
+
+func (s *filterStats) rejected(filter Filter) {
+	s.get(filter.Id).rejected.Add(1)
+	if s.metrics != nil {
+		s.metrics.rejected.WithLabelValues(filter.Id, filter.Tag).Inc()
+	}
+}
+
+func (s *filterStats) remove(filter Filter) {
+	s.counters.Delete(filter.Id)
+	if s.metrics != nil {
+		s.metrics.matched.DeleteLabelValues(filter.Id, filter.Tag)
+		s.metrics.stored.DeleteLabelValues(filter.Id, filter.Tag)
+		s.metrics.rejected.DeleteLabelValues(filter.Id, filter.Tag)
+		s.metrics.lastMatch.DeleteLabelValues(filter.Id, filter.Tag)
+	}
+}
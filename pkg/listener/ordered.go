@@ -0,0 +1,63 @@
+package listener
+
+import (
+	"context"
+	"hash/fnv"
+	"time"
+
+	inx "github.com/iotaledger/inx/go"
+	iotago "github.com/iotaledger/iota.go/v3"
+)
+
+// orderedQueueSize is the number of blocks waiting for each ordered worker before the node stream is paused.
+const orderedQueueSize = 64
+
+// referencedBlock is a block waiting to be run through the filters.
+type referencedBlock struct {
+	filters      []Filter
+	taggedData   iotago.TaggedData
+	block        iotago.Block
+	blockId      *inx.BlockId
+	referencedAt time.Time
+}
+
+// orderedWorkers stores the blocks one at a time per worker, a tag always being handled by the same worker,
+// so that the blocks of a tag are stored in the order they were referenced while different tags proceed concurrently.
+type orderedWorkers struct {
+	queues []chan *referencedBlock
+}
+
+// startOrderedWorkers starts count workers storing the blocks with ctx, until stop is called.
+func (l *Listener) startOrderedWorkers(count int, ctx context.Context) *orderedWorkers {
+	w := &orderedWorkers{queues: make([]chan *referencedBlock, count)}
+	for i := range w.queues {
+		queue := make(chan *referencedBlock, orderedQueueSize)
+		w.queues[i] = queue
+		go func() {
+			for b := range queue {
+				l.storeBlock(b, ctx)
+				l.inFlight.done()
+			}
+		}()
+	}
+	return w
+}
+
+// dispatch queues a block on the worker of its tag, waiting while the worker is behind. It returns false if ctx is done.
+func (w *orderedWorkers) dispatch(b *referencedBlock, ctx context.Context) bool {
+	hash := fnv.New32a()
+	hash.Write(b.taggedData.Tag)
+	select {
+	case w.queues[hash.Sum32()%uint32(len(w.queues))] <- b:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// stop lets the workers store the queued blocks and exit.
+func (w *orderedWorkers) stop() {
+	for _, queue := range w.queues {
+		close(queue)
+	}
+}
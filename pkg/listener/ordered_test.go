@@ -0,0 +1,77 @@
+package listener
+
+import (
+	"collector/pkg/storage"
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	iotago "github.com/iotaledger/iota.go/v3"
+	"github.com/minio/minio-go/v7"
+)
+
+// orderBackend records the order of the uploads, delaying them so that unordered stores would overtake each other.
+type orderBackend struct {
+	*storage.MemoryBackend
+	sync.Mutex
+	order []string
+}
+
+func (b *orderBackend) PutObject(ctx context.Context, bucketName string, objectName string, reader io.Reader, objectSize int64, opts minio.PutObjectOptions) (minio.UploadInfo, error) {
+	time.Sleep(time.Millisecond)
+	b.Lock()
+	b.order = append(b.order, objectName)
+	b.Unlock()
+	return b.MemoryBackend.PutObject(ctx, bucketName, objectName, reader, objectSize, opts)
+}
+
+func TestOrderedWorkers(t *testing.T) {
+	backend := &orderBackend{MemoryBackend: storage.NewMemoryBackend()}
+	l := newTestListenerWithBackend(t, backend, func(params *Parameters) {
+		params.OrderedWorkers = 2
+	})
+	tags := []string{"first", "second", "third"}
+	for _, tag := range tags {
+		addTaggedDataFilter(t, l, tag)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client := &fakeINXClient{blocks: make(map[string]*iotago.Block), cancel: cancel}
+	sent := make(map[string][]string)
+	tagOf := make(map[string]string)
+	for i := 0; i < 60; i++ {
+		tag := tags[i%len(tags)]
+		blockId := client.addBlock(byte(i), tag, 1)
+		sent[tag] = append(sent[tag], blockId)
+		tagOf[blockId] = tag
+	}
+
+	workers := l.startOrderedWorkers(l.orderedWorkers, context.Background())
+	if _, err := l.listen(client, ctx, context.Background(), workers); err != nil {
+		t.Fatalf("listening failed: %v", err)
+	}
+	workers.stop()
+	if pending := l.Drain(10 * time.Second); pending != 0 {
+		t.Fatalf("%d blocks still in flight", pending)
+	}
+
+	stored := make(map[string][]string)
+	backend.Lock()
+	for _, blockId := range backend.order {
+		stored[tagOf[blockId]] = append(stored[tagOf[blockId]], blockId)
+	}
+	backend.Unlock()
+	for _, tag := range tags {
+		if len(stored[tag]) != len(sent[tag]) {
+			t.Fatalf("tag '%s': got %d blocks stored, expected %d", tag, len(stored[tag]), len(sent[tag]))
+		}
+		for i := range sent[tag] {
+			if stored[tag][i] != sent[tag][i] {
+				t.Fatalf("tag '%s': got block '%s' stored at position %d, expected '%s'", tag, stored[tag][i], i, sent[tag][i])
+			}
+		}
+	}
+}
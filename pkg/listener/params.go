@@ -1,7 +1,90 @@
 package listener
 
+import "time"
+
 // ParametersListener contains the definition of the parameters used by the Listener
 type Parameters struct {
 	// Filters is a json string which sets startup filters
 	Filters string `default:"" usage:"startup filters from env or config.json in a string format"`
+
+	// FiltersFile is the path of a json file which sets startup filters, overridden by Filters
+	FiltersFile string `default:"" usage:"the path of a json file with startup filters, in the format of filters, which override them"`
+
+	// StartupFiltersConcurrency is the number of startup filters deployed in parallel
+	StartupFiltersConcurrency int `default:"4" usage:"the number of startup filters deployed in parallel"`
+
+	// StartupFiltersFailFast defines whether a startup filter that can't be deployed stops the plugin
+	StartupFiltersFailFast bool `default:"false" usage:"whether a startup filter that can't be deployed stops the plugin, otherwise it is logged and skipped"`
+
+	// AutoCreateBuckets defines whether the bucket of a new filter is created if it doesn't exist
+	AutoCreateBuckets bool `default:"true" usage:"whether the bucket of a new filter is created with the default expiration if it doesn't exist, otherwise the filter is refused"`
+
+	// BackfillConcurrency is the number of milestones a backfill processes in parallel
+	BackfillConcurrency int `default:"4" usage:"the number of milestones a backfill processes in parallel"`
+
+	// MatchAllEnabled defines whether filters matching every block can be added
+	MatchAllEnabled bool `default:"false" usage:"whether filters matching every block can be added, they store the whole stream of referenced blocks"`
+
+	// TagAllowlist is a comma separated list of the tags clients may subscribe to
+	TagAllowlist string `default:"" usage:"a comma separated list of the tags clients may subscribe to through the API, a trailing * matches a prefix, every tag if empty"`
+
+	// TagDenylist is a comma separated list of the tags clients may not subscribe to
+	TagDenylist string `default:"" usage:"a comma separated list of the tags clients may not subscribe to through the API, a trailing * matches a prefix, it overrides the allowlist"`
+
+	// RetryQueueSize is the maximum number of failed uploads waiting to be retried
+	RetryQueueSize int `default:"1000" usage:"the maximum number of failed uploads waiting to be retried, 0 disables retries"`
+
+	// RetryMaxAttempts is the number of retries of a failed upload before it is dropped
+	RetryMaxAttempts int `default:"5" usage:"the number of retries of a failed upload before it is dropped"`
+
+	// RetryInterval is the delay before the first retry of a failed upload, doubled at every attempt
+	RetryInterval time.Duration `default:"5s" usage:"the delay before the first retry of a failed upload, doubled at every attempt"`
+
+	// RetrySpillDirectory is where failed uploads are persisted until retried
+	RetrySpillDirectory string `default:"" usage:"the directory where failed uploads are persisted until retried, they are kept in memory if empty"`
+
+	// MaxInFlightBlocks is the maximum number of blocks being stored at the same time
+	MaxInFlightBlocks int `default:"0" usage:"the maximum number of blocks being stored at the same time, 0 means unlimited"`
+
+	// InFlightPolicy is what happens to new blocks when MaxInFlightBlocks is reached
+	InFlightPolicy string `default:"drop" usage:"what happens to new blocks when maxInFlightBlocks is reached: drop them, or block the node stream"`
+
+	// OrderedWorkers is the number of workers storing the blocks in arrival order within each tag
+	OrderedWorkers int `default:"0" usage:"the number of workers storing the blocks of a tag one at a time, in arrival order, 0 stores every block concurrently"`
+
+	// TrackAttachments defines whether the blocks carrying the same tagged data are grouped in a manifest
+	TrackAttachments bool `default:"false" usage:"whether the blocks carrying the same tagged data are recorded together, to serve all the attachments of a payload"`
+
+	// WebhookURLs is a comma separated list of URLs notified when a block is stored
+	WebhookURLs string `default:"" usage:"a comma separated list of URLs notified with a POST when a block is stored, disabled if empty"`
+
+	// WebhookSecret is the key of the HMAC signature of the webhook notifications, they are not signed if empty
+	WebhookSecret string `default:"" usage:"the key of the HMAC-SHA256 signature sent with the webhook notifications, they are not signed if empty"`
+
+	// WebhookMaxAttempts is the number of deliveries of a notification before it is dropped
+	WebhookMaxAttempts int `default:"5" usage:"the number of deliveries of a webhook notification before it is dropped"`
+
+	// WebhookRetryInterval is the delay before the first retry of a failed notification, doubled at every attempt
+	WebhookRetryInterval time.Duration `default:"5s" usage:"the delay before the first retry of a failed webhook notification, doubled at every attempt"`
+
+	// WebhookQueueSize is the maximum number of notifications waiting to be delivered
+	WebhookQueueSize int `default:"1000" usage:"the maximum number of webhook notifications waiting to be delivered, further ones are dropped"`
+
+	// TransformFailurePolicy is what happens to a block whose payload transform fails
+	TransformFailurePolicy string `default:"store-original" usage:"what happens to a block whose payload transform fails: store-original or drop"`
+
+	// LogSamplingWindow is the interval at which repeated errors are logged again, with their count
+	LogSamplingWindow time.Duration `default:"1m" usage:"the interval at which repeated errors are logged again, with their count, 0 logs every occurrence"`
+
+	// ShutdownTimeout is how long the listener waits on shutdown for the blocks being stored
+	ShutdownTimeout time.Duration `default:"30s" usage:"how long the listener waits on shutdown for the blocks being stored"`
+
+	// FallbackINXAddresses is a comma separated list of the INX addresses of the nodes listened to when the node bridge fails
+	FallbackINXAddresses string `default:"" usage:"a comma separated list of the INX addresses of other nodes, in order of preference, listened to when the stream of the node bridge drops, disabled if empty"`
+
+	// NodeCheckInterval is how often the node bridge is checked while a fallback node is listened to
+	NodeCheckInterval time.Duration `default:"30s" usage:"how often the node bridge is checked while a fallback node is listened to, to switch back once it is healthy, 0 disables the check"`
+
+	// MaxBlockAge is the age of its referencing milestone beyond which a matched block is dropped
+	MaxBlockAge time.Duration `default:"0s" usage:"the age of its referencing milestone beyond which a block received from the node is dropped instead of stored, 0 stores every block"`
 }
@@ -0,0 +1,76 @@
+package listener
+
+import (
+	"fmt"
+	"sync"
+
+	iotago "github.com/iotaledger/iota.go/v3"
+)
+
+// filterRegistry holds the active filters, shared between the API handlers and the block stream.
+// Matching only takes the read lock, so it doesn't contend with other readers.
+type filterRegistry struct {
+	sync.RWMutex
+	filters map[string]Filter
+}
+
+func newFilterRegistry() *filterRegistry {
+	return &filterRegistry{filters: make(map[string]Filter)}
+}
+
+// add registers a filter, failing if its id is already taken.
+func (r *filterRegistry) add(filter Filter) error {
+	r.Lock()
+	defer r.Unlock()
+	if _, ok := r.filters[filter.Id]; ok {
+		return fmt.Errorf("Filter id '%s' already exists", filter.Id)
+	}
+	r.filters[filter.Id] = filter
+	return nil
+}
+
+// remove unregisters a filter, returning it if it was registered.
+func (r *filterRegistry) remove(filterId string) (Filter, bool) {
+	r.Lock()
+	defer r.Unlock()
+	filter, ok := r.filters[filterId]
+	delete(r.filters, filterId)
+	return filter, ok
+}
+
+func (r *filterRegistry) get(filterId string) (Filter, bool) {
+	r.RLock()
+	defer r.RUnlock()
+	filter, ok := r.filters[filterId]
+	return filter, ok
+}
+
+func (r *filterRegistry) len() int {
+	r.RLock()
+	defer r.RUnlock()
+	return len(r.filters)
+}
+
+// list returns a snapshot of the registered filters.
+func (r *filterRegistry) list() []Filter {
+	r.RLock()
+	defer r.RUnlock()
+	filters := make([]Filter, 0, len(r.filters))
+	for _, filter := range r.filters {
+		filters = append(filters, filter)
+	}
+	return filters
+}
+
+// matching returns the filters matching a tagged data payload.
+func (r *filterRegistry) matching(taggedData iotago.TaggedData) []Filter {
+	r.RLock()
+	defer r.RUnlock()
+	var filters []Filter
+	for _, filter := range r.filters {
+		if filter.matches(taggedData) {
+			filters = append(filters, filter)
+		}
+	}
+	return filters
+}
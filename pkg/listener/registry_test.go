@@ -0,0 +1,69 @@
+package listener
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	iotago "github.com/iotaledger/iota.go/v3"
+)
+
+// TestRegistryConcurrentAccess adds, matches and removes filters from several goroutines, meant to be run with -race.
+func TestRegistryConcurrentAccess(t *testing.T) {
+	const (
+		goroutines = 8
+		rounds     = 200
+	)
+	r := newFilterRegistry()
+	// a filter staying registered throughout, every match must find it
+	if err := r.add(Filter{Id: "always", Tag: "shared"}); err != nil {
+		t.Fatal(err)
+	}
+	taggedData := iotago.TaggedData{Tag: []byte("shared")}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, goroutines*2)
+	for g := 0; g < goroutines; g++ {
+		wg.Add(2)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				filterId := fmt.Sprintf("filter-%d-%d", g, i)
+				if err := r.add(Filter{Id: filterId, Tag: "shared"}); err != nil {
+					errs <- err
+					return
+				}
+				if _, ok := r.get(filterId); !ok {
+					errs <- fmt.Errorf("filter '%s' not found after it was added", filterId)
+					return
+				}
+				if _, ok := r.remove(filterId); !ok {
+					errs <- fmt.Errorf("filter '%s' not removed", filterId)
+					return
+				}
+			}
+		}(g)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				if len(r.matching(taggedData)) == 0 {
+					errs <- fmt.Errorf("filter 'always' not matched")
+					return
+				}
+				_ = r.list()
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	if n := r.len(); n != 1 {
+		t.Errorf("got %d filters left, expected 1", n)
+	}
+	if err := r.add(Filter{Id: "always", Tag: "other"}); err == nil {
+		t.Error("a filter id taken was added again")
+	}
+}
@@ -0,0 +1,249 @@
+package listener
+
+import (
+	"collector/pkg/storage"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxRetryBackoff caps the delay between two attempts of the same upload.
+const maxRetryBackoff = 10 * time.Minute
+
+// pendingUpload is a block whose upload failed, waiting to be retried.
+type pendingUpload struct {
+	BlockId     string         `json:"blockId"`
+	BucketName  string         `json:"bucketName"`
+	FilterId    string         `json:"filterId"`
+	Object      storage.Object `json:"object"`
+	Alias       string         `json:"alias,omitempty"`
+	IndexTags   []string       `json:"indexTags,omitempty"`
+	Attempts    int            `json:"attempts"`
+	NextAttempt time.Time      `json:"nextAttempt"`
+}
+
+// fileName identifies the upload in the spill directory, an object can be pending for several buckets.
+func (u *pendingUpload) fileName() string {
+	return fmt.Sprintf("%s_%s.json", u.BucketName, u.BlockId)
+}
+
+// retryQueue holds the failed uploads, in memory and, if a spill directory is set, on disk
+// so that they survive a restart.
+type retryQueue struct {
+	sync.Mutex
+	pending     map[string]*pendingUpload
+	maxSize     int
+	maxAttempts int
+	interval    time.Duration
+	directory   string
+	metrics     *Metrics
+}
+
+func newRetryQueue(params Parameters, metrics *Metrics) (*retryQueue, error) {
+	q := &retryQueue{
+		pending:     make(map[string]*pendingUpload),
+		maxSize:     params.RetryQueueSize,
+		maxAttempts: params.RetryMaxAttempts,
+		interval:    params.RetryInterval,
+		directory:   params.RetrySpillDirectory,
+		metrics:     metrics,
+	}
+	if q.directory == "" {
+		return q, nil
+	}
+
+	err := os.MkdirAll(q.directory, 0o700)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(q.directory)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(q.directory, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var upload pendingUpload
+		err = json.Unmarshal(content, &upload)
+		if err != nil {
+			return nil, fmt.Errorf("can't read spilled upload '%s', error: %w", entry.Name(), err)
+		}
+		q.pending[upload.fileName()] = &upload
+	}
+	q.metrics.setRetryQueueDepth(len(q.pending))
+	return q, nil
+}
+
+func (q *retryQueue) enabled() bool {
+	return q.maxSize > 0 && q.maxAttempts > 0
+}
+
+// enqueue schedules the retry of a failed upload, returning false if the queue is full or disabled.
+func (q *retryQueue) enqueue(blockId string, bucketName string, filterId string, object storage.Object) (bool, error) {
+	if !q.enabled() {
+		return false, nil
+	}
+
+	q.Lock()
+	defer q.Unlock()
+	if len(q.pending) >= q.maxSize {
+		return false, nil
+	}
+
+	upload := &pendingUpload{
+		BlockId:     blockId,
+		BucketName:  bucketName,
+		FilterId:    filterId,
+		Object:      object,
+		Alias:       object.Alias,
+		IndexTags:   object.IndexTags,
+		NextAttempt: time.Now().Add(q.interval),
+	}
+	err := q.persist(upload)
+	if err != nil {
+		return false, err
+	}
+	q.pending[upload.fileName()] = upload
+	q.metrics.setRetryQueueDepth(len(q.pending))
+	return true, nil
+}
+
+// due returns the uploads whose next attempt is scheduled before now.
+func (q *retryQueue) due(now time.Time) []*pendingUpload {
+	q.Lock()
+	defer q.Unlock()
+	var uploads []*pendingUpload
+	for _, upload := range q.pending {
+		if !upload.NextAttempt.After(now) {
+			uploads = append(uploads, upload)
+		}
+	}
+	return uploads
+}
+
+// failed records a failed attempt, returning true if the upload has been dropped.
+func (q *retryQueue) failed(upload *pendingUpload) (bool, error) {
+	q.Lock()
+	defer q.Unlock()
+	upload.Attempts++
+	if upload.Attempts >= q.maxAttempts {
+		q.metrics.incUploadsDropped()
+		return true, q.remove(upload)
+	}
+
+	backoff := q.interval << upload.Attempts
+	if backoff > maxRetryBackoff || backoff <= 0 {
+		backoff = maxRetryBackoff
+	}
+	upload.NextAttempt = time.Now().Add(backoff)
+	return false, q.persist(upload)
+}
+
+func (q *retryQueue) succeeded(upload *pendingUpload) error {
+	q.Lock()
+	defer q.Unlock()
+	return q.remove(upload)
+}
+
+// len returns the number of pending uploads.
+func (q *retryQueue) len() int {
+	q.Lock()
+	defer q.Unlock()
+	return len(q.pending)
+}
+
+// remove drops an upload from the queue, the lock must be held.
+func (q *retryQueue) remove(upload *pendingUpload) error {
+	delete(q.pending, upload.fileName())
+	q.metrics.setRetryQueueDepth(len(q.pending))
+	if q.directory == "" {
+		return nil
+	}
+	err := os.Remove(filepath.Join(q.directory, upload.fileName()))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// persist writes an upload to the spill directory, if any.
+func (q *retryQueue) persist(upload *pendingUpload) error {
+	if q.directory == "" {
+		return nil
+	}
+	content, err := json.Marshal(upload)
+	if err != nil {
+		return err
+	}
+	// write then rename, so that a crash never leaves a truncated file behind
+	path := filepath.Join(q.directory, upload.fileName())
+	err = os.WriteFile(path+".tmp", content, 0o600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// RetryUploads retries the failed uploads until ctx is done.
+func (l *Listener) RetryUploads(ctx context.Context) {
+	if !l.retries.enabled() {
+		return
+	}
+	ctx = storage.ContextWithOrigin(ctx, storage.OriginRetry)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if pending := l.retries.len(); pending > 0 && l.retries.directory == "" {
+				l.WrappedLogger.LogWarnf("%d failed uploads were not retried and are lost", pending)
+			}
+			return
+		case <-ticker.C:
+		}
+
+		for _, upload := range l.retries.due(time.Now()) {
+			// the alias is not part of the persisted object
+			object := upload.Object
+			object.Alias = upload.Alias
+			object.IndexTags = upload.IndexTags
+			err := l.Storage.UploadObject(upload.BlockId, upload.BucketName, object, ctx)
+			if err == nil {
+				l.WrappedLogger.LogInfof("Retrying upload of block '%s' to bucket '%s' ... done", upload.BlockId, upload.BucketName)
+				tag := ""
+				if filter, ok := l.filters.get(upload.FilterId); ok {
+					l.filterStats.stored(filter)
+					tag = filter.Tag
+				}
+				l.status.blockStored(upload.BlockId)
+				l.notifyStored(upload.BlockId, upload.BucketName, tag)
+				err = l.retries.succeeded(upload)
+				if err != nil {
+					l.WrappedLogger.LogWarnf("Can't remove the spilled upload of block '%s', error: %w", upload.BlockId, err)
+				}
+				continue
+			}
+
+			dropped, spillErr := l.retries.failed(upload)
+			if dropped {
+				l.WrappedLogger.LogErrorf("Retrying upload of block '%s' to bucket '%s' ... failed %d times, dropping it, error: %w", upload.BlockId, upload.BucketName, upload.Attempts, err)
+			} else {
+				l.sampledLog.LogWarnf("Retrying upload of block '%s' to bucket '%s' ... failed, error: %w", upload.BlockId, upload.BucketName, err)
+			}
+			if spillErr != nil {
+				l.WrappedLogger.LogWarnf("Can't update the spilled upload of block '%s', error: %w", upload.BlockId, spillErr)
+			}
+		}
+	}
+}
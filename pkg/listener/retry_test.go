@@ -0,0 +1,125 @@
+package listener
+
+import (
+	"collector/pkg/storage"
+	"context"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// outageBackend fails the uploads while failures are left, a negative count failing them all.
+type outageBackend struct {
+	*storage.MemoryBackend
+	sync.Mutex
+	failures int
+}
+
+func (b *outageBackend) PutObject(ctx context.Context, bucketName string, objectName string, reader io.Reader, objectSize int64, opts minio.PutObjectOptions) (minio.UploadInfo, error) {
+	b.Lock()
+	failing := b.failures != 0
+	if b.failures > 0 {
+		b.failures--
+	}
+	b.Unlock()
+	if failing {
+		return minio.UploadInfo{}, errors.New("storage unavailable")
+	}
+	return b.MemoryBackend.PutObject(ctx, bucketName, objectName, reader, objectSize, opts)
+}
+
+func retryParams(directory string, maxAttempts int) func(params *Parameters) {
+	return func(params *Parameters) {
+		params.RetrySpillDirectory = directory
+		params.RetryMaxAttempts = maxAttempts
+		params.RetryInterval = 10 * time.Millisecond
+	}
+}
+
+// waitFor polls condition until it holds, failing the test after a few retry ticks.
+func waitFor(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestRetryAfterOutage(t *testing.T) {
+	directory := t.TempDir()
+	backend := &outageBackend{MemoryBackend: storage.NewMemoryBackend()}
+	l := newTestListenerWithBackend(t, backend, retryParams(directory, 5))
+	addTaggedDataFilter(t, l, "outage")
+
+	// the first upload and the first retry fail
+	backend.failures = 2
+	block := referenced(1, "outage", "retried", time.Now(), l)
+	blockId := hex.EncodeToString(block.blockId.GetId())
+	l.storeBlock(block, context.Background())
+
+	spilled := filepath.Join(directory, l.Storage.DefaultBucketName+"_"+blockId+".json")
+	if _, err := os.Stat(spilled); err != nil {
+		t.Fatalf("the failed upload was not spilled: %v", err)
+	}
+	if depth := testutil.ToFloat64(l.metrics.retryQueueDepth); depth != 1 {
+		t.Fatalf("got a retry queue depth of %v, expected 1", depth)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go l.RetryUploads(ctx)
+	waitFor(t, "the retried block to be stored", func() bool {
+		_, err := storedData(l, blockId)
+		return err == nil
+	})
+
+	if data, _ := storedData(l, blockId); data != "retried" {
+		t.Errorf("got data '%s' for the retried block", data)
+	}
+	waitFor(t, "the retry queue to empty", func() bool {
+		return testutil.ToFloat64(l.metrics.retryQueueDepth) == 0
+	})
+	if _, err := os.Stat(spilled); !os.IsNotExist(err) {
+		t.Errorf("got error %v for the spilled upload once stored, expected it removed", err)
+	}
+	if dropped := testutil.ToFloat64(l.metrics.uploadsDropped); dropped != 0 {
+		t.Errorf("got %v uploads dropped, expected none", dropped)
+	}
+}
+
+func TestRetryDropped(t *testing.T) {
+	directory := t.TempDir()
+	backend := &outageBackend{MemoryBackend: storage.NewMemoryBackend(), failures: -1}
+	l := newTestListenerWithBackend(t, backend, retryParams(directory, 1))
+	addTaggedDataFilter(t, l, "outage")
+
+	block := referenced(1, "outage", "dropped", time.Now(), l)
+	l.storeBlock(block, context.Background())
+	if depth := testutil.ToFloat64(l.metrics.retryQueueDepth); depth != 1 {
+		t.Fatalf("got a retry queue depth of %v, expected 1", depth)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go l.RetryUploads(ctx)
+	waitFor(t, "the upload to be dropped", func() bool {
+		return testutil.ToFloat64(l.metrics.uploadsDropped) == 1
+	})
+	if depth := testutil.ToFloat64(l.metrics.retryQueueDepth); depth != 0 {
+		t.Errorf("got a retry queue depth of %v once dropped, expected 0", depth)
+	}
+	if entries, err := os.ReadDir(directory); err != nil || len(entries) != 0 {
+		t.Errorf("got spilled uploads %v, error %v once dropped, expected none", entries, err)
+	}
+}
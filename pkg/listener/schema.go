@@ -0,0 +1,299 @@
+package listener
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"unicode/utf8"
+)
+
+// payloadSchema is a compiled JSON Schema a payload must conform to. Only the validation keywords of
+// the common subset below are supported, a schema using another one is refused rather than half applied.
+type payloadSchema struct {
+	types                []string
+	properties           map[string]*payloadSchema
+	required             []string
+	additionalProperties *bool
+	items                *payloadSchema
+	enum                 []any
+	minimum              *float64
+	maximum              *float64
+	minLength            *int
+	maxLength            *int
+	minItems             *int
+	maxItems             *int
+	pattern              *regexp.Regexp
+}
+
+// annotationKeywords are accepted and ignored, they don't constrain the payload.
+var annotationKeywords = map[string]bool{"$schema": true, "$id": true, "$comment": true, "title": true, "description": true, "examples": true, "default": true}
+
+var schemaTypes = map[string]bool{"object": true, "array": true, "string": true, "number": true, "integer": true, "boolean": true, "null": true}
+
+// compileSchema compiles a JSON Schema document.
+func compileSchema(document json.RawMessage) (*payloadSchema, error) {
+	var value any
+	err := json.Unmarshal(document, &value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema, error: %w", err)
+	}
+	return compileSchemaValue(value, "$")
+}
+
+func compileSchemaValue(value any, path string) (*payloadSchema, error) {
+	keywords, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid schema at '%s': expected an object", path)
+	}
+
+	s := &payloadSchema{}
+	// sorted, for the reported error not to depend on the map order
+	names := make([]string, 0, len(keywords))
+	for name := range keywords {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var err error
+	for _, name := range names {
+		keyword := keywords[name]
+		switch name {
+		case "type":
+			s.types, err = compileTypes(keyword)
+		case "properties":
+			properties, ok := keyword.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("invalid schema at '%s': properties must be an object", path)
+			}
+			s.properties = make(map[string]*payloadSchema, len(properties))
+			for property, propertySchema := range properties {
+				s.properties[property], err = compileSchemaValue(propertySchema, path+"."+property)
+				if err != nil {
+					return nil, err
+				}
+			}
+		case "required":
+			s.required, err = compileStrings(keyword)
+		case "additionalProperties":
+			additional, ok := keyword.(bool)
+			if !ok {
+				err = fmt.Errorf("only boolean values are supported")
+			}
+			s.additionalProperties = &additional
+		case "items":
+			s.items, err = compileSchemaValue(keyword, path+"[]")
+			if err != nil {
+				return nil, err
+			}
+		case "enum":
+			enum, ok := keyword.([]any)
+			if !ok || len(enum) == 0 {
+				err = fmt.Errorf("expected a non empty array")
+			}
+			s.enum = enum
+		case "const":
+			s.enum = []any{keyword}
+		case "minimum":
+			s.minimum, err = compileNumber(keyword)
+		case "maximum":
+			s.maximum, err = compileNumber(keyword)
+		case "minLength":
+			s.minLength, err = compileCount(keyword)
+		case "maxLength":
+			s.maxLength, err = compileCount(keyword)
+		case "minItems":
+			s.minItems, err = compileCount(keyword)
+		case "maxItems":
+			s.maxItems, err = compileCount(keyword)
+		case "pattern":
+			pattern, ok := keyword.(string)
+			if !ok {
+				err = fmt.Errorf("expected a string")
+				break
+			}
+			s.pattern, err = regexp.Compile(pattern)
+		default:
+			if !annotationKeywords[name] {
+				return nil, fmt.Errorf("invalid schema at '%s': keyword '%s' is not supported", path, name)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid schema at '%s': invalid %s, error: %w", path, name, err)
+		}
+	}
+	return s, nil
+}
+
+func compileTypes(keyword any) ([]string, error) {
+	var types []string
+	switch v := keyword.(type) {
+	case string:
+		types = []string{v}
+	case []any:
+		var err error
+		types, err = compileStrings(v)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("expected a string or an array of strings")
+	}
+	for _, t := range types {
+		if !schemaTypes[t] {
+			return nil, fmt.Errorf("unknown type '%s'", t)
+		}
+	}
+	return types, nil
+}
+
+func compileStrings(keyword any) ([]string, error) {
+	values, ok := keyword.([]any)
+	if !ok {
+		return nil, fmt.Errorf("expected an array of strings")
+	}
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected an array of strings")
+		}
+		result = append(result, s)
+	}
+	return result, nil
+}
+
+func compileNumber(keyword any) (*float64, error) {
+	number, ok := keyword.(float64)
+	if !ok {
+		return nil, fmt.Errorf("expected a number")
+	}
+	return &number, nil
+}
+
+func compileCount(keyword any) (*int, error) {
+	number, ok := keyword.(float64)
+	if !ok || number < 0 || number != math.Trunc(number) {
+		return nil, fmt.Errorf("expected a non negative integer")
+	}
+	count := int(number)
+	return &count, nil
+}
+
+// validate checks that a JSON payload conforms to the schema.
+func (s *payloadSchema) validate(payload []byte) error {
+	var value any
+	err := json.Unmarshal(payload, &value)
+	if err != nil {
+		return fmt.Errorf("payload is not JSON, error: %w", err)
+	}
+	return s.validateValue(value, "$")
+}
+
+func (s *payloadSchema) validateValue(value any, path string) error {
+	if len(s.types) > 0 && !hasSchemaType(value, s.types) {
+		return fmt.Errorf("'%s' is not of type %v", path, s.types)
+	}
+	if s.enum != nil {
+		found := false
+		for _, allowed := range s.enum {
+			if reflect.DeepEqual(value, allowed) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("'%s' is not one of the allowed values", path)
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		for _, property := range s.required {
+			if _, ok := v[property]; !ok {
+				return fmt.Errorf("'%s' misses the required property '%s'", path, property)
+			}
+		}
+		for property, propertyValue := range v {
+			propertySchema, ok := s.properties[property]
+			if !ok {
+				if s.additionalProperties != nil && !*s.additionalProperties {
+					return fmt.Errorf("'%s' has the unexpected property '%s'", path, property)
+				}
+				continue
+			}
+			err := propertySchema.validateValue(propertyValue, path+"."+property)
+			if err != nil {
+				return err
+			}
+		}
+	case []any:
+		if s.minItems != nil && len(v) < *s.minItems {
+			return fmt.Errorf("'%s' has less than %d items", path, *s.minItems)
+		}
+		if s.maxItems != nil && len(v) > *s.maxItems {
+			return fmt.Errorf("'%s' has more than %d items", path, *s.maxItems)
+		}
+		if s.items != nil {
+			for i, item := range v {
+				err := s.items.validateValue(item, fmt.Sprintf("%s[%d]", path, i))
+				if err != nil {
+					return err
+				}
+			}
+		}
+	case string:
+		length := utf8.RuneCountInString(v)
+		if s.minLength != nil && length < *s.minLength {
+			return fmt.Errorf("'%s' is shorter than %d characters", path, *s.minLength)
+		}
+		if s.maxLength != nil && length > *s.maxLength {
+			return fmt.Errorf("'%s' is longer than %d characters", path, *s.maxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			return fmt.Errorf("'%s' doesn't match the pattern '%s'", path, s.pattern)
+		}
+	case float64:
+		if s.minimum != nil && v < *s.minimum {
+			return fmt.Errorf("'%s' is less than %v", path, *s.minimum)
+		}
+		if s.maximum != nil && v > *s.maximum {
+			return fmt.Errorf("'%s' is greater than %v", path, *s.maximum)
+		}
+	}
+	return nil
+}
+
+func hasSchemaType(value any, types []string) bool {
+	for _, t := range types {
+		switch v := value.(type) {
+		case map[string]any:
+			if t == "object" {
+				return true
+			}
+		case []any:
+			if t == "array" {
+				return true
+			}
+		case string:
+			if t == "string" {
+				return true
+			}
+		case float64:
+			if t == "number" || (t == "integer" && v == math.Trunc(v)) {
+				return true
+			}
+		case bool:
+			if t == "boolean" {
+				return true
+			}
+		case nil:
+			if t == "null" {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package listener
+
+import (
+	"collector/pkg/storage"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+const testSchema = `{
+	"type": "object",
+	"required": ["id", "kind"],
+	"additionalProperties": false,
+	"properties": {
+		"id": {"type": "integer", "minimum": 1},
+		"kind": {"enum": ["reading", "alert"]},
+		"label": {"type": "string", "maxLength": 5, "pattern": "^[a-z]+$"},
+		"values": {"type": "array", "minItems": 1, "items": {"type": "number"}}
+	}
+}`
+
+func TestSchema(t *testing.T) {
+	schema, err := compileSchema(json.RawMessage(testSchema))
+	if err != nil {
+		t.Fatalf("can't compile the schema: %v", err)
+	}
+	for _, tc := range []struct {
+		payload    string
+		conforming bool
+	}{
+		{`{"id": 1, "kind": "reading"}`, true},
+		{`{"id": 2, "kind": "alert", "label": "abc", "values": [1, 2.5]}`, true},
+		{`{"id": 1}`, false},
+		{`{"id": 0, "kind": "reading"}`, false},
+		{`{"id": 1.5, "kind": "reading"}`, false},
+		{`{"id": 1, "kind": "other"}`, false},
+		{`{"id": 1, "kind": "reading", "label": "toolong"}`, false},
+		{`{"id": 1, "kind": "reading", "label": "ABC"}`, false},
+		{`{"id": 1, "kind": "reading", "values": []}`, false},
+		{`{"id": 1, "kind": "reading", "values": ["1"]}`, false},
+		{`{"id": 1, "kind": "reading", "extra": true}`, false},
+		{`not json`, false},
+	} {
+		if err := schema.validate([]byte(tc.payload)); (err == nil) != tc.conforming {
+			t.Errorf("payload %s: got error %v, expected conforming: %t", tc.payload, err, tc.conforming)
+		}
+	}
+
+	for _, invalid := range []string{
+		`{"type": "unknown"}`,
+		`{"oneOf": [{"type": "string"}]}`,
+		`{"pattern": "("}`,
+		`[]`,
+	} {
+		if _, err := compileSchema(json.RawMessage(invalid)); err == nil {
+			t.Errorf("schema %s was compiled, expected an error", invalid)
+		}
+	}
+}
+
+func TestSchemaFilter(t *testing.T) {
+	l := newTestListener(t, nil)
+	filter, err := NewFilter("schema", false, "", l.Storage.DefaultBucketName, "", false, StoreFormatTaggedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	filter.Schema = json.RawMessage(testSchema)
+	if _, err := l.AddFilter(filter); err != nil {
+		t.Fatalf("can't add the filter: %v", err)
+	}
+
+	conforming := referenced(1, "schema", `{"id": 1, "kind": "reading"}`, time.Now(), l)
+	malformed := referenced(2, "schema", `{"id": "1"}`, time.Now(), l)
+	l.storeBlock(conforming, context.Background())
+	l.storeBlock(malformed, context.Background())
+
+	if _, err := storedData(l, hex.EncodeToString(conforming.blockId.GetId())); err != nil {
+		t.Errorf("the conforming block was not stored: %v", err)
+	}
+	if _, err := storedData(l, hex.EncodeToString(malformed.blockId.GetId())); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("got error %v for the malformed block, expected ErrNotFound", err)
+	}
+	filters := l.ListFilters()
+	if len(filters) != 1 || filters[0].Stats.BlocksRejected != 1 {
+		t.Errorf("got filters %+v, expected 1 block rejected", filters)
+	}
+
+	invalid, _ := NewFilter("invalid", false, "", l.Storage.DefaultBucketName, "", false, StoreFormatTaggedData)
+	invalid.Schema = json.RawMessage(`{"type": "unknown"}`)
+	if _, err := l.AddFilter(invalid); err == nil {
+		t.Error("a filter with an invalid schema was added")
+	}
+}
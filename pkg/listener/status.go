@@ -0,0 +1,86 @@
+package listener
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// ListenerStatus tells whether the listener is alive and receiving blocks.
+type ListenerStatus struct {
+	Connected       bool       `json:"connected"`
+	Node            string     `json:"node"`
+	ActiveFilters   int        `json:"activeFilters"`
+	LastBlockId     string     `json:"lastBlockId,omitempty"`
+	LastBlockAt     *time.Time `json:"lastBlockAt,omitempty"`
+	LastStoredId    string     `json:"lastStoredId,omitempty"`
+	LastStoredAt    *time.Time `json:"lastStoredAt,omitempty"`
+	RetryQueueDepth int        `json:"retryQueueDepth"`
+}
+
+// blockEvent is the last block seen at some point of the processing path.
+type blockEvent struct {
+	id string
+	at time.Time
+}
+
+// SessionCounters counts the blocks handled by the listener since it started, with the depths of its queues.
+type SessionCounters struct {
+	BlocksProcessed   uint64 `json:"blocksProcessed"`
+	BlocksStored      uint64 `json:"blocksStored"`
+	BlocksFailed      uint64 `json:"blocksFailed"`
+	RetryQueueDepth   int    `json:"retryQueueDepth"`
+	WebhookQueueDepth int    `json:"webhookQueueDepth"`
+}
+
+// status is updated on the processing path without locking.
+type status struct {
+	connected      atomic.Bool
+	processed      atomic.Pointer[blockEvent]
+	stored         atomic.Pointer[blockEvent]
+	processedCount atomic.Uint64
+	storedCount    atomic.Uint64
+	failedCount    atomic.Uint64
+}
+
+func (s *status) blockProcessed(blockId string) {
+	s.processed.Store(&blockEvent{id: blockId, at: time.Now()})
+	s.processedCount.Add(1)
+}
+
+func (s *status) blockStored(blockId string) {
+	s.stored.Store(&blockEvent{id: blockId, at: time.Now()})
+	s.storedCount.Add(1)
+}
+
+// SessionCounters returns the counters of the session, for the summary logged on shutdown.
+func (l *Listener) SessionCounters() SessionCounters {
+	counters := SessionCounters{
+		BlocksProcessed: l.status.processedCount.Load(),
+		BlocksStored:    l.status.storedCount.Load(),
+		BlocksFailed:    l.status.failedCount.Load(),
+		RetryQueueDepth: l.retries.len(),
+	}
+	if l.webhooks != nil {
+		counters.WebhookQueueDepth = len(l.webhooks.queue)
+	}
+	return counters
+}
+
+// Status returns the current state of the listener, a stale LastBlockAt reveals an ingestion stall.
+func (l *Listener) Status() ListenerStatus {
+	status := ListenerStatus{
+		Connected:       l.status.connected.Load(),
+		Node:            l.bridges.activeNode().address,
+		ActiveFilters:   l.filters.len(),
+		RetryQueueDepth: l.retries.len(),
+	}
+	if processed := l.status.processed.Load(); processed != nil {
+		status.LastBlockId = processed.id
+		status.LastBlockAt = &processed.at
+	}
+	if stored := l.status.stored.Load(); stored != nil {
+		status.LastStoredId = stored.id
+		status.LastStoredAt = &stored.at
+	}
+	return status
+}
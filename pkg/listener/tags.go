@@ -0,0 +1,65 @@
+package listener
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrTagNotAllowed is returned for the subscriptions to tags excluded by the tag allowlist or denylist.
+var ErrTagNotAllowed = errors.New("tag not allowed")
+
+// tagLists restricts the tags clients can subscribe to. A pattern ending with '*' matches every tag starting
+// with the preceding prefix.
+type tagLists struct {
+	allowed []string
+	denied  []string
+}
+
+// SetTagLists replaces the comma separated lists of the tags clients may and may not subscribe to, the filters
+// already subscribed are kept.
+func (l *Listener) SetTagLists(allowlist string, denylist string) {
+	l.tagLists.Store(&tagLists{allowed: parseTagPatterns(allowlist), denied: parseTagPatterns(denylist)})
+}
+
+func parseTagPatterns(list string) []string {
+	var patterns []string
+	for _, pattern := range strings.Split(list, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns
+}
+
+func matchesTagPattern(tag string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if strings.HasSuffix(pattern, "*") && strings.HasPrefix(tag, strings.TrimSuffix(pattern, "*")) {
+			return true
+		}
+		if pattern == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckSubscribable fails with ErrTagNotAllowed if clients may not subscribe to a tag: a denied tag is refused
+// even if it is allowed, and with an allowlist only the tags it matches are accepted. While tags are restricted
+// clients can't subscribe to every block either. Startup filters are not restricted.
+func (l *Listener) CheckSubscribable(tag string, matchAll bool) error {
+	lists := l.tagLists.Load()
+	restricted := len(lists.allowed) > 0 || len(lists.denied) > 0
+	switch {
+	case matchAll && restricted:
+		return fmt.Errorf("%w: filters matching every block are not allowed while tags are restricted", ErrTagNotAllowed)
+	case matchAll:
+		return nil
+	case matchesTagPattern(tag, lists.denied):
+		return fmt.Errorf("%w: tag '%s' is denied", ErrTagNotAllowed, tag)
+	case len(lists.allowed) > 0 && !matchesTagPattern(tag, lists.allowed):
+		return fmt.Errorf("%w: tag '%s' is not in the allowlist", ErrTagNotAllowed, tag)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package listener
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckSubscribable(t *testing.T) {
+	for _, tc := range []struct {
+		allowlist string
+		denylist  string
+		tag       string
+		matchAll  bool
+		allowed   bool
+	}{
+		{"", "", "any", false, true},
+		{"", "", "", true, true},
+		{"sensor, app*", "", "sensor", false, true},
+		{"sensor, app*", "", "app-orders", false, true},
+		{"sensor, app*", "", "sensors", false, false},
+		{"sensor, app*", "", "other", false, false},
+		{"", "system*", "system-heartbeat", false, false},
+		{"", "system*", "sensor", false, true},
+		{"app*", "app-internal", "app-internal", false, false},
+		{"app*", "app-internal", "app-public", false, true},
+		{"", "system*", "", true, false},
+		{"app*", "", "", true, false},
+	} {
+		l := newTestListener(t, func(params *Parameters) {
+			params.TagAllowlist = tc.allowlist
+			params.TagDenylist = tc.denylist
+		})
+		err := l.CheckSubscribable(tc.tag, tc.matchAll)
+		if tc.allowed && err != nil {
+			t.Errorf("allowlist '%s', denylist '%s': got error %v for tag '%s', expected it allowed", tc.allowlist, tc.denylist, err, tc.tag)
+		}
+		if !tc.allowed && !errors.Is(err, ErrTagNotAllowed) {
+			t.Errorf("allowlist '%s', denylist '%s': got error %v for tag '%s', expected ErrTagNotAllowed", tc.allowlist, tc.denylist, err, tc.tag)
+		}
+	}
+
+	// the lists can be replaced while running
+	l := newTestListener(t, nil)
+	l.SetTagLists("", "blocked")
+	if err := l.CheckSubscribable("blocked", false); !errors.Is(err, ErrTagNotAllowed) {
+		t.Errorf("got error %v for a tag denied while running, expected ErrTagNotAllowed", err)
+	}
+}
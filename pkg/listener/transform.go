@@ -0,0 +1,119 @@
+package listener
+
+import (
+	"bytes"
+	"collector/pkg/storage"
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	// TransformNone stores the payload as is.
+	TransformNone = "none"
+	// TransformJSONMinify removes the insignificant whitespace of a JSON payload.
+	TransformJSONMinify = "json-minify"
+	// TransformFieldRedact replaces the values of the filter's RedactFields, at any depth of a JSON payload.
+	TransformFieldRedact = "field-redact"
+
+	// TransformFailureStoreOriginal stores the original payload when a transform fails.
+	TransformFailureStoreOriginal = "store-original"
+	// TransformFailureDrop discards the block when a transform fails.
+	TransformFailureDrop = "drop"
+
+	redactedValue = "[REDACTED]"
+)
+
+func (f *Filter) validateTransform() error {
+	switch f.Transform {
+	case "", TransformNone:
+		return nil
+	case TransformJSONMinify, TransformFieldRedact:
+		// a transformed block would no longer match its id nor its proof
+		if f.StoreFormat != StoreFormatTaggedData && f.StoreFormat != StoreFormatSignedDataPlaintext {
+			return fmt.Errorf("transform '%s' needs a payload only store format", f.Transform)
+		}
+		if f.Transform == TransformFieldRedact && len(f.RedactFields) == 0 {
+			return fmt.Errorf("transform '%s' needs the fields to redact", f.Transform)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown transform '%s'", f.Transform)
+	}
+}
+
+// transform applies the filter's transform to a payload.
+func (f *Filter) transform(payload []byte) ([]byte, error) {
+	switch f.Transform {
+	case TransformJSONMinify:
+		var minified bytes.Buffer
+		err := json.Compact(&minified, payload)
+		if err != nil {
+			return nil, err
+		}
+		return minified.Bytes(), nil
+	case TransformFieldRedact:
+		decoder := json.NewDecoder(bytes.NewReader(payload))
+		// keeps the numbers as they are written
+		decoder.UseNumber()
+		var document any
+		err := decoder.Decode(&document)
+		if err != nil {
+			return nil, err
+		}
+		fields := make(map[string]bool, len(f.RedactFields))
+		for _, field := range f.RedactFields {
+			fields[field] = true
+		}
+		return json.Marshal(redact(document, fields))
+	default:
+		return payload, nil
+	}
+}
+
+func redact(value any, fields map[string]bool) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, field := range v {
+			if fields[key] {
+				v[key] = redactedValue
+				continue
+			}
+			v[key] = redact(field, fields)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = redact(item, fields)
+		}
+	}
+	return value
+}
+
+// transformObject applies the filter's transform to the payload of object, returning false if the block
+// must be discarded.
+func (l *Listener) transformObject(filter Filter, object *storage.Object, blockId string) bool {
+	if filter.Transform == "" || filter.Transform == TransformNone {
+		return true
+	}
+
+	var payload *[]byte
+	switch {
+	case object.Data != nil:
+		payload = &object.Data
+	case object.TaggedData != nil:
+		payload = &object.TaggedData.Data
+	default:
+		return true
+	}
+
+	transformed, err := filter.transform(*payload)
+	if err != nil {
+		if l.transformFailurePolicy == TransformFailureDrop {
+			l.WrappedLogger.LogWarnf("Transform '%s' failed on block '%s', discarding it, error: %w", filter.Transform, blockId, err)
+			return false
+		}
+		l.WrappedLogger.LogWarnf("Transform '%s' failed on block '%s', storing the original payload, error: %w", filter.Transform, blockId, err)
+		return true
+	}
+	*payload = transformed
+	return true
+}
@@ -0,0 +1,87 @@
+package listener
+
+import (
+	"collector/pkg/storage"
+	"context"
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTransform(t *testing.T) {
+	for _, tc := range []struct {
+		filter   Filter
+		payload  string
+		expected string
+		valid    bool
+	}{
+		{Filter{Transform: TransformNone}, `{ "a": 1 }`, `{ "a": 1 }`, true},
+		{Filter{Transform: TransformJSONMinify}, "{ \"a\": [1, 2],\n \"b\": \"c d\" }", `{"a":[1,2],"b":"c d"}`, true},
+		{Filter{Transform: TransformJSONMinify}, `not json`, "", false},
+		{Filter{Transform: TransformFieldRedact, RedactFields: []string{"secret"}},
+			`{"secret": "s", "items": [{"secret": {"x": 1}, "kept": 12345678901234567890}]}`,
+			`{"items":[{"kept":12345678901234567890,"secret":"[REDACTED]"}],"secret":"[REDACTED]"}`, true},
+		{Filter{Transform: TransformFieldRedact, RedactFields: []string{"secret"}}, `[1,`, "", false},
+	} {
+		transformed, err := tc.filter.transform([]byte(tc.payload))
+		if (err == nil) != tc.valid {
+			t.Errorf("transform '%s' of '%s': got error %v, expected valid %v", tc.filter.Transform, tc.payload, err, tc.valid)
+			continue
+		}
+		if tc.valid && string(transformed) != tc.expected {
+			t.Errorf("transform '%s' of '%s': got '%s', expected '%s'", tc.filter.Transform, tc.payload, transformed, tc.expected)
+		}
+	}
+}
+
+func TestValidateTransform(t *testing.T) {
+	for _, tc := range []struct {
+		filter Filter
+		valid  bool
+	}{
+		{Filter{}, true},
+		{Filter{Transform: TransformJSONMinify, StoreFormat: StoreFormatTaggedData}, true},
+		{Filter{Transform: TransformFieldRedact, StoreFormat: StoreFormatSignedDataPlaintext, RedactFields: []string{"a"}}, true},
+		// the block would no longer match its id
+		{Filter{Transform: TransformJSONMinify, StoreFormat: StoreFormatFullBlock}, false},
+		{Filter{Transform: TransformFieldRedact, StoreFormat: StoreFormatTaggedData}, false},
+		{Filter{Transform: "uppercase", StoreFormat: StoreFormatTaggedData}, false},
+	} {
+		if err := tc.filter.validateTransform(); (err == nil) != tc.valid {
+			t.Errorf("filter %+v: got error %v, expected valid %v", tc.filter, err, tc.valid)
+		}
+	}
+}
+
+func TestTransformFailurePolicy(t *testing.T) {
+	for _, policy := range []string{TransformFailureStoreOriginal, TransformFailureDrop} {
+		l := newTestListener(t, func(params *Parameters) {
+			params.TransformFailurePolicy = policy
+		})
+		filter, err := NewFilter("transform", false, "", l.Storage.DefaultBucketName, "", false, StoreFormatTaggedData)
+		if err != nil {
+			t.Fatal(err)
+		}
+		filter.Transform = TransformJSONMinify
+		if _, err := l.AddFilter(filter); err != nil {
+			t.Fatalf("can't add the filter: %v", err)
+		}
+
+		minified := referenced(1, "transform", `{ "a": 1 }`, time.Now(), l)
+		invalid := referenced(2, "transform", `not json`, time.Now(), l)
+		l.storeBlock(minified, context.Background())
+		l.storeBlock(invalid, context.Background())
+
+		if data, err := storedData(l, hex.EncodeToString(minified.blockId.GetId())); err != nil || data != `{"a":1}` {
+			t.Errorf("policy '%s': got data '%s', error %v, expected the minified payload", policy, data, err)
+		}
+		data, err := storedData(l, hex.EncodeToString(invalid.blockId.GetId()))
+		if policy == TransformFailureDrop && !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("policy '%s': got data '%s', error %v, expected the block dropped", policy, data, err)
+		}
+		if policy == TransformFailureStoreOriginal && data != "not json" {
+			t.Errorf("policy '%s': got data '%s', error %v, expected the original payload", policy, data, err)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package listener
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// HeaderWebhookSignature carries the hex encoded HMAC-SHA256 of a notification body, keyed by the webhook secret.
+	HeaderWebhookSignature = "X-Collector-Signature"
+
+	// webhookTimeout bounds a single delivery attempt.
+	webhookTimeout = 10 * time.Second
+)
+
+// BlockStoredNotification is the body posted to the webhooks when a block is stored.
+type BlockStoredNotification struct {
+	BlockId   string    `json:"blockId"`
+	Bucket    string    `json:"bucket"`
+	Tag       string    `json:"tag"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// webhookDelivery is a notification to post to a webhook.
+type webhookDelivery struct {
+	url      string
+	body     []byte
+	attempts int
+}
+
+// webhooks posts the notifications from a queue, so that the listener never waits for a receiver.
+// Failed deliveries are queued again after a doubling delay, until maxAttempts.
+type webhooks struct {
+	urls        []string
+	secret      []byte
+	maxAttempts int
+	interval    time.Duration
+	queue       chan webhookDelivery
+	client      *http.Client
+}
+
+func newWebhooks(params Parameters) (*webhooks, error) {
+	if params.WebhookURLs == "" {
+		return nil, nil
+	}
+	w := &webhooks{
+		secret:      []byte(params.WebhookSecret),
+		maxAttempts: params.WebhookMaxAttempts,
+		interval:    params.WebhookRetryInterval,
+		queue:       make(chan webhookDelivery, params.WebhookQueueSize),
+		client:      &http.Client{Timeout: webhookTimeout},
+	}
+	for _, u := range strings.Split(params.WebhookURLs, ",") {
+		u = strings.TrimSpace(u)
+		parsed, err := url.Parse(u)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+			return nil, fmt.Errorf("invalid webhook url '%s'", u)
+		}
+		w.urls = append(w.urls, u)
+	}
+	if w.maxAttempts < 1 || w.interval <= 0 || params.WebhookQueueSize < 1 {
+		return nil, fmt.Errorf("webhook attempts, retry interval and queue size must be positive")
+	}
+	return w, nil
+}
+
+// sign returns the signature of a notification body.
+func (w *webhooks) sign(body []byte) string {
+	mac := hmac.New(sha256.New, w.secret)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// enqueue queues a delivery without blocking, returning false if the queue is full.
+func (w *webhooks) enqueue(delivery webhookDelivery) bool {
+	select {
+	case w.queue <- delivery:
+		return true
+	default:
+		return false
+	}
+}
+
+func (w *webhooks) post(delivery webhookDelivery, ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.url, bytes.NewReader(delivery.body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// a signature keyed by an empty secret can be forged by anyone, it would only mislead the receiver
+	if len(w.secret) > 0 {
+		req.Header.Set(HeaderWebhookSignature, w.sign(delivery.body))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// notifyStored queues the notification of a stored block for every webhook.
+func (l *Listener) notifyStored(blockId string, bucketName string, tag string) {
+	if l.webhooks == nil {
+		return
+	}
+	body, err := json.Marshal(BlockStoredNotification{BlockId: blockId, Bucket: bucketName, Tag: tag, Timestamp: time.Now().UTC()})
+	if err != nil {
+		l.sampledLog.LogErrorf("Can't encode the notification of block '%s', error: %w", blockId, err)
+		return
+	}
+	for _, u := range l.webhooks.urls {
+		if !l.webhooks.enqueue(webhookDelivery{url: u, body: body}) {
+			l.sampledLog.LogWarnf("Webhook queue full, dropping the notification of block '%s' to '%s'", blockId, u)
+		}
+	}
+}
+
+// RunWebhooks delivers the queued notifications until ctx is done.
+func (l *Listener) RunWebhooks(ctx context.Context) {
+	if l.webhooks == nil {
+		return
+	}
+	for {
+		var delivery webhookDelivery
+		select {
+		case <-ctx.Done():
+			if pending := len(l.webhooks.queue); pending > 0 {
+				l.WrappedLogger.LogWarnf("%d webhook notifications were not delivered and are lost", pending)
+			}
+			return
+		case delivery = <-l.webhooks.queue:
+		}
+
+		err := l.webhooks.post(delivery, ctx)
+		if err == nil {
+			continue
+		}
+		delivery.attempts++
+		if delivery.attempts >= l.webhooks.maxAttempts {
+			l.WrappedLogger.LogErrorf("Notifying webhook '%s' ... failed %d times, dropping the notification, error: %w", delivery.url, delivery.attempts, err)
+			continue
+		}
+		l.sampledLog.LogWarnf("Notifying webhook '%s' ... failed, retrying, error: %w", delivery.url, err)
+		backoff := l.webhooks.interval << (delivery.attempts - 1)
+		if backoff <= 0 || backoff > maxRetryBackoff {
+			backoff = maxRetryBackoff
+		}
+		time.AfterFunc(backoff, func() {
+			if !l.webhooks.enqueue(delivery) {
+				l.sampledLog.LogWarnf("Webhook queue full, dropping a notification to '%s'", delivery.url)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package listener
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// webhookReceiver records the notifications it accepts, failing the first requests while failures are left.
+type webhookReceiver struct {
+	sync.Mutex
+	failures      int
+	requests      int
+	notifications []BlockStoredNotification
+	signatures    []string
+	bodies        [][]byte
+}
+
+func (r *webhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.Lock()
+	defer r.Unlock()
+	r.requests++
+	if r.failures > 0 {
+		r.failures--
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	var notification BlockStoredNotification
+	if err := json.Unmarshal(body, &notification); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	r.notifications = append(r.notifications, notification)
+	r.signatures = append(r.signatures, req.Header.Get(HeaderWebhookSignature))
+	r.bodies = append(r.bodies, body)
+}
+
+func (r *webhookReceiver) received() int {
+	r.Lock()
+	defer r.Unlock()
+	return len(r.notifications)
+}
+
+func webhookParams(url string, secret string) func(params *Parameters) {
+	return func(params *Parameters) {
+		params.WebhookURLs = url
+		params.WebhookSecret = secret
+		params.WebhookMaxAttempts = 5
+		params.WebhookRetryInterval = 10 * time.Millisecond
+	}
+}
+
+func TestWebhookSigned(t *testing.T) {
+	const secret = "webhook-secret"
+	receiver := &webhookReceiver{}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+	l := newTestListener(t, webhookParams(server.URL, secret))
+	addTaggedDataFilter(t, l, "hook")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go l.RunWebhooks(ctx)
+
+	block := referenced(1, "hook", "data", time.Now(), l)
+	l.storeBlock(block, context.Background())
+	waitFor(t, "the notification", func() bool { return receiver.received() == 1 })
+
+	receiver.Lock()
+	defer receiver.Unlock()
+	notification := receiver.notifications[0]
+	blockId := hex.EncodeToString(block.blockId.GetId())
+	if notification.BlockId != blockId || notification.Bucket != l.Storage.DefaultBucketName || notification.Tag != "hook" {
+		t.Errorf("got notification %+v, expected block '%s' of tag 'hook' in the default bucket", notification, blockId)
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(receiver.bodies[0])
+	if expected := hex.EncodeToString(mac.Sum(nil)); receiver.signatures[0] != expected {
+		t.Errorf("got signature '%s', expected '%s'", receiver.signatures[0], expected)
+	}
+}
+
+func TestWebhookWithoutSecret(t *testing.T) {
+	receiver := &webhookReceiver{}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+	l := newTestListener(t, webhookParams(server.URL, ""))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go l.RunWebhooks(ctx)
+
+	l.notifyStored("block", l.Storage.DefaultBucketName, "hook")
+	waitFor(t, "the notification", func() bool { return receiver.received() == 1 })
+
+	receiver.Lock()
+	defer receiver.Unlock()
+	if receiver.signatures[0] != "" {
+		t.Errorf("got signature '%s' without a secret, expected none", receiver.signatures[0])
+	}
+}
+
+func TestWebhookRecovers(t *testing.T) {
+	receiver := &webhookReceiver{failures: 3}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+	l := newTestListener(t, webhookParams(server.URL, "webhook-secret"))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go l.RunWebhooks(ctx)
+
+	l.notifyStored("block", l.Storage.DefaultBucketName, "hook")
+	waitFor(t, "the notification", func() bool { return receiver.received() == 1 })
+
+	// no further delivery once one succeeded
+	time.Sleep(100 * time.Millisecond)
+	receiver.Lock()
+	defer receiver.Unlock()
+	if receiver.requests != 4 || len(receiver.notifications) != 1 {
+		t.Errorf("got %d requests and %d notifications, expected 4 and 1", receiver.requests, len(receiver.notifications))
+	}
+}
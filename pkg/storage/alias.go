@@ -0,0 +1,127 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/minio/minio-go/v7"
+)
+
+const (
+	// aliasesPrefix namespaces the alias index, an object per alias naming the block it resolves to.
+	aliasesPrefix = "aliases/"
+	// MetadataAlias is set on the objects stored with an alias, for the alias to be removed along with them.
+	MetadataAlias = "Alias"
+
+	maxAliasLength = 256
+)
+
+// Alias is an entry of the alias index, mapping an external key to a stored block.
+type Alias struct {
+	Alias   string `json:"alias"`
+	BlockId string `json:"blockId"`
+}
+
+// ValidateAlias checks that an alias can be indexed.
+func ValidateAlias(alias string) error {
+	if alias == "" || len(alias) > maxAliasLength {
+		return fmt.Errorf("invalid alias '%s': expected 1 to %d characters", alias, maxAliasLength)
+	}
+	return nil
+}
+
+// aliasObjectName escapes the alias, for it to name a single object whatever its characters.
+func aliasObjectName(alias string) string {
+	return aliasesPrefix + url.PathEscape(alias)
+}
+
+// indexAlias makes an alias resolve to a block. The first block claiming an alias keeps it as long as it is
+// stored: a later block with the same alias is stored without taking it over, which is logged. Concurrent
+// updates are only serialized within this process.
+func (s *Storage) indexAlias(bucketName string, alias string, blockId string, ctx context.Context) error {
+	s.aliasesLock.Lock()
+	defer s.aliasesLock.Unlock()
+
+	current, err := s.getAlias(bucketName, alias, ctx)
+	switch {
+	case err == nil && current.BlockId == blockId:
+		return nil
+	case err == nil:
+		_, err = s.GetObjectInfo(bucketName, current.BlockId, "", ctx)
+		if err == nil {
+			s.WrappedLogger.LogWarnf("Alias '%s' of block '%s' already resolves to block '%s' in bucket '%s', keeping it", alias, blockId, current.BlockId, bucketName)
+			return nil
+		}
+		// the block holding the alias has expired or been deleted, the alias is free again
+		if !errors.Is(err, ErrNotFound) {
+			return err
+		}
+	case !errors.Is(err, ErrNotFound):
+		return err
+	}
+
+	data, err := json.Marshal(Alias{Alias: alias, BlockId: blockId})
+	if err != nil {
+		return err
+	}
+	_, err = s.client.PutObject(ctx, bucketName, s.objectKey(aliasObjectName(alias)), bytes.NewReader(data), int64(len(data)), minio.PutObjectOptions{ContentType: ContentTypeJSON})
+	if err != nil {
+		s.WrappedLogger.LogErrorf("Indexing alias '%s' of block '%s' in bucket '%s' ... failed, error: %w", alias, blockId, bucketName, err)
+		return translateError(err)
+	}
+	return nil
+}
+
+func (s *Storage) getAlias(bucketName string, alias string, ctx context.Context) (Alias, error) {
+	var entry Alias
+	object, err := s.client.GetObject(ctx, bucketName, s.objectKey(aliasObjectName(alias)), minio.GetObjectOptions{})
+	if err != nil {
+		return entry, translateError(err)
+	}
+	defer object.Close()
+
+	err = json.NewDecoder(object).Decode(&entry)
+	return entry, err
+}
+
+// ResolveAlias returns the block an alias resolves to, ErrNotFound if the alias is unknown or its block is no
+// longer stored.
+func (s *Storage) ResolveAlias(bucketName string, alias string, ctx context.Context) (string, error) {
+	entry, err := s.getAlias(bucketName, alias, ctx)
+	if err != nil {
+		return "", err
+	}
+	_, err = s.GetObjectInfo(bucketName, entry.BlockId, "", ctx)
+	if err != nil {
+		return "", err
+	}
+	return entry.BlockId, nil
+}
+
+// removeAlias drops an alias from the index, unless it has been taken over by another block meanwhile.
+func (s *Storage) removeAlias(bucketName string, alias string, blockId string, ctx context.Context) error {
+	s.aliasesLock.Lock()
+	defer s.aliasesLock.Unlock()
+
+	current, err := s.getAlias(bucketName, alias, ctx)
+	if errors.Is(err, ErrNotFound) || (err == nil && current.BlockId != blockId) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return translateError(s.client.RemoveObject(ctx, bucketName, s.objectKey(aliasObjectName(alias)), minio.RemoveObjectOptions{}))
+}
+
+// aliasOf returns the alias an object was stored with, read from its metadata.
+func aliasOf(info minio.ObjectInfo) string {
+	alias, err := url.QueryUnescape(info.UserMetadata[MetadataAlias])
+	if err != nil {
+		return ""
+	}
+	return alias
+}
@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestAlias(t *testing.T) {
+	s, _ := newTestStorage(t, nil)
+	ctx := context.Background()
+	upload := func(name string, alias string) {
+		t.Helper()
+		object := taggedDataObject("alias", name)
+		object.Alias = alias
+		if err := s.UploadObject(name, s.DefaultBucketName, object, ctx); err != nil {
+			t.Fatalf("can't upload object '%s': %v", name, err)
+		}
+	}
+	resolve := func(alias string, expected string) {
+		t.Helper()
+		blockId, err := s.ResolveAlias(s.DefaultBucketName, alias, ctx)
+		if expected == "" {
+			if !errors.Is(err, ErrNotFound) {
+				t.Errorf("got block '%s', error %v resolving alias '%s', expected ErrNotFound", blockId, err, alias)
+			}
+			return
+		}
+		if err != nil || blockId != expected {
+			t.Errorf("got block '%s', error %v resolving alias '%s', expected '%s'", blockId, err, alias, expected)
+		}
+	}
+
+	upload("first", "order/1")
+	resolve("order/1", "first")
+	resolve("unknown", "")
+
+	// the first block keeps the alias while it is stored
+	upload("second", "order/1")
+	resolve("order/1", "first")
+
+	// deleting the block removes its alias, which is free again
+	if err := s.DeleteObject(s.DefaultBucketName, "first", "", ctx); err != nil {
+		t.Fatalf("can't delete object 'first': %v", err)
+	}
+	resolve("order/1", "")
+	upload("third", "order/1")
+	resolve("order/1", "third")
+
+	// deleting a block that didn't get the alias leaves it
+	if err := s.DeleteObject(s.DefaultBucketName, "second", "", ctx); err != nil {
+		t.Fatalf("can't delete object 'second': %v", err)
+	}
+	resolve("order/1", "third")
+
+	if err := ValidateAlias(""); err == nil {
+		t.Error("got an empty alias validated, expected an error")
+	}
+}
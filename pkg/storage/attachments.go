@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// attachmentsPrefix namespaces the attachment manifests, stored by the hash of the payload they group.
+const attachmentsPrefix = "attachments/"
+
+// Attachments is the manifest of the blocks carrying the same payload, any of them is a valid attachment.
+type Attachments struct {
+	PayloadHash string   `json:"payloadHash"`
+	BlockIds    []string `json:"blockIds"`
+}
+
+// AddAttachment records a block in the manifest of its payload, a block already recorded is ignored.
+// Concurrent updates are only serialized within this process.
+func (s *Storage) AddAttachment(bucketName string, payloadHash string, blockId string, ctx context.Context) error {
+	s.attachmentsLock.Lock()
+	defer s.attachmentsLock.Unlock()
+
+	attachments, err := s.GetAttachments(bucketName, payloadHash, ctx)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	for _, id := range attachments.BlockIds {
+		if id == blockId {
+			return nil
+		}
+	}
+	attachments.PayloadHash = payloadHash
+	attachments.BlockIds = append(attachments.BlockIds, blockId)
+
+	data, err := json.Marshal(attachments)
+	if err != nil {
+		return err
+	}
+	_, err = s.client.PutObject(ctx, bucketName, s.objectKey(attachmentsPrefix+payloadHash), bytes.NewReader(data), int64(len(data)), minio.PutObjectOptions{ContentType: ContentTypeJSON})
+	if err != nil {
+		s.WrappedLogger.LogErrorf("Recording attachment '%s' of payload '%s' in bucket '%s' ... failed, error: %w", blockId, payloadHash, bucketName, err)
+		return translateError(err)
+	}
+	return nil
+}
+
+// GetAttachments returns the manifest of the blocks carrying a payload, ErrNotFound if none was recorded.
+func (s *Storage) GetAttachments(bucketName string, payloadHash string, ctx context.Context) (Attachments, error) {
+	var attachments Attachments
+	object, err := s.client.GetObject(ctx, bucketName, s.objectKey(attachmentsPrefix+payloadHash), minio.GetObjectOptions{})
+	if err != nil {
+		return attachments, translateError(err)
+	}
+	defer object.Close()
+
+	err = json.NewDecoder(object).Decode(&attachments)
+	return attachments, err
+}
@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/lifecycle"
+)
+
+// Backend is the object storage Storage persists to. Its methods mirror the subset of the minio client
+// Storage uses, so that *minio.Client based and other (in-memory, filesystem, ...) implementations are
+// interchangeable. Missing objects and buckets must be reported as minio.ErrorResponse with the
+// NoSuchKey/NoSuchBucket codes.
+type Backend interface {
+	EndpointURL() *url.URL
+
+	ListBuckets(ctx context.Context) ([]minio.BucketInfo, error)
+	BucketExists(ctx context.Context, bucketName string) (bool, error)
+	MakeBucket(ctx context.Context, bucketName string, opts minio.MakeBucketOptions) error
+	EnableVersioning(ctx context.Context, bucketName string) error
+	SetBucketLifecycle(ctx context.Context, bucketName string, config *lifecycle.Configuration) error
+	GetBucketLifecycle(ctx context.Context, bucketName string) (*lifecycle.Configuration, error)
+	SetBucketPolicy(ctx context.Context, bucketName string, policy string) error
+	GetBucketPolicy(ctx context.Context, bucketName string) (string, error)
+	GetObjectLockConfig(ctx context.Context, bucketName string) (objectLock string, mode *minio.RetentionMode, validity *uint, unit *minio.ValidityUnit, err error)
+
+	PutObject(ctx context.Context, bucketName string, objectName string, reader io.Reader, objectSize int64, opts minio.PutObjectOptions) (minio.UploadInfo, error)
+	GetObject(ctx context.Context, bucketName string, objectName string, opts minio.GetObjectOptions) (*ObjectReader, error)
+	StatObject(ctx context.Context, bucketName string, objectName string, opts minio.StatObjectOptions) (minio.ObjectInfo, error)
+	CopyObject(ctx context.Context, dst minio.CopyDestOptions, src minio.CopySrcOptions) (minio.UploadInfo, error)
+	RemoveObject(ctx context.Context, bucketName string, objectName string, opts minio.RemoveObjectOptions) error
+	RemoveObjects(ctx context.Context, bucketName string, objectsCh <-chan minio.ObjectInfo, opts minio.RemoveObjectsOptions) <-chan minio.RemoveObjectError
+	ListObjects(ctx context.Context, bucketName string, opts minio.ListObjectsOptions) <-chan minio.ObjectInfo
+}
+
+// ObjectReader is an object retrieved from the storage, along with its stat.
+type ObjectReader struct {
+	io.ReadCloser
+	Info minio.ObjectInfo
+}
+
+// Decode reads the whole object, according to the encoding of its content type.
+func (o *ObjectReader) Decode() (Object, error) {
+	return DecodeObject(o, o.Info.ContentType)
+}
+
+func (o *ObjectReader) Stat() (minio.ObjectInfo, error) {
+	return o.Info, nil
+}
+
+// minioBackend is the Backend of an S3 compatible storage.
+type minioBackend struct {
+	*minio.Client
+}
+
+func newMinioBackend(params Parameters) (*minioBackend, error) {
+	creds, err := newCredentials(params)
+	if err != nil {
+		return nil, err
+	}
+
+	transport, err := newTransport(params)
+	if err != nil {
+		return nil, err
+	}
+
+	if params.MaxRetries < 1 {
+		return nil, fmt.Errorf("max retries must be at least 1, got %d", params.MaxRetries)
+	}
+	// the retry count is global to the minio package
+	minio.MaxRetry = params.MaxRetries
+
+	// Initialize minio client object.
+	client, err := minio.New(params.Endpoint, &minio.Options{
+		Creds:     creds,
+		Secure:    params.Secure,
+		Transport: transport,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &minioBackend{Client: client}, nil
+}
+
+// GetObject stats the object right away, minio would otherwise only contact the storage on first read.
+func (b *minioBackend) GetObject(ctx context.Context, bucketName string, objectName string, opts minio.GetObjectOptions) (*ObjectReader, error) {
+	object, err := b.Client.GetObject(ctx, bucketName, objectName, opts)
+	if err != nil {
+		return nil, err
+	}
+	info, err := object.Stat()
+	if err != nil {
+		object.Close()
+		return nil, err
+	}
+	return &ObjectReader{ReadCloser: object, Info: info}, nil
+}
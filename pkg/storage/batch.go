@@ -0,0 +1,593 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+const (
+	// batchesPrefix namespaces the archives of batched objects.
+	batchesPrefix = "batches/"
+	// batchIndexPrefix namespaces the index of the batched objects, sharded by the first character of their name.
+	// A batch writes one index segment per shard it touches, named after the batch.
+	batchIndexPrefix = "batch-index/"
+
+	ContentTypeBatch = "application/gzip"
+
+	// maxPendingBatchFactor bounds the bytes buffered for a bucket whose batches can't be written, in max batch
+	// sizes, further objects are uploaded on their own.
+	maxPendingBatchFactor = 4
+)
+
+// BatchEntry locates an object in the archive of its batch.
+type BatchEntry struct {
+	Offset      int64  `json:"offset"`
+	Length      int64  `json:"length"`
+	Size        int64  `json:"size"`
+	ContentType string `json:"contentType"`
+	// UserMetadata is the metadata the object would have been stored with on its own
+	UserMetadata map[string]string `json:"userMetadata,omitempty"`
+	// Deleted marks a tombstone, the object was deleted after being batched and remains in the archive until it expires
+	Deleted bool `json:"deleted,omitempty"`
+}
+
+// metadata returns the user metadata of a batched object, never nil.
+func (e BatchEntry) metadata() map[string]string {
+	if e.UserMetadata == nil {
+		return map[string]string{}
+	}
+	return e.UserMetadata
+}
+
+// batchIndexSegment is the part of a batch's index falling in one shard.
+type batchIndexSegment struct {
+	Batch   string                `json:"batch"`
+	Entries map[string]BatchEntry `json:"entries"`
+}
+
+// pendingBatch accumulates the objects of a bucket until they are written as one archive. Every object is
+// compressed as a gzip member of its own, so that it can be extracted without decompressing the others.
+type pendingBatch struct {
+	archive bytes.Buffer
+	entries map[string]BatchEntry
+	started time.Time
+}
+
+// batcher groups small objects into compressed archives, per bucket.
+type batcher struct {
+	sync.Mutex
+	maxCount      int
+	maxBytes      int
+	maxAge        time.Duration
+	maxObjectSize int
+	pending       map[string]*pendingBatch
+	closed        bool
+}
+
+func newBatcher(params Parameters) *batcher {
+	if params.BatchMaxCount < 1 {
+		return nil
+	}
+	return &batcher{
+		maxCount:      params.BatchMaxCount,
+		maxBytes:      params.BatchMaxBytes,
+		maxAge:        params.BatchMaxAge,
+		maxObjectSize: params.BatchMaxObjectSize,
+		pending:       make(map[string]*pendingBatch),
+	}
+}
+
+func validateBatchParams(params Parameters) error {
+	if params.BatchMaxCount < 1 {
+		return nil
+	}
+	if params.BatchMaxBytes < 1 || params.BatchMaxAge <= 0 || params.BatchMaxObjectSize < 1 {
+		return fmt.Errorf("the batch max bytes, max age and max object size must be positive")
+	}
+	return nil
+}
+
+func batchIndexShard(objectName string) string {
+	if objectName == "" {
+		return "_"
+	}
+	return strings.ToLower(objectName[:1])
+}
+
+// AddToBatch buffers a small object in the pending batch of its bucket and returns true, or returns false if
+// batching is disabled or the object must be uploaded on its own: because it is too large, or it is stored with
+// a proof, an alias, index tags or a retention. The batch is written once it holds BatchMaxCount objects or BatchMaxBytes,
+// or once it is BatchMaxAge old; until then the object is only kept in memory.
+func (s *Storage) AddToBatch(objectName string, bucketName string, object Object, ctx context.Context) bool {
+	if s.batches == nil || object.Proof != nil || object.Alias != "" || len(object.IndexTags) > 0 {
+		return false
+	}
+	// the upload reports the quota
+	if s.checkQuota(bucketName, ctx) != nil {
+		return false
+	}
+	if retention := s.DefaultRetention(); retention.Days > 0 || retention.LegalHold {
+		return false
+	}
+	s.describePayload(&object)
+	objectReader, contentType, err := object.Encode(s.storeEncoding)
+	if err != nil || objectReader.Size() > int64(s.batches.maxObjectSize) {
+		return false
+	}
+
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	_, err = io.Copy(writer, objectReader)
+	if err == nil {
+		err = writer.Close()
+	}
+	if err != nil {
+		return false
+	}
+
+	s.batches.Lock()
+	if s.batches.closed {
+		s.batches.Unlock()
+		return false
+	}
+	batch, ok := s.batches.pending[bucketName]
+	if !ok {
+		batch = &pendingBatch{entries: make(map[string]BatchEntry), started: time.Now()}
+		s.batches.pending[bucketName] = batch
+	}
+	if batch.archive.Len() >= maxPendingBatchFactor*s.batches.maxBytes {
+		s.batches.Unlock()
+		return false
+	}
+	batch.entries[objectName] = BatchEntry{
+		Offset:       int64(batch.archive.Len()),
+		Length:       int64(compressed.Len()),
+		Size:         objectReader.Size(),
+		ContentType:  contentType,
+		UserMetadata: object.userMetadata(),
+	}
+	batch.archive.Write(compressed.Bytes())
+	full := len(batch.entries) >= s.batches.maxCount || batch.archive.Len() >= s.batches.maxBytes
+	s.batches.Unlock()
+
+	if full {
+		s.flushBatch(bucketName, ctx)
+	}
+	return true
+}
+
+// RunBatches writes the batches reaching BatchMaxAge until ctx is done.
+func (s *Storage) RunBatches(ctx context.Context) {
+	if s.batches == nil {
+		return
+	}
+	interval := s.batches.maxAge / 4
+	if interval < time.Second {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		var expired []string
+		s.batches.Lock()
+		for bucketName, batch := range s.batches.pending {
+			if time.Since(batch.started) >= s.batches.maxAge {
+				expired = append(expired, bucketName)
+			}
+		}
+		s.batches.Unlock()
+		for _, bucketName := range expired {
+			s.flushBatch(bucketName, ctx)
+		}
+	}
+}
+
+// FlushBatches writes every pending batch, after which objects are no longer batched. It is called on shutdown,
+// once no more objects are stored.
+func (s *Storage) FlushBatches(ctx context.Context) {
+	if s.batches == nil {
+		return
+	}
+	s.batches.Lock()
+	s.batches.closed = true
+	bucketNames := make([]string, 0, len(s.batches.pending))
+	for bucketName := range s.batches.pending {
+		bucketNames = append(bucketNames, bucketName)
+	}
+	s.batches.Unlock()
+
+	for _, bucketName := range bucketNames {
+		s.flushBatch(bucketName, ctx)
+	}
+	s.batches.Lock()
+	defer s.batches.Unlock()
+	for bucketName, batch := range s.batches.pending {
+		s.WrappedLogger.LogWarnf("%d batched objects of bucket '%s' were not written and are lost", len(batch.entries), bucketName)
+	}
+}
+
+// PendingBatchedObjects returns how many objects wait in the pending batches, only kept in memory.
+func (s *Storage) PendingBatchedObjects() int {
+	if s.batches == nil {
+		return 0
+	}
+	s.batches.Lock()
+	defer s.batches.Unlock()
+	count := 0
+	for _, batch := range s.batches.pending {
+		count += len(batch.entries)
+	}
+	return count
+}
+
+// flushBatch writes the pending batch of a bucket, its archive first and then its index. A batch that can't be
+// written is put back, to be written with the following objects.
+func (s *Storage) flushBatch(bucketName string, ctx context.Context) {
+	s.batches.Lock()
+	batch, ok := s.batches.pending[bucketName]
+	delete(s.batches.pending, bucketName)
+	s.batches.Unlock()
+	if !ok {
+		return
+	}
+
+	err := s.writeBatch(bucketName, batch, ctx)
+	if err == nil {
+		return
+	}
+	s.batches.Lock()
+	defer s.batches.Unlock()
+	if next, ok := s.batches.pending[bucketName]; ok {
+		// the objects batched meanwhile follow the failed ones
+		offset := int64(batch.archive.Len())
+		for objectName, entry := range next.entries {
+			entry.Offset += offset
+			batch.entries[objectName] = entry
+		}
+		batch.archive.Write(next.archive.Bytes())
+	}
+	s.batches.pending[bucketName] = batch
+}
+
+// newBatchName returns a unique batch name, batch names sort by creation time.
+func newBatchName() string {
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+	return time.Now().UTC().Format("20060102T150405.000000000Z") + "-" + hex.EncodeToString(suffix)
+}
+
+func (s *Storage) writeBatch(bucketName string, batch *pendingBatch, ctx context.Context) error {
+	// the index is read before the batch is written, for the usage to count the objects batched again once
+	_, _ = s.loadedBatchIndex(bucketName, ctx)
+	batchName := newBatchName()
+
+	s.WrappedLogger.LogInfof("Uploading batch '%s' of %d objects to bucket '%s' ...", batchName, len(batch.entries), bucketName)
+	opts := minio.PutObjectOptions{ContentType: ContentTypeBatch, PartSize: s.partSize}
+	start := time.Now()
+	_, err := s.client.PutObject(ctx, bucketName, s.objectKey(batchesPrefix+batchName), bytes.NewReader(batch.archive.Bytes()), int64(batch.archive.Len()), opts)
+	s.metrics.observe(operationUpload, start, err)
+	if err != nil {
+		s.WrappedLogger.LogErrorf("Uploading batch '%s' of %d objects to bucket '%s' ... failed, error: %w", batchName, len(batch.entries), bucketName, err)
+		return err
+	}
+	s.metrics.addBytesUploaded(int64(batch.archive.Len()))
+
+	segments := make(map[string]*batchIndexSegment)
+	for objectName, entry := range batch.entries {
+		shard := batchIndexShard(objectName)
+		segment, ok := segments[shard]
+		if !ok {
+			segment = &batchIndexSegment{Batch: batchName, Entries: make(map[string]BatchEntry)}
+			segments[shard] = segment
+		}
+		segment.Entries[objectName] = entry
+	}
+	for shard, segment := range segments {
+		err = s.putBatchIndexSegment(bucketName, shard, segment, ctx)
+		if err != nil {
+			s.WrappedLogger.LogErrorf("Uploading batch '%s' of %d objects to bucket '%s' ... failed, error: %w", batchName, len(batch.entries), bucketName, err)
+			return err
+		}
+	}
+
+	objects, size := s.indexBatch(bucketName, batchName, batch.entries)
+	s.addUsage(bucketName, objects, size)
+	for objectName := range batch.entries {
+		s.recordEvent(EventStore, objectName, bucketName, ctx)
+	}
+	s.WrappedLogger.LogInfof("Uploading batch '%s' of %d objects to bucket '%s' ... done", batchName, len(batch.entries), bucketName)
+	return nil
+}
+
+// batchIndex keeps the batch index of the buckets in memory, so that the objects missing from a bucket are looked
+// up without listing its index segments. The index of a bucket is read whole the first time an object of the bucket
+// is looked up, and then kept up to date by the batches written. The batches written by another collector to the same
+// bucket are only seen after a restart.
+type batchIndex struct {
+	sync.Mutex
+	buckets map[string]*bucketBatchIndex
+}
+
+type bucketBatchIndex struct {
+	sync.Mutex
+	loaded  bool
+	entries batchLocations
+	// reloading is set while the index is read again, the segments written meanwhile are kept in written to be
+	// applied on top of what was read
+	reloading bool
+	written   []batchIndexSegment
+}
+
+// batchLocation is the batch holding an object, along with the entry locating it in the batch's archive.
+type batchLocation struct {
+	batch string
+	entry BatchEntry
+}
+
+// batchLocations maps the batched objects to their location.
+type batchLocations map[string]batchLocation
+
+func newBatchIndex() *batchIndex {
+	return &batchIndex{buckets: make(map[string]*bucketBatchIndex)}
+}
+
+// bucket returns the index of a bucket, created empty and not loaded.
+func (i *batchIndex) bucket(bucketName string) *bucketBatchIndex {
+	i.Lock()
+	defer i.Unlock()
+	index, ok := i.buckets[bucketName]
+	if !ok {
+		index = &bucketBatchIndex{entries: make(batchLocations)}
+		i.buckets[bucketName] = index
+	}
+	return index
+}
+
+// add records the entries of a batch and returns how the number and size of the batched
+// objects changed. An object keeps the entry of the most recent batch holding it, batch names sorting by creation time.
+func (l batchLocations) add(batchName string, entries map[string]BatchEntry) (int64, int64) {
+	var objects, size int64
+	for objectName, entry := range entries {
+		current, ok := l[objectName]
+		if ok && current.batch > batchName {
+			continue
+		}
+		if ok && !current.entry.Deleted {
+			objects--
+			size -= current.entry.Size
+		}
+		if !entry.Deleted {
+			objects++
+			size += entry.Size
+		}
+		l[objectName] = batchLocation{batch: batchName, entry: entry}
+	}
+	return objects, size
+}
+
+// readBatchIndex reads every index segment of a bucket.
+func (s *Storage) readBatchIndex(bucketName string, ctx context.Context) (batchLocations, error) {
+	listCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	locations := make(batchLocations)
+	for info := range s.client.ListObjects(listCtx, bucketName, minio.ListObjectsOptions{Prefix: s.keyPrefix + batchIndexPrefix, Recursive: true}) {
+		if info.Err != nil {
+			return nil, translateError(info.Err)
+		}
+		segment, err := s.readBatchIndexSegment(bucketName, info.Key, ctx)
+		if err != nil {
+			return nil, err
+		}
+		locations.add(segment.Batch, segment.Entries)
+	}
+	return locations, nil
+}
+
+// loadedBatchIndex returns the index of a bucket, reading its index segments the first time.
+func (s *Storage) loadedBatchIndex(bucketName string, ctx context.Context) (*bucketBatchIndex, error) {
+	index := s.batchIndex.bucket(bucketName)
+	index.Lock()
+	defer index.Unlock()
+	if index.loaded {
+		return index, nil
+	}
+	// not loaded on error, the index is read again next time
+	entries, err := s.readBatchIndex(bucketName, ctx)
+	if err != nil {
+		return nil, err
+	}
+	index.entries, index.loaded = entries, true
+	return index, nil
+}
+
+// reloadBatchIndex reads the index of a bucket again, e.g. to forget the batches expired, and returns a copy of it.
+// The index keeps serving the lookups meanwhile.
+func (s *Storage) reloadBatchIndex(bucketName string, ctx context.Context) (batchLocations, error) {
+	index := s.batchIndex.bucket(bucketName)
+	index.Lock()
+	index.reloading = true
+	index.Unlock()
+
+	entries, err := s.readBatchIndex(bucketName, ctx)
+
+	index.Lock()
+	defer index.Unlock()
+	written := index.written
+	index.reloading, index.written = false, nil
+	if err != nil {
+		return nil, err
+	}
+	copied := make(batchLocations, len(entries))
+	for objectName, location := range entries {
+		copied[objectName] = location
+	}
+	for _, segment := range written {
+		entries.add(segment.Batch, segment.Entries)
+	}
+	index.entries, index.loaded = entries, true
+	return copied, nil
+}
+
+// indexBatch records a batch just written in the index of its bucket, and returns how the number and size of the
+// batched objects changed. If the index wasn't read, every object of the batch is reported as new.
+func (s *Storage) indexBatch(bucketName string, batchName string, entries map[string]BatchEntry) (int64, int64) {
+	index := s.batchIndex.bucket(bucketName)
+	index.Lock()
+	defer index.Unlock()
+	if index.reloading {
+		index.written = append(index.written, batchIndexSegment{Batch: batchName, Entries: entries})
+	}
+	if index.loaded {
+		return index.entries.add(batchName, entries)
+	}
+	return make(batchLocations).add(batchName, entries)
+}
+
+func (s *Storage) putBatchIndexSegment(bucketName string, shard string, segment *batchIndexSegment, ctx context.Context) error {
+	data, err := json.Marshal(segment)
+	if err != nil {
+		return err
+	}
+	_, err = s.client.PutObject(ctx, bucketName, s.objectKey(batchIndexPrefix+shard+"/"+segment.Batch), bytes.NewReader(data), int64(len(data)), minio.PutObjectOptions{ContentType: ContentTypeJSON})
+	return translateError(err)
+}
+
+// deleteBatched deletes a batched object by writing a tombstone to the index of its bucket, the archive holding it
+// is left as is. It does nothing if the object isn't batched.
+func (s *Storage) deleteBatched(bucketName string, objectName string, ctx context.Context) error {
+	if !s.batchesWritten(bucketName, ctx) {
+		return nil
+	}
+	_, _, err := s.findBatched(bucketName, objectName, ctx)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	tombstone := &batchIndexSegment{Batch: newBatchName(), Entries: map[string]BatchEntry{objectName: {Deleted: true}}}
+	err = s.putBatchIndexSegment(bucketName, batchIndexShard(objectName), tombstone, ctx)
+	if err != nil {
+		return err
+	}
+	objects, size := s.indexBatch(bucketName, tombstone.Batch, tombstone.Entries)
+	s.addUsage(bucketName, objects, size)
+	return nil
+}
+
+// findBatched looks an object up in the index of the batches of a bucket, the most recent batch holding it wins.
+func (s *Storage) findBatched(bucketName string, objectName string, ctx context.Context) (string, BatchEntry, error) {
+	index, err := s.loadedBatchIndex(bucketName, ctx)
+	if err != nil {
+		return "", BatchEntry{}, err
+	}
+	index.Lock()
+	location, ok := index.entries[objectName]
+	index.Unlock()
+	if !ok || location.entry.Deleted {
+		return "", BatchEntry{}, ErrNotFound
+	}
+	return location.batch, location.entry, nil
+}
+
+func (s *Storage) readBatchIndexSegment(bucketName string, key string, ctx context.Context) (batchIndexSegment, error) {
+	var segment batchIndexSegment
+	object, err := s.client.GetObject(ctx, bucketName, key, minio.GetObjectOptions{})
+	if err != nil {
+		return segment, translateError(err)
+	}
+	defer object.Close()
+	err = json.NewDecoder(object).Decode(&segment)
+	return segment, err
+}
+
+// getBatched extracts an object from the archive of its batch.
+func (s *Storage) getBatched(bucketName string, objectName string, ctx context.Context) (*ObjectReader, error) {
+	batchName, entry, err := s.findBatched(bucketName, objectName, ctx)
+	if err != nil {
+		return nil, err
+	}
+	archive, err := s.client.GetObject(ctx, bucketName, s.objectKey(batchesPrefix+batchName), minio.GetObjectOptions{})
+	if err != nil {
+		return nil, translateError(err)
+	}
+	defer archive.Close()
+
+	// the archive is at most a few batch sizes, it is read whole rather than by range for every backend to serve it
+	data, err := io.ReadAll(archive)
+	if err != nil {
+		return nil, err
+	}
+	if entry.Offset < 0 || entry.Offset+entry.Length > int64(len(data)) {
+		return nil, fmt.Errorf("object '%s' is out of the bounds of batch '%s'", objectName, batchName)
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(data[entry.Offset : entry.Offset+entry.Length]))
+	if err != nil {
+		return nil, err
+	}
+	decompressed, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	s.metrics.addBytesDownloaded(int64(len(decompressed)))
+
+	info := minio.ObjectInfo{
+		Key:          s.objectKey(objectName),
+		Size:         int64(len(decompressed)),
+		ContentType:  entry.ContentType,
+		LastModified: archive.Info.LastModified,
+		UserMetadata: entry.metadata(),
+	}
+	return &ObjectReader{ReadCloser: io.NopCloser(bytes.NewReader(decompressed)), Info: info}, nil
+}
+
+// getBatchedInfo returns the stat of a batched object.
+func (s *Storage) getBatchedInfo(bucketName string, objectName string, ctx context.Context) (minio.ObjectInfo, error) {
+	batchName, entry, err := s.findBatched(bucketName, objectName, ctx)
+	if err != nil {
+		return minio.ObjectInfo{}, err
+	}
+	archive, err := s.client.StatObject(ctx, bucketName, s.objectKey(batchesPrefix+batchName), minio.StatObjectOptions{})
+	if err != nil {
+		// ErrNotFound once the archive expired, even if its index is still there
+		return minio.ObjectInfo{}, translateError(err)
+	}
+	return minio.ObjectInfo{
+		Key:          s.objectKey(objectName),
+		Size:         entry.Size,
+		ContentType:  entry.ContentType,
+		LastModified: archive.LastModified,
+		UserMetadata: entry.metadata(),
+	}, nil
+}
+
+// batchesWritten tells whether a bucket holds batches, for the objects missing from a bucket without any not to
+// be looked up in its batch index.
+func (s *Storage) batchesWritten(bucketName string, ctx context.Context) bool {
+	index, err := s.loadedBatchIndex(bucketName, ctx)
+	if err != nil {
+		return false
+	}
+	index.Lock()
+	defer index.Unlock()
+	return len(index.entries) > 0
+}
@@ -0,0 +1,230 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// failingBatchBackend fails the uploads of the batch archives while fail is set, calling onFail first.
+type failingBatchBackend struct {
+	*MemoryBackend
+	fail   bool
+	onFail func()
+}
+
+func (b *failingBatchBackend) PutObject(ctx context.Context, bucketName string, objectName string, reader io.Reader, objectSize int64, opts minio.PutObjectOptions) (minio.UploadInfo, error) {
+	if b.fail && strings.HasPrefix(objectName, batchesPrefix) {
+		if b.onFail != nil {
+			b.onFail()
+		}
+		return minio.UploadInfo{}, errors.New("batch upload failed")
+	}
+	return b.MemoryBackend.PutObject(ctx, bucketName, objectName, reader, objectSize, opts)
+}
+
+func batchParams(count int, bytes int, age time.Duration) func(params *Parameters) {
+	return func(params *Parameters) {
+		params.BatchMaxCount = count
+		params.BatchMaxBytes = bytes
+		params.BatchMaxAge = age
+	}
+}
+
+// addToBatch batches the objects named after their data.
+func addToBatch(t *testing.T, s *Storage, data ...string) {
+	t.Helper()
+	for _, d := range data {
+		if !s.AddToBatch(d, s.DefaultBucketName, taggedDataObject("batch", d), context.Background()) {
+			t.Fatalf("object '%s' was not batched", d)
+		}
+	}
+}
+
+func TestBatchFlushOnCount(t *testing.T) {
+	s, _ := newTestStorage(t, batchParams(3, 1<<20, time.Hour))
+	addToBatch(t, &s, "a", "b")
+	if pending := s.PendingBatchedObjects(); pending != 2 {
+		t.Fatalf("got %d pending objects, expected 2", pending)
+	}
+	if _, err := s.GetObject(s.DefaultBucketName, "a", "", context.Background()); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("got error %v for an object of a pending batch, expected ErrNotFound", err)
+	}
+
+	addToBatch(t, &s, "c")
+	if pending := s.PendingBatchedObjects(); pending != 0 {
+		t.Fatalf("got %d pending objects, expected the batch to be written", pending)
+	}
+	for _, name := range []string{"a", "b", "c"} {
+		if data := getData(t, s, s.DefaultBucketName, name); data != name {
+			t.Errorf("got data '%s' for object '%s'", data, name)
+		}
+		info, err := s.GetObjectInfo(s.DefaultBucketName, name, "", context.Background())
+		if err != nil || info.ContentType != ContentTypeJSON {
+			t.Errorf("got info %+v, error %v for object '%s'", info, err, name)
+		}
+	}
+}
+
+func TestBatchFlushOnBytes(t *testing.T) {
+	// every compressed object is a few dozen bytes
+	s, _ := newTestStorage(t, batchParams(100, 100, time.Hour))
+	written := false
+	for i := 0; i < 10 && !written; i++ {
+		addToBatch(t, &s, fmt.Sprintf("object-%d", i))
+		written = s.PendingBatchedObjects() == 0
+	}
+	if !written {
+		t.Fatal("the batch was not written once it reached the max bytes")
+	}
+	if data := getData(t, s, s.DefaultBucketName, "object-0"); data != "object-0" {
+		t.Errorf("got data '%s' for object 'object-0'", data)
+	}
+}
+
+func TestBatchFlushOnAge(t *testing.T) {
+	s, _ := newTestStorage(t, batchParams(100, 1<<20, 100*time.Millisecond))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.RunBatches(ctx)
+
+	addToBatch(t, &s, "aged")
+	// the batches are checked every second at least
+	deadline := time.Now().Add(5 * time.Second)
+	for s.PendingBatchedObjects() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("the batch was not written once it reached the max age")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if data := getData(t, s, s.DefaultBucketName, "aged"); data != "aged" {
+		t.Errorf("got data '%s' for object 'aged'", data)
+	}
+}
+
+// TestBatchFailedFlush batches objects while the write of the previous ones fails, they must follow the failed ones
+// in the archive written next.
+func TestBatchFailedFlush(t *testing.T) {
+	backend := &failingBatchBackend{MemoryBackend: NewMemoryBackend(), fail: true}
+	s := newTestStorageWithBackend(t, backend, batchParams(2, 1<<20, time.Hour))
+	backend.onFail = func() {
+		backend.onFail = nil
+		addToBatch(t, &s, "c")
+	}
+
+	addToBatch(t, &s, "a", "b")
+	if pending := s.PendingBatchedObjects(); pending != 3 {
+		t.Fatalf("got %d pending objects, expected the failed batch merged with the following object", pending)
+	}
+
+	backend.fail = false
+	s.FlushBatches(context.Background())
+	if pending := s.PendingBatchedObjects(); pending != 0 {
+		t.Fatalf("got %d pending objects after the flush", pending)
+	}
+	for _, name := range []string{"a", "b", "c"} {
+		if data := getData(t, s, s.DefaultBucketName, name); data != name {
+			t.Errorf("got data '%s' for object '%s'", data, name)
+		}
+	}
+}
+
+func TestBatchNewestWins(t *testing.T) {
+	s, _ := newTestStorage(t, batchParams(2, 1<<20, time.Hour))
+	ctx := context.Background()
+	for _, version := range []string{"first", "second"} {
+		if !s.AddToBatch("x", s.DefaultBucketName, taggedDataObject("batch", version), ctx) {
+			t.Fatal("object 'x' was not batched")
+		}
+		addToBatch(t, &s, "filler-"+version)
+		// batch names have a nanosecond resolution, they sort by creation time
+		time.Sleep(time.Millisecond)
+	}
+	if pending := s.PendingBatchedObjects(); pending != 0 {
+		t.Fatalf("got %d pending objects, expected both batches written", pending)
+	}
+	if data := getData(t, s, s.DefaultBucketName, "x"); data != "second" {
+		t.Errorf("got data '%s' for object 'x', expected the one of the newest batch", data)
+	}
+}
+
+// countingBackend counts the reads of the objects whose key starts with prefix.
+type countingBackend struct {
+	*MemoryBackend
+	prefix string
+	reads  int
+}
+
+func (b *countingBackend) GetObject(ctx context.Context, bucketName string, objectName string, opts minio.GetObjectOptions) (*ObjectReader, error) {
+	if strings.HasPrefix(objectName, b.prefix) {
+		b.reads++
+	}
+	return b.MemoryBackend.GetObject(ctx, bucketName, objectName, opts)
+}
+
+func TestBatchIndexReadOnce(t *testing.T) {
+	backend := &countingBackend{MemoryBackend: NewMemoryBackend(), prefix: batchIndexPrefix}
+	written := newTestStorageWithBackend(t, backend, batchParams(2, 1<<20, time.Hour))
+	addToBatch(t, &written, "a", "b")
+
+	// a restarted collector reads the index written, once
+	s := newTestStorageWithBackend(t, backend, batchParams(2, 1<<20, time.Hour))
+	backend.reads = 0
+	if _, err := s.GetObject(s.DefaultBucketName, "missing", "", context.Background()); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("got error %v for a missing object, expected ErrNotFound", err)
+	}
+	// one segment per shard
+	if backend.reads != 2 {
+		t.Fatalf("got %d index segment reads loading the index, expected 2", backend.reads)
+	}
+
+	addToBatch(t, &s, "c", "d")
+	for _, name := range []string{"a", "b", "c", "d"} {
+		if data := getData(t, s, s.DefaultBucketName, name); data != name {
+			t.Errorf("got data '%s' for object '%s'", data, name)
+		}
+	}
+	if _, err := s.GetObjectInfo(s.DefaultBucketName, "missing", "", context.Background()); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("got error %v for a missing object, expected ErrNotFound", err)
+	}
+	if backend.reads != 2 {
+		t.Errorf("got %d index segment reads, expected the index to be read once", backend.reads)
+	}
+}
+
+func TestBatchDelete(t *testing.T) {
+	s, _ := newTestStorage(t, batchParams(2, 1<<20, time.Hour))
+	ctx := context.Background()
+	addToBatch(t, &s, "a", "b")
+	if err := s.DeleteObject(s.DefaultBucketName, "a", "", ctx); err != nil {
+		t.Fatalf("can't delete the batched object: %v", err)
+	}
+	if _, err := s.GetObject(s.DefaultBucketName, "a", "", ctx); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got error %v for a deleted batched object, expected ErrNotFound", err)
+	}
+	if _, err := s.GetObjectInfo(s.DefaultBucketName, "a", "", ctx); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got error %v for the info of a deleted batched object, expected ErrNotFound", err)
+	}
+	if data := getData(t, s, s.DefaultBucketName, "b"); data != "b" {
+		t.Errorf("got data '%s' for object 'b' of the same batch", data)
+	}
+
+	// the tombstone is persisted
+	restarted := newTestStorageWithBackend(t, s.client, batchParams(2, 1<<20, time.Hour))
+	if _, err := restarted.GetObject(restarted.DefaultBucketName, "a", "", ctx); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got error %v for a deleted batched object after a restart, expected ErrNotFound", err)
+	}
+
+	// batching the object again brings it back, in a batch named after the tombstone
+	time.Sleep(time.Millisecond)
+	addToBatch(t, &s, "a", "c")
+	if data := getData(t, s, s.DefaultBucketName, "a"); data != "a" {
+		t.Errorf("got data '%s' for object 'a' batched again", data)
+	}
+}
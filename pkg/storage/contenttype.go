@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+
+	iotago "github.com/iotaledger/iota.go/v3"
+)
+
+const (
+	// MetadataPayloadContentType is the content type of the payload an object carries, the object itself has the
+	// content type of its encoding.
+	MetadataPayloadContentType = "Payload-Content-Type"
+
+	// sniffLength is the number of bytes http.DetectContentType considers.
+	sniffLength = 512
+)
+
+// payload returns the data an object carries: the signed data plaintext, else the tagged data payload, stored on
+// its own or within the block.
+func (o *Object) payload() []byte {
+	switch {
+	case o.Data != nil:
+		return o.Data
+	case o.TaggedData != nil:
+		return o.TaggedData.Data
+	case o.Block != nil:
+		if taggedData, ok := o.Block.Payload.(*iotago.TaggedData); ok {
+			return taggedData.Data
+		}
+	}
+	return nil
+}
+
+// DetectPayloadContentType returns the content type of a payload, sniffed from its first bytes unless it is JSON,
+// which can't be told from them.
+func DetectPayloadContentType(payload []byte) string {
+	if json.Valid(payload) {
+		return ContentTypeJSON
+	}
+	if len(payload) > sniffLength {
+		payload = payload[:sniffLength]
+	}
+	return http.DetectContentType(payload)
+}
+
+// NormalizeContentType validates a content type given by a client, returning it in its canonical form.
+func NormalizeContentType(contentType string) (string, error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", fmt.Errorf("invalid content type '%s', error: %w", contentType, err)
+	}
+	// the user metadata travels in headers, FormatMediaType fails on what they can't carry
+	normalized := mime.FormatMediaType(mediaType, params)
+	if normalized == "" {
+		return "", fmt.Errorf("invalid content type '%s'", contentType)
+	}
+	return normalized, nil
+}
+
+// describePayload records the content type of the payload of an object, unless it is already given or the detection
+// is disabled.
+func (s *Storage) describePayload(object *Object) {
+	if object.PayloadContentType != "" || !s.detectContentType {
+		return
+	}
+	if payload := object.payload(); len(payload) > 0 {
+		object.PayloadContentType = DetectPayloadContentType(payload)
+	}
+}
@@ -0,0 +1,121 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+var pngHeader = "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+
+func TestDetectPayloadContentType(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		payload  string
+		expected string
+	}{
+		{"json object", `{"temperature": 21.5}`, ContentTypeJSON},
+		{"json array", `[1, 2, 3]`, ContentTypeJSON},
+		{"png", pngHeader, "image/png"},
+		{"text", "plain text", "text/plain; charset=utf-8"},
+		{"unknown binary", "\x00\x01\x02\x03", "application/octet-stream"},
+		// only the first bytes are sniffed
+		{"long text", strings.Repeat("text ", sniffLength/5+1) + "\x00\x01", "text/plain; charset=utf-8"},
+	} {
+		if got := DetectPayloadContentType([]byte(tc.payload)); got != tc.expected {
+			t.Errorf("%s: got content type '%s', expected '%s'", tc.name, got, tc.expected)
+		}
+	}
+}
+
+func TestNormalizeContentType(t *testing.T) {
+	for _, tc := range []struct {
+		contentType string
+		expected    string
+		valid       bool
+	}{
+		{"image/png", "image/png", true},
+		{"Text/Plain; Charset=UTF-8", "text/plain; charset=UTF-8", true},
+		{"", "", false},
+		{"image/", "", false},
+		{"text/plain; charset", "", false},
+	} {
+		got, err := NormalizeContentType(tc.contentType)
+		if (err == nil) != tc.valid || got != tc.expected {
+			t.Errorf("got '%s' (error %v) for '%s', expected '%s' (valid %t)", got, err, tc.contentType, tc.expected, tc.valid)
+		}
+	}
+}
+
+// payloadContentType returns the content type of the payload recorded with an object, and the object's own.
+func payloadContentType(t *testing.T, s Storage, objectName string) (string, string) {
+	t.Helper()
+	info, err := s.GetObjectInfo(s.DefaultBucketName, objectName, "", context.Background())
+	if err != nil {
+		t.Fatalf("can't stat object '%s': %v", objectName, err)
+	}
+	return info.UserMetadata[MetadataPayloadContentType], info.ContentType
+}
+
+func TestUploadDetectsContentType(t *testing.T) {
+	ctx := context.Background()
+	for _, detect := range []bool{true, false} {
+		s, _ := newTestStorage(t, func(params *Parameters) {
+			params.DetectContentType = detect
+		})
+		forced := taggedDataObject("content", `{"forced": true}`)
+		forced.PayloadContentType = "text/csv"
+		for name, object := range map[string]Object{
+			"json":   taggedDataObject("content", `{"temperature": 21.5}`),
+			"binary": taggedDataObject("content", pngHeader),
+			"block":  {Block: testBlock(pngHeader)},
+			"forced": forced,
+		} {
+			if err := s.UploadObject(name, s.DefaultBucketName, object, ctx); err != nil {
+				t.Fatalf("can't upload object '%s': %v", name, err)
+			}
+		}
+
+		expected := map[string]string{"json": ContentTypeJSON, "binary": "image/png", "block": "image/png", "forced": "text/csv"}
+		if !detect {
+			// a given content type is recorded even without the detection
+			expected = map[string]string{"json": "", "binary": "", "block": "", "forced": "text/csv"}
+		}
+		for name, contentType := range expected {
+			payload, own := payloadContentType(t, s, name)
+			if payload != contentType {
+				t.Errorf("detection %t: got payload content type '%s' for object '%s', expected '%s'", detect, payload, name, contentType)
+			}
+			// the object keeps the content type of its encoding, it is read back by it
+			if own != ContentTypeJSON {
+				t.Errorf("detection %t: got content type '%s' for object '%s', expected '%s'", detect, own, name, ContentTypeJSON)
+			}
+		}
+		if data := getData(t, s, s.DefaultBucketName, "binary"); data != pngHeader {
+			t.Errorf("detection %t: got data %q, expected %q", detect, data, pngHeader)
+		}
+	}
+}
+
+func TestBatchedContentType(t *testing.T) {
+	s, _ := newTestStorage(t, func(params *Parameters) {
+		batchParams(2, 1<<20, time.Hour)(params)
+		params.DetectContentType = true
+	})
+	for i, data := range []string{`{"batched": true}`, pngHeader} {
+		name := fmt.Sprintf("batched-%d", i)
+		if !s.AddToBatch(name, s.DefaultBucketName, taggedDataObject("batch", data), context.Background()) {
+			t.Fatalf("object '%s' was not batched", name)
+		}
+	}
+	if pending := s.PendingBatchedObjects(); pending != 0 {
+		t.Fatalf("got %d pending objects, expected the batch to be written", pending)
+	}
+	for name, expected := range map[string]string{"batched-0": ContentTypeJSON, "batched-1": "image/png"} {
+		if payload, _ := payloadContentType(t, s, name); payload != expected {
+			t.Errorf("got payload content type '%s' for batched object '%s', expected '%s'", payload, name, expected)
+		}
+	}
+}
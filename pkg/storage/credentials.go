@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+const (
+	// CredentialsModeStatic uses the configured access key id and secret access key.
+	CredentialsModeStatic = "static"
+	// CredentialsModeAnonymous accesses the storage without credentials.
+	CredentialsModeAnonymous = "anonymous"
+	// CredentialsModeIAM retrieves credentials from the IAM role of the host (EC2/ECS/EKS).
+	CredentialsModeIAM = "iam"
+	// CredentialsModeSTSWebIdentity exchanges a web identity token for temporary credentials via STS.
+	CredentialsModeSTSWebIdentity = "sts-web-identity"
+	// CredentialsModeEnv reads credentials from the AWS_* or MINIO_* environment variables.
+	CredentialsModeEnv = "env"
+)
+
+func newCredentials(params Parameters) (*credentials.Credentials, error) {
+	switch params.CredentialsMode {
+	case CredentialsModeStatic, "":
+		// empty keys are accepted, e.g. for a storage allowing anonymous access, it rejects the requests requiring them
+		return credentials.NewStaticV4(params.AccessKeyID, params.SecretAccessKey, ""), nil
+
+	case CredentialsModeAnonymous:
+		return credentials.NewStaticV4("", "", ""), nil
+
+	case CredentialsModeIAM:
+		// an empty endpoint lets minio pick the default metadata service
+		return credentials.NewIAM(""), nil
+
+	case CredentialsModeSTSWebIdentity:
+		if params.STSEndpoint == "" || params.WebIdentityTokenFile == "" {
+			return nil, fmt.Errorf("credentials mode '%s' requires both stsEndpoint and webIdentityTokenFile", CredentialsModeSTSWebIdentity)
+		}
+		tokenFile := params.WebIdentityTokenFile
+		return credentials.NewSTSWebIdentity(params.STSEndpoint, func() (*credentials.WebIdentityToken, error) {
+			// the token is read on every refresh as it gets rotated on disk
+			token, err := os.ReadFile(tokenFile)
+			if err != nil {
+				return nil, err
+			}
+			return &credentials.WebIdentityToken{Token: string(token)}, nil
+		})
+
+	case CredentialsModeEnv:
+		return credentials.NewChainCredentials([]credentials.Provider{
+			&credentials.EnvAWS{},
+			&credentials.EnvMinio{},
+		}), nil
+
+	default:
+		return nil, fmt.Errorf("unknown credentials mode '%s'", params.CredentialsMode)
+	}
+}
@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+const (
+	// contentPrefix namespaces the deduplicated payloads, stored by their content hash.
+	contentPrefix = "content/"
+	// metadataContentRef is the user metadata of a pointer object holding the hash of its payload.
+	metadataContentRef = "Content-Ref"
+	// metadataLastReferenced is refreshed every time a payload is referenced again.
+	metadataLastReferenced = "Last-Referenced"
+)
+
+// uploadDeduplicated stores the payload once under its content hash and the object as a pointer to it, and returns
+// the bytes it added to the bucket: the pointer, and the payload unless it was already stored.
+// Every new reference rewrites the payload in place, resetting its age, so that the bucket lifecycle
+// never expires a payload before the last pointer referencing it.
+func (s *Storage) uploadDeduplicated(objectName string, bucketName string, objectReader io.Reader, opts minio.PutObjectOptions, ctx context.Context) (int64, error) {
+	data, err := io.ReadAll(objectReader)
+	if err != nil {
+		return 0, err
+	}
+	var stored int64
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	contentKey := s.objectKey(contentPrefix + hash)
+
+	s.WrappedLogger.LogInfof("Uploading object '%s' to bucket '%s' (content '%s') ...", objectName, bucketName, hash)
+	start := time.Now()
+	_, err = s.client.StatObject(ctx, bucketName, contentKey, minio.StatObjectOptions{})
+	err = translateError(err)
+	switch {
+	case err == nil:
+		// server side copy onto itself, the metadata must change for the copy to be accepted
+		metadata := map[string]string{
+			"Content-Type":         opts.ContentType,
+			metadataLastReferenced: time.Now().UTC().Format(time.RFC3339Nano),
+		}
+		for key, value := range opts.UserMetadata {
+			metadata[key] = value
+		}
+		_, err = s.client.CopyObject(ctx, minio.CopyDestOptions{
+			Bucket:          bucketName,
+			Object:          contentKey,
+			ReplaceMetadata: true,
+			UserMetadata:    metadata,
+			Mode:            opts.Mode,
+			RetainUntilDate: opts.RetainUntilDate,
+			LegalHold:       opts.LegalHold,
+		}, minio.CopySrcOptions{Bucket: bucketName, Object: contentKey})
+	case errors.Is(err, ErrNotFound):
+		_, err = s.client.PutObject(ctx, bucketName, contentKey, bytes.NewReader(data), int64(len(data)), opts)
+		if err == nil {
+			s.metrics.addBytesUploaded(int64(len(data)))
+			stored += int64(len(data))
+		}
+	}
+	if err == nil {
+		pointer := []byte("{}")
+		pointerOpts := opts
+		pointerOpts.UserMetadata = map[string]string{metadataContentRef: hash}
+		for key, value := range opts.UserMetadata {
+			pointerOpts.UserMetadata[key] = value
+		}
+		_, err = s.client.PutObject(ctx, bucketName, s.objectKey(objectName), bytes.NewReader(pointer), int64(len(pointer)), pointerOpts)
+		stored += int64(len(pointer))
+	}
+	s.metrics.observe(operationUpload, start, err)
+	if err != nil {
+		s.WrappedLogger.LogErrorf("Uploading object '%s' to bucket '%s' ... failed, error: %w", objectName, bucketName, err)
+		return 0, err
+	}
+
+	s.WrappedLogger.LogInfof("Uploading object '%s' to bucket '%s' ... done", objectName, bucketName)
+	return stored, nil
+}
+
+// contentRef returns the payload an object points to, if it is a deduplication pointer.
+func contentRef(info minio.ObjectInfo) (string, bool) {
+	ref, ok := info.UserMetadata[metadataContentRef]
+	if !ok || ref == "" {
+		return "", false
+	}
+	return contentPrefix + ref, true
+}
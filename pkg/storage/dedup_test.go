@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// contentKeys returns the keys of the deduplicated payloads of a bucket.
+func contentKeys(t *testing.T, backend *MemoryBackend, bucketName string) []string {
+	t.Helper()
+	var keys []string
+	for info := range backend.ListObjects(context.Background(), bucketName, minio.ListObjectsOptions{Prefix: contentPrefix, Recursive: true}) {
+		if info.Err != nil {
+			t.Fatalf("can't list the payloads: %v", info.Err)
+		}
+		keys = append(keys, info.Key)
+	}
+	return keys
+}
+
+func TestDedup(t *testing.T) {
+	s, backend := newTestStorage(t, func(params *Parameters) {
+		params.DedupEnabled = true
+		params.QuotaMaxObjects = 10
+	})
+	ctx := context.Background()
+	if _, err := s.GetBucketUsage(s.DefaultBucketName, ctx); err != nil {
+		t.Fatalf("can't count the bucket: %v", err)
+	}
+
+	for _, name := range []string{"a", "b"} {
+		if err := s.UploadObject(name, s.DefaultBucketName, taggedDataObject("dedup", "shared"), ctx); err != nil {
+			t.Fatalf("can't upload object '%s': %v", name, err)
+		}
+	}
+	keys := contentKeys(t, backend, s.DefaultBucketName)
+	if len(keys) != 1 || !strings.HasPrefix(keys[0], contentPrefix) {
+		t.Fatalf("got payloads %v, expected one shared payload", keys)
+	}
+	assertReconciled := func(objects int64) {
+		t.Helper()
+		maintained, err := s.GetBucketUsage(s.DefaultBucketName, ctx)
+		if err != nil {
+			t.Fatalf("can't get the usage: %v", err)
+		}
+		if err := s.reconcileUsage(s.DefaultBucketName, ctx); err != nil {
+			t.Fatalf("can't reconcile the usage: %v", err)
+		}
+		reconciled, _ := s.GetBucketUsage(s.DefaultBucketName, ctx)
+		if maintained.Objects != objects || maintained.Objects != reconciled.Objects || maintained.Bytes != reconciled.Bytes {
+			t.Errorf("got usage %+v, reconciled %+v, expected %d blocks both", maintained, reconciled, objects)
+		}
+	}
+	assertReconciled(2)
+
+	if err := s.DeleteObject(s.DefaultBucketName, "a", "", ctx); err != nil {
+		t.Fatalf("can't delete object 'a': %v", err)
+	}
+	if _, err := s.GetObject(s.DefaultBucketName, "a", "", ctx); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got error %v for the deleted object, expected ErrNotFound", err)
+	}
+	if data := getData(t, s, s.DefaultBucketName, "b"); data != "shared" {
+		t.Errorf("got data '%s' for the object sharing the payload", data)
+	}
+	if keys := contentKeys(t, backend, s.DefaultBucketName); len(keys) != 1 {
+		t.Errorf("got payloads %v after a delete, expected the shared payload kept", keys)
+	}
+	assertReconciled(1)
+}
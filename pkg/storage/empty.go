@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// emptyBatchSize is the number of objects removed per request, the most S3 accepts.
+const emptyBatchSize = 1000
+
+// EmptyProgress reports the progress of a job emptying a bucket.
+type EmptyProgress struct {
+	BucketName string `json:"bucketName"`
+	Removed    uint64 `json:"removed"`
+	Failed     uint64 `json:"failed"`
+	LastError  string `json:"lastError,omitempty"`
+}
+
+// EmptyBucket removes every object of a bucket, every version on a versioned bucket, keeping the bucket and its
+// configuration. Objects are removed in batches until done or ctx is cancelled; as removed objects are no longer
+// listed, an interrupted or partially failed run is resumed by running it again. Locked objects can't be removed,
+// they are counted as failures.
+func (s *Storage) EmptyBucket(bucketName string, ctx context.Context, report func(progress any)) error {
+	s.WrappedLogger.LogInfof("Emptying bucket '%s' ...", bucketName)
+	progress := EmptyProgress{BucketName: bucketName}
+	report(progress)
+
+	listCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	batch := make([]minio.ObjectInfo, 0, emptyBatchSize)
+	for info := range s.client.ListObjects(listCtx, bucketName, minio.ListObjectsOptions{Recursive: true, WithVersions: s.VersioningEnabled}) {
+		if info.Err != nil {
+			err := translateError(info.Err)
+			s.WrappedLogger.LogErrorf("Emptying bucket '%s' ... failed, error: %w", bucketName, err)
+			return err
+		}
+		batch = append(batch, info)
+		if len(batch) == emptyBatchSize {
+			s.removeBatch(bucketName, batch, &progress, ctx)
+			report(progress)
+			batch = batch[:0]
+		}
+	}
+	if ctx.Err() != nil {
+		s.WrappedLogger.LogInfof("Emptying bucket '%s' ... cancelled", bucketName)
+		return ctx.Err()
+	}
+	if len(batch) > 0 {
+		s.removeBatch(bucketName, batch, &progress, ctx)
+		report(progress)
+	}
+
+	if progress.Failed > 0 {
+		err := fmt.Errorf("%d objects of bucket '%s' could not be removed, last error: %s", progress.Failed, bucketName, progress.LastError)
+		s.WrappedLogger.LogErrorf("Emptying bucket '%s' ... failed, error: %w", bucketName, err)
+		return err
+	}
+	s.WrappedLogger.LogInfof("Emptying bucket '%s' ... done, %d objects removed", bucketName, progress.Removed)
+	return nil
+}
+
+// removeBatch removes a batch of objects, counting the removed and failed ones, and records the removed blocks.
+func (s *Storage) removeBatch(bucketName string, batch []minio.ObjectInfo, progress *EmptyProgress, ctx context.Context) {
+	objects := make(chan minio.ObjectInfo, len(batch))
+	for _, info := range batch {
+		if bucketName == s.DefaultBucketName {
+			s.deletions.markOwn(info.Key)
+		}
+		objects <- info
+	}
+	close(objects)
+
+	start := time.Now()
+	var err error
+	failed := make(map[string]struct{})
+	for removeErr := range s.client.RemoveObjects(ctx, bucketName, objects, minio.RemoveObjectsOptions{}) {
+		failed[removeErr.ObjectName+"/"+removeErr.VersionID] = struct{}{}
+		err = translateError(removeErr.Err)
+		progress.LastError = err.Error()
+	}
+	s.metrics.observe(operationDelete, start, err)
+
+	for _, info := range batch {
+		if _, ok := failed[info.Key+"/"+info.VersionID]; ok {
+			progress.Failed++
+			continue
+		}
+		progress.Removed++
+		if name, ok := s.objectNameFromKey(info.Key); ok && (info.IsLatest || !s.VersioningEnabled) && !info.IsDeleteMarker {
+			s.addUsage(bucketName, -1, -info.Size)
+			s.recordEvent(EventDelete, name, bucketName, ctx)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// lockedBackend refuses to remove the objects whose key contains locked, while they are locked.
+type lockedBackend struct {
+	*MemoryBackend
+	sync.Mutex
+	locked  string
+	batches int
+}
+
+func (b *lockedBackend) unlock() {
+	b.Lock()
+	defer b.Unlock()
+	b.locked = ""
+}
+
+func (b *lockedBackend) RemoveObjects(ctx context.Context, bucketName string, objectsCh <-chan minio.ObjectInfo, opts minio.RemoveObjectsOptions) <-chan minio.RemoveObjectError {
+	b.Lock()
+	locked := b.locked
+	b.batches++
+	b.Unlock()
+	errs := make(chan minio.RemoveObjectError)
+	go func() {
+		defer close(errs)
+		for info := range objectsCh {
+			err := fmt.Errorf("object is locked")
+			if locked == "" || !strings.Contains(info.Key, locked) {
+				err = b.MemoryBackend.RemoveObject(ctx, bucketName, info.Key, minio.RemoveObjectOptions{VersionID: info.VersionID})
+			}
+			if err != nil {
+				errs <- minio.RemoveObjectError{ObjectName: info.Key, VersionID: info.VersionID, Err: err}
+			}
+		}
+	}()
+	return errs
+}
+
+func TestEmptyBucket(t *testing.T) {
+	backend := &lockedBackend{MemoryBackend: NewMemoryBackend(), locked: "locked"}
+	s := newTestStorageWithBackend(t, backend, nil)
+	ctx := context.Background()
+	const objects = 2*emptyBatchSize + 10
+	for i := 0; i < objects; i++ {
+		name := fmt.Sprintf("block-%d", i)
+		if i%500 == 0 {
+			name = fmt.Sprintf("locked-%d", i)
+		}
+		if err := s.UploadObject(name, s.DefaultBucketName, taggedDataObject("empty", name), ctx); err != nil {
+			t.Fatalf("can't upload object '%s': %v", name, err)
+		}
+	}
+	empty := func() (EmptyProgress, error) {
+		var progress EmptyProgress
+		err := s.EmptyBucket(s.DefaultBucketName, ctx, func(p any) { progress = p.(EmptyProgress) })
+		return progress, err
+	}
+
+	// the locked objects fail, the others are removed in batches
+	progress, err := empty()
+	if err == nil || progress.Removed != objects-5 || progress.Failed != 5 || progress.LastError == "" {
+		t.Errorf("got progress %+v, error %v, expected %d removed and 5 failed", progress, err, objects-5)
+	}
+	if backend.batches != 3 {
+		t.Errorf("got %d batches, expected 3", backend.batches)
+	}
+	if remaining := keys(t, backend.MemoryBackend, s.DefaultBucketName); len(remaining) != 5 {
+		t.Errorf("got %d objects left, expected the 5 locked ones", len(remaining))
+	}
+
+	// running it again resumes with the objects left
+	backend.unlock()
+	progress, err = empty()
+	if err != nil || progress.Removed != 5 || progress.Failed != 0 {
+		t.Errorf("got progress %+v, error %v, expected the 5 objects left removed", progress, err)
+	}
+	if remaining := keys(t, backend.MemoryBackend, s.DefaultBucketName); len(remaining) != 0 {
+		t.Errorf("got objects %v left, expected none", remaining)
+	}
+	if exists, err := s.BucketExists(s.DefaultBucketName, ctx); err != nil || !exists {
+		t.Errorf("got bucket existing: %t, error %v, expected it kept", exists, err)
+	}
+}
@@ -0,0 +1,26 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// ErrNotFound is returned when the requested object or bucket doesn't exist in the storage.
+var ErrNotFound = errors.New("not found")
+
+// ErrQuotaExceeded is returned when storing into a bucket which reached its quota.
+var ErrQuotaExceeded = errors.New("bucket quota exceeded")
+
+// translateError maps minio's missing object/bucket responses to ErrNotFound, leaving other errors untouched.
+func translateError(err error) error {
+	if err == nil {
+		return nil
+	}
+	switch minio.ToErrorResponse(err).Code {
+	case "NoSuchKey", "NoSuchBucket", "NoSuchVersion":
+		return fmt.Errorf("%w: %s", ErrNotFound, err)
+	}
+	return err
+}
@@ -0,0 +1,299 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+const (
+	EventStore  = "store"
+	EventDelete = "delete"
+
+	OriginAPI       = "api"
+	OriginListener  = "listener"
+	OriginBackfill  = "backfill"
+	OriginRetry     = "retry"
+	OriginMigration = "migration"
+	// OriginExternal marks the deletions made outside of the collector, e.g. by a lifecycle rule
+	OriginExternal = "external"
+
+	// eventRetryInterval is the delay before writing an event to the log again, after a failure.
+	eventRetryInterval = 5 * time.Second
+)
+
+// Event is a mutation of the storage, recorded in the event log.
+type Event struct {
+	Offset    uint64    `json:"offset"`
+	Type      string    `json:"type"`
+	BlockId   string    `json:"blockId"`
+	Bucket    string    `json:"bucket"`
+	Timestamp time.Time `json:"timestamp"`
+	Origin    string    `json:"origin"`
+}
+
+type originKey struct{}
+
+// ContextWithOrigin returns a context whose storage mutations are recorded with the given origin, api by default.
+func ContextWithOrigin(ctx context.Context, origin string) context.Context {
+	return context.WithValue(ctx, originKey{}, origin)
+}
+
+func originFromContext(ctx context.Context) string {
+	if origin, ok := ctx.Value(originKey{}).(string); ok {
+		return origin
+	}
+	return OriginAPI
+}
+
+// eventLog queues the events to be written to the events bucket by a single writer, which numbers them in the order
+// they were recorded.
+type eventLog struct {
+	bucketName string
+	queue      chan Event
+	// drain is closed for the writer to write the queued events and stop
+	drain     chan struct{}
+	drainOnce *sync.Once
+	// stopped is closed once the writer stopped, the events recorded later are lost
+	stopped chan struct{}
+	// next is the offset of the next event, the offsets up to reserved are reserved in the events bucket; both are
+	// only used by the writer
+	next     uint64
+	reserved uint64
+}
+
+const (
+	// eventOffsetKey holds the last offset reserved in the events bucket, it sorts after the event keys.
+	eventOffsetKey = "offset"
+	// eventOffsetReservation is how many offsets are reserved at once, the ones left unused at shutdown are skipped.
+	eventOffsetReservation = 1000
+)
+
+func newEventLog(params Parameters) *eventLog {
+	if params.EventsBucketName == "" {
+		return nil
+	}
+	return &eventLog{
+		bucketName: params.EventsBucketName,
+		queue:      make(chan Event, params.EventsQueueSize),
+		drain:      make(chan struct{}),
+		drainOnce:  &sync.Once{},
+		stopped:    make(chan struct{}),
+	}
+}
+
+// eventKey returns the key of the event with the given offset, zero padded for the keys to sort like the offsets.
+func eventKey(offset uint64) string {
+	return fmt.Sprintf("%020d", offset)
+}
+
+// recordEvent queues an event for the log, waiting for the log to catch up if the queue is full. The event is only
+// lost, with a warning, once the writer stopped.
+func (s *Storage) recordEvent(eventType string, blockId string, bucketName string, ctx context.Context) {
+	if s.events == nil {
+		return
+	}
+	event := Event{Type: eventType, BlockId: blockId, Bucket: bucketName, Timestamp: time.Now().UTC(), Origin: originFromContext(ctx)}
+	select {
+	case <-s.events.stopped:
+	default:
+		select {
+		case s.events.queue <- event:
+			return
+		case <-s.events.stopped:
+		}
+	}
+	s.WrappedLogger.LogWarnf("Event log stopped, dropping %s event of block '%s'", eventType, blockId)
+}
+
+// EventQueueDepth returns how many events wait to be written to the event log.
+func (s *Storage) EventQueueDepth() int {
+	if s.events == nil {
+		return 0
+	}
+	return len(s.events.queue)
+}
+
+// RunEventLog writes the queued events to the events bucket until ctx is done, or until the log is drained.
+// Events are written one at a time, in the order they were recorded, and a failed write is retried until it
+// succeeds, so a reader never sees an offset before the ones preceding it. Offsets are reserved in the events
+// bucket, they keep growing across restarts.
+func (s *Storage) RunEventLog(ctx context.Context) {
+	if s.events == nil {
+		return
+	}
+	defer close(s.events.stopped)
+	defer func() {
+		if pending := len(s.events.queue); pending > 0 {
+			s.WrappedLogger.LogWarnf("%d events were not written to the event log and are lost", pending)
+		}
+	}()
+
+	if !s.retryEventLog(ctx, "Reading the last event offset", s.loadEventOffset) {
+		return
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-s.events.queue:
+			if !s.writeEventRetrying(event, ctx) {
+				return
+			}
+		case <-s.events.drain:
+			for {
+				select {
+				case event := <-s.events.queue:
+					if !s.writeEventRetrying(event, ctx) {
+						return
+					}
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// DrainEventLog has the writer write the queued events and stop, waiting for it until ctx is done. It is called on
+// shutdown, once no more blocks are stored.
+func (s *Storage) DrainEventLog(ctx context.Context) {
+	if s.events == nil {
+		return
+	}
+	s.events.drainOnce.Do(func() {
+		close(s.events.drain)
+	})
+	select {
+	case <-s.events.stopped:
+	case <-ctx.Done():
+	}
+}
+
+// writeEventRetrying numbers an event and writes it, retrying until it succeeds; it returns false once ctx is done.
+func (s *Storage) writeEventRetrying(event Event, ctx context.Context) bool {
+	if s.events.next > s.events.reserved {
+		if !s.retryEventLog(ctx, "Reserving event offsets", s.reserveEventOffsets) {
+			return false
+		}
+	}
+	event.Offset = s.events.next
+	if !s.retryEventLog(ctx, fmt.Sprintf("Writing event %d", event.Offset), func(ctx context.Context) error {
+		return s.writeEvent(event, ctx)
+	}) {
+		return false
+	}
+	s.events.next++
+	return true
+}
+
+// retryEventLog runs an operation of the writer until it succeeds, it returns false once ctx is done.
+func (s *Storage) retryEventLog(ctx context.Context, operation string, fn func(ctx context.Context) error) bool {
+	for {
+		err := fn(ctx)
+		if err == nil {
+			return true
+		}
+		s.WrappedLogger.LogWarnf("%s to bucket '%s' ... failed, retrying, error: %w", operation, s.events.bucketName, err)
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(eventRetryInterval):
+		}
+	}
+}
+
+// loadEventOffset reads the last offset reserved by the previous run. Without any, the offsets start from the clock,
+// after those of the versions deriving them from it.
+func (s *Storage) loadEventOffset(ctx context.Context) error {
+	object, err := s.client.GetObject(ctx, s.events.bucketName, eventOffsetKey, minio.GetObjectOptions{})
+	err = translateError(err)
+	if errors.Is(err, ErrNotFound) {
+		s.events.reserved = uint64(time.Now().UnixNano())
+		s.events.next = s.events.reserved + 1
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer object.Close()
+
+	var reserved uint64
+	err = json.NewDecoder(object).Decode(&reserved)
+	if err != nil {
+		return err
+	}
+	s.events.reserved = reserved
+	s.events.next = reserved + 1
+	return nil
+}
+
+// reserveEventOffsets persists the next range of offsets before any is used.
+func (s *Storage) reserveEventOffsets(ctx context.Context) error {
+	reserved := s.events.next + eventOffsetReservation - 1
+	data, err := json.Marshal(reserved)
+	if err != nil {
+		return err
+	}
+	_, err = s.client.PutObject(ctx, s.events.bucketName, eventOffsetKey, bytes.NewReader(data), int64(len(data)), minio.PutObjectOptions{ContentType: ContentTypeJSON})
+	if err != nil {
+		return err
+	}
+	s.events.reserved = reserved
+	return nil
+}
+
+func (s *Storage) writeEvent(event Event, ctx context.Context) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = s.client.PutObject(ctx, s.events.bucketName, eventKey(event.Offset), bytes.NewReader(data), int64(len(data)), minio.PutObjectOptions{ContentType: ContentTypeJSON})
+	return err
+}
+
+// ReadEvents returns up to limit events of the log with an offset greater than since, in offset order.
+func (s *Storage) ReadEvents(since uint64, limit int, ctx context.Context) ([]Event, error) {
+	if s.events == nil {
+		return nil, fmt.Errorf("the event log is not enabled")
+	}
+	listCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	events := make([]Event, 0)
+	for info := range s.client.ListObjects(listCtx, s.events.bucketName, minio.ListObjectsOptions{StartAfter: eventKey(since)}) {
+		if info.Err != nil {
+			return nil, translateError(info.Err)
+		}
+		if info.Key == eventOffsetKey {
+			continue
+		}
+		event, err := s.readEvent(info.Key, listCtx)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+		if len(events) == limit {
+			break
+		}
+	}
+	return events, nil
+}
+
+func (s *Storage) readEvent(key string, ctx context.Context) (Event, error) {
+	var event Event
+	object, err := s.client.GetObject(ctx, s.events.bucketName, key, minio.GetObjectOptions{})
+	if err != nil {
+		return event, translateError(err)
+	}
+	defer object.Close()
+
+	err = json.NewDecoder(object).Decode(&event)
+	return event, err
+}
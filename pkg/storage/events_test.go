@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func eventParams(queueSize int) func(params *Parameters) {
+	return func(params *Parameters) {
+		params.EventsBucketName = "events"
+		params.EventsQueueSize = queueSize
+	}
+}
+
+// newEventStorage returns a storage logging events to a created events bucket of backend.
+func newEventStorage(t *testing.T, backend *MemoryBackend, queueSize int) Storage {
+	t.Helper()
+	s := newTestStorageWithBackend(t, backend, eventParams(queueSize))
+	if _, err := s.CheckCreateBucket(s.EventsBucketName, context.Background()); err != nil {
+		t.Fatalf("can't create the events bucket: %v", err)
+	}
+	return s
+}
+
+// runEventLog runs the writer of the event log while record records events, then drains the log.
+func runEventLog(t *testing.T, s Storage, record func()) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.RunEventLog(ctx)
+	record()
+	drainCtx, cancelDrain := context.WithTimeout(ctx, 5*time.Second)
+	defer cancelDrain()
+	s.DrainEventLog(drainCtx)
+	if drainCtx.Err() != nil {
+		t.Fatal("the event log was not drained")
+	}
+}
+
+func readEvents(t *testing.T, s Storage, since uint64) []Event {
+	t.Helper()
+	events, err := s.ReadEvents(since, 1000, context.Background())
+	if err != nil {
+		t.Fatalf("can't read the events: %v", err)
+	}
+	return events
+}
+
+func TestEventOrder(t *testing.T) {
+	// a queue of one, the events must wait rather than being dropped
+	s := newEventStorage(t, NewMemoryBackend(), 1)
+	const count = 20
+	runEventLog(t, s, func() {
+		for i := 0; i < count; i++ {
+			s.recordEvent(EventStore, fmt.Sprintf("block-%02d", i), s.DefaultBucketName, context.Background())
+		}
+	})
+
+	events := readEvents(t, s, 0)
+	if len(events) != count {
+		t.Fatalf("got %d events, expected %d", len(events), count)
+	}
+	for i, event := range events {
+		if expected := fmt.Sprintf("block-%02d", i); event.BlockId != expected {
+			t.Errorf("got event of block '%s' at position %d, expected '%s'", event.BlockId, i, expected)
+		}
+		if i > 0 && event.Offset <= events[i-1].Offset {
+			t.Errorf("got offset %d after %d", event.Offset, events[i-1].Offset)
+		}
+	}
+}
+
+func TestEventResume(t *testing.T) {
+	backend := NewMemoryBackend()
+	first := newEventStorage(t, backend, 10)
+	runEventLog(t, first, func() {
+		first.recordEvent(EventStore, "before", first.DefaultBucketName, context.Background())
+	})
+	before := readEvents(t, first, 0)
+	if len(before) != 1 {
+		t.Fatalf("got %d events before the restart, expected 1", len(before))
+	}
+
+	restarted := newEventStorage(t, backend, 10)
+	runEventLog(t, restarted, func() {
+		restarted.recordEvent(EventDelete, "after", restarted.DefaultBucketName, context.Background())
+	})
+	after := readEvents(t, restarted, before[0].Offset)
+	if len(after) != 1 || after[0].BlockId != "after" || after[0].Type != EventDelete {
+		t.Fatalf("got events %+v resuming after offset %d, expected the one recorded after the restart", after, before[0].Offset)
+	}
+	if after[0].Offset <= before[0].Offset {
+		t.Errorf("got offset %d after the restart, expected more than %d", after[0].Offset, before[0].Offset)
+	}
+}
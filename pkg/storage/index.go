@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+)
+
+const (
+	// indexPrefix namespaces the index tags, an empty object per tag and block under index/<tag>/<block>.
+	indexPrefix = "index/"
+	// MetadataIndexTags is set on the objects stored with index tags, for their entries to be removed along with them.
+	MetadataIndexTags = "Index-Tags"
+
+	MaxIndexTags      = 8
+	maxIndexTagLength = 64
+)
+
+// ValidateIndexTag checks that a tag can be indexed.
+func ValidateIndexTag(tag string) error {
+	if tag == "" || len(tag) > maxIndexTagLength {
+		return fmt.Errorf("invalid index tag '%s': expected 1 to %d characters", tag, maxIndexTagLength)
+	}
+	return nil
+}
+
+// indexTagPrefix escapes the tag, for it to name a single level of the index whatever its characters.
+func (s *Storage) indexTagPrefix(tag string) string {
+	return s.keyPrefix + indexPrefix + url.PathEscape(tag) + "/"
+}
+
+// indexTags records a block under each of its index tags. Entries are written with the block and share its bucket's
+// lifecycle, storing a block again rewrites the same entries.
+func (s *Storage) indexTags(bucketName string, tags []string, objectName string, ctx context.Context) error {
+	for _, tag := range tags {
+		_, err := s.client.PutObject(ctx, bucketName, s.indexTagPrefix(tag)+objectName+s.objectExtension, bytes.NewReader(nil), 0, minio.PutObjectOptions{})
+		if err != nil {
+			s.WrappedLogger.LogErrorf("Indexing tag '%s' of block '%s' in bucket '%s' ... failed, error: %w", tag, objectName, bucketName, err)
+			return translateError(err)
+		}
+	}
+	return nil
+}
+
+// removeIndexTags drops the entries of a block from the index.
+func (s *Storage) removeIndexTags(bucketName string, tags []string, objectName string, ctx context.Context) error {
+	for _, tag := range tags {
+		err := translateError(s.client.RemoveObject(ctx, bucketName, s.indexTagPrefix(tag)+objectName+s.objectExtension, minio.RemoveObjectOptions{}))
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+// indexTagsOf returns the index tags an object was stored with, read from its metadata.
+func indexTagsOf(info minio.ObjectInfo) []string {
+	value := info.UserMetadata[MetadataIndexTags]
+	if value == "" {
+		return nil
+	}
+	var tags []string
+	for _, escaped := range strings.Split(value, ",") {
+		tag, err := url.QueryUnescape(escaped)
+		if err == nil {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// ListTagged returns the names of the blocks of a bucket indexed under a tag, in lexical order, at most limit of them
+// after the name startAfter. A block whose tag entry outlived it, e.g. when it was removed outside of the
+// collector, is still listed.
+func (s *Storage) ListTagged(bucketName string, tag string, startAfter string, limit int, ctx context.Context) ([]string, error) {
+	prefix := s.indexTagPrefix(tag)
+	opts := minio.ListObjectsOptions{Prefix: prefix}
+	if startAfter != "" {
+		opts.StartAfter = prefix + startAfter + s.objectExtension
+	}
+
+	listCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	objectNames := make([]string, 0)
+	for info := range s.client.ListObjects(listCtx, bucketName, opts) {
+		if info.Err != nil {
+			return nil, translateError(info.Err)
+		}
+		if !strings.HasSuffix(info.Key, s.objectExtension) {
+			continue
+		}
+		objectNames = append(objectNames, strings.TrimSuffix(strings.TrimPrefix(info.Key, prefix), s.objectExtension))
+		if len(objectNames) == limit {
+			break
+		}
+	}
+	return objectNames, nil
+}
+
+// indexObject indexes a stored object under its alias and its index tags.
+func (s *Storage) indexObject(bucketName string, objectName string, alias string, tags []string, ctx context.Context) error {
+	if alias != "" {
+		err := s.indexAlias(bucketName, alias, objectName, ctx)
+		if err != nil {
+			return err
+		}
+	}
+	return s.indexTags(bucketName, tags, objectName, ctx)
+}
@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestIndexTags(t *testing.T) {
+	s, _ := newTestStorage(t, nil)
+	ctx := context.Background()
+	for name, tags := range map[string][]string{
+		"a": {"red", "round"},
+		"b": {"red", "with/slash"},
+		"c": {"red"},
+		"d": nil,
+	} {
+		object := taggedDataObject("index", name)
+		object.IndexTags = tags
+		if err := s.UploadObject(name, s.DefaultBucketName, object, ctx); err != nil {
+			t.Fatalf("can't upload object '%s': %v", name, err)
+		}
+	}
+	listTagged := func(tag string, startAfter string, limit int) []string {
+		t.Helper()
+		names, err := s.ListTagged(s.DefaultBucketName, tag, startAfter, limit, ctx)
+		if err != nil {
+			t.Fatalf("can't list the blocks tagged '%s': %v", tag, err)
+		}
+		return names
+	}
+
+	for _, tc := range []struct {
+		tag        string
+		startAfter string
+		limit      int
+		expected   []string
+	}{
+		{"red", "", 10, []string{"a", "b", "c"}},
+		{"red", "", 2, []string{"a", "b"}},
+		{"red", "b", 2, []string{"c"}},
+		{"round", "", 10, []string{"a"}},
+		{"with/slash", "", 10, []string{"b"}},
+		{"unknown", "", 10, []string{}},
+	} {
+		if names := listTagged(tc.tag, tc.startAfter, tc.limit); !reflect.DeepEqual(names, tc.expected) {
+			t.Errorf("tag '%s' after '%s': got %v, expected %v", tc.tag, tc.startAfter, names, tc.expected)
+		}
+	}
+	// the index entries are not listed as blocks
+	if names := listNames(t, s, s.DefaultBucketName); len(names) != 4 {
+		t.Errorf("got objects %v, expected the 4 blocks", names)
+	}
+
+	// deleting a block removes its entries
+	if err := s.DeleteObject(s.DefaultBucketName, "a", "", ctx); err != nil {
+		t.Fatalf("can't delete object 'a': %v", err)
+	}
+	if names := listTagged("red", "", 10); !reflect.DeepEqual(names, []string{"b", "c"}) {
+		t.Errorf("got %v tagged 'red' once 'a' is deleted, expected [b c]", names)
+	}
+	if names := listTagged("round", "", 10); len(names) != 0 {
+		t.Errorf("got %v tagged 'round' once 'a' is deleted, expected none", names)
+	}
+}
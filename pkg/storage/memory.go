@@ -0,0 +1,474 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/lifecycle"
+)
+
+// MemoryBackend is a Backend keeping buckets and objects in memory, for tests and local development.
+// Lifecycle expiration is simulated lazily: expired objects are dropped whenever the bucket is accessed.
+// Listing is always flat, as if recursive.
+type MemoryBackend struct {
+	sync.Mutex
+	buckets map[string]*memoryBucket
+	// Now returns the current time, tests can replace it to simulate the passing of time.
+	Now func() time.Time
+
+	versionCounter uint64
+}
+
+type memoryBucket struct {
+	created    time.Time
+	versioning bool
+	objectLock bool
+	lifecycle  *lifecycle.Configuration
+	policy     string
+	// the versions of every key, the last one is the latest
+	objects map[string][]*memoryObject
+}
+
+type memoryObject struct {
+	data []byte
+	info minio.ObjectInfo
+}
+
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{
+		buckets: make(map[string]*memoryBucket),
+		Now:     time.Now,
+	}
+}
+
+func memoryError(code string, statusCode int, bucketName string, objectName string) error {
+	return minio.ErrorResponse{
+		Code:       code,
+		Message:    fmt.Sprintf("%s: bucket '%s', object '%s'", code, bucketName, objectName),
+		BucketName: bucketName,
+		Key:        objectName,
+		StatusCode: statusCode,
+	}
+}
+
+func (m *MemoryBackend) EndpointURL() *url.URL {
+	return &url.URL{Scheme: "memory", Host: "memory"}
+}
+
+// bucket returns a bucket after dropping its expired objects, the lock must be held.
+func (m *MemoryBackend) bucket(bucketName string) (*memoryBucket, error) {
+	bucket, ok := m.buckets[bucketName]
+	if !ok {
+		return nil, memoryError("NoSuchBucket", http.StatusNotFound, bucketName, "")
+	}
+
+	days := 0
+	if bucket.lifecycle != nil {
+		for _, rule := range bucket.lifecycle.Rules {
+			if rule.Status == "Enabled" && rule.Expiration.Days > 0 {
+				days = int(rule.Expiration.Days)
+			}
+		}
+	}
+	if days > 0 {
+		expiration := m.Now().AddDate(0, 0, -days)
+		for key, versions := range bucket.objects {
+			latest := versions[len(versions)-1]
+			if latest.info.LastModified.Before(expiration) {
+				delete(bucket.objects, key)
+			}
+		}
+	}
+	return bucket, nil
+}
+
+// version returns the requested version of an object, the latest one if versionId is empty.
+func (b *memoryBucket) version(bucketName string, objectName string, versionId string) (*memoryObject, error) {
+	versions, ok := b.objects[objectName]
+	if !ok {
+		return nil, memoryError("NoSuchKey", http.StatusNotFound, bucketName, objectName)
+	}
+	if versionId == "" {
+		latest := versions[len(versions)-1]
+		if latest.info.IsDeleteMarker {
+			return nil, memoryError("NoSuchKey", http.StatusNotFound, bucketName, objectName)
+		}
+		return latest, nil
+	}
+	for _, version := range versions {
+		if version.info.VersionID == versionId {
+			return version, nil
+		}
+	}
+	return nil, memoryError("NoSuchVersion", http.StatusNotFound, bucketName, objectName)
+}
+
+func (m *MemoryBackend) nextVersionId(bucket *memoryBucket) string {
+	if !bucket.versioning {
+		return ""
+	}
+	m.versionCounter++
+	return fmt.Sprintf("%016x", m.versionCounter)
+}
+
+func (m *MemoryBackend) store(bucket *memoryBucket, object *memoryObject) {
+	if !bucket.versioning {
+		bucket.objects[object.info.Key] = []*memoryObject{object}
+		return
+	}
+	for _, version := range bucket.objects[object.info.Key] {
+		version.info.IsLatest = false
+	}
+	bucket.objects[object.info.Key] = append(bucket.objects[object.info.Key], object)
+}
+
+func (m *MemoryBackend) ListBuckets(ctx context.Context) ([]minio.BucketInfo, error) {
+	m.Lock()
+	defer m.Unlock()
+	buckets := make([]minio.BucketInfo, 0, len(m.buckets))
+	for name, bucket := range m.buckets {
+		buckets = append(buckets, minio.BucketInfo{Name: name, CreationDate: bucket.created})
+	}
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i].Name < buckets[j].Name })
+	return buckets, nil
+}
+
+func (m *MemoryBackend) BucketExists(ctx context.Context, bucketName string) (bool, error) {
+	m.Lock()
+	defer m.Unlock()
+	_, ok := m.buckets[bucketName]
+	return ok, nil
+}
+
+func (m *MemoryBackend) MakeBucket(ctx context.Context, bucketName string, opts minio.MakeBucketOptions) error {
+	m.Lock()
+	defer m.Unlock()
+	if _, ok := m.buckets[bucketName]; ok {
+		return memoryError("BucketAlreadyOwnedByYou", http.StatusConflict, bucketName, "")
+	}
+	m.buckets[bucketName] = &memoryBucket{
+		created:    m.Now(),
+		objectLock: opts.ObjectLocking,
+		// object locking implies versioning
+		versioning: opts.ObjectLocking,
+		objects:    make(map[string][]*memoryObject),
+	}
+	return nil
+}
+
+func (m *MemoryBackend) EnableVersioning(ctx context.Context, bucketName string) error {
+	m.Lock()
+	defer m.Unlock()
+	bucket, err := m.bucket(bucketName)
+	if err != nil {
+		return err
+	}
+	bucket.versioning = true
+	return nil
+}
+
+func (m *MemoryBackend) SetBucketLifecycle(ctx context.Context, bucketName string, config *lifecycle.Configuration) error {
+	m.Lock()
+	defer m.Unlock()
+	bucket, err := m.bucket(bucketName)
+	if err != nil {
+		return err
+	}
+	if config == nil || len(config.Rules) == 0 {
+		bucket.lifecycle = nil
+		return nil
+	}
+	bucket.lifecycle = config
+	return nil
+}
+
+func (m *MemoryBackend) GetBucketLifecycle(ctx context.Context, bucketName string) (*lifecycle.Configuration, error) {
+	m.Lock()
+	defer m.Unlock()
+	bucket, err := m.bucket(bucketName)
+	if err != nil {
+		return nil, err
+	}
+	if bucket.lifecycle == nil {
+		return lifecycle.NewConfiguration(), memoryError("NoSuchLifecycleConfiguration", http.StatusNotFound, bucketName, "")
+	}
+	return bucket.lifecycle, nil
+}
+
+func (m *MemoryBackend) SetBucketPolicy(ctx context.Context, bucketName string, policy string) error {
+	m.Lock()
+	defer m.Unlock()
+	bucket, err := m.bucket(bucketName)
+	if err != nil {
+		return err
+	}
+	bucket.policy = policy
+	return nil
+}
+
+func (m *MemoryBackend) GetBucketPolicy(ctx context.Context, bucketName string) (string, error) {
+	m.Lock()
+	defer m.Unlock()
+	bucket, err := m.bucket(bucketName)
+	if err != nil {
+		return "", err
+	}
+	return bucket.policy, nil
+}
+
+func (m *MemoryBackend) GetObjectLockConfig(ctx context.Context, bucketName string) (string, *minio.RetentionMode, *uint, *minio.ValidityUnit, error) {
+	m.Lock()
+	defer m.Unlock()
+	bucket, err := m.bucket(bucketName)
+	if err != nil {
+		return "", nil, nil, nil, err
+	}
+	if !bucket.objectLock {
+		return "", nil, nil, nil, memoryError("ObjectLockConfigurationNotFoundError", http.StatusNotFound, bucketName, "")
+	}
+	return "Enabled", nil, nil, nil, nil
+}
+
+func (m *MemoryBackend) PutObject(ctx context.Context, bucketName string, objectName string, reader io.Reader, objectSize int64, opts minio.PutObjectOptions) (minio.UploadInfo, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return minio.UploadInfo{}, err
+	}
+
+	m.Lock()
+	defer m.Unlock()
+	bucket, err := m.bucket(bucketName)
+	if err != nil {
+		return minio.UploadInfo{}, err
+	}
+
+	sum := md5.Sum(data)
+	object := &memoryObject{
+		data: data,
+		info: minio.ObjectInfo{
+			Key:          objectName,
+			Size:         int64(len(data)),
+			ETag:         hex.EncodeToString(sum[:]),
+			LastModified: m.Now(),
+			ContentType:  opts.ContentType,
+			UserMetadata: opts.UserMetadata,
+			VersionID:    m.nextVersionId(bucket),
+			IsLatest:     true,
+		},
+	}
+	m.store(bucket, object)
+
+	return minio.UploadInfo{
+		Bucket:    bucketName,
+		Key:       objectName,
+		ETag:      object.info.ETag,
+		Size:      object.info.Size,
+		VersionID: object.info.VersionID,
+	}, nil
+}
+
+func (m *MemoryBackend) GetObject(ctx context.Context, bucketName string, objectName string, opts minio.GetObjectOptions) (*ObjectReader, error) {
+	m.Lock()
+	defer m.Unlock()
+	bucket, err := m.bucket(bucketName)
+	if err != nil {
+		return nil, err
+	}
+	object, err := bucket.version(bucketName, objectName, opts.VersionID)
+	if err != nil {
+		return nil, err
+	}
+	data, err := memoryRange(object.data, opts.Header().Get("Range"), bucketName, objectName)
+	if err != nil {
+		return nil, err
+	}
+	return &ObjectReader{ReadCloser: io.NopCloser(bytes.NewReader(data)), Info: object.info}, nil
+}
+
+// memoryRange cuts the range set by GetObjectOptions.SetRange out of the data of an object.
+func memoryRange(data []byte, rangeHeader string, bucketName string, objectName string) ([]byte, error) {
+	if rangeHeader == "" {
+		return data, nil
+	}
+	var start, end int64
+	size := int64(len(data))
+	switch {
+	case strings.HasPrefix(rangeHeader, "bytes=-"):
+		_, err := fmt.Sscanf(rangeHeader, "bytes=-%d", &end)
+		if err != nil {
+			return nil, err
+		}
+		start, end = size-end, size-1
+		if start < 0 {
+			start = 0
+		}
+	case strings.HasSuffix(rangeHeader, "-"):
+		_, err := fmt.Sscanf(rangeHeader, "bytes=%d-", &start)
+		if err != nil {
+			return nil, err
+		}
+		end = size - 1
+	default:
+		_, err := fmt.Sscanf(rangeHeader, "bytes=%d-%d", &start, &end)
+		if err != nil {
+			return nil, err
+		}
+		if end >= size {
+			end = size - 1
+		}
+	}
+	if start >= size || start > end {
+		return nil, memoryError("InvalidRange", http.StatusRequestedRangeNotSatisfiable, bucketName, objectName)
+	}
+	return data[start : end+1], nil
+}
+
+func (m *MemoryBackend) StatObject(ctx context.Context, bucketName string, objectName string, opts minio.StatObjectOptions) (minio.ObjectInfo, error) {
+	m.Lock()
+	defer m.Unlock()
+	bucket, err := m.bucket(bucketName)
+	if err != nil {
+		return minio.ObjectInfo{}, err
+	}
+	object, err := bucket.version(bucketName, objectName, opts.VersionID)
+	if err != nil {
+		return minio.ObjectInfo{}, err
+	}
+	return object.info, nil
+}
+
+func (m *MemoryBackend) CopyObject(ctx context.Context, dst minio.CopyDestOptions, src minio.CopySrcOptions) (minio.UploadInfo, error) {
+	m.Lock()
+	srcBucket, err := m.bucket(src.Bucket)
+	if err != nil {
+		m.Unlock()
+		return minio.UploadInfo{}, err
+	}
+	object, err := srcBucket.version(src.Bucket, src.Object, src.VersionID)
+	m.Unlock()
+	if err != nil {
+		return minio.UploadInfo{}, err
+	}
+
+	opts := minio.PutObjectOptions{ContentType: object.info.ContentType, UserMetadata: object.info.UserMetadata}
+	if dst.ReplaceMetadata {
+		opts.UserMetadata = make(map[string]string)
+		for key, value := range dst.UserMetadata {
+			if strings.EqualFold(key, "Content-Type") {
+				opts.ContentType = value
+				continue
+			}
+			opts.UserMetadata[key] = value
+		}
+	}
+	return m.PutObject(ctx, dst.Bucket, dst.Object, bytes.NewReader(object.data), int64(len(object.data)), opts)
+}
+
+func (m *MemoryBackend) RemoveObject(ctx context.Context, bucketName string, objectName string, opts minio.RemoveObjectOptions) error {
+	m.Lock()
+	defer m.Unlock()
+	bucket, err := m.bucket(bucketName)
+	if err != nil {
+		return err
+	}
+
+	versions, ok := bucket.objects[objectName]
+	if !ok {
+		// removing a missing object is not an error in S3
+		return nil
+	}
+
+	switch {
+	case !bucket.versioning:
+		delete(bucket.objects, objectName)
+	case opts.VersionID == "":
+		m.store(bucket, &memoryObject{info: minio.ObjectInfo{
+			Key:            objectName,
+			LastModified:   m.Now(),
+			VersionID:      m.nextVersionId(bucket),
+			IsLatest:       true,
+			IsDeleteMarker: true,
+		}})
+	default:
+		remaining := versions[:0]
+		for _, version := range versions {
+			if version.info.VersionID != opts.VersionID {
+				remaining = append(remaining, version)
+			}
+		}
+		if len(remaining) == 0 {
+			delete(bucket.objects, objectName)
+			return nil
+		}
+		remaining[len(remaining)-1].info.IsLatest = true
+		bucket.objects[objectName] = remaining
+	}
+	return nil
+}
+
+// RemoveObjects removes the objects one at a time, reporting the failures.
+func (m *MemoryBackend) RemoveObjects(ctx context.Context, bucketName string, objectsCh <-chan minio.ObjectInfo, opts minio.RemoveObjectsOptions) <-chan minio.RemoveObjectError {
+	errs := make(chan minio.RemoveObjectError)
+	go func() {
+		defer close(errs)
+		for info := range objectsCh {
+			err := m.RemoveObject(ctx, bucketName, info.Key, minio.RemoveObjectOptions{VersionID: info.VersionID})
+			if err != nil {
+				errs <- minio.RemoveObjectError{ObjectName: info.Key, VersionID: info.VersionID, Err: err}
+			}
+		}
+	}()
+	return errs
+}
+
+func (m *MemoryBackend) ListObjects(ctx context.Context, bucketName string, opts minio.ListObjectsOptions) <-chan minio.ObjectInfo {
+	// take a snapshot, so that the caller can use the backend while consuming the channel
+	var infos []minio.ObjectInfo
+	m.Lock()
+	bucket, err := m.bucket(bucketName)
+	if err != nil {
+		infos = append(infos, minio.ObjectInfo{Err: err})
+	} else {
+		for key, versions := range bucket.objects {
+			if !strings.HasPrefix(key, opts.Prefix) || (opts.StartAfter != "" && key <= opts.StartAfter) {
+				continue
+			}
+			if opts.WithVersions {
+				for i := len(versions) - 1; i >= 0; i-- {
+					infos = append(infos, versions[i].info)
+				}
+				continue
+			}
+			if latest := versions[len(versions)-1]; !latest.info.IsDeleteMarker {
+				infos = append(infos, latest.info)
+			}
+		}
+	}
+	m.Unlock()
+
+	sort.SliceStable(infos, func(i, j int) bool { return infos[i].Key < infos[j].Key })
+
+	objects := make(chan minio.ObjectInfo)
+	go func() {
+		defer close(objects)
+		for _, info := range infos {
+			select {
+			case <-ctx.Done():
+				return
+			case objects <- info:
+			}
+		}
+	}()
+	return objects
+}
@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/iotaledger/hive.go/core/configuration"
+	"github.com/iotaledger/hive.go/core/logger"
+	iotago "github.com/iotaledger/iota.go/v3"
+	"github.com/prometheus/client_golang/prometheus"
+	flag "github.com/spf13/pflag"
+)
+
+// newTestStorage returns a storage over a memory backend, with the default parameters changed by configure, and
+// its default bucket created.
+func newTestStorage(t *testing.T, configure func(params *Parameters)) (Storage, *MemoryBackend) {
+	t.Helper()
+	backend := NewMemoryBackend()
+	return newTestStorageWithBackend(t, backend, configure), backend
+}
+
+func newTestStorageWithBackend(t *testing.T, backend Backend, configure func(params *Parameters)) Storage {
+	t.Helper()
+	params := &Parameters{}
+	configuration.New().BindParameters(configuration.NewUnsortedFlagSet("test", flag.ContinueOnError), "storage", params)
+	if configure != nil {
+		configure(params)
+	}
+	s, err := NewStorageWithBackend(*params, backend, prometheus.NewRegistry(), logger.NewWrappedLogger(logger.NewNopLogger()))
+	if err != nil {
+		t.Fatalf("can't create the storage: %v", err)
+	}
+	if _, err := s.CheckCreateBucket(s.DefaultBucketName, context.Background()); err != nil {
+		t.Fatalf("can't create the default bucket: %v", err)
+	}
+	return s
+}
+
+func taggedDataObject(tag string, data string) Object {
+	return Object{TaggedData: &iotago.TaggedData{Tag: []byte(tag), Data: []byte(data)}}
+}
+
+// getData returns the data of the tagged data payload of an object.
+func getData(t *testing.T, s Storage, bucketName string, objectName string) string {
+	t.Helper()
+	reader, err := s.GetObject(bucketName, objectName, "", context.Background())
+	if err != nil {
+		t.Fatalf("can't get object '%s': %v", objectName, err)
+	}
+	defer reader.Close()
+	object, err := reader.Decode()
+	if err != nil {
+		t.Fatalf("can't decode object '%s': %v", objectName, err)
+	}
+	if object.TaggedData == nil {
+		t.Fatalf("object '%s' has no tagged data", objectName)
+	}
+	return string(object.TaggedData.Data)
+}
+
+func TestMemoryBackendRoundTrip(t *testing.T) {
+	s, _ := newTestStorage(t, nil)
+	ctx := context.Background()
+
+	for _, name := range []string{"a", "b"} {
+		if err := s.UploadObject(name, s.DefaultBucketName, taggedDataObject("round-trip", "data of "+name), ctx); err != nil {
+			t.Fatalf("can't upload object '%s': %v", name, err)
+		}
+	}
+	if data := getData(t, s, s.DefaultBucketName, "a"); data != "data of a" {
+		t.Errorf("got data '%s', expected 'data of a'", data)
+	}
+
+	var names []string
+	for entry := range s.ListObjects(s.DefaultBucketName, time.Time{}, ctx) {
+		if entry.Err != nil {
+			t.Fatalf("can't list the objects: %v", entry.Err)
+		}
+		names = append(names, entry.Name)
+	}
+	if len(names) != 2 || names[0] != "a" || names[1] != "b" {
+		t.Errorf("got objects %v, expected [a b]", names)
+	}
+
+	if err := s.DeleteObject(s.DefaultBucketName, "a", "", ctx); err != nil {
+		t.Fatalf("can't delete object 'a': %v", err)
+	}
+	if _, err := s.GetObject(s.DefaultBucketName, "a", "", ctx); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got error %v for a deleted object, expected ErrNotFound", err)
+	}
+}
+
+func TestMemoryBackendLifecycle(t *testing.T) {
+	s, backend := newTestStorage(t, nil)
+	ctx := context.Background()
+
+	if err := s.SetBucketExpirationDays(s.DefaultBucketName, 1, ctx); err != nil {
+		t.Fatalf("can't set the expiration days: %v", err)
+	}
+	if days, err := s.GetBucketExpirationDays(s.DefaultBucketName, ctx); err != nil || days != 1 {
+		t.Fatalf("got expiration days %d, error %v, expected 1", days, err)
+	}
+	if err := s.UploadObject("expiring", s.DefaultBucketName, taggedDataObject("lifecycle", "data"), ctx); err != nil {
+		t.Fatalf("can't upload the object: %v", err)
+	}
+
+	now := time.Now()
+	backend.Now = func() time.Time { return now.Add(12 * time.Hour) }
+	getData(t, s, s.DefaultBucketName, "expiring")
+
+	backend.Now = func() time.Time { return now.Add(49 * time.Hour) }
+	if _, err := s.GetObject(s.DefaultBucketName, "expiring", "", ctx); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got error %v for an expired object, expected ErrNotFound", err)
+	}
+}
@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	operationUpload = "upload"
+	operationGet    = "get"
+	operationStat   = "stat"
+	operationDelete = "delete"
+
+	resultSuccess  = "success"
+	resultNotFound = "not_found"
+	resultError    = "error"
+)
+
+// Metrics collects the Prometheus metrics of the storage operations.
+// A nil *Metrics is valid and records nothing.
+type Metrics struct {
+	operations      *prometheus.CounterVec
+	duration        *prometheus.HistogramVec
+	bytesUploaded   prometheus.Counter
+	bytesDownloaded prometheus.Counter
+	objects         *prometheus.GaugeVec
+}
+
+func NewMetrics(registerer prometheus.Registerer) (*Metrics, error) {
+	m := &Metrics{
+		operations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "storage_operations_total",
+			Help: "Number of storage operations, by operation and result.",
+		}, []string{"op", "result"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "storage_operation_duration_seconds",
+			Help:    "Duration of storage operations.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"op"}),
+		bytesUploaded: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "storage_bytes_uploaded_total",
+			Help: "Number of bytes uploaded to the storage.",
+		}),
+		bytesDownloaded: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "storage_bytes_downloaded_total",
+			Help: "Number of bytes retrieved from the storage.",
+		}),
+		objects: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "storage_bucket_objects",
+			Help: "Number of objects in the managed buckets, refreshed periodically.",
+		}, []string{"bucket"}),
+	}
+
+	for _, collector := range []prometheus.Collector{m.operations, m.duration, m.bytesUploaded, m.bytesDownloaded, m.objects} {
+		if err := registerer.Register(collector); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func (m *Metrics) observe(operation string, start time.Time, err error) {
+	if m == nil {
+		return
+	}
+	result := resultSuccess
+	if errors.Is(err, ErrNotFound) {
+		result = resultNotFound
+	} else if err != nil {
+		result = resultError
+	}
+	m.operations.WithLabelValues(operation, result).Inc()
+	m.duration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
+
+func (m *Metrics) addBytesUploaded(size int64) {
+	if m == nil {
+		return
+	}
+	m.bytesUploaded.Add(float64(size))
+}
+
+func (m *Metrics) addBytesDownloaded(size int64) {
+	if m == nil {
+		return
+	}
+	m.bytesDownloaded.Add(float64(size))
+}
+
+// RefreshObjectCounts counts the objects of each bucket and updates the object count gauge.
+func (s *Storage) RefreshObjectCounts(bucketNames []string, ctx context.Context) error {
+	if s.metrics == nil {
+		return nil
+	}
+	for _, bucketName := range bucketNames {
+		count := 0
+		for info := range s.client.ListObjects(ctx, bucketName, minio.ListObjectsOptions{Prefix: s.keyPrefix, Recursive: true}) {
+			if info.Err != nil {
+				return translateError(info.Err)
+			}
+			// the prefix alone also matches the deduplicated payloads and, with an extension, foreign keys
+			if _, ok := s.objectNameFromKey(info.Key); !ok {
+				continue
+			}
+			count++
+		}
+		s.metrics.objects.WithLabelValues(bucketName).Set(float64(count))
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// maxMigrationFailures bounds the failures listed by a migration progress, the following ones are only counted.
+const maxMigrationFailures = 100
+
+// MigrationProgress reports the progress of a migration job.
+type MigrationProgress struct {
+	BucketName    string             `json:"bucketName"`
+	FromExtension string             `json:"fromExtension"`
+	Objects       uint64             `json:"objects"`
+	Processed     uint64             `json:"processed"`
+	Migrated      uint64             `json:"migrated"`
+	Skipped       uint64             `json:"skipped"`
+	Failed        uint64             `json:"failed"`
+	Failures      []MigrationFailure `json:"failures,omitempty"`
+}
+
+// MigrationFailure is an object a migration couldn't rewrite.
+type MigrationFailure struct {
+	Key   string `json:"key"`
+	Error string `json:"error"`
+}
+
+// migrationKey is a key in the scope of a migration.
+type migrationKey struct {
+	key  string
+	name string
+	// outdated is set for keys with fromExtension, to be moved to the configured extension
+	outdated bool
+	// internal is set for the deduplicated payloads and the attachment manifests, which are only moved
+	internal bool
+}
+
+// Migrate rewrites the objects of a bucket stored with fromExtension, or with another encoding, to the configured
+// extension and encoding, until done or ctx is cancelled. An object is written under its new key before the old
+// one is removed, so an interrupted migration can be run again: objects already migrated are skipped.
+func (s *Storage) Migrate(bucketName string, fromExtension string, ctx context.Context, report func(progress any)) error {
+	s.WrappedLogger.LogInfof("Migrating bucket '%s' from extension '%s' ...", bucketName, fromExtension)
+	ctx = ContextWithOrigin(ctx, OriginMigration)
+	progress := MigrationProgress{BucketName: bucketName, FromExtension: fromExtension}
+
+	keys, err := s.migrationKeys(bucketName, fromExtension, ctx)
+	if err != nil {
+		s.WrappedLogger.LogErrorf("Migrating bucket '%s' from extension '%s' ... failed, error: %w", bucketName, fromExtension, err)
+		return err
+	}
+	progress.Objects = uint64(len(keys))
+	report(progress)
+
+	for _, k := range keys {
+		if ctx.Err() != nil {
+			s.WrappedLogger.LogInfof("Migrating bucket '%s' from extension '%s' ... cancelled", bucketName, fromExtension)
+			return ctx.Err()
+		}
+		migrated, err := s.migrateKey(bucketName, k, ctx)
+		progress.Processed++
+		switch {
+		case err != nil:
+			progress.Failed++
+			if len(progress.Failures) < maxMigrationFailures {
+				// appending never modifies the elements of the progress values already reported
+				progress.Failures = append(progress.Failures, MigrationFailure{Key: k.key, Error: err.Error()})
+			}
+			s.WrappedLogger.LogWarnf("Migrating object '%s' of bucket '%s' ... failed, error: %w", k.key, bucketName, err)
+		case migrated:
+			progress.Migrated++
+		default:
+			progress.Skipped++
+		}
+		report(progress)
+	}
+
+	s.WrappedLogger.LogInfof("Migrating bucket '%s' from extension '%s' ... done, %d migrated, %d failed", bucketName, fromExtension, progress.Migrated, progress.Failed)
+	return nil
+}
+
+// migrationKeys lists the keys of a bucket in the scope of a migration, the deduplicated payloads and the attachment
+// manifests first, since the objects pointing to them can only be read once they have been moved.
+func (s *Storage) migrationKeys(bucketName string, fromExtension string, ctx context.Context) ([]migrationKey, error) {
+	var internal, objects []migrationKey
+	for info := range s.client.ListObjects(ctx, bucketName, minio.ListObjectsOptions{Prefix: s.keyPrefix, Recursive: true}) {
+		if info.Err != nil {
+			return nil, translateError(info.Err)
+		}
+		k, ok := s.parseMigrationKey(info.Key, fromExtension)
+		if !ok {
+			continue
+		}
+		if isInternalName(k.name) {
+			k.internal = true
+			if k.outdated {
+				internal = append(internal, k)
+			}
+			continue
+		}
+		objects = append(objects, k)
+	}
+	return append(internal, objects...), nil
+}
+
+// parseMigrationKey returns the object name of a key with either extension, preferring the longest one that matches.
+func (s *Storage) parseMigrationKey(key string, fromExtension string) (migrationKey, bool) {
+	if !strings.HasPrefix(key, s.keyPrefix) {
+		return migrationKey{}, false
+	}
+	current := strings.HasSuffix(key, s.objectExtension)
+	outdated := strings.HasSuffix(key, fromExtension) && fromExtension != s.objectExtension
+	if current && outdated {
+		outdated = len(fromExtension) > len(s.objectExtension)
+		current = !outdated
+	}
+
+	extension := s.objectExtension
+	if outdated {
+		extension = fromExtension
+	} else if !current {
+		return migrationKey{}, false
+	}
+	name := strings.TrimSuffix(strings.TrimPrefix(key, s.keyPrefix), extension)
+	if name == "" {
+		return migrationKey{}, false
+	}
+	return migrationKey{key: key, name: name, outdated: outdated}, true
+}
+
+// migrateKey moves a key to the configured extension and rewrites it with the configured encoding if needed,
+// returning whether anything changed.
+func (s *Storage) migrateKey(bucketName string, k migrationKey, ctx context.Context) (bool, error) {
+	newKey := s.objectKey(k.name)
+	if k.outdated {
+		// a previous run wrote the new key but didn't remove the old one
+		_, err := s.client.StatObject(ctx, bucketName, newKey, minio.StatObjectOptions{})
+		err = translateError(err)
+		if err == nil {
+			return true, translateError(s.client.RemoveObject(ctx, bucketName, k.key, minio.RemoveObjectOptions{}))
+		}
+		if !errors.Is(err, ErrNotFound) {
+			return false, err
+		}
+	}
+
+	reencoded := false
+	if !k.internal {
+		var err error
+		reencoded, err = s.reencode(bucketName, k, ctx)
+		if err != nil {
+			return false, err
+		}
+	}
+	if !k.outdated {
+		return reencoded, nil
+	}
+	if !reencoded {
+		// server side copy, the deduplication pointers and the metadata are kept as they are
+		_, err := s.client.CopyObject(ctx, minio.CopyDestOptions{Bucket: bucketName, Object: newKey}, minio.CopySrcOptions{Bucket: bucketName, Object: k.key})
+		if err != nil {
+			return false, translateError(err)
+		}
+	}
+	return true, translateError(s.client.RemoveObject(ctx, bucketName, k.key, minio.RemoveObjectOptions{}))
+}
+
+// reencode uploads an object under its name with the configured encoding, if it is stored with another one.
+func (s *Storage) reencode(bucketName string, k migrationKey, ctx context.Context) (bool, error) {
+	// listings don't carry the content type
+	info, err := s.client.StatObject(ctx, bucketName, k.key, minio.StatObjectOptions{})
+	if err != nil {
+		return false, translateError(err)
+	}
+	switch {
+	case info.ContentType == ContentTypeBinary && s.storeEncoding == EncodingBinary:
+		return false, nil
+	case info.ContentType == ContentTypeJSON && s.storeEncoding == EncodingJSON:
+		return false, nil
+	case info.ContentType != ContentTypeBinary && info.ContentType != ContentTypeJSON:
+		// not a block, e.g. a large object stored as read
+		return false, nil
+	}
+
+	object, err := s.getObjectByKey(bucketName, k.key, ctx)
+	if err != nil {
+		return false, err
+	}
+	defer object.Close()
+	decoded, err := object.Decode()
+	if err != nil {
+		return false, err
+	}
+	// only bare blocks have a binary encoding
+	_, contentType, err := decoded.Encode(s.storeEncoding)
+	if err != nil || contentType == info.ContentType {
+		return false, err
+	}
+	return true, s.UploadObjectWithRetention(k.name, bucketName, decoded, s.DefaultRetention(), ctx)
+}
+
+// getObjectByKey retrieves an object by its storage key, following a deduplication pointer.
+func (s *Storage) getObjectByKey(bucketName string, key string, ctx context.Context) (*ObjectReader, error) {
+	object, err := s.client.GetObject(ctx, bucketName, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, translateError(err)
+	}
+	if ref, ok := contentRef(object.Info); ok {
+		object.Close()
+		return s.GetObject(bucketName, ref, "", ctx)
+	}
+	return object, nil
+}
@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// keys returns the sorted keys of a bucket.
+func keys(t *testing.T, backend *MemoryBackend, bucketName string) []string {
+	t.Helper()
+	var keys []string
+	for info := range backend.ListObjects(context.Background(), bucketName, minio.ListObjectsOptions{Recursive: true}) {
+		if info.Err != nil {
+			t.Fatalf("can't list the keys: %v", info.Err)
+		}
+		keys = append(keys, info.Key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestMigrate(t *testing.T) {
+	backend := NewMemoryBackend()
+	old := newTestStorageWithBackend(t, backend, func(params *Parameters) {
+		params.ObjectExtension = ".json"
+		params.DedupEnabled = true
+	})
+	ctx := context.Background()
+	if err := old.UploadObject("block", old.DefaultBucketName, Object{Block: testBlock("migrated")}, ctx); err != nil {
+		t.Fatalf("can't upload the block: %v", err)
+	}
+	if err := old.UploadObject("payload", old.DefaultBucketName, taggedDataObject("migrate", "payload"), ctx); err != nil {
+		t.Fatalf("can't upload the payload: %v", err)
+	}
+
+	s := newTestStorageWithBackend(t, backend, func(params *Parameters) {
+		params.StoreEncoding = EncodingBinary
+		params.DedupEnabled = true
+	})
+	migrate := func() MigrationProgress {
+		t.Helper()
+		var progress MigrationProgress
+		if err := s.Migrate(s.DefaultBucketName, ".json", ctx, func(p any) { progress = p.(MigrationProgress) }); err != nil {
+			t.Fatalf("can't migrate the bucket: %v", err)
+		}
+		return progress
+	}
+
+	// the deduplicated payloads are moved before the pointers to them
+	if contents := contentKeys(t, backend, s.DefaultBucketName); len(contents) != 2 || !strings.HasSuffix(contents[0], ".json") {
+		t.Fatalf("got payloads %v, expected both stored with the old extension", contents)
+	}
+	progress := migrate()
+	if progress.Failed != 0 || progress.Migrated != progress.Objects || progress.Processed != progress.Objects {
+		t.Fatalf("got progress %+v, expected every object migrated", progress)
+	}
+	for _, key := range keys(t, backend, s.DefaultBucketName) {
+		if strings.HasSuffix(key, ".json") {
+			t.Errorf("key '%s' still has the old extension", key)
+		}
+	}
+	info, err := s.GetObjectInfo(s.DefaultBucketName, "block", "", ctx)
+	if err != nil || info.ContentType != ContentTypeBinary {
+		t.Errorf("got content type '%s', error %v, expected the block encoded in binary", info.ContentType, err)
+	}
+	if data := getData(t, s, s.DefaultBucketName, "payload"); data != "payload" {
+		t.Errorf("got data '%s', expected 'payload'", data)
+	}
+
+	// running it again has nothing left to do
+	if progress := migrate(); progress.Migrated != 0 || progress.Failed != 0 || progress.Skipped != progress.Objects {
+		t.Errorf("got progress %+v running the migration again, expected every object skipped", progress)
+	}
+}
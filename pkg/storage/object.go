@@ -3,16 +3,43 @@ package storage
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"io"
+	"net/url"
+	"strings"
 
+	"github.com/iotaledger/hive.go/serializer/v2"
 	iotago "github.com/iotaledger/iota.go/v3"
 	"github.com/iotaledger/iota.go/v3/merklehasher"
 )
 
+const (
+	// EncodingJSON stores objects as JSON documents.
+	EncodingJSON = "json"
+	// EncodingBinary stores bare blocks with their IOTA binary serialization, other objects are still stored as JSON.
+	EncodingBinary = "binary"
+
+	ContentTypeJSON   = "application/json"
+	ContentTypeBinary = "application/octet-stream"
+
+	// MetadataProofOfInclusion is set on the objects stored along with their Proof of Inclusion.
+	MetadataProofOfInclusion = "Proof-Of-Inclusion"
+)
+
+// Object is the document stored for a block. Depending on the filter's store format only the block (with its POI),
+// the tagged data payload or the signed data plaintext are set.
 type Object struct {
-	Milestone *iotago.Milestone   `json:"milestone,omitempty"`
-	Block     *iotago.Block       `json:"block"`
-	Proof     *merklehasher.Proof `json:"proof,omitempty"`
+	Milestone  *iotago.Milestone   `json:"milestone,omitempty"`
+	Block      *iotago.Block       `json:"block,omitempty"`
+	Proof      *merklehasher.Proof `json:"proof,omitempty"`
+	TaggedData *iotago.TaggedData  `json:"taggedData,omitempty"`
+	Data       []byte              `json:"data,omitempty"`
+	// Alias is an external key resolving to the block, it is indexed rather than stored in the document
+	Alias string `json:"-"`
+	// IndexTags are secondary tags the block can be listed by, they are indexed rather than stored in the document
+	IndexTags []string `json:"-"`
+	// PayloadContentType is the content type of the payload, given or detected, recorded in the object's metadata
+	PayloadContentType string `json:"-"`
 }
 
 func NewObject(reader io.Reader) (Object, error) {
@@ -41,3 +68,68 @@ func (o *Object) GetByteReader() (*bytes.Reader, error) {
 	blockReader = bytes.NewReader(objectJson)
 	return blockReader, nil
 }
+
+// isBareBlock returns whether the object holds nothing but a block, the only content with a binary encoding.
+func (o *Object) isBareBlock() bool {
+	return o.Block != nil && o.Milestone == nil && o.Proof == nil && o.TaggedData == nil && o.Data == nil
+}
+
+// userMetadata returns the user metadata describing the object in the storage.
+func (o *Object) userMetadata() map[string]string {
+	metadata := make(map[string]string)
+	if o.Proof != nil {
+		metadata[MetadataProofOfInclusion] = "true"
+	}
+	if o.Alias != "" {
+		// user metadata travels in headers, which only carry ASCII
+		metadata[MetadataAlias] = url.QueryEscape(o.Alias)
+	}
+	if len(o.IndexTags) > 0 {
+		escaped := make([]string, 0, len(o.IndexTags))
+		for _, tag := range o.IndexTags {
+			escaped = append(escaped, url.QueryEscape(tag))
+		}
+		metadata[MetadataIndexTags] = strings.Join(escaped, ",")
+	}
+	if o.PayloadContentType != "" {
+		metadata[MetadataPayloadContentType] = o.PayloadContentType
+	}
+	return metadata
+}
+
+// Encode serializes the object with the given encoding, returning the bytes and their content type.
+func (o *Object) Encode(encoding string) (*bytes.Reader, string, error) {
+	if encoding == EncodingBinary && o.isBareBlock() {
+		blockBytes, err := o.Block.Serialize(serializer.DeSeriModeNoValidation, &iotago.ProtocolParameters{})
+		if err != nil {
+			return nil, "", err
+		}
+		return bytes.NewReader(blockBytes), ContentTypeBinary, nil
+	}
+
+	objectReader, err := o.GetByteReader()
+	if err != nil {
+		return nil, "", err
+	}
+	return objectReader, ContentTypeJSON, nil
+}
+
+// DecodeObject reads an object stored with the encoding matching its content type.
+func DecodeObject(reader io.Reader, contentType string) (Object, error) {
+	if contentType != ContentTypeBinary {
+		return NewObject(reader)
+	}
+
+	var object Object
+	blockBytes, err := io.ReadAll(reader)
+	if err != nil {
+		return object, err
+	}
+	block := &iotago.Block{}
+	_, err = block.Deserialize(blockBytes, serializer.DeSeriModeNoValidation, &iotago.ProtocolParameters{})
+	if err != nil {
+		return object, fmt.Errorf("can't decode binary block, error: %w", err)
+	}
+	object.Block = block
+	return object, nil
+}
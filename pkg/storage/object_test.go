@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/iotaledger/hive.go/serializer/v2"
+	iotago "github.com/iotaledger/iota.go/v3"
+	"github.com/minio/minio-go/v7"
+)
+
+func testBlock(data string) *iotago.Block {
+	return &iotago.Block{
+		ProtocolVersion: 2,
+		Parents:         iotago.BlockIDs{{}},
+		Payload:         &iotago.TaggedData{Tag: []byte("encoding"), Data: []byte(data)},
+	}
+}
+
+func TestBinaryEncoding(t *testing.T) {
+	backend := NewMemoryBackend()
+	s := newTestStorageWithBackend(t, backend, func(params *Parameters) {
+		params.StoreEncoding = EncodingBinary
+	})
+	ctx := context.Background()
+	block := testBlock("binary")
+	if err := s.UploadObject("block", s.DefaultBucketName, Object{Block: block}, ctx); err != nil {
+		t.Fatalf("can't upload the block: %v", err)
+	}
+	// a payload only has no binary encoding
+	if err := s.UploadObject("payload", s.DefaultBucketName, taggedDataObject("encoding", "json"), ctx); err != nil {
+		t.Fatalf("can't upload the payload: %v", err)
+	}
+
+	expected, err := block.Serialize(serializer.DeSeriModeNoValidation, &iotago.ProtocolParameters{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		objectName  string
+		contentType string
+	}{
+		{"block", ContentTypeBinary},
+		{"payload", ContentTypeJSON},
+	} {
+		info, err := backend.StatObject(ctx, s.DefaultBucketName, s.objectKey(tc.objectName), minio.StatObjectOptions{})
+		if err != nil || info.ContentType != tc.contentType {
+			t.Errorf("object '%s': got content type '%s', error %v, expected '%s'", tc.objectName, info.ContentType, err, tc.contentType)
+		}
+	}
+
+	// the objects are read whatever the configured encoding, buckets may hold both
+	jsonStorage := newTestStorageWithBackend(t, backend, nil)
+	reader, err := jsonStorage.GetObject(s.DefaultBucketName, "block", "", ctx)
+	if err != nil {
+		t.Fatalf("can't get the block: %v", err)
+	}
+	defer reader.Close()
+	object, err := reader.Decode()
+	if err != nil {
+		t.Fatalf("can't decode the block: %v", err)
+	}
+	if object.Block == nil {
+		t.Fatal("got no block")
+	}
+	decoded, err := object.Block.Serialize(serializer.DeSeriModeNoValidation, &iotago.ProtocolParameters{})
+	if err != nil || !bytes.Equal(decoded, expected) {
+		t.Errorf("got block %x, error %v, expected %x", decoded, err, expected)
+	}
+	if data := getData(t, jsonStorage, s.DefaultBucketName, "payload"); data != "json" {
+		t.Errorf("got data '%s', expected 'json'", data)
+	}
+}
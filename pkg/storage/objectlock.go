@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// Retention defines the object lock applied to an uploaded object.
+// Object lock requires the bucket to have been created with locking enabled.
+type Retention struct {
+	Days      int
+	LegalHold bool
+}
+
+func (r Retention) isSet() bool {
+	return r.Days > 0 || r.LegalHold
+}
+
+type objectLock struct {
+	enabled bool
+	mode    minio.RetentionMode
+	// default retention applied when a request doesn't specify one
+	retention Retention
+	// buckets already verified to have object locking enabled
+	lockedBuckets *sync.Map
+}
+
+func newObjectLock(params Parameters) (objectLock, error) {
+	lock := objectLock{
+		enabled:       params.ObjectLockEnabled,
+		mode:          minio.RetentionMode(params.RetentionMode),
+		retention:     Retention{Days: params.RetentionDays, LegalHold: params.LegalHold},
+		lockedBuckets: &sync.Map{},
+	}
+
+	if !lock.enabled {
+		if lock.retention.isSet() {
+			return lock, fmt.Errorf("retentionDays and legalHold require objectLockEnabled")
+		}
+		return lock, nil
+	}
+	if !lock.mode.IsValid() {
+		return lock, fmt.Errorf("invalid retention mode '%s', must be %s or %s", params.RetentionMode, minio.Governance, minio.Compliance)
+	}
+	if lock.retention.Days < 0 {
+		return lock, fmt.Errorf("retentionDays must not be negative")
+	}
+	return lock, nil
+}
+
+// DefaultRetention returns the retention configured for every upload.
+func (s *Storage) DefaultRetention() Retention {
+	return s.objectLock.retention
+}
+
+// CheckObjectLock verifies that the bucket was created with object locking enabled.
+func (s *Storage) CheckObjectLock(bucketName string, ctx context.Context) error {
+	if _, ok := s.objectLock.lockedBuckets.Load(bucketName); ok {
+		return nil
+	}
+
+	objectLock, _, _, _, err := s.client.GetObjectLockConfig(ctx, bucketName)
+	if err != nil {
+		return fmt.Errorf("can't read object lock configuration of bucket '%s', error: %w", bucketName, err)
+	}
+	if objectLock != "Enabled" {
+		return fmt.Errorf("bucket '%s' was not created with object locking enabled", bucketName)
+	}
+
+	s.objectLock.lockedBuckets.Store(bucketName, struct{}{})
+	return nil
+}
+
+func (s *Storage) applyRetention(opts *minio.PutObjectOptions, bucketName string, retention Retention, ctx context.Context) error {
+	if !retention.isSet() {
+		return nil
+	}
+	if !s.objectLock.enabled {
+		return fmt.Errorf("object lock is not enabled")
+	}
+
+	err := s.CheckObjectLock(bucketName, ctx)
+	if err != nil {
+		return err
+	}
+
+	if retention.Days > 0 {
+		opts.Mode = s.objectLock.mode
+		opts.RetainUntilDate = time.Now().UTC().AddDate(0, 0, retention.Days)
+	}
+	if retention.LegalHold {
+		opts.LegalHold = minio.LegalHoldEnabled
+	}
+	return nil
+}
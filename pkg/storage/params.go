@@ -1,28 +1,147 @@
 package storage
 
+import "time"
+
 // ParametersRestAPI contains the definition of the parameters used by the Collector to access the S3 storage
 type Parameters struct {
+	// InMemory defines whether blocks are kept in memory instead of an S3 storage, for local development only
+	InMemory bool `default:"false" usage:"whether blocks are kept in memory instead of an S3 storage, for local development only"`
+
 	// Endpoint defines the endpoint for the S3 storage
 	Endpoint string `default:"" usage:"the storage endpoint"`
 
+	// CredentialsMode defines how the credentials for the S3 storage are obtained (static, anonymous, iam, sts-web-identity, env)
+	CredentialsMode string `default:"static" usage:"how the credentials for the storage are obtained (static, anonymous, iam, sts-web-identity, env)"`
+
 	// AccessId defines the access id for the S3 storage
 	AccessKeyID string `default:"" usage:"the access id for the storage"`
 
 	// Password defines the password for the given access id of the S3 storage
 	SecretAccessKey string `default:"" usage:"the password for the given access id"`
 
+	// STSEndpoint defines the STS endpoint used by the sts-web-identity credentials mode
+	STSEndpoint string `default:"" usage:"the STS endpoint used by the sts-web-identity credentials mode"`
+
+	// WebIdentityTokenFile defines the path of the web identity token used by the sts-web-identity credentials mode
+	WebIdentityTokenFile string `default:"" usage:"the path of the web identity token used by the sts-web-identity credentials mode"`
+
 	// DefaultBucketName sets the default bucket's name
 	DefaultBucketName string `default:"shimmer-mainnet-default" usage:"sets the default bucket's name"`
 
 	// DefaultBucketExpirationDays sets the default bucket's expiration days
 	DefaultBucketExpirationDays int `default:"30" usage:"sets the default bucket's expiration days"`
 
+	// POIBucketName sets the bucket where the Proofs of Inclusion are kept apart from the blocks, disabled if empty
+	POIBucketName string `default:"" usage:"the bucket where the proofs of inclusion are kept apart from the blocks, they are stored along with the blocks if empty"`
+
+	// POIBucketExpirationDays sets the POI bucket's expiration days
+	POIBucketExpirationDays int `default:"30" usage:"sets the POI bucket's expiration days"`
+
+	// EventsBucketName sets the bucket where the storage mutations are logged, disabled if empty
+	EventsBucketName string `default:"" usage:"the bucket where the stored and deleted blocks are logged as events, the event log is disabled if empty"`
+
+	// EventsBucketExpirationDays sets the events bucket's expiration days
+	EventsBucketExpirationDays int `default:"30" usage:"sets the events bucket's expiration days"`
+
+	// EventsQueueSize sets the number of events waiting to be written to the log before the stores wait for it
+	EventsQueueSize int `default:"10000" usage:"the number of events waiting to be written to the log before the stores wait for it"`
+
+	// VersioningEnabled defines whether buckets created by the Collector are versioned and object versions are exposed
+	VersioningEnabled bool `default:"false" usage:"whether buckets created by the collector are versioned and object versions are exposed"`
+
+	// ObjectLockEnabled defines whether buckets created by the Collector have object locking (WORM) enabled
+	ObjectLockEnabled bool `default:"false" usage:"whether buckets created by the collector have object locking (WORM) enabled"`
+
+	// RetentionMode defines the object lock retention mode (GOVERNANCE or COMPLIANCE)
+	RetentionMode string `default:"GOVERNANCE" usage:"the object lock retention mode (GOVERNANCE or COMPLIANCE)"`
+
+	// RetentionDays defines the default number of days stored objects are locked for, 0 means no retention
+	RetentionDays int `default:"0" usage:"the default number of days stored objects are locked for, 0 means no retention"`
+
+	// LegalHold defines whether stored objects are placed under legal hold by default
+	LegalHold bool `default:"false" usage:"whether stored objects are placed under legal hold by default"`
+
 	// Region defines the region of the S3 storage
 	Region string `default:"eu-south-1" usage:"defines the region of the S3 storage"`
 
 	// ObjectExtension sets the file extension for the object inside the storage
 	ObjectExtension string `default:"" usage:"sets the file extension for the object inside the storage"`
 
+	// StoreEncoding sets how blocks are encoded inside the storage
+	StoreEncoding string `default:"json" usage:"how blocks are encoded inside the storage: json or binary, objects with a proof of inclusion or a payload only are always json"`
+
+	// KeyPrefix sets a prefix prepended to every object name inside the storage, to namespace datasets sharing a bucket
+	KeyPrefix string `default:"" usage:"sets a prefix prepended to every object name inside the storage"`
+
+	// DedupEnabled defines whether identical payloads are stored once, with the objects pointing to them
+	DedupEnabled bool `default:"false" usage:"whether identical payloads are stored once, with the objects pointing to them"`
+
+	// DetectContentType defines whether the content type of the payloads is detected and recorded with their objects
+	DetectContentType bool `default:"false" usage:"whether the content type of the payloads is detected and recorded in the Payload-Content-Type metadata of their objects"`
+
 	// Secure defines whether the connection to S3 storage should be secure
 	Secure bool `default:"true" usage:"whether the connection to storage should be secure"`
+
+	// DialTimeout defines how long connecting to the S3 storage may take
+	DialTimeout time.Duration `default:"30s" usage:"how long connecting to the storage may take"`
+
+	// TLSHandshakeTimeout defines how long the TLS handshake with the S3 storage may take
+	TLSHandshakeTimeout time.Duration `default:"10s" usage:"how long the TLS handshake with the storage may take"`
+
+	// ResponseHeaderTimeout defines how long the S3 storage may take to answer a request, once sent
+	ResponseHeaderTimeout time.Duration `default:"1m" usage:"how long the storage may take to answer a request, once sent"`
+
+	// MaxRetries defines how many times a failed request to the S3 storage is attempted
+	MaxRetries int `default:"10" usage:"how many times a failed request to the storage is attempted, 1 disables retries"`
+
+	// ObjectCountInterval defines how often the object count metric of the managed buckets is refreshed, 0 disables it
+	ObjectCountInterval time.Duration `default:"5m" usage:"how often the object count metric of the managed buckets is refreshed, 0 disables it"`
+
+	// PingMaxAttempts defines how many times the storage connectivity is checked at startup before giving up
+	PingMaxAttempts int `default:"5" usage:"how many times the storage connectivity is checked at startup before giving up"`
+
+	// PingInterval defines the initial interval between startup connectivity checks, doubled after every failed attempt
+	PingInterval time.Duration `default:"2s" usage:"the initial interval between startup connectivity checks, doubled after every failed attempt"`
+
+	// ClientCheckInterval defines how often the storage connectivity is checked while running, 0 disables it
+	ClientCheckInterval time.Duration `default:"1m" usage:"how often the storage connectivity is checked while running, 0 disables it"`
+
+	// ClientReloadAfterFailures defines after how many consecutive failed checks the storage client is rebuilt
+	ClientReloadAfterFailures int `default:"3" usage:"after how many consecutive failed connectivity checks the storage client is rebuilt with fresh credentials and connections"`
+
+	// BucketPolicies defines the policies applied to buckets when they are created
+	BucketPolicies string `default:"" usage:"the policies applied to buckets when they are created, as a JSON object mapping bucket names to policy documents"`
+
+	// PartitionTemplate defines the buckets blocks are stored into, by the time their milestone referenced them
+	PartitionTemplate string `default:"" usage:"the bucket name template of time partitioned buckets, with the placeholders {bucket}, {yyyy}, {mm} and {dd}, disabled if empty"`
+
+	// BatchMaxCount defines how many small objects are written together as one compressed archive, 0 disables batching
+	BatchMaxCount int `default:"0" usage:"how many small blocks stored by the listener are written together as one compressed archive, 0 disables batching"`
+
+	// BatchMaxBytes defines the compressed size at which a batch is written
+	BatchMaxBytes int `default:"1048576" usage:"the compressed size in bytes at which a batch is written"`
+
+	// BatchMaxAge defines how long objects wait in a batch before it is written
+	BatchMaxAge time.Duration `default:"30s" usage:"how long blocks wait in a batch before it is written"`
+
+	// BatchMaxObjectSize defines the size up to which an object is batched
+	BatchMaxObjectSize int `default:"4096" usage:"the size in bytes up to which a block is batched, larger ones are stored on their own"`
+
+	// WatchDeletions defines whether the deletions made outside of the collector in the default bucket are followed
+	WatchDeletions bool `default:"false" usage:"whether to listen to the deletion notifications of the default bucket, to detect the blocks deleted outside of the collector (MinIO only)"`
+
+	// QuotaMaxObjects defines how many blocks a bucket can hold, 0 disables the limit
+	QuotaMaxObjects int64 `default:"0" usage:"how many blocks a bucket can hold before new stores are refused, 0 disables the limit"`
+
+	// QuotaMaxBytes defines how many bytes of blocks a bucket can hold, 0 disables the limit
+	QuotaMaxBytes int64 `default:"0" usage:"how many bytes of blocks a bucket can hold before new stores are refused, 0 disables the limit"`
+
+	// QuotaSoftPercent defines the share of a quota at which a warning is logged
+	QuotaSoftPercent int `default:"80" usage:"the percentage of a bucket quota at which a warning is logged, 0 disables the warning"`
+
+	// QuotaReconcileInterval defines how often the tracked bucket usages are recounted
+	QuotaReconcileInterval time.Duration `default:"10m" usage:"how often the usage of the buckets is recounted with a listing, 0 disables it"`
+
+	// PartSize defines the size of the parts of multipart uploads, at least 5MiB
+	PartSize uint64 `default:"16777216" usage:"the size in bytes of the parts of multipart uploads, at least 5MiB"`
 }
@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// the placeholders of a partition template, dates are in UTC
+const (
+	partitionBucket = "{bucket}"
+	partitionYear   = "{yyyy}"
+	partitionMonth  = "{mm}"
+	partitionDay    = "{dd}"
+)
+
+func validatePartitionTemplate(template string) error {
+	if template != "" && !strings.Contains(template, partitionBucket) {
+		return fmt.Errorf("partition template '%s' must contain %s", template, partitionBucket)
+	}
+	return nil
+}
+
+// Partitioned returns whether blocks are stored in time partitioned buckets.
+func (s *Storage) Partitioned() bool {
+	return s.partitionTemplate != ""
+}
+
+// PartitionBucketName returns the bucket holding the blocks of bucketName referenced at t.
+func (s *Storage) PartitionBucketName(bucketName string, t time.Time) string {
+	if !s.Partitioned() {
+		return bucketName
+	}
+	t = t.UTC()
+	return strings.NewReplacer(
+		partitionBucket, bucketName,
+		partitionYear, fmt.Sprintf("%04d", t.Year()),
+		partitionMonth, fmt.Sprintf("%02d", t.Month()),
+		partitionDay, fmt.Sprintf("%02d", t.Day()),
+	).Replace(s.partitionTemplate)
+}
+
+// EnsurePartition returns the partition of bucketName for t, creating it with the default expiration if needed.
+func (s *Storage) EnsurePartition(bucketName string, t time.Time, ctx context.Context) (string, error) {
+	partition := s.PartitionBucketName(bucketName, t)
+	if partition == bucketName {
+		return bucketName, nil
+	}
+	if _, ok := s.partitions.Load(partition); ok {
+		return partition, nil
+	}
+
+	exists, err := s.CheckCreateBucket(partition, ctx)
+	if err != nil {
+		return "", fmt.Errorf("can't create partition '%s', error: %w", partition, err)
+	}
+	if !exists {
+		err = s.SetBucketExpirationDays(partition, s.DefaultBucketExpirationDays, ctx)
+		if err != nil {
+			return "", err
+		}
+	}
+	s.partitions.Store(partition, struct{}{})
+	return partition, nil
+}
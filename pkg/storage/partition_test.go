@@ -0,0 +1,36 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPartitions(t *testing.T) {
+	s, _ := newTestStorage(t, func(params *Parameters) {
+		params.PartitionTemplate = "{bucket}-{yyyy}-{mm}-{dd}"
+		params.DefaultBucketExpirationDays = 7
+	})
+	ctx := context.Background()
+	// dates are taken in UTC
+	at := time.Date(2023, time.March, 9, 23, 30, 0, 0, time.FixedZone("UTC-2", -2*60*60))
+
+	if name := s.PartitionBucketName("blocks", at); name != "blocks-2023-03-10" {
+		t.Errorf("got partition '%s', expected 'blocks-2023-03-10'", name)
+	}
+	partition, err := s.EnsurePartition("blocks", at, ctx)
+	if err != nil || partition != "blocks-2023-03-10" {
+		t.Fatalf("got partition '%s', error %v, expected 'blocks-2023-03-10'", partition, err)
+	}
+	if days, err := s.GetBucketExpirationDays(partition, ctx); err != nil || days != 7 {
+		t.Errorf("got %d expiration days, error %v, expected the default 7", days, err)
+	}
+
+	unpartitioned, _ := newTestStorage(t, nil)
+	if name := unpartitioned.PartitionBucketName("blocks", at); name != "blocks" {
+		t.Errorf("got bucket '%s' without partitions, expected 'blocks'", name)
+	}
+	if err := validatePartitionTemplate("blocks-{yyyy}"); err == nil {
+		t.Error("a template without the bucket placeholder was accepted")
+	}
+}
@@ -0,0 +1,176 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/s3utils"
+)
+
+// pinnedBucketSuffix names the bucket holding the pinned objects of a bucket. An S3 lifecycle rule can't
+// exempt single objects, so pinned objects are copied to a companion bucket without lifecycle and reads
+// fall back to it once the original expires.
+const pinnedBucketSuffix = "-pinned"
+
+func pinnedBucketName(bucketName string) string {
+	return bucketName + pinnedBucketSuffix
+}
+
+func isPinnedBucket(bucketName string) bool {
+	return strings.HasSuffix(bucketName, pinnedBucketSuffix)
+}
+
+// PinObject keeps an object indefinitely, by copying its payload to the pinned bucket of its bucket.
+func (s *Storage) PinObject(bucketName string, objectName string, ctx context.Context) error {
+	if isPinnedBucket(bucketName) {
+		return fmt.Errorf("bucket '%s' already holds pinned objects", bucketName)
+	}
+	pinnedBucket := pinnedBucketName(bucketName)
+	err := s3utils.CheckValidBucketNameStrict(pinnedBucket)
+	if err != nil {
+		return fmt.Errorf("can't pin objects of bucket '%s', error: %w", bucketName, err)
+	}
+
+	object, err := s.GetObject(bucketName, objectName, "", ctx)
+	if err != nil {
+		return err
+	}
+	defer object.Close()
+
+	_, err = s.CheckCreateBucket(pinnedBucket, ctx)
+	if err != nil {
+		return err
+	}
+
+	s.WrappedLogger.LogInfof("Pinning object '%s' of bucket '%s' ...", objectName, bucketName)
+	_, err = s.client.PutObject(ctx, pinnedBucket, s.objectKey(objectName), object, object.Info.Size, minio.PutObjectOptions{ContentType: object.Info.ContentType, UserMetadata: object.Info.UserMetadata})
+	if err != nil {
+		s.WrappedLogger.LogErrorf("Pinning object '%s' of bucket '%s' ... failed, error: %w", objectName, bucketName, err)
+		return err
+	}
+	s.WrappedLogger.LogInfof("Pinning object '%s' of bucket '%s' ... done", objectName, bucketName)
+	return nil
+}
+
+// UnpinObject stores a pinned object back in its bucket, where its lifecycle starts over, and removes the pinned copy.
+func (s *Storage) UnpinObject(bucketName string, objectName string, ctx context.Context) error {
+	pinnedBucket := pinnedBucketName(bucketName)
+	object, err := s.client.GetObject(ctx, pinnedBucket, s.objectKey(objectName), minio.GetObjectOptions{})
+	err = translateError(err)
+	if err != nil {
+		return fmt.Errorf("object '%s' of bucket '%s' is not pinned: %w", objectName, bucketName, err)
+	}
+	defer object.Close()
+
+	s.WrappedLogger.LogInfof("Unpinning object '%s' of bucket '%s' ...", objectName, bucketName)
+	_, err = s.client.PutObject(ctx, bucketName, s.objectKey(objectName), object, object.Info.Size, minio.PutObjectOptions{ContentType: object.Info.ContentType, UserMetadata: object.Info.UserMetadata})
+	if err == nil {
+		err = s.client.RemoveObject(ctx, pinnedBucket, s.objectKey(objectName), minio.RemoveObjectOptions{})
+	}
+	if err != nil {
+		s.WrappedLogger.LogErrorf("Unpinning object '%s' of bucket '%s' ... failed, error: %w", objectName, bucketName, err)
+		return err
+	}
+	s.WrappedLogger.LogInfof("Unpinning object '%s' of bucket '%s' ... done", objectName, bucketName)
+	return nil
+}
+
+// removePinned drops the pinned copy of an object, if any.
+func (s *Storage) removePinned(bucketName string, objectName string, ctx context.Context) error {
+	if isPinnedBucket(bucketName) {
+		return nil
+	}
+	err := translateError(s.client.RemoveObject(ctx, pinnedBucketName(bucketName), s.objectKey(objectName), minio.RemoveObjectOptions{}))
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// maxPinFailures bounds the failures listed by a batch pin progress, the following ones are only counted.
+const maxPinFailures = 100
+
+// PinProgress reports the progress of a job pinning or unpinning a selection of objects.
+type PinProgress struct {
+	BucketName string       `json:"bucketName"`
+	Unpin      bool         `json:"unpin,omitempty"`
+	Processed  uint64       `json:"processed"`
+	Succeeded  uint64       `json:"succeeded"`
+	Failed     uint64       `json:"failed"`
+	Failures   []PinFailure `json:"failures,omitempty"`
+}
+
+// PinFailure is an object a batch pin couldn't pin or unpin.
+type PinFailure struct {
+	ObjectName string `json:"objectName"`
+	Error      string `json:"error"`
+}
+
+// PinObjects pins, or unpins, the objects of a bucket named in objectNames, or else every object whose name
+// starts with prefix, until done or ctx is cancelled. Pinning is idempotent, so an interrupted run can be run again.
+func (s *Storage) PinObjects(bucketName string, prefix string, objectNames []string, unpin bool, ctx context.Context, report func(progress any)) error {
+	action := "Pinning"
+	if unpin {
+		action = "Unpinning"
+	}
+	s.WrappedLogger.LogInfof("%s objects of bucket '%s' ...", action, bucketName)
+	progress := PinProgress{BucketName: bucketName, Unpin: unpin}
+	report(progress)
+
+	pin := func(objectName string) {
+		var err error
+		if unpin {
+			err = s.UnpinObject(bucketName, objectName, ctx)
+		} else {
+			err = s.PinObject(bucketName, objectName, ctx)
+		}
+		progress.Processed++
+		if err != nil {
+			progress.Failed++
+			if len(progress.Failures) < maxPinFailures {
+				// appending never modifies the elements of the progress values already reported
+				progress.Failures = append(progress.Failures, PinFailure{ObjectName: objectName, Error: err.Error()})
+			}
+		} else {
+			progress.Succeeded++
+		}
+		report(progress)
+	}
+
+	if len(objectNames) > 0 {
+		for _, objectName := range objectNames {
+			if ctx.Err() != nil {
+				break
+			}
+			pin(objectName)
+		}
+	} else {
+		// the pinned objects are listed from the pinned bucket, the ones to pin from the bucket itself
+		listedBucket := bucketName
+		if unpin {
+			listedBucket = pinnedBucketName(bucketName)
+		}
+		listCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		for info := range s.client.ListObjects(listCtx, listedBucket, minio.ListObjectsOptions{Prefix: s.keyPrefix + prefix, Recursive: true}) {
+			if info.Err != nil {
+				err := translateError(info.Err)
+				s.WrappedLogger.LogErrorf("%s objects of bucket '%s' ... failed, error: %w", action, bucketName, err)
+				return err
+			}
+			if objectName, ok := s.objectNameFromKey(info.Key); ok {
+				pin(objectName)
+			}
+		}
+	}
+	if ctx.Err() != nil {
+		s.WrappedLogger.LogInfof("%s objects of bucket '%s' ... cancelled", action, bucketName)
+		return ctx.Err()
+	}
+
+	s.WrappedLogger.LogInfof("%s objects of bucket '%s' ... done, %d succeeded, %d failed", action, bucketName, progress.Succeeded, progress.Failed)
+	return nil
+}
@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/minio/minio-go/v7"
+)
+
+func TestPinRoundTrip(t *testing.T) {
+	s, backend := newTestStorage(t, nil)
+	ctx := context.Background()
+	object := taggedDataObject("pin", "pinned")
+	object.Alias = "pinned-alias"
+	if err := s.UploadObject("x", s.DefaultBucketName, object, ctx); err != nil {
+		t.Fatalf("can't upload object 'x': %v", err)
+	}
+	assertAlias := func(bucketName string) {
+		t.Helper()
+		info, err := s.GetObjectInfo(bucketName, "x", "", ctx)
+		if err != nil {
+			t.Fatalf("can't stat object 'x' of bucket '%s': %v", bucketName, err)
+		}
+		if alias := aliasOf(info); alias != "pinned-alias" {
+			t.Errorf("got alias '%s' for object 'x' of bucket '%s', expected its metadata kept", alias, bucketName)
+		}
+	}
+
+	if err := s.PinObject(s.DefaultBucketName, "x", ctx); err != nil {
+		t.Fatalf("can't pin object 'x': %v", err)
+	}
+	assertAlias(pinnedBucketName(s.DefaultBucketName))
+
+	// the original expires, its pinned copy is served
+	if err := backend.RemoveObject(ctx, s.DefaultBucketName, s.objectKey("x"), minio.RemoveObjectOptions{}); err != nil {
+		t.Fatalf("can't remove object 'x': %v", err)
+	}
+	if data := getData(t, s, s.DefaultBucketName, "x"); data != "pinned" {
+		t.Errorf("got data '%s' for the pinned object", data)
+	}
+
+	if err := s.UnpinObject(s.DefaultBucketName, "x", ctx); err != nil {
+		t.Fatalf("can't unpin object 'x': %v", err)
+	}
+	assertAlias(s.DefaultBucketName)
+	if _, err := s.GetObjectInfo(pinnedBucketName(s.DefaultBucketName), "x", "", ctx); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got error %v for the pinned copy once unpinned, expected ErrNotFound", err)
+	}
+	if data := getData(t, s, s.DefaultBucketName, "x"); data != "pinned" {
+		t.Errorf("got data '%s' for the unpinned object", data)
+	}
+}
+
+func TestPinObjects(t *testing.T) {
+	s, _ := newTestStorage(t, nil)
+	ctx := context.Background()
+	for _, name := range []string{"a1", "a2", "b1"} {
+		if err := s.UploadObject(name, s.DefaultBucketName, taggedDataObject("pin", name), ctx); err != nil {
+			t.Fatalf("can't upload object '%s': %v", name, err)
+		}
+	}
+	pinObjects := func(prefix string, objectNames []string, unpin bool, ctx context.Context) (PinProgress, error) {
+		var progress PinProgress
+		err := s.PinObjects(s.DefaultBucketName, prefix, objectNames, unpin, ctx, func(p any) { progress = p.(PinProgress) })
+		return progress, err
+	}
+	pinnedBucket := pinnedBucketName(s.DefaultBucketName)
+
+	if progress, err := pinObjects("a", nil, false, ctx); err != nil || progress.Processed != 2 || progress.Succeeded != 2 {
+		t.Errorf("got progress %+v, error %v pinning by prefix, expected 2 pinned", progress, err)
+	}
+	if names := listNames(t, s, pinnedBucket); len(names) != 2 {
+		t.Errorf("got pinned objects %v, expected a1 and a2", names)
+	}
+
+	// the failures are reported per object
+	progress, err := pinObjects("", []string{"b1", "missing"}, false, ctx)
+	if err != nil || progress.Succeeded != 1 || progress.Failed != 1 || len(progress.Failures) != 1 || progress.Failures[0].ObjectName != "missing" {
+		t.Errorf("got progress %+v, error %v pinning by ids, expected b1 pinned and missing failed", progress, err)
+	}
+
+	// unpinning by prefix lists the pinned objects
+	if progress, err := pinObjects("", nil, true, ctx); err != nil || !progress.Unpin || progress.Succeeded != 3 || progress.Failed != 0 {
+		t.Errorf("got progress %+v, error %v unpinning, expected the 3 pinned objects unpinned", progress, err)
+	}
+	if names := listNames(t, s, pinnedBucket); len(names) != 0 {
+		t.Errorf("got pinned objects %v left, expected none", names)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if progress, err := pinObjects("", []string{"a1", "a2"}, false, cancelled); !errors.Is(err, context.Canceled) || progress.Processed != 0 {
+		t.Errorf("got progress %+v, error %v with a cancelled context, expected nothing processed", progress, err)
+	}
+}
@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// proofsPrefix namespaces the proofs fetched for blocks stored without them, when there is no POI bucket.
+const proofsPrefix = "proofs/"
+
+// uploadPOI stores the Proof of Inclusion of an object in the POI bucket, keyed like the object.
+func (s *Storage) uploadPOI(objectName string, object Object, ctx context.Context) error {
+	data, err := json.Marshal(Object{Milestone: object.Milestone, Proof: object.Proof})
+	if err != nil {
+		return err
+	}
+
+	s.WrappedLogger.LogInfof("Uploading Proof of Inclusion of '%s' to bucket '%s' ...", objectName, s.POIBucketName)
+	_, err = s.client.PutObject(ctx, s.POIBucketName, s.objectKey(objectName), bytes.NewReader(data), int64(len(data)), minio.PutObjectOptions{ContentType: ContentTypeJSON})
+	if err != nil {
+		s.WrappedLogger.LogErrorf("Uploading Proof of Inclusion of '%s' to bucket '%s' ... failed, error: %w", objectName, s.POIBucketName, err)
+		return err
+	}
+	s.WrappedLogger.LogInfof("Uploading Proof of Inclusion of '%s' to bucket '%s' ... done", objectName, s.POIBucketName)
+	return nil
+}
+
+// GetPOI returns the Proof of Inclusion of an object kept in the POI bucket, with only Milestone and Proof set.
+// It returns ErrNotFound if there is none or no POI bucket is configured.
+func (s *Storage) GetPOI(objectName string, ctx context.Context) (Object, error) {
+	if s.POIBucketName == "" {
+		return Object{}, fmt.Errorf("no POI bucket configured: %w", ErrNotFound)
+	}
+	object, err := s.client.GetObject(ctx, s.POIBucketName, s.objectKey(objectName), minio.GetObjectOptions{})
+	if err != nil {
+		return Object{}, translateError(err)
+	}
+	defer object.Close()
+	return object.Decode()
+}
+
+// StoreProof keeps the Proof of Inclusion of a block stored without it, keyed by block id: in the POI bucket if
+// configured, otherwise next to the block under proofs/, sharing the lifecycle of its bucket.
+func (s *Storage) StoreProof(bucketName string, blockId string, object Object, ctx context.Context) error {
+	if s.POIBucketName != "" {
+		return s.uploadPOI(blockId, object, ctx)
+	}
+	data, err := json.Marshal(Object{Milestone: object.Milestone, Proof: object.Proof})
+	if err != nil {
+		return err
+	}
+	_, err = s.client.PutObject(ctx, bucketName, s.objectKey(proofsPrefix+blockId), bytes.NewReader(data), int64(len(data)), minio.PutObjectOptions{ContentType: ContentTypeJSON})
+	if err != nil {
+		s.WrappedLogger.LogErrorf("Storing Proof of Inclusion of '%s' in bucket '%s' ... failed, error: %w", blockId, bucketName, err)
+		return translateError(err)
+	}
+	return nil
+}
+
+// GetProof returns the Proof of Inclusion kept by StoreProof for a block, with only Milestone and Proof set.
+// It returns ErrNotFound if there is none.
+func (s *Storage) GetProof(bucketName string, blockId string, ctx context.Context) (Object, error) {
+	if s.POIBucketName != "" {
+		return s.GetPOI(blockId, ctx)
+	}
+	object, err := s.client.GetObject(ctx, bucketName, s.objectKey(proofsPrefix+blockId), minio.GetObjectOptions{})
+	if err != nil {
+		return Object{}, translateError(err)
+	}
+	defer object.Close()
+	return object.Decode()
+}
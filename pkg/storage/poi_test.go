@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"context"
+	"crypto"
+	"errors"
+	"testing"
+
+	iotago "github.com/iotaledger/iota.go/v3"
+	"github.com/iotaledger/iota.go/v3/merklehasher"
+)
+
+// proofObject returns a block with its proof of inclusion among two blocks.
+func proofObject(t *testing.T) Object {
+	t.Helper()
+	blockIds := iotago.BlockIDs{{1}, {2}}
+	proof, err := merklehasher.NewHasher(crypto.BLAKE2b_256).ComputeProof(blockIds, blockIds[0])
+	if err != nil {
+		t.Fatalf("can't compute the proof: %v", err)
+	}
+	return Object{Block: testBlock("proven"), Proof: proof, Milestone: &iotago.Milestone{Index: 7}}
+}
+
+func TestPOIBucket(t *testing.T) {
+	s, _ := newTestStorage(t, func(params *Parameters) {
+		params.POIBucketName = "proofs"
+	})
+	ctx := context.Background()
+	if _, err := s.CheckCreateBucket(s.POIBucketName, ctx); err != nil {
+		t.Fatalf("can't create the POI bucket: %v", err)
+	}
+	object := proofObject(t)
+	if err := s.UploadObject("proven", s.DefaultBucketName, object, ctx); err != nil {
+		t.Fatalf("can't upload the block: %v", err)
+	}
+
+	// the block is stored on its own, its metadata telling a proof exists
+	reader, err := s.GetObject(s.DefaultBucketName, "proven", "", ctx)
+	if err != nil {
+		t.Fatalf("can't get the block: %v", err)
+	}
+	stored, err := reader.Decode()
+	reader.Close()
+	if err != nil || stored.Block == nil || stored.Proof != nil || stored.Milestone != nil {
+		t.Errorf("got object %+v, error %v, expected the block without its proof", stored, err)
+	}
+	if reader.Info.UserMetadata[MetadataProofOfInclusion] != "true" {
+		t.Errorf("got metadata %v, expected the proof of inclusion flagged", reader.Info.UserMetadata)
+	}
+
+	poi, err := s.GetPOI("proven", ctx)
+	if err != nil {
+		t.Fatalf("can't get the proof: %v", err)
+	}
+	if poi.Block != nil || poi.Proof == nil || poi.Milestone == nil || poi.Milestone.Index != 7 {
+		t.Errorf("got proof object %+v, expected the milestone and proof only", poi)
+	}
+	expected, _ := object.Proof.MarshalJSON()
+	if got, _ := poi.Proof.MarshalJSON(); string(got) != string(expected) {
+		t.Errorf("got proof %s, expected %s", got, expected)
+	}
+}
+
+func TestStoreProof(t *testing.T) {
+	for _, poiBucketName := range []string{"", "proofs"} {
+		s, _ := newTestStorage(t, func(params *Parameters) {
+			params.POIBucketName = poiBucketName
+		})
+		ctx := context.Background()
+		if poiBucketName != "" {
+			if _, err := s.CheckCreateBucket(poiBucketName, ctx); err != nil {
+				t.Fatalf("can't create the POI bucket: %v", err)
+			}
+		}
+		if err := s.UploadObject("block", s.DefaultBucketName, Object{Block: testBlock("without proof")}, ctx); err != nil {
+			t.Fatalf("can't upload the block: %v", err)
+		}
+		if _, err := s.GetProof(s.DefaultBucketName, "block", ctx); !errors.Is(err, ErrNotFound) {
+			t.Errorf("POI bucket '%s': got error %v before the proof is kept, expected ErrNotFound", poiBucketName, err)
+		}
+
+		object := proofObject(t)
+		if err := s.StoreProof(s.DefaultBucketName, "block", object, ctx); err != nil {
+			t.Fatalf("POI bucket '%s': can't keep the proof: %v", poiBucketName, err)
+		}
+		poi, err := s.GetProof(s.DefaultBucketName, "block", ctx)
+		if err != nil || poi.Block != nil || poi.Proof == nil || poi.Milestone == nil || poi.Milestone.Index != 7 {
+			t.Errorf("POI bucket '%s': got proof object %+v, error %v, expected the milestone and proof only", poiBucketName, poi, err)
+		}
+		// the proofs kept next to the blocks are not listed
+		if names := listNames(t, s, s.DefaultBucketName); len(names) != 1 || names[0] != "block" {
+			t.Errorf("POI bucket '%s': got objects %v, expected the block only", poiBucketName, names)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// bucketPolicy is the part of an S3 bucket policy needed to validate it and to detect public access.
+type bucketPolicy struct {
+	Version   string            `json:"Version"`
+	Statement []policyStatement `json:"Statement"`
+}
+
+type policyStatement struct {
+	Effect    string          `json:"Effect"`
+	Principal json.RawMessage `json:"Principal"`
+	Action    json.RawMessage `json:"Action"`
+	Resource  json.RawMessage `json:"Resource"`
+}
+
+// isPublic returns whether the statement allows anonymous access, its principal being "*" or {"AWS": "*"}.
+func (s policyStatement) isPublic() bool {
+	if s.Effect != "Allow" {
+		return false
+	}
+	var principal string
+	if json.Unmarshal(s.Principal, &principal) == nil {
+		return principal == "*"
+	}
+	var principals map[string]json.RawMessage
+	if json.Unmarshal(s.Principal, &principals) != nil {
+		return false
+	}
+	aws := principals["AWS"]
+	if json.Unmarshal(aws, &principal) == nil {
+		return principal == "*"
+	}
+	var awsPrincipals []string
+	if json.Unmarshal(aws, &awsPrincipals) == nil {
+		for _, p := range awsPrincipals {
+			if p == "*" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseBucketPolicy validates a bucket policy document, returning whether it grants public access.
+func parseBucketPolicy(policy string) (bool, error) {
+	var parsed bucketPolicy
+	err := json.Unmarshal([]byte(policy), &parsed)
+	if err != nil {
+		return false, fmt.Errorf("invalid bucket policy, error: %w", err)
+	}
+	if len(parsed.Statement) == 0 {
+		return false, fmt.Errorf("invalid bucket policy, no statement")
+	}
+	public := false
+	for i, statement := range parsed.Statement {
+		if statement.Effect != "Allow" && statement.Effect != "Deny" {
+			return false, fmt.Errorf("invalid bucket policy, statement %d has effect '%s'", i, statement.Effect)
+		}
+		if len(statement.Principal) == 0 || len(statement.Action) == 0 || len(statement.Resource) == 0 {
+			return false, fmt.Errorf("invalid bucket policy, statement %d needs a principal, an action and a resource", i)
+		}
+		public = public || statement.isPublic()
+	}
+	return public, nil
+}
+
+// parseBucketPolicies reads the per bucket policies of the parameters, a JSON object keyed by bucket name.
+func parseBucketPolicies(policies string) (map[string]string, error) {
+	parsed := make(map[string]string)
+	if policies == "" {
+		return parsed, nil
+	}
+
+	var documents map[string]json.RawMessage
+	err := json.Unmarshal([]byte(policies), &documents)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bucket policies, error: %w", err)
+	}
+	for bucketName, document := range documents {
+		_, err := parseBucketPolicy(string(document))
+		if err != nil {
+			return nil, fmt.Errorf("bucket '%s': %w", bucketName, err)
+		}
+		parsed[bucketName] = string(document)
+	}
+	return parsed, nil
+}
+
+// SetBucketPolicy applies a bucket policy, an empty policy removes it.
+func (s *Storage) SetBucketPolicy(bucketName string, policy string, ctx context.Context) error {
+	if policy != "" {
+		public, err := parseBucketPolicy(policy)
+		if err != nil {
+			return err
+		}
+		if public {
+			s.WrappedLogger.LogWarnf("Bucket '%s' is being given a PUBLIC policy, its objects will be readable without credentials", bucketName)
+		}
+	}
+
+	s.WrappedLogger.LogInfof("Setting policy of bucket '%s' ...", bucketName)
+	err := translateError(s.client.SetBucketPolicy(ctx, bucketName, policy))
+	if err != nil {
+		s.WrappedLogger.LogErrorf("Setting policy of bucket '%s' ... failed, error: %w", bucketName, err)
+		return err
+	}
+	s.WrappedLogger.LogInfof("Setting policy of bucket '%s' ... done", bucketName)
+	return nil
+}
+
+// GetBucketPolicy returns the policy of a bucket, empty if it has none.
+func (s *Storage) GetBucketPolicy(bucketName string, ctx context.Context) (string, error) {
+	policy, err := s.client.GetBucketPolicy(ctx, bucketName)
+	return policy, translateError(err)
+}
@@ -0,0 +1,272 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// BucketUsage is the approximate number and size of the blocks stored in a bucket, maintained on every upload and
+// delete and reconciled with a listing. The latest versions only are counted, and the internal objects aren't.
+type BucketUsage struct {
+	BucketName   string    `json:"bucketName"`
+	Objects      int64     `json:"objects"`
+	Bytes        int64     `json:"bytes"`
+	MaxObjects   int64     `json:"maxObjects,omitempty"`
+	MaxBytes     int64     `json:"maxBytes,omitempty"`
+	ReconciledAt time.Time `json:"reconciledAt"`
+}
+
+// quotas tracks the usage of the buckets written to, refusing the uploads to the buckets reaching maxObjects or
+// maxBytes. A bucket is tracked once it is counted, in the background from its first upload, or the first time its
+// usage is asked.
+type quotas struct {
+	sync.Mutex
+	maxObjects        int64
+	maxBytes          int64
+	softPercent       int
+	reconcileInterval time.Duration
+	usages            map[string]*bucketUsage
+	// counting holds the buckets being counted in the background
+	counting map[string]bool
+}
+
+type bucketUsage struct {
+	BucketUsage
+	// warned is set once the soft threshold has been reported, until the usage falls below it
+	warned bool
+}
+
+func newQuotas(params Parameters) *quotas {
+	return &quotas{
+		maxObjects:        params.QuotaMaxObjects,
+		maxBytes:          params.QuotaMaxBytes,
+		softPercent:       params.QuotaSoftPercent,
+		reconcileInterval: params.QuotaReconcileInterval,
+		usages:            make(map[string]*bucketUsage),
+		counting:          make(map[string]bool),
+	}
+}
+
+func (q *quotas) limited() bool {
+	return q.maxObjects > 0 || q.maxBytes > 0
+}
+
+// percent returns the usage of the most used limit, in percent.
+func (q *quotas) percent(usage BucketUsage) int64 {
+	var percent int64
+	if q.maxObjects > 0 {
+		percent = usage.Objects * 100 / q.maxObjects
+	}
+	if q.maxBytes > 0 && usage.Bytes*100/q.maxBytes > percent {
+		percent = usage.Bytes * 100 / q.maxBytes
+	}
+	return percent
+}
+
+// GetBucketUsage returns the usage of a bucket, listing it the first time.
+func (s *Storage) GetBucketUsage(bucketName string, ctx context.Context) (BucketUsage, error) {
+	s.quotas.Lock()
+	_, tracked := s.quotas.usages[bucketName]
+	s.quotas.Unlock()
+	if !tracked {
+		err := s.reconcileUsage(bucketName, ctx)
+		if err != nil {
+			return BucketUsage{}, err
+		}
+	}
+
+	s.quotas.Lock()
+	defer s.quotas.Unlock()
+	result := s.quotas.usages[bucketName].BucketUsage
+	result.MaxObjects, result.MaxBytes = s.quotas.maxObjects, s.quotas.maxBytes
+	return result, nil
+}
+
+// checkQuota fails with ErrQuotaExceeded when a bucket reached its quota. A bucket whose usage isn't known yet is
+// counted in the background, its uploads are accepted meanwhile.
+func (s *Storage) checkQuota(bucketName string, ctx context.Context) error {
+	if !s.quotas.limited() {
+		return nil
+	}
+	s.quotas.Lock()
+	defer s.quotas.Unlock()
+	usage, ok := s.quotas.usages[bucketName]
+	if !ok {
+		if !s.quotas.counting[bucketName] {
+			s.quotas.counting[bucketName] = true
+			go s.countUsage(bucketName)
+		}
+		return nil
+	}
+	if (s.quotas.maxObjects > 0 && usage.Objects >= s.quotas.maxObjects) || (s.quotas.maxBytes > 0 && usage.Bytes >= s.quotas.maxBytes) {
+		return fmt.Errorf("%w: bucket '%s' holds %d blocks, %d bytes", ErrQuotaExceeded, bucketName, usage.Objects, usage.Bytes)
+	}
+	return nil
+}
+
+// countUsage counts a bucket in the background, it is counted again on the next upload if it fails.
+func (s *Storage) countUsage(bucketName string) {
+	err := s.reconcileUsage(bucketName, context.Background())
+	if err != nil {
+		s.WrappedLogger.LogWarnf("Counting the usage of bucket '%s' ... failed, error: %w", bucketName, err)
+	}
+	s.quotas.Lock()
+	delete(s.quotas.counting, bucketName)
+	s.quotas.Unlock()
+}
+
+// usageTracked tells whether the usage of a bucket is maintained.
+func (s *Storage) usageTracked(bucketName string) bool {
+	s.quotas.Lock()
+	defer s.quotas.Unlock()
+	_, ok := s.quotas.usages[bucketName]
+	return ok
+}
+
+// storedSize returns the size of the latest version of an object about to be overwritten, for the usage of its
+// bucket to count the difference only, or -1 if there is none or the usage isn't tracked.
+func (s *Storage) storedSize(bucketName string, objectName string, ctx context.Context) int64 {
+	if !s.usageTracked(bucketName) {
+		return -1
+	}
+	info, err := s.client.StatObject(ctx, bucketName, s.objectKey(objectName), minio.StatObjectOptions{})
+	if err != nil {
+		return -1
+	}
+	return info.Size
+}
+
+// addStoredUsage counts a block stored, overwriting a block of storedSize bytes unless storedSize is negative.
+func (s *Storage) addStoredUsage(bucketName string, storedSize int64, size int64) {
+	if storedSize < 0 {
+		s.addUsage(bucketName, 1, size)
+		return
+	}
+	s.addUsage(bucketName, 0, size-storedSize)
+}
+
+// addUsage counts blocks stored in, or removed from, a tracked bucket, warning once its usage crosses the soft threshold.
+func (s *Storage) addUsage(bucketName string, objects int64, bytes int64) {
+	s.quotas.Lock()
+	defer s.quotas.Unlock()
+	usage, ok := s.quotas.usages[bucketName]
+	if !ok {
+		return
+	}
+	usage.Objects += objects
+	usage.Bytes += bytes
+	// an overwritten or already removed block is counted twice until the next reconciliation
+	if usage.Objects < 0 {
+		usage.Objects = 0
+	}
+	if usage.Bytes < 0 {
+		usage.Bytes = 0
+	}
+	s.warnUsage(usage)
+}
+
+// warnUsage reports a bucket crossing the soft threshold, the lock being held.
+func (s *Storage) warnUsage(usage *bucketUsage) {
+	if !s.quotas.limited() || s.quotas.softPercent <= 0 {
+		return
+	}
+	percent := s.quotas.percent(usage.BucketUsage)
+	switch {
+	case percent >= int64(s.quotas.softPercent) && !usage.warned:
+		usage.warned = true
+		s.WrappedLogger.LogWarnf("Bucket '%s' reached %d%% of its quota, %d blocks, %d bytes", usage.BucketName, percent, usage.Objects, usage.Bytes)
+	case percent < int64(s.quotas.softPercent):
+		usage.warned = false
+	}
+}
+
+// reconcileUsage counts the blocks of a bucket with a listing, along with the blocks of its batch index, which is
+// read again. The uploads and deletes happening during the listing may be counted twice or missed, until the next
+// reconciliation.
+func (s *Storage) reconcileUsage(bucketName string, ctx context.Context) error {
+	batched, err := s.reloadBatchIndex(bucketName, ctx)
+	if err != nil {
+		return err
+	}
+	usage := BucketUsage{BucketName: bucketName}
+	for _, location := range batched {
+		if !location.entry.Deleted {
+			usage.Objects++
+			usage.Bytes += location.entry.Size
+		}
+	}
+	for info := range s.client.ListObjects(ctx, bucketName, minio.ListObjectsOptions{Prefix: s.keyPrefix, Recursive: true}) {
+		if info.Err != nil {
+			return translateError(info.Err)
+		}
+		objectName, ok := s.objectNameFromKey(info.Key)
+		if !ok {
+			// the deduplicated payloads take space, while not being blocks
+			if strings.HasPrefix(info.Key, s.keyPrefix+contentPrefix) {
+				usage.Bytes += info.Size
+			}
+			continue
+		}
+		usage.Objects++
+		usage.Bytes += info.Size
+		// a block stored on its own is served instead of its batched copy, which isn't counted
+		if location, ok := batched[objectName]; ok && !location.entry.Deleted {
+			usage.Objects--
+			usage.Bytes -= location.entry.Size
+		}
+	}
+	usage.ReconciledAt = time.Now().UTC()
+
+	s.quotas.Lock()
+	defer s.quotas.Unlock()
+	tracked, ok := s.quotas.usages[bucketName]
+	if !ok {
+		tracked = &bucketUsage{}
+		s.quotas.usages[bucketName] = tracked
+	}
+	tracked.BucketUsage = usage
+	s.warnUsage(tracked)
+	return nil
+}
+
+// RunUsageReconciliation counts the default bucket when quotas are set, then reconciles the usage of the tracked
+// buckets every QuotaReconcileInterval until ctx is done.
+func (s *Storage) RunUsageReconciliation(ctx context.Context) {
+	if s.quotas.limited() {
+		err := s.reconcileUsage(s.DefaultBucketName, ctx)
+		if err != nil && ctx.Err() == nil {
+			s.WrappedLogger.LogWarnf("Counting the usage of bucket '%s' ... failed, error: %w", s.DefaultBucketName, err)
+		}
+	}
+	if s.quotas.reconcileInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(s.quotas.reconcileInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		s.quotas.Lock()
+		bucketNames := make([]string, 0, len(s.quotas.usages))
+		for bucketName := range s.quotas.usages {
+			bucketNames = append(bucketNames, bucketName)
+		}
+		s.quotas.Unlock()
+
+		for _, bucketName := range bucketNames {
+			err := s.reconcileUsage(bucketName, ctx)
+			if err != nil && ctx.Err() == nil {
+				s.WrappedLogger.LogWarnf("Reconciling the usage of bucket '%s' ... failed, error: %w", bucketName, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/iotaledger/hive.go/core/logger"
+	"github.com/minio/minio-go/v7"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestQuota(t *testing.T) {
+	s, _ := newTestStorage(t, func(params *Parameters) {
+		params.QuotaMaxObjects = 10
+		params.QuotaSoftPercent = 80
+	})
+	core, logs := observer.New(zapcore.WarnLevel)
+	s.WrappedLogger = logger.NewWrappedLogger(zap.New(core).Sugar())
+	ctx := context.Background()
+	// counted as at startup, rather than in the background on the first upload
+	if _, err := s.GetBucketUsage(s.DefaultBucketName, ctx); err != nil {
+		t.Fatalf("can't count the bucket: %v", err)
+	}
+
+	upload := func(from int, to int) {
+		t.Helper()
+		for i := from; i < to; i++ {
+			if err := s.UploadObject(fmt.Sprintf("object-%d", i), s.DefaultBucketName, taggedDataObject("quota", "data"), ctx); err != nil {
+				t.Fatalf("can't upload object %d: %v", i, err)
+			}
+		}
+	}
+	warnings := func() int {
+		return logs.FilterMessageSnippet("of its quota").Len()
+	}
+
+	upload(0, 7)
+	if n := warnings(); n != 0 {
+		t.Fatalf("got %d warnings below the soft threshold", n)
+	}
+	upload(7, 9)
+	if n := warnings(); n != 1 {
+		t.Fatalf("got %d warnings past the soft threshold, expected 1", n)
+	}
+
+	// falling below the threshold re-arms the warning
+	for _, name := range []string{"object-7", "object-8"} {
+		if err := s.DeleteObject(s.DefaultBucketName, name, "", ctx); err != nil {
+			t.Fatalf("can't delete object '%s': %v", name, err)
+		}
+	}
+	upload(7, 8)
+	if n := warnings(); n != 2 {
+		t.Fatalf("got %d warnings after crossing the soft threshold again, expected 2", n)
+	}
+
+	upload(8, 10)
+	err := s.UploadObject("object-10", s.DefaultBucketName, taggedDataObject("quota", "data"), ctx)
+	if !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("got error %v past the quota, expected ErrQuotaExceeded", err)
+	}
+	usage, err := s.GetBucketUsage(s.DefaultBucketName, ctx)
+	if err != nil || usage.Objects != 10 || usage.MaxObjects != 10 {
+		t.Errorf("got usage %+v, error %v, expected 10 of 10 blocks", usage, err)
+	}
+}
+
+func TestQuotaWithBatches(t *testing.T) {
+	s, _ := newTestStorage(t, func(params *Parameters) {
+		batchParams(2, 1<<20, time.Hour)(params)
+		params.QuotaMaxObjects = 4
+	})
+	ctx := context.Background()
+	usage := func() BucketUsage {
+		t.Helper()
+		usage, err := s.GetBucketUsage(s.DefaultBucketName, ctx)
+		if err != nil {
+			t.Fatalf("can't get the usage: %v", err)
+		}
+		return usage
+	}
+	reconciled := func() BucketUsage {
+		t.Helper()
+		if err := s.reconcileUsage(s.DefaultBucketName, ctx); err != nil {
+			t.Fatalf("can't reconcile the usage: %v", err)
+		}
+		return usage()
+	}
+
+	reconciled()
+	addToBatch(t, &s, "a", "b")
+	first := usage()
+	if first.Objects != 2 || first.Bytes <= 0 {
+		t.Fatalf("got usage %+v once a batch is written, expected its 2 blocks", first)
+	}
+	// batching a block again counts it once
+	time.Sleep(time.Millisecond)
+	addToBatch(t, &s, "a", "c")
+	if got := usage(); got.Objects != 3 {
+		t.Fatalf("got usage %+v, expected 3 blocks", got)
+	}
+	if err := s.DeleteObject(s.DefaultBucketName, "b", "", ctx); err != nil {
+		t.Fatalf("can't delete the batched object: %v", err)
+	}
+	maintained := usage()
+	if maintained.Objects != 2 || maintained.Bytes >= first.Bytes*2 {
+		t.Fatalf("got usage %+v after deleting a batched block, expected 2 blocks", maintained)
+	}
+	if got := reconciled(); got.Objects != maintained.Objects || got.Bytes != maintained.Bytes {
+		t.Errorf("got usage %+v once reconciled, expected the maintained %+v", got, maintained)
+	}
+
+	// a block stored on its own as well is counted once
+	if err := s.UploadObject("c", s.DefaultBucketName, taggedDataObject("batch", "c"), ctx); err != nil {
+		t.Fatalf("can't upload object 'c': %v", err)
+	}
+	if got := reconciled(); got.Objects != 2 {
+		t.Errorf("got usage %+v once reconciled, expected 2 blocks", got)
+	}
+
+	addToBatch(t, &s, "d", "e")
+	if got := usage(); got.Objects != 4 {
+		t.Fatalf("got usage %+v, expected 4 blocks", got)
+	}
+	if s.AddToBatch("f", s.DefaultBucketName, taggedDataObject("batch", "f"), ctx) {
+		t.Error("an object was batched past the quota")
+	}
+	if err := s.UploadObject("f", s.DefaultBucketName, taggedDataObject("batch", "f"), ctx); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("got error %v past the quota, expected ErrQuotaExceeded", err)
+	}
+}
+
+func TestQuotaOverwrite(t *testing.T) {
+	s, _ := newTestStorage(t, func(params *Parameters) {
+		params.QuotaMaxObjects = 10
+	})
+	ctx := context.Background()
+	if _, err := s.GetBucketUsage(s.DefaultBucketName, ctx); err != nil {
+		t.Fatalf("can't count the bucket: %v", err)
+	}
+
+	for _, data := range []string{"short", "a longer payload"} {
+		if err := s.UploadObject("x", s.DefaultBucketName, taggedDataObject("quota", data), ctx); err != nil {
+			t.Fatalf("can't upload object 'x': %v", err)
+		}
+	}
+	usage, err := s.GetBucketUsage(s.DefaultBucketName, ctx)
+	if err != nil {
+		t.Fatalf("can't get the usage: %v", err)
+	}
+	info, err := s.GetObjectInfo(s.DefaultBucketName, "x", "", ctx)
+	if err != nil {
+		t.Fatalf("can't stat object 'x': %v", err)
+	}
+	if usage.Objects != 1 || usage.Bytes != info.Size {
+		t.Errorf("got usage %+v after overwriting a block, expected 1 block of %d bytes", usage, info.Size)
+	}
+}
+
+// blockingListBackend blocks the listings until release is closed.
+type blockingListBackend struct {
+	*MemoryBackend
+	release chan struct{}
+}
+
+func (b *blockingListBackend) ListObjects(ctx context.Context, bucketName string, opts minio.ListObjectsOptions) <-chan minio.ObjectInfo {
+	<-b.release
+	return b.MemoryBackend.ListObjects(ctx, bucketName, opts)
+}
+
+func TestQuotaCountedInBackground(t *testing.T) {
+	backend := &blockingListBackend{MemoryBackend: NewMemoryBackend(), release: make(chan struct{})}
+	s := newTestStorageWithBackend(t, backend, func(params *Parameters) {
+		params.QuotaMaxObjects = 10
+	})
+	ctx := context.Background()
+
+	uploaded := make(chan error)
+	go func() {
+		uploaded <- s.UploadObject("x", s.DefaultBucketName, taggedDataObject("quota", "data"), ctx)
+	}()
+	select {
+	case err := <-uploaded:
+		if err != nil {
+			t.Fatalf("can't upload object 'x': %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		close(backend.release)
+		t.Fatal("the first upload waited for the bucket to be listed")
+	}
+
+	close(backend.release)
+	deadline := time.Now().Add(5 * time.Second)
+	for !s.usageTracked(s.DefaultBucketName) {
+		if time.Now().After(deadline) {
+			t.Fatal("the bucket was not counted in the background")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	usage, err := s.GetBucketUsage(s.DefaultBucketName, ctx)
+	if err != nil || usage.Objects != 1 {
+		t.Errorf("got usage %+v, error %v, expected 1 block", usage, err)
+	}
+}
@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// GetObjectRange retrieves the bytes start to end, inclusive, of an object as stored, a negative end reads up to
+// its end. An empty versionId (or versioning disabled) reads the latest version. The range must start within the
+// object.
+func (s *Storage) GetObjectRange(bucketName string, objectName string, versionId string, start int64, end int64, ctx context.Context) (io.ReadCloser, error) {
+	if start < 0 || (end >= 0 && end < start) {
+		return nil, fmt.Errorf("invalid range %d-%d", start, end)
+	}
+	key := s.objectKey(objectName)
+	info, err := s.client.StatObject(ctx, bucketName, key, minio.StatObjectOptions{VersionID: s.versionId(versionId)})
+	err = translateError(err)
+	if errors.Is(err, ErrNotFound) && versionId == "" {
+		// the object may only be kept as a pinned copy, or in a batch
+		if !isPinnedBucket(bucketName) {
+			if reader, pinnedErr := s.GetObjectRange(pinnedBucketName(bucketName), objectName, "", start, end, ctx); pinnedErr == nil {
+				return reader, nil
+			}
+		}
+		return s.getBatchedRange(bucketName, objectName, start, end, ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// the range applies to the payload of a deduplication pointer
+	if ref, ok := contentRef(info); ok {
+		key = s.objectKey(ref)
+		versionId = ""
+	}
+	opts := minio.GetObjectOptions{VersionID: s.versionId(versionId)}
+	switch {
+	case end >= 0:
+		err = opts.SetRange(start, end)
+	case start > 0:
+		// an end of 0 reads up to the end of the object
+		err = opts.SetRange(start, 0)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.WrappedLogger.LogInfof("Retrieving bytes %d-%d of object '%s' from bucket '%s' ...", start, end, objectName, bucketName)
+	startTime := time.Now()
+	object, err := s.client.GetObject(ctx, bucketName, key, opts)
+	err = translateError(err)
+	s.metrics.observe(operationGet, startTime, err)
+	if err != nil {
+		s.WrappedLogger.LogInfof("Retrieving bytes %d-%d of object '%s' from bucket '%s' ... failed, error: %w", start, end, objectName, bucketName, err)
+		return nil, err
+	}
+	return object, nil
+}
+
+// getBatchedRange cuts a range out of a batched object, which is extracted whole.
+func (s *Storage) getBatchedRange(bucketName string, objectName string, start int64, end int64, ctx context.Context) (io.ReadCloser, error) {
+	if !s.batchesWritten(bucketName, ctx) {
+		return nil, ErrNotFound
+	}
+	object, err := s.getBatched(bucketName, objectName, ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer object.Close()
+	data, err := io.ReadAll(object)
+	if err != nil {
+		return nil, err
+	}
+	if start >= int64(len(data)) {
+		return nil, fmt.Errorf("range %d-%d starts after the %d bytes of object '%s'", start, end, len(data), objectName)
+	}
+	if end < 0 || end >= int64(len(data)) {
+		end = int64(len(data)) - 1
+	}
+	return io.NopCloser(bytes.NewReader(data[start : end+1])), nil
+}
@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"testing"
+)
+
+func TestGetObjectRange(t *testing.T) {
+	for _, dedup := range []bool{false, true} {
+		s, _ := newTestStorage(t, func(params *Parameters) {
+			params.DedupEnabled = dedup
+		})
+		ctx := context.Background()
+		object := taggedDataObject("range", "some data read by ranges")
+		if err := s.UploadObject("ranged", s.DefaultBucketName, object, ctx); err != nil {
+			t.Fatalf("can't upload the object: %v", err)
+		}
+		reader, err := object.GetByteReader()
+		if err != nil {
+			t.Fatal(err)
+		}
+		stored, _ := io.ReadAll(reader)
+		size := int64(len(stored))
+
+		for _, tc := range []struct {
+			start    int64
+			end      int64
+			expected []byte
+		}{
+			{0, -1, stored},
+			{0, 0, stored[:1]},
+			{5, 9, stored[5:10]},
+			{size - 3, -1, stored[size-3:]},
+			{size - 3, size + 10, stored[size-3:]},
+		} {
+			reader, err := s.GetObjectRange(s.DefaultBucketName, "ranged", "", tc.start, tc.end, ctx)
+			if err != nil {
+				t.Errorf("dedup %t: can't read bytes %d-%d: %v", dedup, tc.start, tc.end, err)
+				continue
+			}
+			data, err := io.ReadAll(reader)
+			reader.Close()
+			if err != nil || string(data) != string(tc.expected) {
+				t.Errorf("dedup %t: got bytes %d-%d '%s', error %v, expected '%s'", dedup, tc.start, tc.end, data, err, tc.expected)
+			}
+		}
+
+		for _, invalid := range [][2]int64{{-1, 5}, {5, 2}} {
+			if _, err := s.GetObjectRange(s.DefaultBucketName, "ranged", "", invalid[0], invalid[1], ctx); err == nil {
+				t.Errorf("dedup %t: got bytes %d-%d read, expected an error", dedup, invalid[0], invalid[1])
+			}
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/lifecycle"
+)
+
+// maxClientCheckBackoff bounds the delay between the health checks of a failing storage.
+const maxClientCheckBackoff = 10 * time.Minute
+
+// reloadableBackend is a minio backend that can be rebuilt while in use, to pick up fresh credentials and connections.
+// Every call runs on the backend current when it starts, so in-flight operations complete on the previous backend.
+type reloadableBackend struct {
+	sync.RWMutex
+	backend *minioBackend
+	params  Parameters
+	// reloading serializes the reloads
+	reloading sync.Mutex
+}
+
+func newReloadableBackend(params Parameters) (*reloadableBackend, error) {
+	backend, err := newMinioBackend(params)
+	if err != nil {
+		return nil, err
+	}
+	return &reloadableBackend{backend: backend, params: params}, nil
+}
+
+func (b *reloadableBackend) current() *minioBackend {
+	b.RLock()
+	defer b.RUnlock()
+	return b.backend
+}
+
+// reload builds a new backend and switches to it once it reached the bucket, keeping the current one otherwise.
+func (b *reloadableBackend) reload(bucketName string, ctx context.Context) error {
+	b.reloading.Lock()
+	defer b.reloading.Unlock()
+
+	backend, err := newMinioBackend(b.params)
+	if err != nil {
+		return err
+	}
+	_, err = backend.BucketExists(ctx, bucketName)
+	if err != nil {
+		return fmt.Errorf("the new client can't reach the storage, error: %w", err)
+	}
+
+	b.Lock()
+	b.backend = backend
+	b.Unlock()
+	return nil
+}
+
+func (b *reloadableBackend) EndpointURL() *url.URL {
+	return b.current().EndpointURL()
+}
+
+func (b *reloadableBackend) ListBuckets(ctx context.Context) ([]minio.BucketInfo, error) {
+	return b.current().ListBuckets(ctx)
+}
+
+func (b *reloadableBackend) BucketExists(ctx context.Context, bucketName string) (bool, error) {
+	return b.current().BucketExists(ctx, bucketName)
+}
+
+func (b *reloadableBackend) MakeBucket(ctx context.Context, bucketName string, opts minio.MakeBucketOptions) error {
+	return b.current().MakeBucket(ctx, bucketName, opts)
+}
+
+func (b *reloadableBackend) EnableVersioning(ctx context.Context, bucketName string) error {
+	return b.current().EnableVersioning(ctx, bucketName)
+}
+
+func (b *reloadableBackend) SetBucketLifecycle(ctx context.Context, bucketName string, config *lifecycle.Configuration) error {
+	return b.current().SetBucketLifecycle(ctx, bucketName, config)
+}
+
+func (b *reloadableBackend) GetBucketLifecycle(ctx context.Context, bucketName string) (*lifecycle.Configuration, error) {
+	return b.current().GetBucketLifecycle(ctx, bucketName)
+}
+
+func (b *reloadableBackend) SetBucketPolicy(ctx context.Context, bucketName string, policy string) error {
+	return b.current().SetBucketPolicy(ctx, bucketName, policy)
+}
+
+func (b *reloadableBackend) GetBucketPolicy(ctx context.Context, bucketName string) (string, error) {
+	return b.current().GetBucketPolicy(ctx, bucketName)
+}
+
+func (b *reloadableBackend) GetObjectLockConfig(ctx context.Context, bucketName string) (string, *minio.RetentionMode, *uint, *minio.ValidityUnit, error) {
+	return b.current().GetObjectLockConfig(ctx, bucketName)
+}
+
+func (b *reloadableBackend) PutObject(ctx context.Context, bucketName string, objectName string, reader io.Reader, objectSize int64, opts minio.PutObjectOptions) (minio.UploadInfo, error) {
+	return b.current().PutObject(ctx, bucketName, objectName, reader, objectSize, opts)
+}
+
+func (b *reloadableBackend) GetObject(ctx context.Context, bucketName string, objectName string, opts minio.GetObjectOptions) (*ObjectReader, error) {
+	return b.current().GetObject(ctx, bucketName, objectName, opts)
+}
+
+func (b *reloadableBackend) StatObject(ctx context.Context, bucketName string, objectName string, opts minio.StatObjectOptions) (minio.ObjectInfo, error) {
+	return b.current().StatObject(ctx, bucketName, objectName, opts)
+}
+
+func (b *reloadableBackend) CopyObject(ctx context.Context, dst minio.CopyDestOptions, src minio.CopySrcOptions) (minio.UploadInfo, error) {
+	return b.current().CopyObject(ctx, dst, src)
+}
+
+func (b *reloadableBackend) RemoveObject(ctx context.Context, bucketName string, objectName string, opts minio.RemoveObjectOptions) error {
+	return b.current().RemoveObject(ctx, bucketName, objectName, opts)
+}
+
+func (b *reloadableBackend) RemoveObjects(ctx context.Context, bucketName string, objectsCh <-chan minio.ObjectInfo, opts minio.RemoveObjectsOptions) <-chan minio.RemoveObjectError {
+	return b.current().RemoveObjects(ctx, bucketName, objectsCh, opts)
+}
+
+func (b *reloadableBackend) ListObjects(ctx context.Context, bucketName string, opts minio.ListObjectsOptions) <-chan minio.ObjectInfo {
+	return b.current().ListObjects(ctx, bucketName, opts)
+}
+
+// ReloadClient rebuilds the storage client, fetching new credentials and opening new connections, and switches to it
+// once it reached the storage. Operations in progress complete with the previous client.
+func (s *Storage) ReloadClient(ctx context.Context) error {
+	backend, ok := s.client.(*reloadableBackend)
+	if !ok {
+		return fmt.Errorf("the storage client can't be reloaded")
+	}
+	s.WrappedLogger.LogInfof("Reloading the client of storage '%s' ...", s.client.EndpointURL().Host)
+	err := backend.reload(s.DefaultBucketName, ctx)
+	if err != nil {
+		s.WrappedLogger.LogErrorf("Reloading the client of storage '%s' ... failed, error: %w", s.client.EndpointURL().Host, err)
+		return err
+	}
+	s.WrappedLogger.LogInfof("Reloading the client of storage '%s' ... done", s.client.EndpointURL().Host)
+	return nil
+}
+
+// runsClientCheck tells whether the storage is checked while running.
+func (s *Storage) runsClientCheck() bool {
+	_, ok := s.client.(*reloadableBackend)
+	return ok && s.clientCheckInterval.Load() > 0 && s.clientReloadAfterFailures >= 1
+}
+
+// SetClientCheckInterval sets the interval of the storage checks, used from the next check on. It returns false,
+// leaving the interval as is, if the checks are disabled or would be: that requires a restart.
+func (s *Storage) SetClientCheckInterval(interval time.Duration) bool {
+	if !s.runsClientCheck() || interval <= 0 {
+		return false
+	}
+	s.clientCheckInterval.Store(int64(interval))
+	return true
+}
+
+// RunClientCheck checks that the storage is reachable every clientCheckInterval until ctx is done, reloading the
+// client after clientReloadAfterFailures consecutive failures. While the storage stays unreachable the checks are
+// spaced out, the interval doubling after every failed reload.
+func (s *Storage) RunClientCheck(ctx context.Context) {
+	if !s.runsClientCheck() {
+		return
+	}
+	interval := time.Duration(s.clientCheckInterval.Load())
+	failures := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+
+		_, err := s.client.BucketExists(ctx, s.DefaultBucketName)
+		if err == nil {
+			failures = 0
+			interval = time.Duration(s.clientCheckInterval.Load())
+			continue
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		failures++
+		s.WrappedLogger.LogWarnf("Checking storage '%s' ... failed %d times, error: %w", s.client.EndpointURL().Host, failures, err)
+		if failures < s.clientReloadAfterFailures {
+			continue
+		}
+
+		if s.ReloadClient(ctx) == nil {
+			failures = 0
+			interval = time.Duration(s.clientCheckInterval.Load())
+			continue
+		}
+		interval *= 2
+		if interval > maxClientCheckBackoff {
+			interval = maxClientCheckBackoff
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package storage
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/iotaledger/hive.go/core/logger"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// rotatingServer is a storage accepting the requests signed with its current access key only.
+type rotatingServer struct {
+	sync.Mutex
+	accessKey string
+}
+
+func (r *rotatingServer) rotate(t *testing.T, accessKey string) {
+	r.Lock()
+	r.accessKey = accessKey
+	r.Unlock()
+	t.Setenv("AWS_ACCESS_KEY_ID", accessKey)
+}
+
+func (r *rotatingServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	// the client looks the region of the bucket up first
+	if req.URL.Query().Has("location") {
+		w.Write([]byte(`<LocationConstraint xmlns="http://s3.amazonaws.com/doc/2006-03-01/">us-east-1</LocationConstraint>`))
+		return
+	}
+	r.Lock()
+	accessKey := r.accessKey
+	r.Unlock()
+	if !strings.Contains(req.Header.Get("Authorization"), "Credential="+accessKey+"/") {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`<Error><Code>InvalidAccessKeyId</Code><Message>expired</Message></Error>`))
+	}
+}
+
+func newRotatingStorage(t *testing.T, configure func(params *Parameters)) (Storage, *rotatingServer) {
+	t.Helper()
+	rotating := &rotatingServer{}
+	rotating.rotate(t, "first")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	server := httptest.NewServer(rotating)
+	t.Cleanup(server.Close)
+
+	params := transportParams(strings.TrimPrefix(server.URL, "http://"))
+	params.CredentialsMode = CredentialsModeEnv
+	if configure != nil {
+		configure(&params)
+	}
+	s, err := NewStorage(params, prometheus.NewRegistry(), logger.NewWrappedLogger(logger.NewNopLogger()))
+	if err != nil {
+		t.Fatalf("can't create the storage: %v", err)
+	}
+	if _, err := s.BucketExists(s.DefaultBucketName, context.Background()); err != nil {
+		t.Fatalf("got error %v, expected the storage reached", err)
+	}
+	return s, rotating
+}
+
+func TestReloadClient(t *testing.T) {
+	s, rotating := newRotatingStorage(t, nil)
+	ctx := context.Background()
+
+	// the client keeps the credentials it was built with
+	rotating.rotate(t, "second")
+	if _, err := s.BucketExists(s.DefaultBucketName, ctx); err == nil {
+		t.Fatal("got the storage reached with rotated credentials, expected an error")
+	}
+	if err := s.ReloadClient(ctx); err != nil {
+		t.Fatalf("can't reload the client: %v", err)
+	}
+	if _, err := s.BucketExists(s.DefaultBucketName, ctx); err != nil {
+		t.Errorf("got error %v after the reload, expected the storage reached", err)
+	}
+
+	// a client that can't reach the storage is not swapped in
+	backend := s.client.(*reloadableBackend)
+	current := backend.current()
+	t.Setenv("AWS_ACCESS_KEY_ID", "wrong")
+	if err := s.ReloadClient(ctx); err == nil {
+		t.Error("got a client reloaded with wrong credentials, expected an error")
+	}
+	if backend.current() != current {
+		t.Error("got the client swapped after a failed reload, expected the previous one kept")
+	}
+
+	memory, _ := newTestStorage(t, nil)
+	if err := memory.ReloadClient(ctx); err == nil {
+		t.Error("got the in-memory storage reloaded, expected an error")
+	}
+}
+
+func TestClientCheckReloads(t *testing.T) {
+	s, rotating := newRotatingStorage(t, func(params *Parameters) {
+		params.ClientCheckInterval = 10 * time.Millisecond
+		params.ClientReloadAfterFailures = 2
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.RunClientCheck(ctx)
+
+	rotating.rotate(t, "second")
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := s.BucketExists(s.DefaultBucketName, ctx); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the client was not reloaded after the credentials were rotated")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
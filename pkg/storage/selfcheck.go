@@ -0,0 +1,154 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+)
+
+const (
+	// maxCheckIssues bounds the issues listed by a self-check progress, the following ones are only counted.
+	maxCheckIssues = 100
+
+	// CheckIssueCorrupt is an object whose body can't be read or decoded.
+	CheckIssueCorrupt = "corrupt"
+	// CheckIssueDangling is a deduplication pointer whose payload is missing.
+	CheckIssueDangling = "dangling"
+	// CheckIssueUnreferenced is a deduplicated payload no object points to anymore.
+	CheckIssueUnreferenced = "unreferenced"
+)
+
+// CheckProgress reports the progress of a self-check job.
+type CheckProgress struct {
+	BucketName   string       `json:"bucketName"`
+	Checked      uint64       `json:"checked"`
+	Healthy      uint64       `json:"healthy"`
+	Corrupt      uint64       `json:"corrupt"`
+	Dangling     uint64       `json:"dangling"`
+	Unreferenced uint64       `json:"unreferenced"`
+	Issues       []CheckIssue `json:"issues,omitempty"`
+}
+
+// CheckIssue is an inconsistency found by a self-check.
+type CheckIssue struct {
+	Key     string `json:"key"`
+	Problem string `json:"problem"`
+	Error   string `json:"error,omitempty"`
+}
+
+func (p *CheckProgress) addIssue(key string, problem string, err error) {
+	switch problem {
+	case CheckIssueCorrupt:
+		p.Corrupt++
+	case CheckIssueDangling:
+		p.Dangling++
+	case CheckIssueUnreferenced:
+		p.Unreferenced++
+	}
+	if len(p.Issues) < maxCheckIssues {
+		issue := CheckIssue{Key: key, Problem: problem}
+		if err != nil {
+			issue.Error = err.Error()
+		}
+		// appending never modifies the elements of the progress values already reported
+		p.Issues = append(p.Issues, issue)
+	}
+}
+
+// Check reads every object of a bucket, until done or ctx is cancelled, reporting the objects that can't be read
+// or decoded, the deduplication pointers whose payload is missing and the payloads no pointer references.
+// Nothing is modified.
+func (s *Storage) Check(bucketName string, ctx context.Context, report func(progress any)) error {
+	s.WrappedLogger.LogInfof("Checking bucket '%s' ...", bucketName)
+	progress := CheckProgress{BucketName: bucketName}
+	report(progress)
+
+	var payloads []string
+	referenced := make(map[string]struct{})
+	for info := range s.client.ListObjects(ctx, bucketName, minio.ListObjectsOptions{Prefix: s.keyPrefix, Recursive: true}) {
+		if info.Err != nil {
+			err := translateError(info.Err)
+			s.WrappedLogger.LogErrorf("Checking bucket '%s' ... failed, error: %w", bucketName, err)
+			return err
+		}
+		if !strings.HasPrefix(info.Key, s.keyPrefix) || !strings.HasSuffix(info.Key, s.objectExtension) {
+			continue
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(info.Key, s.keyPrefix), s.objectExtension)
+		switch {
+		case strings.HasPrefix(name, contentPrefix):
+			payloads = append(payloads, info.Key)
+			continue
+		case name == "" || isInternalName(name):
+			continue
+		}
+
+		ref, problem, err := s.checkKey(bucketName, info.Key, ctx)
+		if ctx.Err() != nil {
+			s.WrappedLogger.LogInfof("Checking bucket '%s' ... cancelled", bucketName)
+			return ctx.Err()
+		}
+		if ref != "" {
+			referenced[s.objectKey(ref)] = struct{}{}
+		}
+		progress.Checked++
+		if problem != "" {
+			progress.addIssue(info.Key, problem, err)
+			s.WrappedLogger.LogWarnf("Checking object '%s' of bucket '%s' ... %s, error: %v", info.Key, bucketName, problem, err)
+		} else {
+			progress.Healthy++
+		}
+		report(progress)
+	}
+	if ctx.Err() != nil {
+		s.WrappedLogger.LogInfof("Checking bucket '%s' ... cancelled", bucketName)
+		return ctx.Err()
+	}
+
+	// unreferenced payloads are harmless, they expire with the bucket lifecycle
+	for _, key := range payloads {
+		if _, ok := referenced[key]; !ok {
+			progress.addIssue(key, CheckIssueUnreferenced, nil)
+		}
+	}
+	report(progress)
+
+	s.WrappedLogger.LogInfof("Checking bucket '%s' ... done, %d checked, %d corrupt, %d dangling, %d unreferenced", bucketName, progress.Checked, progress.Corrupt, progress.Dangling, progress.Unreferenced)
+	return nil
+}
+
+// checkKey reads an object, following its deduplication pointer, and returns the payload it points to along with
+// the problem found, if any.
+func (s *Storage) checkKey(bucketName string, key string, ctx context.Context) (string, string, error) {
+	object, err := s.client.GetObject(ctx, bucketName, key, minio.GetObjectOptions{})
+	if err != nil {
+		return "", CheckIssueCorrupt, translateError(err)
+	}
+	ref, ok := contentRef(object.Info)
+	if ok {
+		object.Close()
+		object, err = s.client.GetObject(ctx, bucketName, s.objectKey(ref), minio.GetObjectOptions{})
+		err = translateError(err)
+		if errors.Is(err, ErrNotFound) {
+			return ref, CheckIssueDangling, err
+		}
+		if err != nil {
+			return ref, CheckIssueCorrupt, err
+		}
+	}
+	defer object.Close()
+
+	if object.Info.ContentType == ContentTypeBinary || object.Info.ContentType == ContentTypeJSON {
+		_, err = object.Decode()
+	} else {
+		// not a block, e.g. a large object stored as read
+		_, err = io.Copy(io.Discard, object)
+	}
+	if err != nil {
+		return ref, CheckIssueCorrupt, err
+	}
+	return ref, "", nil
+}
@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/minio/minio-go/v7"
+)
+
+func TestCheck(t *testing.T) {
+	s, backend := newTestStorage(t, func(params *Parameters) {
+		params.DedupEnabled = true
+	})
+	ctx := context.Background()
+	bucketName := s.DefaultBucketName
+	upload := func(name string) string {
+		t.Helper()
+		before := contentKeys(t, backend, bucketName)
+		if err := s.UploadObject(name, bucketName, taggedDataObject("check", name), ctx); err != nil {
+			t.Fatalf("can't upload object '%s': %v", name, err)
+		}
+		for _, key := range contentKeys(t, backend, bucketName) {
+			if !contains(before, key) {
+				return key
+			}
+		}
+		t.Fatalf("object '%s' has no deduplicated payload", name)
+		return ""
+	}
+	upload("healthy")
+	// the payload of a pointer is removed
+	danglingPayload := upload("dangling")
+	if err := backend.RemoveObject(ctx, bucketName, danglingPayload, minio.RemoveObjectOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	// the pointer to a payload is removed
+	unreferencedPayload := upload("unreferenced")
+	if err := backend.RemoveObject(ctx, bucketName, s.objectKey("unreferenced"), minio.RemoveObjectOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	// an object can't be decoded
+	garbage := "not a block"
+	if _, err := backend.PutObject(ctx, bucketName, s.objectKey("corrupt"), strings.NewReader(garbage), int64(len(garbage)), minio.PutObjectOptions{ContentType: ContentTypeJSON}); err != nil {
+		t.Fatal(err)
+	}
+
+	var progress CheckProgress
+	reports := 0
+	if err := s.Check(bucketName, ctx, func(p any) {
+		progress = p.(CheckProgress)
+		reports++
+	}); err != nil {
+		t.Fatalf("can't check the bucket: %v", err)
+	}
+	if progress.Checked != 3 || progress.Healthy != 1 || progress.Corrupt != 1 || progress.Dangling != 1 || progress.Unreferenced != 1 {
+		t.Errorf("got progress %+v, expected 3 checked, 1 healthy, corrupt, dangling and unreferenced", progress)
+	}
+	issues := make(map[string]string)
+	for _, issue := range progress.Issues {
+		issues[issue.Problem] = issue.Key
+	}
+	for problem, key := range map[string]string{
+		CheckIssueCorrupt:      s.objectKey("corrupt"),
+		CheckIssueDangling:     s.objectKey("dangling"),
+		CheckIssueUnreferenced: unreferencedPayload,
+	} {
+		if issues[problem] != key {
+			t.Errorf("got key '%s' %s, expected '%s'", issues[problem], problem, key)
+		}
+	}
+	if reports < 2 {
+		t.Errorf("got %d progress reports, expected the progress reported while checking", reports)
+	}
+
+	// nothing is modified
+	if _, err := backend.StatObject(ctx, bucketName, s.objectKey("corrupt"), minio.StatObjectOptions{}); err != nil {
+		t.Errorf("got error %v, expected the corrupt object kept", err)
+	}
+}
+
+func contains(keys []string, key string) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
@@ -2,45 +2,239 @@ package storage
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode"
 
 	"github.com/iotaledger/hive.go/core/logger"
 	"github.com/minio/minio-go/v7"
-	"github.com/minio/minio-go/v7/pkg/credentials"
 	"github.com/minio/minio-go/v7/pkg/lifecycle"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
+// minPartSize is the smallest part S3 accepts in a multipart upload, but for the last one.
+const minPartSize = 5 * 1024 * 1024
+
+// maxObjectNameLength bounds the names derived from payloads, within the 1024 bytes of an S3 key.
+const maxObjectNameLength = 256
+
 type Storage struct {
 	*logger.WrappedLogger
-	client                      *minio.Client
+	client                      Backend
 	DefaultBucketName           string
 	DefaultBucketExpirationDays int
+	POIBucketName               string
+	POIBucketExpirationDays     int
+	EventsBucketName            string
+	EventsBucketExpirationDays  int
+	VersioningEnabled           bool
 	region                      string
 	objectExtension             string
+	storeEncoding               string
+	keyPrefix                   string
+	dedupEnabled                bool
+	detectContentType           bool
+	pingMaxAttempts             int
+	pingInterval                time.Duration
+	clientCheckInterval         *atomic.Int64
+	clientReloadAfterFailures   int
+	partSize                    uint64
+	partitionTemplate           string
+	partitions                  *sync.Map
+	bucketPolicies              map[string]string
+	attachmentsLock             *sync.Mutex
+	aliasesLock                 *sync.Mutex
+	batches                     *batcher
+	batchIndex                  *batchIndex
+	events                      *eventLog
+	deletions                   *deletionWatch
+	quotas                      *quotas
+	objectLock                  objectLock
+	metrics                     *Metrics
+}
+
+// NewStorage returns a Storage backed by the S3 compatible storage at params.Endpoint,
+// or by memory if params.InMemory is set.
+func NewStorage(params Parameters, registerer prometheus.Registerer, log *logger.WrappedLogger) (Storage, error) {
+	if params.InMemory {
+		log.LogWarn("Using an in-memory storage, stored blocks will be lost on shutdown")
+		return NewStorageWithBackend(params, NewMemoryBackend(), registerer, log)
+	}
+
+	backend, err := newReloadableBackend(params)
+	if err != nil {
+		return Storage{}, err
+	}
+	return NewStorageWithBackend(params, backend, registerer, log)
 }
 
-func NewStorage(params Parameters, log *logger.WrappedLogger) (Storage, error) {
+// NewStorageWithBackend returns a Storage persisting to the given backend, the endpoint and credentials
+// parameters are ignored.
+func NewStorageWithBackend(params Parameters, backend Backend, registerer prometheus.Registerer, log *logger.WrappedLogger) (Storage, error) {
+
+	objectLock, err := newObjectLock(params)
+	if err != nil {
+		return Storage{}, err
+	}
+
+	if params.PartSize < minPartSize {
+		return Storage{}, fmt.Errorf("part size %d is below the minimum of %d bytes", params.PartSize, minPartSize)
+	}
+
+	err = validatePartitionTemplate(params.PartitionTemplate)
+	if err != nil {
+		return Storage{}, err
+	}
+
+	bucketPolicies, err := parseBucketPolicies(params.BucketPolicies)
+	if err != nil {
+		return Storage{}, err
+	}
+
+	switch params.StoreEncoding {
+	case EncodingJSON, EncodingBinary:
+	default:
+		return Storage{}, fmt.Errorf("unknown store encoding '%s'", params.StoreEncoding)
+	}
 
-	// Initialize minio client object.
-	client, err := minio.New(params.Endpoint, &minio.Options{
-		Creds:  credentials.NewStaticV4(params.AccessKeyID, params.SecretAccessKey, ""),
-		Secure: params.Secure,
-	})
+	err = validateBatchParams(params)
+	if err != nil {
+		return Storage{}, err
+	}
+
+	if params.EventsBucketName != "" && params.EventsQueueSize < 1 {
+		return Storage{}, fmt.Errorf("the event log queue size must be at least 1, got %d", params.EventsQueueSize)
+	}
+
+	metrics, err := NewMetrics(registerer)
 	if err != nil {
 		return Storage{}, err
 	}
 
 	storage := Storage{
 		WrappedLogger:               logger.NewWrappedLogger(log.LoggerNamed("Storage")),
-		client:                      client,
+		client:                      backend,
 		DefaultBucketName:           params.DefaultBucketName,
 		DefaultBucketExpirationDays: params.DefaultBucketExpirationDays,
+		POIBucketName:               params.POIBucketName,
+		POIBucketExpirationDays:     params.POIBucketExpirationDays,
+		EventsBucketName:            params.EventsBucketName,
+		EventsBucketExpirationDays:  params.EventsBucketExpirationDays,
+		VersioningEnabled:           params.VersioningEnabled,
 		region:                      params.Region,
 		objectExtension:             params.ObjectExtension,
+		storeEncoding:               params.StoreEncoding,
+		keyPrefix:                   params.KeyPrefix,
+		dedupEnabled:                params.DedupEnabled,
+		detectContentType:           params.DetectContentType,
+		pingMaxAttempts:             params.PingMaxAttempts,
+		pingInterval:                params.PingInterval,
+		clientCheckInterval:         &atomic.Int64{},
+		clientReloadAfterFailures:   params.ClientReloadAfterFailures,
+		partSize:                    params.PartSize,
+		partitionTemplate:           params.PartitionTemplate,
+		partitions:                  &sync.Map{},
+		bucketPolicies:              bucketPolicies,
+		attachmentsLock:             &sync.Mutex{},
+		aliasesLock:                 &sync.Mutex{},
+		batches:                     newBatcher(params),
+		batchIndex:                  newBatchIndex(),
+		events:                      newEventLog(params),
+		deletions:                   newDeletionWatch(params),
+		quotas:                      newQuotas(params),
+		objectLock:                  objectLock,
+		metrics:                     metrics,
 	}
+	storage.clientCheckInterval.Store(int64(params.ClientCheckInterval))
 
 	return storage, nil
 }
 
+// Ping checks that the storage is reachable, retrying with a doubling interval until the attempts are exhausted.
+func (s *Storage) Ping(ctx context.Context) error {
+	maxAttempts := s.pingMaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	interval := s.pingInterval
+
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		s.WrappedLogger.LogInfof("Connecting to storage '%s' (attempt %d/%d) ...", s.client.EndpointURL().Host, attempt, maxAttempts)
+		_, err = s.client.BucketExists(ctx, s.DefaultBucketName)
+		if err == nil {
+			s.WrappedLogger.LogInfof("Connecting to storage '%s' ... done", s.client.EndpointURL().Host)
+			return nil
+		}
+		s.WrappedLogger.LogWarnf("Connecting to storage '%s' ... failed, error: %w", s.client.EndpointURL().Host, err)
+
+		if attempt == maxAttempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+		interval *= 2
+	}
+
+	return fmt.Errorf("storage '%s' unreachable after %d attempts, error: %w", s.client.EndpointURL().Host, maxAttempts, err)
+}
+
+// ValidateObjectName checks that a name derived from a payload can name a block: 1 to 256 characters, without
+// slashes nor control characters, so that it maps to a single object outside of the internal prefixes.
+func ValidateObjectName(objectName string) error {
+	if objectName == "" || len(objectName) > maxObjectNameLength {
+		return fmt.Errorf("invalid object name '%s': expected 1 to %d characters", objectName, maxObjectNameLength)
+	}
+	if objectName == "." || objectName == ".." {
+		return fmt.Errorf("invalid object name '%s'", objectName)
+	}
+	for _, r := range objectName {
+		if r == '/' || unicode.IsControl(r) {
+			return fmt.Errorf("invalid object name '%s': slashes and control characters are not allowed", objectName)
+		}
+	}
+	return nil
+}
+
+// objectKey returns the storage key of an object, namespaced by the key prefix and suffixed by the object extension.
+func (s *Storage) objectKey(objectName string) string {
+	return s.keyPrefix + objectName + s.objectExtension
+}
+
+// internalPrefixes namespace the objects the collector keeps next to the blocks: the deduplicated payloads,
+// attachment manifests, alias index, batches, proofs and tag index.
+var internalPrefixes = []string{contentPrefix, attachmentsPrefix, aliasesPrefix, batchesPrefix, batchIndexPrefix, proofsPrefix, indexPrefix}
+
+func isInternalName(objectName string) bool {
+	for _, prefix := range internalPrefixes {
+		if strings.HasPrefix(objectName, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// objectNameFromKey is the inverse of objectKey, it returns false for keys outside the collector's namespace
+// and for the internal objects. With an empty extension every key under the prefix is an object.
+func (s *Storage) objectNameFromKey(key string) (string, bool) {
+	if !strings.HasPrefix(key, s.keyPrefix) || !strings.HasSuffix(key, s.objectExtension) {
+		return "", false
+	}
+	objectName := strings.TrimSuffix(strings.TrimPrefix(key, s.keyPrefix), s.objectExtension)
+	if objectName == "" || isInternalName(objectName) {
+		return "", false
+	}
+	return objectName, true
+}
+
 func (s *Storage) CheckCreateBucket(bucketName string, ctx context.Context) (bool, error) {
 	exists, err := s.BucketExists(bucketName, ctx)
 	if err != nil {
@@ -58,36 +252,52 @@ func (s *Storage) CheckCreateBucket(bucketName string, ctx context.Context) (boo
 
 func (s *Storage) CreateBucket(bucketName string, ctx context.Context) error {
 	s.WrappedLogger.LogInfof("Creating bucket '%s' ...", bucketName)
-	err := s.client.MakeBucket(ctx, bucketName, minio.MakeBucketOptions{Region: s.region})
+	err := s.client.MakeBucket(ctx, bucketName, minio.MakeBucketOptions{Region: s.region, ObjectLocking: s.objectLock.enabled})
 	if err != nil {
 		s.WrappedLogger.LogErrorf("Creating bucket '%s' ... failed, error: %w", bucketName, err)
 		return err
 	}
 
+	if s.VersioningEnabled {
+		err = s.client.EnableVersioning(ctx, bucketName)
+		if err != nil {
+			s.WrappedLogger.LogErrorf("Creating bucket '%s' ... failed enabling versioning, error: %w", bucketName, err)
+			return err
+		}
+	}
+
+	if policy, ok := s.bucketPolicies[bucketName]; ok {
+		err = s.SetBucketPolicy(bucketName, policy, ctx)
+		if err != nil {
+			return err
+		}
+	}
+
 	s.WrappedLogger.LogInfof("Creating bucket '%s' ... done", bucketName)
 	return nil
 }
 
 func (s *Storage) SetBucketExpirationDays(bucketName string, days int, ctx context.Context) error {
-	// days = 0 means that the bucket has no expiration
+	config := lifecycle.NewConfiguration()
+
+	// days = 0 means that the bucket has no expiration, an empty configuration removes the lifecycle
 	if days == 0 {
 		s.WrappedLogger.LogInfof("No lifecycle for bucket '%s'", bucketName)
-		return nil
-	}
-
-	config := lifecycle.NewConfiguration()
-	config.Rules = []lifecycle.Rule{
-		{
-			ID:     "expire-bucket",
-			Status: "Enabled",
-			Expiration: lifecycle.Expiration{
-				Days: lifecycle.ExpirationDays(days),
+	} else {
+		config.Rules = []lifecycle.Rule{
+			{
+				ID:     "expire-bucket",
+				Status: "Enabled",
+				Expiration: lifecycle.Expiration{
+					Days: lifecycle.ExpirationDays(days),
+				},
 			},
-		},
+		}
 	}
 	err := s.client.SetBucketLifecycle(ctx, bucketName, config)
 	if err != nil {
 		s.WrappedLogger.LogInfof("Failed setting lifecycle for bucket '%s', error: %w", bucketName, err)
+		return err
 	}
 	return nil
 }
@@ -100,6 +310,9 @@ func (s *Storage) GetBucketExpirationDays(bucketName string, ctx context.Context
 	}
 	// days = 0 means that the bucket has no expiration
 	days := 0
+	if config == nil {
+		return days, nil
+	}
 	for _, rule := range config.Rules {
 		if rule.ID == "expire-bucket" && rule.Status == "Enabled" {
 			days = int(rule.Expiration.Days)
@@ -110,6 +323,35 @@ func (s *Storage) GetBucketExpirationDays(bucketName string, ctx context.Context
 	return days, nil
 }
 
+// BucketInfo describes a bucket of the storage, ExpirationDays is only set when requested.
+type BucketInfo struct {
+	Name           string    `json:"name"`
+	CreationDate   time.Time `json:"creationDate"`
+	ExpirationDays *int      `json:"expirationDays,omitempty"`
+}
+
+// ListBuckets returns every bucket of the storage, including the ones the collector didn't create.
+func (s *Storage) ListBuckets(withExpiration bool, ctx context.Context) ([]BucketInfo, error) {
+	buckets, err := s.client.ListBuckets(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't list the buckets of storage '%s', error: %w", s.client.EndpointURL().Host, err)
+	}
+
+	infos := make([]BucketInfo, 0, len(buckets))
+	for _, bucket := range buckets {
+		info := BucketInfo{Name: bucket.Name, CreationDate: bucket.CreationDate}
+		if withExpiration {
+			days, err := s.GetBucketExpirationDays(bucket.Name, ctx)
+			if err != nil {
+				return nil, err
+			}
+			info.ExpirationDays = &days
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
 func (s *Storage) BucketExists(bucketName string, ctx context.Context) (bool, error) {
 	exists, err := s.client.BucketExists(ctx, bucketName)
 	if err == nil && exists {
@@ -121,35 +363,260 @@ func (s *Storage) BucketExists(bucketName string, ctx context.Context) (bool, er
 }
 
 func (s *Storage) UploadObject(objectName string, bucketName string, object Object, ctx context.Context) error {
+	return s.UploadObjectWithRetention(objectName, bucketName, object, s.DefaultRetention(), ctx)
+}
+
+func (s *Storage) UploadObjectWithRetention(objectName string, bucketName string, object Object, retention Retention, ctx context.Context) error {
+	err := s.checkQuota(bucketName, ctx)
+	if err != nil {
+		return err
+	}
+	s.describePayload(&object)
+	metadata := object.userMetadata()
+	alias, indexTags := object.Alias, object.IndexTags
+	if s.POIBucketName != "" && object.Proof != nil {
+		err := s.uploadPOI(objectName, object, ctx)
+		if err != nil {
+			return err
+		}
+		// the block is stored on its own, its metadata still tells a proof exists
+		object = Object{Block: object.Block}
+	}
+
+	objectReader, contentType, err := object.Encode(s.storeEncoding)
+	if err != nil {
+		return err
+	}
+	size := objectReader.Size()
 
-	objectReader, err := object.GetByteReader()
+	opts := minio.PutObjectOptions{ContentType: contentType, UserMetadata: metadata, PartSize: s.partSize}
+	err = s.applyRetention(&opts, bucketName, retention, ctx)
 	if err != nil {
 		return err
 	}
+	storedSize := s.storedSize(bucketName, objectName, ctx)
+
+	if s.dedupEnabled {
+		// the usage counts the pointers, and the payloads once, as they are stored; deleting a block releases its
+		// pointer only, the payload expiring with the bucket lifecycle
+		stored, err := s.uploadDeduplicated(objectName, bucketName, objectReader, opts, ctx)
+		if err != nil {
+			return err
+		}
+		s.addStoredUsage(bucketName, storedSize, stored)
+		s.recordEvent(EventStore, objectName, bucketName, ctx)
+		return s.indexObject(bucketName, objectName, alias, indexTags, ctx)
+	}
 
 	s.WrappedLogger.LogInfof("Uploading object '%s' to bucket '%s' ...", objectName, bucketName)
-	_, err = s.client.PutObject(ctx, bucketName, objectName+s.objectExtension, objectReader, objectReader.Size(), minio.PutObjectOptions{ContentType: "application/json"})
+	start := time.Now()
+	_, err = s.client.PutObject(ctx, bucketName, s.objectKey(objectName), objectReader, objectReader.Size(), opts)
+	s.metrics.observe(operationUpload, start, err)
 	if err != nil {
 		s.WrappedLogger.LogErrorf("Uploading object '%s' to bucket '%s' ... failed, error: %w", objectName, bucketName, err)
 		return err
 	}
+	s.metrics.addBytesUploaded(size)
+	s.addStoredUsage(bucketName, storedSize, size)
+	s.recordEvent(EventStore, objectName, bucketName, ctx)
 
 	s.WrappedLogger.LogInfof("Uploading object '%s' to bucket '%s' ... done", objectName, bucketName)
+	return s.indexObject(bucketName, objectName, alias, indexTags, ctx)
+}
+
+// UploadLargeObject streams an object of any size to the storage with a multipart upload, a negative size
+// uploads until the reader is exhausted. The content is stored as read, deduplication doesn't apply.
+func (s *Storage) UploadLargeObject(objectName string, bucketName string, reader io.Reader, size int64, contentType string, retention Retention, ctx context.Context) error {
+	if size < 0 {
+		size = -1
+	}
+	err := s.checkQuota(bucketName, ctx)
+	if err != nil {
+		return err
+	}
+	opts := minio.PutObjectOptions{ContentType: contentType, PartSize: s.partSize}
+	err = s.applyRetention(&opts, bucketName, retention, ctx)
+	if err != nil {
+		return err
+	}
+	storedSize := s.storedSize(bucketName, objectName, ctx)
+
+	s.WrappedLogger.LogInfof("Uploading large object '%s' to bucket '%s' ...", objectName, bucketName)
+	start := time.Now()
+	info, err := s.client.PutObject(ctx, bucketName, s.objectKey(objectName), reader, size, opts)
+	s.metrics.observe(operationUpload, start, err)
+	if err != nil {
+		s.WrappedLogger.LogErrorf("Uploading large object '%s' to bucket '%s' ... failed, error: %w", objectName, bucketName, err)
+		return err
+	}
+	s.metrics.addBytesUploaded(info.Size)
+	s.addStoredUsage(bucketName, storedSize, info.Size)
+	s.recordEvent(EventStore, objectName, bucketName, ctx)
+
+	s.WrappedLogger.LogInfof("Uploading large object '%s' to bucket '%s' ... done, %d bytes", objectName, bucketName, info.Size)
 	return nil
 }
 
-func (s *Storage) GetObject(bucketName string, objectName string, ctx context.Context) (*minio.Object, error) {
+// GetObjectInfo returns the stat of an object, ErrNotFound if it doesn't exist.
+// An empty versionId (or versioning disabled) returns the latest version.
+func (s *Storage) GetObjectInfo(bucketName string, objectName string, versionId string, ctx context.Context) (minio.ObjectInfo, error) {
+	start := time.Now()
+	info, err := s.client.StatObject(ctx, bucketName, s.objectKey(objectName), minio.StatObjectOptions{VersionID: s.versionId(versionId)})
+	err = translateError(err)
+	s.metrics.observe(operationStat, start, err)
+	if err != nil {
+		// the object may have expired, while its pinned copy is kept
+		if errors.Is(err, ErrNotFound) && versionId == "" && !isPinnedBucket(bucketName) {
+			if pinned, pinnedErr := s.GetObjectInfo(pinnedBucketName(bucketName), objectName, "", ctx); pinnedErr == nil {
+				return pinned, nil
+			}
+			// or it may have been stored in a batch
+			if s.batchesWritten(bucketName, ctx) {
+				if batched, batchedErr := s.getBatchedInfo(bucketName, objectName, ctx); batchedErr == nil {
+					return batched, nil
+				}
+			}
+		}
+		return info, err
+	}
+
+	// report the payload instead of the deduplication pointer
+	if ref, ok := contentRef(info); ok {
+		return s.GetObjectInfo(bucketName, ref, "", ctx)
+	}
+	return info, nil
+}
+
+// GetObject retrieves an object, an empty versionId (or versioning disabled) retrieves the latest version.
+func (s *Storage) GetObject(bucketName string, objectName string, versionId string, ctx context.Context) (*ObjectReader, error) {
 	s.WrappedLogger.LogInfof("Retrieving object '%s' from bucket '%s' ... ", objectName, bucketName)
-	object, err := s.client.GetObject(ctx, bucketName, objectName+s.objectExtension, minio.GetObjectOptions{})
+	start := time.Now()
+	object, err := s.client.GetObject(ctx, bucketName, s.objectKey(objectName), minio.GetObjectOptions{VersionID: s.versionId(versionId)})
+	err = translateError(err)
+	s.metrics.observe(operationGet, start, err)
 	if err != nil {
+		// the object may have expired, while its pinned copy is kept
+		if errors.Is(err, ErrNotFound) && versionId == "" && !isPinnedBucket(bucketName) {
+			if pinned, pinnedErr := s.GetObject(pinnedBucketName(bucketName), objectName, "", ctx); pinnedErr == nil {
+				return pinned, nil
+			}
+			// or it may have been stored in a batch
+			if s.batchesWritten(bucketName, ctx) {
+				if batched, batchedErr := s.getBatched(bucketName, objectName, ctx); batchedErr == nil {
+					return batched, nil
+				}
+			}
+		}
 		s.WrappedLogger.LogInfof("Retrieving object '%s' from bucket '%s' ... failed, error: %w", objectName, bucketName, err)
 		return nil, err
 	}
 
+	// follow a deduplication pointer to its payload
+	if ref, ok := contentRef(object.Info); ok {
+		object.Close()
+		return s.GetObject(bucketName, ref, "", ctx)
+	}
+	s.metrics.addBytesDownloaded(object.Info.Size)
+
 	s.WrappedLogger.LogInfof("Retrieving object '%s' from bucket '%s' ... done", objectName, bucketName)
 	return object, nil
 }
 
-func (s *Storage) DeleteObject(bucketName string, objectName string, ctx context.Context) error {
-	return s.client.RemoveObject(ctx, bucketName, objectName+s.objectExtension, minio.RemoveObjectOptions{})
+// ObjectEntry is an object found while listing a bucket.
+type ObjectEntry struct {
+	Name         string
+	LastModified time.Time
+	Err          error
+}
+
+// ListObjects lists the collector's objects of a bucket modified after since, a zero since lists them all.
+// The listing stops when ctx is done, an error is sent as the last entry.
+func (s *Storage) ListObjects(bucketName string, since time.Time, ctx context.Context) <-chan ObjectEntry {
+	entries := make(chan ObjectEntry)
+	go func() {
+		defer close(entries)
+		for info := range s.client.ListObjects(ctx, bucketName, minio.ListObjectsOptions{Prefix: s.keyPrefix, Recursive: true}) {
+			var entry ObjectEntry
+			if info.Err != nil {
+				entry.Err = translateError(info.Err)
+			} else {
+				name, ok := s.objectNameFromKey(info.Key)
+				if !ok || info.LastModified.Before(since) {
+					continue
+				}
+				entry = ObjectEntry{Name: name, LastModified: info.LastModified}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case entries <- entry:
+			}
+			if entry.Err != nil {
+				return
+			}
+		}
+	}()
+	return entries
+}
+
+// StreamObject copies an object to w as stored, without buffering it, returning the number of bytes written.
+func (s *Storage) StreamObject(bucketName string, objectName string, versionId string, w io.Writer, ctx context.Context) (int64, error) {
+	object, err := s.GetObject(bucketName, objectName, versionId, ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer object.Close()
+
+	return io.Copy(w, object)
+}
+
+// DeleteObject removes an object. On a versioned bucket an empty versionId only adds a delete marker,
+// use PermanentlyDeleteObject to remove every version.
+func (s *Storage) DeleteObject(bucketName string, objectName string, versionId string, ctx context.Context) error {
+	var alias string
+	var indexTags []string
+	size := int64(-1)
+	if versionId == "" {
+		if info, err := s.client.StatObject(ctx, bucketName, s.objectKey(objectName), minio.StatObjectOptions{}); err == nil {
+			alias = aliasOf(info)
+			indexTags = indexTagsOf(info)
+			size = info.Size
+		}
+	}
+
+	if bucketName == s.DefaultBucketName {
+		s.deletions.markOwn(s.objectKey(objectName))
+	}
+	start := time.Now()
+	err := s.client.RemoveObject(ctx, bucketName, s.objectKey(objectName), minio.RemoveObjectOptions{VersionID: s.versionId(versionId)})
+	err = translateError(err)
+	s.metrics.observe(operationDelete, start, err)
+	if err != nil {
+		return err
+	}
+	if size >= 0 {
+		s.addUsage(bucketName, -1, -size)
+	}
+	s.recordEvent(EventDelete, objectName, bucketName, ctx)
+
+	if alias != "" {
+		err = s.removeAlias(bucketName, alias, objectName, ctx)
+		if err != nil {
+			return err
+		}
+	}
+	err = s.removeIndexTags(bucketName, indexTags, objectName, ctx)
+	if err != nil {
+		return err
+	}
+	// a deleted object must not be served from its pinned copy, nor from a batch
+	if versionId == "" {
+		err = s.removePinned(bucketName, objectName, ctx)
+		if err != nil {
+			return err
+		}
+		return s.deleteBatched(bucketName, objectName, ctx)
+	}
+	return nil
 }
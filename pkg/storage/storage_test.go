@@ -0,0 +1,227 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iotaledger/hive.go/core/configuration"
+	"github.com/minio/minio-go/v7"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	flag "github.com/spf13/pflag"
+)
+
+func TestGetObjectInfo(t *testing.T) {
+	s, backend := newTestStorage(t, nil)
+	ctx := context.Background()
+	object := taggedDataObject("info", "data")
+	object.Alias = "order-1"
+	if err := s.UploadObject("info", s.DefaultBucketName, object, ctx); err != nil {
+		t.Fatalf("can't upload the object: %v", err)
+	}
+
+	info, err := s.GetObjectInfo(s.DefaultBucketName, "info", "", ctx)
+	if err != nil {
+		t.Fatalf("can't stat the object: %v", err)
+	}
+	stored, err := backend.StatObject(ctx, s.DefaultBucketName, s.objectKey("info"), minio.StatObjectOptions{})
+	if err != nil {
+		t.Fatalf("can't stat the stored key: %v", err)
+	}
+	if info.Size != stored.Size || info.Size == 0 || info.ETag != stored.ETag || info.ETag == "" {
+		t.Errorf("got size %d and etag '%s', expected %d and '%s'", info.Size, info.ETag, stored.Size, stored.ETag)
+	}
+	if alias := info.UserMetadata[MetadataAlias]; alias != "order-1" {
+		t.Errorf("got alias metadata '%s', expected 'order-1'", alias)
+	}
+
+	if _, err := s.GetObjectInfo(s.DefaultBucketName, "missing", "", ctx); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got error %v for a missing object, expected ErrNotFound", err)
+	}
+}
+
+func TestNotFound(t *testing.T) {
+	s, _ := newTestStorage(t, func(params *Parameters) {
+		params.VersioningEnabled = true
+	})
+	ctx := context.Background()
+	if err := s.UploadObject("stored", s.DefaultBucketName, taggedDataObject("missing", "data"), ctx); err != nil {
+		t.Fatalf("can't upload the object: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name       string
+		bucketName string
+		objectName string
+		versionId  string
+	}{
+		{"missing object", s.DefaultBucketName, "missing", ""},
+		{"missing bucket", "missing-bucket", "stored", ""},
+		{"missing version", s.DefaultBucketName, "stored", "ffffffffffffffff"},
+	} {
+		if _, err := s.GetObject(tc.bucketName, tc.objectName, tc.versionId, ctx); !errors.Is(err, ErrNotFound) {
+			t.Errorf("GetObject of a %s: got error %v, expected ErrNotFound", tc.name, err)
+		}
+		if _, err := s.GetObjectInfo(tc.bucketName, tc.objectName, tc.versionId, ctx); !errors.Is(err, ErrNotFound) {
+			t.Errorf("GetObjectInfo of a %s: got error %v, expected ErrNotFound", tc.name, err)
+		}
+	}
+	if _, err := s.ListObjectVersions("missing-bucket", "stored", ctx); !errors.Is(err, ErrNotFound) {
+		t.Errorf("ListObjectVersions of a missing bucket: got error %v, expected ErrNotFound", err)
+	}
+}
+
+// listNames returns the names of the objects listed in a bucket.
+func listNames(t *testing.T, s Storage, bucketName string) []string {
+	t.Helper()
+	var names []string
+	for entry := range s.ListObjects(bucketName, time.Time{}, context.Background()) {
+		if entry.Err != nil {
+			t.Fatalf("can't list the objects: %v", entry.Err)
+		}
+		names = append(names, entry.Name)
+	}
+	return names
+}
+
+func TestKeyPrefix(t *testing.T) {
+	s, backend := newTestStorage(t, func(params *Parameters) {
+		params.KeyPrefix = "dataset/"
+	})
+	ctx := context.Background()
+	if err := s.UploadObject("a", s.DefaultBucketName, taggedDataObject("prefix", "data"), ctx); err != nil {
+		t.Fatalf("can't upload the object: %v", err)
+	}
+	// a key of another dataset sharing the bucket
+	if _, err := backend.PutObject(ctx, s.DefaultBucketName, "other/b", strings.NewReader("foreign"), 7, minio.PutObjectOptions{}); err != nil {
+		t.Fatalf("can't put the foreign key: %v", err)
+	}
+
+	if _, err := backend.StatObject(ctx, s.DefaultBucketName, "dataset/a", minio.StatObjectOptions{}); err != nil {
+		t.Errorf("the object is not stored under the prefix: %v", err)
+	}
+	if data := getData(t, s, s.DefaultBucketName, "a"); data != "data" {
+		t.Errorf("got data '%s', expected 'data'", data)
+	}
+	if names := listNames(t, s, s.DefaultBucketName); len(names) != 1 || names[0] != "a" {
+		t.Errorf("got objects %v, expected [a]", names)
+	}
+	if err := s.DeleteObject(s.DefaultBucketName, "a", "", ctx); err != nil {
+		t.Fatalf("can't delete the object: %v", err)
+	}
+	if _, err := backend.StatObject(ctx, s.DefaultBucketName, "dataset/a", minio.StatObjectOptions{}); err == nil {
+		t.Error("the prefixed key was not deleted")
+	}
+}
+
+func TestObjectExtension(t *testing.T) {
+	for _, extension := range []string{"", ".json"} {
+		s, backend := newTestStorage(t, func(params *Parameters) {
+			params.ObjectExtension = extension
+			params.DedupEnabled = true
+		})
+		ctx := context.Background()
+		for _, name := range []string{"a", "b"} {
+			object := taggedDataObject("extension", "data of "+name)
+			object.Alias = "alias of " + name
+			if err := s.UploadObject(name, s.DefaultBucketName, object, ctx); err != nil {
+				t.Fatalf("extension '%s': can't upload object '%s': %v", extension, name, err)
+			}
+		}
+
+		if _, err := backend.StatObject(ctx, s.DefaultBucketName, "a"+extension, minio.StatObjectOptions{}); err != nil {
+			t.Errorf("extension '%s': the object is not stored under its key: %v", extension, err)
+		}
+		if data := getData(t, s, s.DefaultBucketName, "a"); data != "data of a" {
+			t.Errorf("extension '%s': got data '%s', expected 'data of a'", extension, data)
+		}
+		// the payloads and aliases stored next to the blocks are neither listed nor counted
+		if names := listNames(t, s, s.DefaultBucketName); len(names) != 2 || names[0] != "a" || names[1] != "b" {
+			t.Errorf("extension '%s': got objects %v, expected [a b]", extension, names)
+		}
+		if err := s.RefreshObjectCounts([]string{s.DefaultBucketName}, ctx); err != nil {
+			t.Fatalf("extension '%s': can't count the objects: %v", extension, err)
+		}
+		if count := testutil.ToFloat64(s.metrics.objects.WithLabelValues(s.DefaultBucketName)); count != 2 {
+			t.Errorf("extension '%s': got %v objects counted, expected 2", extension, count)
+		}
+	}
+}
+
+func TestStreamObject(t *testing.T) {
+	s, backend := newTestStorage(t, nil)
+	ctx := context.Background()
+	if err := s.UploadObject("streamed", s.DefaultBucketName, taggedDataObject("stream", strings.Repeat("data", 1000)), ctx); err != nil {
+		t.Fatalf("can't upload the object: %v", err)
+	}
+	stored, err := backend.GetObject(ctx, s.DefaultBucketName, s.objectKey("streamed"), minio.GetObjectOptions{})
+	if err != nil {
+		t.Fatalf("can't get the stored key: %v", err)
+	}
+	defer stored.Close()
+	expected, err := io.ReadAll(stored)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var streamed bytes.Buffer
+	written, err := s.StreamObject(s.DefaultBucketName, "streamed", "", &streamed, ctx)
+	if err != nil {
+		t.Fatalf("can't stream the object: %v", err)
+	}
+	if written != int64(len(expected)) || !bytes.Equal(streamed.Bytes(), expected) {
+		t.Errorf("got %d bytes streamed, expected the %d stored bytes", written, len(expected))
+	}
+	if _, err := s.StreamObject(s.DefaultBucketName, "missing", "", &streamed, ctx); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got error %v streaming a missing object, expected ErrNotFound", err)
+	}
+}
+
+// recordingPutBackend records the size and part size of the uploads.
+type recordingPutBackend struct {
+	*MemoryBackend
+	objectSize int64
+	partSize   uint64
+}
+
+func (b *recordingPutBackend) PutObject(ctx context.Context, bucketName string, objectName string, reader io.Reader, objectSize int64, opts minio.PutObjectOptions) (minio.UploadInfo, error) {
+	b.objectSize, b.partSize = objectSize, opts.PartSize
+	return b.MemoryBackend.PutObject(ctx, bucketName, objectName, reader, objectSize, opts)
+}
+
+func TestUploadLargeObject(t *testing.T) {
+	backend := &recordingPutBackend{MemoryBackend: NewMemoryBackend()}
+	s := newTestStorageWithBackend(t, backend, func(params *Parameters) {
+		params.PartSize = minPartSize
+	})
+	ctx := context.Background()
+	// more than two parts, of a size unknown until the stream ends
+	size := 2*minPartSize + 1
+	content := bytes.Repeat([]byte("0123456789abcdef"), size/16+1)[:size]
+	if err := s.UploadLargeObject("large", s.DefaultBucketName, io.MultiReader(bytes.NewReader(content)), -1, ContentTypeBinary, Retention{}, ctx); err != nil {
+		t.Fatalf("can't upload the large object: %v", err)
+	}
+	if backend.objectSize != -1 || backend.partSize != minPartSize {
+		t.Errorf("got size %d and part size %d, expected -1 and %d", backend.objectSize, backend.partSize, minPartSize)
+	}
+
+	var streamed bytes.Buffer
+	if _, err := s.StreamObject(s.DefaultBucketName, "large", "", &streamed, ctx); err != nil {
+		t.Fatalf("can't read the large object: %v", err)
+	}
+	if !bytes.Equal(streamed.Bytes(), content) {
+		t.Errorf("got %d bytes stored, expected the %d uploaded", streamed.Len(), len(content))
+	}
+
+	params := &Parameters{}
+	configuration.New().BindParameters(configuration.NewUnsortedFlagSet("test", flag.ContinueOnError), "storage", params)
+	params.PartSize = minPartSize - 1
+	if _, err := NewStorageWithBackend(*params, NewMemoryBackend(), prometheus.NewRegistry(), s.WrappedLogger); err == nil || !strings.Contains(err.Error(), "part size") {
+		t.Errorf("got error %v creating a storage with a part size below the minimum", err)
+	}
+}
@@ -0,0 +1,29 @@
+package storage
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// newTransport returns the HTTP transport of the minio client: the minio defaults, with the configured timeouts.
+func newTransport(params Parameters) (*http.Transport, error) {
+	if params.DialTimeout <= 0 || params.TLSHandshakeTimeout <= 0 || params.ResponseHeaderTimeout <= 0 {
+		return nil, fmt.Errorf("dial, TLS handshake and response header timeouts must be positive")
+	}
+
+	transport, err := minio.DefaultTransport(params.Secure)
+	if err != nil {
+		return nil, err
+	}
+	transport.DialContext = (&net.Dialer{
+		Timeout:   params.DialTimeout,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	transport.TLSHandshakeTimeout = params.TLSHandshakeTimeout
+	transport.ResponseHeaderTimeout = params.ResponseHeaderTimeout
+	return transport, nil
+}
@@ -0,0 +1,110 @@
+package storage
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/iotaledger/hive.go/core/configuration"
+	"github.com/minio/minio-go/v7"
+	flag "github.com/spf13/pflag"
+)
+
+// transportParams returns the default parameters of a storage reached without TLS at endpoint.
+func transportParams(endpoint string) Parameters {
+	params := &Parameters{}
+	configuration.New().BindParameters(configuration.NewUnsortedFlagSet("test", flag.ContinueOnError), "storage", params)
+	params.Endpoint = endpoint
+	params.Secure = false
+	params.AccessKeyID = "access"
+	params.SecretAccessKey = "secret"
+	return *params
+}
+
+func TestTransportParameters(t *testing.T) {
+	params := transportParams("localhost:9000")
+	params.TLSHandshakeTimeout = 3 * time.Second
+	params.ResponseHeaderTimeout = 4 * time.Second
+	transport, err := newTransport(params)
+	if err != nil {
+		t.Fatalf("can't create the transport: %v", err)
+	}
+	if transport.TLSHandshakeTimeout != 3*time.Second || transport.ResponseHeaderTimeout != 4*time.Second {
+		t.Errorf("got TLS handshake timeout %v and response header timeout %v, expected 3s and 4s", transport.TLSHandshakeTimeout, transport.ResponseHeaderTimeout)
+	}
+
+	for _, configure := range []func(params *Parameters){
+		func(params *Parameters) { params.DialTimeout = 0 },
+		func(params *Parameters) { params.TLSHandshakeTimeout = -time.Second },
+		func(params *Parameters) { params.ResponseHeaderTimeout = 0 },
+		func(params *Parameters) { params.MaxRetries = 0 },
+	} {
+		invalid := transportParams("localhost:9000")
+		configure(&invalid)
+		if _, err := newMinioBackend(invalid); err == nil {
+			t.Errorf("a backend was created with parameters %+v", invalid)
+		}
+	}
+}
+
+func TestTransportTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// slower than the response header timeout
+		select {
+		case <-release:
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	params := transportParams(strings.TrimPrefix(server.URL, "http://"))
+	params.ResponseHeaderTimeout = 100 * time.Millisecond
+	backend, err := newMinioBackend(params)
+	if err != nil {
+		t.Fatalf("can't create the backend: %v", err)
+	}
+	start := time.Now()
+	if _, err := backend.BucketExists(context.Background(), "bucket"); err == nil {
+		t.Fatal("a request to a storage not answering in time succeeded")
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("the request failed after %v, the response header timeout was not applied", elapsed)
+	}
+}
+
+func TestTransportRetries(t *testing.T) {
+	defer func(maxRetry int) { minio.MaxRetry = maxRetry }(minio.MaxRetry)
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the client looks the region of the bucket up first
+		if r.URL.Query().Has("location") {
+			w.Write([]byte(`<LocationConstraint xmlns="http://s3.amazonaws.com/doc/2006-03-01/">us-east-1</LocationConstraint>`))
+			return
+		}
+		requests.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	for _, maxRetries := range []int{1, 2} {
+		params := transportParams(strings.TrimPrefix(server.URL, "http://"))
+		params.MaxRetries = maxRetries
+		backend, err := newMinioBackend(params)
+		if err != nil {
+			t.Fatalf("can't create the backend: %v", err)
+		}
+		requests.Store(0)
+		if _, err := backend.BucketExists(context.Background(), "bucket"); err == nil {
+			t.Fatal("a request to an unavailable storage succeeded")
+		}
+		if n := requests.Load(); int(n) != maxRetries {
+			t.Errorf("got %d attempts, expected %d", n, maxRetries)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+type ObjectVersion struct {
+	VersionId      string    `json:"versionId"`
+	LastModified   time.Time `json:"lastModified"`
+	Size           int64     `json:"size"`
+	IsLatest       bool      `json:"isLatest"`
+	IsDeleteMarker bool      `json:"isDeleteMarker"`
+}
+
+// versionId drops the requested version when versioning is disabled, so non-versioned buckets behave as usual.
+func (s *Storage) versionId(versionId string) string {
+	if !s.VersioningEnabled {
+		return ""
+	}
+	return versionId
+}
+
+func (s *Storage) ListObjectVersions(bucketName string, objectName string, ctx context.Context) ([]ObjectVersion, error) {
+	var versions []ObjectVersion
+	if !s.VersioningEnabled {
+		return versions, fmt.Errorf("versioning is not enabled")
+	}
+
+	key := s.objectKey(objectName)
+	for info := range s.client.ListObjects(ctx, bucketName, minio.ListObjectsOptions{Prefix: key, WithVersions: true}) {
+		if info.Err != nil {
+			return versions, translateError(info.Err)
+		}
+		// the prefix may match longer keys too
+		if info.Key != key {
+			continue
+		}
+		versions = append(versions, ObjectVersion{
+			VersionId:      info.VersionID,
+			LastModified:   info.LastModified,
+			Size:           info.Size,
+			IsLatest:       info.IsLatest,
+			IsDeleteMarker: info.IsDeleteMarker,
+		})
+	}
+
+	if len(versions) == 0 {
+		return versions, ErrNotFound
+	}
+	return versions, nil
+}
+
+// PermanentlyDeleteObject removes every version of an object, delete markers included.
+func (s *Storage) PermanentlyDeleteObject(bucketName string, objectName string, ctx context.Context) error {
+	versions, err := s.ListObjectVersions(bucketName, objectName, ctx)
+	if err != nil {
+		return err
+	}
+
+	s.WrappedLogger.LogInfof("Permanently deleting object '%s' from bucket '%s' ...", objectName, bucketName)
+	for _, version := range versions {
+		start := time.Now()
+		err = translateError(s.client.RemoveObject(ctx, bucketName, s.objectKey(objectName), minio.RemoveObjectOptions{VersionID: version.VersionId}))
+		s.metrics.observe(operationDelete, start, err)
+		if err != nil {
+			s.WrappedLogger.LogErrorf("Permanently deleting object '%s' from bucket '%s' ... failed, error: %w", objectName, bucketName, err)
+			return err
+		}
+	}
+	err = s.deleteBatched(bucketName, objectName, ctx)
+	if err != nil {
+		s.WrappedLogger.LogErrorf("Permanently deleting object '%s' from bucket '%s' ... failed, error: %w", objectName, bucketName, err)
+		return err
+	}
+
+	s.recordEvent(EventDelete, objectName, bucketName, ctx)
+
+	s.WrappedLogger.LogInfof("Permanently deleting object '%s' from bucket '%s' ... done", objectName, bucketName)
+	return nil
+}
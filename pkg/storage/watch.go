@@ -0,0 +1,192 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/notification"
+)
+
+const (
+	// deletionEvents are the notifications of removed objects, including the delete markers of versioned buckets.
+	deletionEvents = "s3:ObjectRemoved:*"
+
+	minWatchReconnectDelay = time.Second
+	maxWatchReconnectDelay = 5 * time.Minute
+	// aliasSweepInterval is how often the alias index is cleaned after external deletions, and the collector's own
+	// deletions are forgotten
+	aliasSweepInterval = 10 * time.Minute
+)
+
+// notificationBackend is a Backend notifying the changes of a bucket, as MinIO does.
+type notificationBackend interface {
+	ListenBucketNotification(ctx context.Context, bucketName string, prefix string, suffix string, events []string) <-chan notification.Info
+}
+
+// deletionWatch follows the removals of the default bucket, to catch the blocks deleted behind the collector's back,
+// by a lifecycle rule or an administrator.
+type deletionWatch struct {
+	// own holds the keys the collector removed itself, with the time of the removal, their notifications are ignored
+	own sync.Map
+	// aliasesStale is set when aliases may resolve to removed blocks
+	aliasesStale atomic.Bool
+}
+
+func newDeletionWatch(params Parameters) *deletionWatch {
+	if !params.WatchDeletions {
+		return nil
+	}
+	return &deletionWatch{}
+}
+
+// markOwn records that the collector removes an object, for its notification not to be taken for an external deletion.
+func (w *deletionWatch) markOwn(key string) {
+	if w == nil {
+		return
+	}
+	w.own.Store(key, time.Now())
+}
+
+func (b *reloadableBackend) ListenBucketNotification(ctx context.Context, bucketName string, prefix string, suffix string, events []string) <-chan notification.Info {
+	return b.current().ListenBucketNotification(ctx, bucketName, prefix, suffix, events)
+}
+
+// RunDeletionWatch listens to the removals notified by the storage for the default bucket until ctx is done, recording
+// the blocks deleted outside of the collector in the event log and cleaning the alias index after them. A dropped
+// notification stream is opened again, waiting longer after every attempt without notifications; the deletions
+// happening meanwhile are missed.
+func (s *Storage) RunDeletionWatch(ctx context.Context) {
+	if s.deletions == nil {
+		return
+	}
+	backend, ok := s.client.(notificationBackend)
+	if !ok {
+		s.WrappedLogger.LogWarn("The storage doesn't notify deletions, external deletions won't be detected")
+		return
+	}
+	go s.runAliasSweep(ctx)
+
+	delay := minWatchReconnectDelay
+	for {
+		s.WrappedLogger.LogInfof("Watching deletions in bucket '%s' ...", s.DefaultBucketName)
+		if s.watchDeletions(backend, ctx) {
+			delay = minWatchReconnectDelay
+		}
+		if ctx.Err() != nil {
+			s.WrappedLogger.LogInfof("Watching deletions in bucket '%s' ... done", s.DefaultBucketName)
+			return
+		}
+		s.WrappedLogger.LogWarnf("Watching deletions in bucket '%s' ... interrupted, reconnecting in %s", s.DefaultBucketName, delay)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay *= 2
+		if delay > maxWatchReconnectDelay {
+			delay = maxWatchReconnectDelay
+		}
+	}
+}
+
+// watchDeletions handles the notifications until the stream ends, returning whether it delivered any.
+func (s *Storage) watchDeletions(backend notificationBackend, ctx context.Context) bool {
+	received := false
+	for info := range backend.ListenBucketNotification(ctx, s.DefaultBucketName, s.keyPrefix, s.objectExtension, []string{deletionEvents}) {
+		if info.Err != nil {
+			if ctx.Err() == nil {
+				s.WrappedLogger.LogWarnf("Watching deletions in bucket '%s' ... failed, error: %w", s.DefaultBucketName, info.Err)
+			}
+			continue
+		}
+		received = true
+		for _, record := range info.Records {
+			s.handleDeletion(record, ctx)
+		}
+	}
+	return received
+}
+
+// handleDeletion records the removal of a block by someone else than the collector. The removal of a noncurrent
+// version, or of a block stored again since, leaves the block stored and is ignored.
+func (s *Storage) handleDeletion(record notification.Event, ctx context.Context) {
+	// keys are URL encoded in the notifications
+	key, err := url.QueryUnescape(record.S3.Object.Key)
+	if err != nil {
+		key = record.S3.Object.Key
+	}
+	if _, own := s.deletions.own.LoadAndDelete(key); own {
+		return
+	}
+	objectName, ok := s.objectNameFromKey(key)
+	if !ok {
+		return
+	}
+	_, err = s.client.StatObject(ctx, s.DefaultBucketName, key, minio.StatObjectOptions{})
+	if !errors.Is(translateError(err), ErrNotFound) {
+		return
+	}
+
+	s.WrappedLogger.LogInfof("Block '%s' was deleted from bucket '%s' outside of the collector", objectName, s.DefaultBucketName)
+	s.recordEvent(EventDelete, objectName, s.DefaultBucketName, ContextWithOrigin(ctx, OriginExternal))
+	s.deletions.aliasesStale.Store(true)
+}
+
+// runAliasSweep removes, every aliasSweepInterval after external deletions, the aliases of the default bucket
+// resolving to blocks no longer stored. Such aliases already resolve to nothing, the sweep only keeps the index tidy.
+func (s *Storage) runAliasSweep(ctx context.Context) {
+	ticker := time.NewTicker(aliasSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// the notifications of the own removals have arrived long ago, or will never arrive
+		s.deletions.own.Range(func(key, removedAt any) bool {
+			if time.Since(removedAt.(time.Time)) > aliasSweepInterval {
+				s.deletions.own.Delete(key)
+			}
+			return true
+		})
+		if s.deletions.aliasesStale.Swap(false) {
+			s.sweepAliases(s.DefaultBucketName, ctx)
+		}
+	}
+}
+
+func (s *Storage) sweepAliases(bucketName string, ctx context.Context) {
+	s.WrappedLogger.LogInfof("Removing the stale aliases of bucket '%s' ...", bucketName)
+	removed := 0
+	for info := range s.client.ListObjects(ctx, bucketName, minio.ListObjectsOptions{Prefix: s.keyPrefix + aliasesPrefix, Recursive: true}) {
+		if info.Err != nil {
+			s.WrappedLogger.LogWarnf("Removing the stale aliases of bucket '%s' ... failed, error: %w", bucketName, translateError(info.Err))
+			s.deletions.aliasesStale.Store(true)
+			return
+		}
+		alias, err := url.PathUnescape(strings.TrimSuffix(strings.TrimPrefix(info.Key, s.keyPrefix+aliasesPrefix), s.objectExtension))
+		if err != nil {
+			continue
+		}
+		entry, err := s.getAlias(bucketName, alias, ctx)
+		if err != nil {
+			continue
+		}
+		_, err = s.GetObjectInfo(bucketName, entry.BlockId, "", ctx)
+		if !errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if s.removeAlias(bucketName, alias, entry.BlockId, ctx) == nil {
+			removed++
+		}
+	}
+	s.WrappedLogger.LogInfof("Removing the stale aliases of bucket '%s' ... done, %d removed", bucketName, removed)
+}
@@ -0,0 +1,145 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/notification"
+)
+
+// notifyingBackend hands out the notification streams of streams, one per call to ListenBucketNotification.
+type notifyingBackend struct {
+	*MemoryBackend
+	sync.Mutex
+	streams chan chan notification.Info
+	listens int
+}
+
+func (b *notifyingBackend) ListenBucketNotification(ctx context.Context, bucketName string, prefix string, suffix string, events []string) <-chan notification.Info {
+	b.Lock()
+	b.listens++
+	b.Unlock()
+	select {
+	case stream := <-b.streams:
+		return stream
+	case <-ctx.Done():
+		closed := make(chan notification.Info)
+		close(closed)
+		return closed
+	}
+}
+
+func (b *notifyingBackend) listened() int {
+	b.Lock()
+	defer b.Unlock()
+	return b.listens
+}
+
+// removal returns the notification of the removal of a key.
+func removal(keys ...string) notification.Info {
+	var info notification.Info
+	for _, key := range keys {
+		var event notification.Event
+		event.EventName = "s3:ObjectRemoved:Delete"
+		event.S3.Object.Key = url.QueryEscape(key)
+		info.Records = append(info.Records, event)
+	}
+	return info
+}
+
+func TestDeletionWatch(t *testing.T) {
+	backend := &notifyingBackend{MemoryBackend: NewMemoryBackend(), streams: make(chan chan notification.Info, 2)}
+	s := newTestStorageWithBackend(t, backend, func(params *Parameters) {
+		eventParams(100)(params)
+		params.WatchDeletions = true
+	})
+	ctx := context.Background()
+	if _, err := s.CheckCreateBucket(s.EventsBucketName, ctx); err != nil {
+		t.Fatalf("can't create the events bucket: %v", err)
+	}
+	for _, name := range []string{"external", "own", "later", "kept"} {
+		object := taggedDataObject("watch", name)
+		object.Alias = "alias of " + name
+		if err := s.UploadObject(name, s.DefaultBucketName, object, ctx); err != nil {
+			t.Fatalf("can't upload object '%s': %v", name, err)
+		}
+	}
+	removeExternally := func(name string) {
+		t.Helper()
+		if err := backend.RemoveObject(ctx, s.DefaultBucketName, s.objectKey(name), minio.RemoveObjectOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	deletions := func() []Event {
+		var deleted []Event
+		for _, event := range readEvents(t, s, 0) {
+			if event.Type == EventDelete {
+				deleted = append(deleted, event)
+			}
+		}
+		return deleted
+	}
+	waitForDeletions := func(count int) []Event {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			deleted := deletions()
+			if len(deleted) >= count || time.Now().After(deadline) {
+				return deleted
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go s.RunEventLog(watchCtx)
+	go s.RunDeletionWatch(watchCtx)
+
+	// the collector's own deletions and the blocks still stored are not external deletions
+	removeExternally("external")
+	if err := s.DeleteObject(s.DefaultBucketName, "own", "", ctx); err != nil {
+		t.Fatalf("can't delete object 'own': %v", err)
+	}
+	first := make(chan notification.Info, 1)
+	backend.streams <- first
+	first <- removal(s.objectKey("external"), s.objectKey("own"), s.objectKey("kept"))
+	deleted := waitForDeletions(2)
+	if len(deleted) != 2 || deleted[0].BlockId != "own" || deleted[1].BlockId != "external" || deleted[1].Origin != OriginExternal {
+		t.Fatalf("got deletions %+v, expected 'own' and 'external' deleted externally", deleted)
+	}
+
+	// a dropped stream is opened again
+	close(first)
+	second := make(chan notification.Info, 1)
+	backend.streams <- second
+	removeExternally("later")
+	second <- removal(s.objectKey("later"))
+	deleted = waitForDeletions(3)
+	if len(deleted) != 3 || deleted[2].BlockId != "later" || deleted[2].Origin != OriginExternal {
+		t.Errorf("got deletions %+v, expected 'later' deleted externally after the reconnection", deleted)
+	}
+	if n := backend.listened(); n != 2 {
+		t.Errorf("got %d listens, expected 2", n)
+	}
+
+	// the aliases of the blocks deleted externally are swept
+	if !s.deletions.aliasesStale.Load() {
+		t.Error("got the aliases up to date, expected them stale after external deletions")
+	}
+	s.sweepAliases(s.DefaultBucketName, ctx)
+	for name, kept := range map[string]bool{"external": false, "later": false, "kept": true} {
+		_, err := s.getAlias(s.DefaultBucketName, "alias of "+name, ctx)
+		if kept && err != nil {
+			t.Errorf("got error %v for the alias of '%s', expected it kept", err, name)
+		}
+		if !kept && !errors.Is(err, ErrNotFound) {
+			t.Errorf("got error %v for the alias of '%s', expected it swept", err, name)
+		}
+	}
+}